@@ -0,0 +1,238 @@
+// Teleport
+// Copyright (C) 2024 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package iam builds the signed sts:GetCallerIdentity request a node
+// presents to prove it's running in a given AWS account/role as part of the
+// IAM join method: Auth never sees the node's AWS credentials, only the
+// signed request, which it replays to AWS STS and inspects the response
+// from.
+package iam
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/gravitational/trace"
+)
+
+// challengeHeader is the header carrying the Auth-issued join challenge, so
+// that the signature over the request also covers (and thus proves
+// freshness for) the specific challenge Auth sent.
+const challengeHeader = "x-teleport-challenge"
+
+// stsRequestBody is the fixed body of the signed sts:GetCallerIdentity
+// request.
+const stsRequestBody = "Action=GetCallerIdentity&Version=2011-06-15"
+
+// disableFIPSEnvVar overrides WithFIPSEndpoint(true) when set, for
+// environments (e.g. AWS China, some GovCloud partitions) where the FIPS
+// STS endpoint either doesn't exist or isn't reachable.
+const disableFIPSEnvVar = "TELEPORT_UNSTABLE_DISABLE_AWS_FIPS"
+
+// defaultRegion is used when neither the environment nor IMDS report a
+// region.
+const defaultRegion = "us-east-1"
+
+// regionGetter is the minimal IMDS surface CreateSignedSTSIdentityRequest
+// needs to discover the instance's region when AWS_REGION isn't set. The
+// production implementation is IMDSClient (imds.go); tests substitute a
+// fake.
+type regionGetter interface {
+	// IsAvailable reports whether the instance metadata service can be
+	// reached from the current host.
+	IsAvailable(ctx context.Context) bool
+	// GetRegion returns the region of the instance IMDS is running on.
+	GetRegion(ctx context.Context) (string, error)
+}
+
+// stsIdentityRequestConfig holds the options CreateSignedSTSIdentityRequest
+// callers may set via ConfigOption.
+type stsIdentityRequestConfig struct {
+	fipsEndpoint   bool
+	dualStack      bool
+	customEndpoint string
+	imdsClient     regionGetter
+}
+
+// ConfigOption configures CreateSignedSTSIdentityRequest.
+type ConfigOption func(cfg *stsIdentityRequestConfig)
+
+// WithFIPSEndpoint selects the FIPS 140-2 validated STS endpoint when fips
+// is true, unless overridden by the TELEPORT_UNSTABLE_DISABLE_AWS_FIPS
+// environment variable.
+func WithFIPSEndpoint(fips bool) ConfigOption {
+	return func(cfg *stsIdentityRequestConfig) {
+		cfg.fipsEndpoint = fips
+	}
+}
+
+// WithIMDSClient sets the instance metadata client used to discover the
+// region when AWS_REGION isn't set in the environment.
+func WithIMDSClient(client regionGetter) ConfigOption {
+	return func(cfg *stsIdentityRequestConfig) {
+		cfg.imdsClient = client
+	}
+}
+
+// WithCustomSTSEndpoint signs the request against host instead of a public
+// STS endpoint, for nodes in isolated VPCs that only have a route to an STS
+// VPC interface endpoint (e.g.
+// "vpce-0123456789abcdef0.sts.us-west-2.vpce.amazonaws.com"). The signing
+// region is still resolved the normal way (AWS_REGION, then IMDS, then
+// defaultRegion), since interface endpoints are regional. Takes precedence
+// over WithFIPSEndpoint and WithDualStack.
+func WithCustomSTSEndpoint(host string) ConfigOption {
+	return func(cfg *stsIdentityRequestConfig) {
+		cfg.customEndpoint = host
+	}
+}
+
+// WithDualStack selects the dual-stack STS endpoint (sts.<region>.api.aws)
+// over the IPv4-only sts.<region>.amazonaws.com. Ignored if
+// WithCustomSTSEndpoint is also set, and also ignored when the FIPS
+// endpoint is in use, since AWS does not publish a combined
+// FIPS/dual-stack STS endpoint.
+func WithDualStack(dualStack bool) ConfigOption {
+	return func(cfg *stsIdentityRequestConfig) {
+		cfg.dualStack = dualStack
+	}
+}
+
+// CreateSignedSTSIdentityRequest creates a SigV4-signed HTTP request for the
+// sts:GetCallerIdentity API, serialized to its raw wire bytes, with
+// challenge bound into the signature via the x-teleport-challenge header.
+// Auth sends this unmodified to the chosen STS endpoint and inspects the
+// response to establish which AWS identity signed it.
+func CreateSignedSTSIdentityRequest(ctx context.Context, challenge string, opts ...ConfigOption) ([]byte, error) {
+	var cfg stsIdentityRequestConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	region, err := resolveRegion(ctx, cfg.imdsClient)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	endpoint, signingRegion := stsEndpoint(region, useFIPSEndpoint(cfg.fipsEndpoint), cfg.dualStack)
+	if cfg.customEndpoint != "" {
+		endpoint, signingRegion = cfg.customEndpoint, region
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(signingRegion))
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to load default AWS config")
+	}
+	creds, err := awsCfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to resolve AWS credentials")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+endpoint+"/", strings.NewReader(stsRequestBody))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Set(challengeHeader, challenge)
+	req.Host = endpoint
+
+	bodyHash := sha256.Sum256([]byte(stsRequestBody))
+	signer := v4.NewSigner()
+	if err := signer.SignHTTP(ctx, creds, req, hex.EncodeToString(bodyHash[:]), "sts", signingRegion, time.Now()); err != nil {
+		return nil, trace.Wrap(err, "failed to sign sts:GetCallerIdentity request")
+	}
+
+	var buf bytes.Buffer
+	if err := req.Write(&buf); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resolveRegion returns, in order of preference: the AWS_REGION
+// environment variable, the region reported by imds (if available), or
+// defaultRegion.
+func resolveRegion(ctx context.Context, imds regionGetter) (string, error) {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region, nil
+	}
+	if imds != nil && imds.IsAvailable(ctx) {
+		region, err := imds.GetRegion(ctx)
+		if err != nil {
+			return "", trace.Wrap(err)
+		}
+		if region != "" {
+			return region, nil
+		}
+	}
+	return defaultRegion, nil
+}
+
+// useFIPSEndpoint applies the TELEPORT_UNSTABLE_DISABLE_AWS_FIPS override
+// to the caller's requested FIPS setting.
+func useFIPSEndpoint(requested bool) bool {
+	if !requested {
+		return false
+	}
+	return os.Getenv(disableFIPSEnvVar) == ""
+}
+
+// isGovCloudRegion reports whether region is in the AWS GovCloud partition,
+// whose STS endpoints are FIPS-validated unconditionally and never carry
+// the "-fips" infix.
+func isGovCloudRegion(region string) bool {
+	return strings.HasPrefix(region, "us-gov-")
+}
+
+// isUSRegion reports whether region is in the AWS standard partition's US
+// geography (including GovCloud), the only regions with a FIPS STS
+// endpoint of their own.
+func isUSRegion(region string) bool {
+	return strings.HasPrefix(region, "us-")
+}
+
+// stsEndpoint returns the STS endpoint host to send the signed request to,
+// and the region the signature should be computed for (which can differ
+// from the instance's own region: non-US regions have no FIPS endpoint of
+// their own and fall back to us-east-1's). Callers wanting a custom (e.g.
+// VPC interface) endpoint bypass this entirely; dualStack is only consulted
+// when fips is false, since AWS does not publish a combined FIPS/dual-stack
+// STS endpoint.
+func stsEndpoint(region string, fips, dualStack bool) (endpoint, signingRegion string) {
+	if isGovCloudRegion(region) {
+		// All GovCloud STS endpoints are FIPS already; there's no
+		// "sts-fips" variant to opt into.
+		return "sts." + region + ".amazonaws.com", region
+	}
+	if fips {
+		if isUSRegion(region) {
+			return "sts-fips." + region + ".amazonaws.com", region
+		}
+		return "sts-fips." + defaultRegion + ".amazonaws.com", defaultRegion
+	}
+	if dualStack {
+		return "sts." + region + ".api.aws", region
+	}
+	return "sts." + region + ".amazonaws.com", region
+}
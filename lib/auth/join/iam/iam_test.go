@@ -45,6 +45,8 @@ func TestCreateSignedSTSIdentityRequest(t *testing.T) {
 		imdsRegion            string
 		fipsMode              bool
 		fipsDisabledByEnv     bool
+		dualStack             bool
+		customEndpoint        string
 		expectError           string
 		expectEndpoint        string
 		expectSignatureRegion string
@@ -168,6 +170,38 @@ func TestCreateSignedSTSIdentityRequest(t *testing.T) {
 			expectEndpoint:        "sts.us-gov-east-1.amazonaws.com",
 			expectSignatureRegion: "us-gov-east-1",
 		},
+		"dual-stack us-west-2": {
+			envRegion:             "us-west-2",
+			dualStack:             true,
+			expectEndpoint:        "sts.us-west-2.api.aws",
+			expectSignatureRegion: "us-west-2",
+		},
+		"dual-stack no region": {
+			dualStack:             true,
+			expectEndpoint:        "sts.us-east-1.api.aws",
+			expectSignatureRegion: "us-east-1",
+		},
+		"dual-stack ignored when fips also requested": {
+			envRegion: "us-west-2",
+			dualStack: true,
+			fipsMode:  true,
+			// AWS does not publish a combined FIPS/dual-stack STS endpoint.
+			expectEndpoint:        "sts-fips.us-west-2.amazonaws.com",
+			expectSignatureRegion: "us-west-2",
+		},
+		"vpce custom endpoint": {
+			envRegion:             "us-west-2",
+			customEndpoint:        "vpce-0123456789abcdef0.sts.us-west-2.vpce.amazonaws.com",
+			expectEndpoint:        "vpce-0123456789abcdef0.sts.us-west-2.vpce.amazonaws.com",
+			expectSignatureRegion: "us-west-2",
+		},
+		"vpce custom endpoint takes precedence over fips": {
+			envRegion:             "us-west-2",
+			fipsMode:              true,
+			customEndpoint:        "vpce-0123456789abcdef0.sts.us-west-2.vpce.amazonaws.com",
+			expectEndpoint:        "vpce-0123456789abcdef0.sts.us-west-2.vpce.amazonaws.com",
+			expectSignatureRegion: "us-west-2",
+		},
 	} {
 		t.Run(desc, func(t *testing.T) {
 			if len(tc.envRegion) > 0 {
@@ -193,6 +227,8 @@ func TestCreateSignedSTSIdentityRequest(t *testing.T) {
 			// HTTP request with a body serialized into a byte slice.
 			req, err := iam.CreateSignedSTSIdentityRequest(ctx, challenge,
 				iam.WithFIPSEndpoint(tc.fipsMode),
+				iam.WithDualStack(tc.dualStack),
+				iam.WithCustomSTSEndpoint(tc.customEndpoint),
 				iam.WithIMDSClient(imdsClient))
 			if tc.expectError != "" {
 				assert.Error(t, err)
@@ -210,6 +246,7 @@ func TestCreateSignedSTSIdentityRequest(t *testing.T) {
 			sigV4, err := aws.ParseSigV4(authHeader)
 			require.NoError(t, err)
 			assert.Contains(t, sigV4.SignedHeaders, "x-teleport-challenge")
+			assert.Contains(t, sigV4.SignedHeaders, "host")
 			assert.Equal(t, challenge, httpReq.Header.Get("x-teleport-challenge"))
 			assert.Equal(t, tc.expectSignatureRegion, sigV4.Region, "signature region did not match expected")
 		})
@@ -0,0 +1,249 @@
+// Teleport
+// Copyright (C) 2024 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// defaultIMDSBaseURL is the well-known link-local address EC2 instances
+// reach their metadata service on.
+const defaultIMDSBaseURL = "http://169.254.169.254/latest"
+
+const (
+	imdsTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+	imdsTokenHeader    = "X-aws-ec2-metadata-token"
+
+	// defaultTokenTTL is the session token lifetime requested on PUT
+	// /latest/api/token, matching the AWS CLI/SDK default.
+	defaultTokenTTL = 21600 * time.Second
+
+	// defaultTokenRefreshSkew is how far ahead of the cached token's actual
+	// expiry IMDSClient proactively fetches a new one, so a call never
+	// races a token expiring mid-flight.
+	defaultTokenRefreshSkew = 60 * time.Second
+)
+
+// InstanceIdentityDocument is the subset of the EC2 instance identity
+// document IMDSClient.GetInstanceIdentityDocument needs callers to
+// cross-check a signed sts:GetCallerIdentity response against: a leaked
+// static credential replayed from outside EC2 won't have a matching
+// document to present.
+type InstanceIdentityDocument struct {
+	AccountID  string `json:"accountId"`
+	Region     string `json:"region"`
+	InstanceID string `json:"instanceId"`
+}
+
+// IMDSClientConfig configures IMDSClient.
+type IMDSClientConfig struct {
+	// HTTPClient is used for all IMDS requests. Defaults to a client with a
+	// short timeout, since IMDS is either reachable on the link-local
+	// address immediately or not reachable at all.
+	HTTPClient *http.Client
+	// BaseURL overrides the IMDS base URL. Defaults to defaultIMDSBaseURL;
+	// only ever set in tests.
+	BaseURL string
+	// TokenTTL is requested on each IMDSv2 token issuance. Defaults to
+	// defaultTokenTTL.
+	TokenTTL time.Duration
+	// TokenRefreshSkew is how far ahead of a cached token's expiry a new
+	// one is proactively fetched. Defaults to defaultTokenRefreshSkew.
+	TokenRefreshSkew time.Duration
+}
+
+func (c *IMDSClientConfig) checkAndSetDefaults() {
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: 500 * time.Millisecond}
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = defaultIMDSBaseURL
+	}
+	if c.TokenTTL <= 0 {
+		c.TokenTTL = defaultTokenTTL
+	}
+	if c.TokenRefreshSkew <= 0 {
+		c.TokenRefreshSkew = defaultTokenRefreshSkew
+	}
+}
+
+func (c *IMDSClientConfig) tokenURL() string {
+	return c.BaseURL + "/api/token"
+}
+
+func (c *IMDSClientConfig) instanceIdentityDocumentURL() string {
+	return c.BaseURL + "/dynamic/instance-identity/document"
+}
+
+// IMDSClient implements the IMDSv2 session-token protocol: it fetches and
+// caches a token via PUT /latest/api/token, attaches it to every
+// GET /latest/meta-data/... call, and transparently refreshes it when the
+// cached TTL runs low or a call comes back 401 (the token expired or was
+// never issued).
+type IMDSClient struct {
+	cfg IMDSClientConfig
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewIMDSClient returns an IMDSClient with no cached token; the first call
+// that needs one fetches it.
+func NewIMDSClient(cfg IMDSClientConfig) *IMDSClient {
+	cfg.checkAndSetDefaults()
+	return &IMDSClient{cfg: cfg}
+}
+
+// IsAvailable reports whether the instance metadata service can be reached
+// from the current host, by attempting to obtain a session token.
+func (c *IMDSClient) IsAvailable(ctx context.Context) bool {
+	_, err := c.getToken(ctx)
+	return err == nil
+}
+
+// GetRegion returns the region of the instance IMDS is running on.
+func (c *IMDSClient) GetRegion(ctx context.Context) (string, error) {
+	doc, err := c.GetInstanceIdentityDocument(ctx)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return doc.Region, nil
+}
+
+// GetInstanceIdentityDocument fetches and parses the instance identity
+// document, so callers can cross-check the account ID, region and instance
+// ID it reports against a signed sts:GetCallerIdentity result: the two
+// should always agree for a request genuinely created on this instance.
+func (c *IMDSClient) GetInstanceIdentityDocument(ctx context.Context) (*InstanceIdentityDocument, error) {
+	body, err := c.get(ctx, c.cfg.instanceIdentityDocumentURL())
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var doc InstanceIdentityDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, trace.Wrap(err, "failed to parse instance identity document")
+	}
+	return &doc, nil
+}
+
+// get issues an authenticated GET against the IMDS endpoint path, fetching
+// a session token first if none is cached, and transparently fetching a
+// fresh one and retrying once if the call comes back 401.
+func (c *IMDSClient) get(ctx context.Context, path string) ([]byte, error) {
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err, "failed to obtain IMDSv2 session token")
+	}
+
+	body, status, err := c.doGet(ctx, path, token)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if status == http.StatusUnauthorized {
+		// The cached token was rejected (expired or revoked); force a
+		// fresh one and retry exactly once.
+		token, err = c.refreshToken(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to refresh IMDSv2 session token after 401")
+		}
+		body, status, err = c.doGet(ctx, path, token)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if status != http.StatusOK {
+		return nil, trace.Errorf("IMDS request to %s failed with status %d", path, status)
+	}
+	return body, nil
+}
+
+func (c *IMDSClient) doGet(ctx context.Context, path, token string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, 0, trace.Wrap(err)
+	}
+	req.Header.Set(imdsTokenHeader, token)
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, trace.Wrap(err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// getToken returns the cached session token, proactively refreshing it if
+// it's within cfg.TokenRefreshSkew of expiring.
+func (c *IMDSClient) getToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token, expiry := c.token, c.tokenExpiry
+	c.mu.Unlock()
+
+	if token != "" && time.Until(expiry) > c.cfg.TokenRefreshSkew {
+		return token, nil
+	}
+	return c.refreshToken(ctx)
+}
+
+// refreshToken unconditionally issues a new session token and caches it.
+func (c *IMDSClient) refreshToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.cfg.tokenURL(), nil)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	req.Header.Set(imdsTokenTTLHeader, strconv.Itoa(int(c.cfg.TokenTTL.Seconds())))
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", trace.Errorf("failed to issue IMDSv2 session token: %s (status %d)", string(body), resp.StatusCode)
+	}
+
+	token := string(body)
+	if token == "" {
+		return "", trace.Errorf("IMDS returned an empty session token")
+	}
+
+	c.mu.Lock()
+	c.token = token
+	c.tokenExpiry = time.Now().Add(c.cfg.TokenTTL)
+	c.mu.Unlock()
+
+	return token, nil
+}
@@ -0,0 +1,117 @@
+// Teleport
+// Copyright (C) 2024 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package iam
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIMDS simulates an EC2 instance metadata service implementing the
+// IMDSv2 session-token protocol, with knobs to exercise token issuance
+// failure and 401-triggered refresh.
+type fakeIMDS struct {
+	tokensIssued  atomic.Int32
+	failNextToken atomic.Bool
+	rejectToken   atomic.Pointer[string]
+	instanceDoc   string
+}
+
+func (f *fakeIMDS) server() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if f.failNextToken.Swap(false) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.tokensIssued.Add(1)
+		fmt.Fprintf(w, "token-%d", f.tokensIssued.Load())
+	})
+	mux.HandleFunc("/latest/dynamic/instance-identity/document", func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(imdsTokenHeader)
+		if rejected := f.rejectToken.Load(); rejected != nil && *rejected == token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, f.instanceDoc)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestIMDSClientTokenIssuanceFailure(t *testing.T) {
+	fake := &fakeIMDS{}
+	fake.failNextToken.Store(true)
+	srv := fake.server()
+	defer srv.Close()
+
+	client := NewIMDSClient(IMDSClientConfig{BaseURL: srv.URL + "/latest"})
+	_, err := client.GetInstanceIdentityDocument(context.Background())
+	require.Error(t, err)
+}
+
+func TestIMDSClientRefreshesTokenOn401(t *testing.T) {
+	fake := &fakeIMDS{instanceDoc: `{"accountId":"1234","region":"us-west-2","instanceId":"i-abc"}`}
+	srv := fake.server()
+	defer srv.Close()
+
+	client := NewIMDSClient(IMDSClientConfig{BaseURL: srv.URL + "/latest"})
+
+	doc, err := client.GetInstanceIdentityDocument(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "us-west-2", doc.Region)
+	require.EqualValues(t, 1, fake.tokensIssued.Load())
+
+	// Simulate the cached token having been revoked server-side; the next
+	// call should transparently fetch a new one and retry.
+	rejected := "token-1"
+	fake.rejectToken.Store(&rejected)
+
+	doc, err = client.GetInstanceIdentityDocument(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "us-west-2", doc.Region)
+	require.EqualValues(t, 2, fake.tokensIssued.Load())
+}
+
+func TestIMDSClientProactiveTokenRefresh(t *testing.T) {
+	fake := &fakeIMDS{instanceDoc: `{"accountId":"1234","region":"us-west-2","instanceId":"i-abc"}`}
+	srv := fake.server()
+	defer srv.Close()
+
+	client := NewIMDSClient(IMDSClientConfig{
+		BaseURL: srv.URL + "/latest",
+		// A refresh skew exceeding the TTL means every cached token is
+		// always "within skew" of expiring, so every call fetches a fresh
+		// one instead of reusing the cache.
+		TokenTTL:         time.Second,
+		TokenRefreshSkew: 2 * time.Second,
+	})
+
+	_, err := client.GetInstanceIdentityDocument(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, fake.tokensIssued.Load())
+
+	_, err = client.GetInstanceIdentityDocument(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 2, fake.tokensIssued.Load())
+}
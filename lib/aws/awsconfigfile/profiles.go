@@ -0,0 +1,342 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package awsconfigfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	sentinelBeginPrefix = "; BEGIN teleport-managed section: "
+	sentinelEndPrefix   = "; END teleport-managed section: "
+)
+
+// SectionKind distinguishes the AWS config file section syntaxes SetProfile
+// can write.
+type SectionKind int
+
+const (
+	// SectionKindProfile renders as [default] for name "default", or
+	// [profile NAME] otherwise - the ordinary way to declare a profile in
+	// ~/.aws/config.
+	SectionKindProfile SectionKind = iota
+	// SectionKindSSOSession renders as [sso-session NAME], an AWS SSO OIDC
+	// token-cache section a profile's sso_session field can point at.
+	SectionKindSSOSession
+)
+
+// SetProfileOption configures SetProfile.
+type SetProfileOption func(*setProfileOptions)
+
+type setProfileOptions struct {
+	kind SectionKind
+}
+
+// WithSectionKind overrides SetProfile's default SectionKindProfile, e.g.
+// to write a [sso-session NAME] block instead of [profile NAME]/[default].
+func WithSectionKind(kind SectionKind) SetProfileOption {
+	return func(o *setProfileOptions) { o.kind = kind }
+}
+
+// profileFieldOrder fixes the rendered order of well-known fields so
+// repeated SetProfile calls against the same inputs produce byte-identical
+// output; any field not listed here is appended afterward in sorted order.
+var profileFieldOrder = []string{
+	"sso_session",
+	"role_arn",
+	"source_profile",
+	"credential_process",
+	"region",
+	"output",
+}
+
+// sectionHeader returns the bracketed section name (without the brackets)
+// SetProfile should use for (kind, name), e.g. "profile foo" or
+// "sso-session foo".
+func sectionHeader(kind SectionKind, name string) string {
+	if kind == SectionKindSSOSession {
+		return "sso-session " + name
+	}
+	if name == "default" {
+		return "default"
+	}
+	return "profile " + name
+}
+
+// SetProfile upserts a Teleport-managed section named name - a [profile
+// NAME]/[default] section, or with WithSectionKind(SectionKindSSOSession) a
+// [sso-session NAME] section - in the AWS config file at path, setting
+// fields as its keys.
+//
+// fields may combine whatever a caller's integration needs: a plain
+// credential_process, an sso_session reference plus region/output defaults
+// for AWS SSO OIDC, or a source_profile/role_arn chain for IAM Roles
+// Anywhere-driven role assumption.
+//
+// The section is wrapped in "; BEGIN/END teleport-managed section"
+// comments, so a later SetProfile call for the same (kind, name) replaces
+// only that block, and RemoveTeleportSections can strip it cleanly,
+// without disturbing any other section, comment, or unmanaged key in the
+// file. SetProfile refuses to touch a section with the same name that
+// already exists and isn't one of its own managed blocks, the same
+// conservative default the older addCredentialProcessToSection took with
+// the default profile.
+func SetProfile(path, name string, fields map[string]string, opts ...SetProfileOption) error {
+	options := setProfileOptions{kind: SectionKindProfile}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	header := sectionHeader(options.kind, name)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return trace.ConvertSystemError(err)
+	}
+
+	doc := parseManagedDoc(string(data))
+	if !doc.isManaged(header) && doc.hasSection(header) {
+		return trace.BadParameter("[%s] already exists in %s and is not managed by Teleport; remove it manually first", header, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return trace.Wrap(err)
+	}
+
+	content := doc.upsertManaged(header, renderManagedBlock(header, fields))
+	return trace.Wrap(os.WriteFile(path, []byte(content), 0o600))
+}
+
+// RemoveTeleportSections strips every Teleport-managed section (every
+// "; BEGIN teleport-managed section"/"; END teleport-managed section" pair
+// and the lines between them) from the AWS config or credentials file at
+// path, leaving everything else untouched. It is a no-op, not an error, if
+// path doesn't exist or has no managed sections.
+func RemoveTeleportSections(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return trace.ConvertSystemError(err)
+	}
+
+	doc := parseManagedDoc(string(data))
+	if len(doc.managed) == 0 {
+		return nil
+	}
+
+	return trace.Wrap(os.WriteFile(path, []byte(doc.withoutManaged()), 0o600))
+}
+
+// renderManagedBlock renders fields as a sentinel-wrapped "[header]"
+// section, in profileFieldOrder followed by any remaining fields sorted
+// alphabetically, so the output is stable across repeated calls.
+func renderManagedBlock(header string, fields map[string]string) string {
+	seen := make(map[string]bool, len(fields))
+	var lines []string
+	lines = append(lines, sentinelBeginPrefix+header)
+	lines = append(lines, "["+header+"]")
+
+	for _, key := range profileFieldOrder {
+		if value, ok := fields[key]; ok {
+			lines = append(lines, fmt.Sprintf("%s = %s", key, value))
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key := range fields {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		lines = append(lines, fmt.Sprintf("%s = %s", key, fields[key]))
+	}
+
+	lines = append(lines, sentinelEndPrefix+header)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// managedBlock locates one Teleport-managed section's sentinel pair within
+// a document's lines, as a half-open [begin, end) line range.
+type managedBlock struct {
+	begin, end int // line indices; end is exclusive and includes the END sentinel line
+}
+
+// managedDoc is an AWS config/credentials file's content, along with the
+// line ranges of every Teleport-managed section already in it, so
+// SetProfile/RemoveTeleportSections can replace or strip exactly those
+// ranges without reparsing or reformatting anything else.
+type managedDoc struct {
+	lines   []string
+	managed map[string]managedBlock // header -> its line range
+}
+
+var sectionHeaderRE = regexp.MustCompile(`^\[(.+)\]\s*$`)
+
+func parseManagedDoc(content string) *managedDoc {
+	doc := &managedDoc{managed: map[string]managedBlock{}}
+	if content == "" {
+		return doc
+	}
+
+	doc.lines = strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for i := 0; i < len(doc.lines); i++ {
+		header, ok := strings.CutPrefix(doc.lines[i], sentinelBeginPrefix)
+		if !ok {
+			continue
+		}
+		for j := i + 1; j < len(doc.lines); j++ {
+			if doc.lines[j] == sentinelEndPrefix+header {
+				doc.managed[header] = managedBlock{begin: i, end: j + 1}
+				i = j
+				break
+			}
+		}
+	}
+	return doc
+}
+
+// isManaged reports whether header has a previously-written Teleport-managed
+// block in this document.
+func (d *managedDoc) isManaged(header string) bool {
+	_, ok := d.managed[header]
+	return ok
+}
+
+// hasSection reports whether "[header]" appears anywhere in the document,
+// managed or not.
+func (d *managedDoc) hasSection(header string) bool {
+	for _, line := range d.lines {
+		if m := sectionHeaderRE.FindStringSubmatch(strings.TrimSpace(line)); m != nil && m[1] == header {
+			return true
+		}
+	}
+	return false
+}
+
+// upsertManaged returns the document's content with header's managed block
+// (if any) replaced by newBlock, or newBlock appended at the end otherwise.
+func (d *managedDoc) upsertManaged(header, newBlock string) string {
+	newBlockLines := strings.Split(strings.TrimRight(newBlock, "\n"), "\n")
+
+	if block, ok := d.managed[header]; ok {
+		out := append([]string{}, d.lines[:block.begin]...)
+		out = append(out, newBlockLines...)
+		out = append(out, d.lines[block.end:]...)
+		return strings.Join(out, "\n") + "\n"
+	}
+
+	out := append([]string{}, d.lines...)
+	if len(out) > 0 && out[len(out)-1] != "" {
+		out = append(out, "")
+	}
+	out = append(out, newBlockLines...)
+	return strings.Join(out, "\n") + "\n"
+}
+
+// withoutManaged returns the document's content with every managed block
+// removed.
+func (d *managedDoc) withoutManaged() string {
+	if len(d.managed) == 0 {
+		return strings.Join(d.lines, "\n") + "\n"
+	}
+
+	removed := make([]bool, len(d.lines))
+	for _, block := range d.managed {
+		for i := block.begin; i < block.end; i++ {
+			removed[i] = true
+		}
+		// Also drop the blank separator line SetProfile inserts before an
+		// appended block, so removing it doesn't leave a stray blank line.
+		if block.begin > 0 && d.lines[block.begin-1] == "" {
+			removed[block.begin-1] = true
+		}
+	}
+
+	var out []string
+	for i, line := range d.lines {
+		if !removed[i] {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// DetectDuplicateProfiles reports profile names that would collide between
+// the AWS config file at configPath and the AWS credentials file at
+// credentialsPath: profiles in config are named "profile NAME" (or
+// "default"), while credentials sections are bare "[NAME]", so both are
+// normalized to NAME before comparing. Either path may not exist, in which
+// case it contributes no names.
+func DetectDuplicateProfiles(configPath, credentialsPath string) ([]string, error) {
+	configNames, err := sectionNames(configPath, true /* stripProfilePrefix */)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	credentialNames, err := sectionNames(credentialsPath, false /* stripProfilePrefix */)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var duplicates []string
+	for name := range configNames {
+		if credentialNames[name] {
+			duplicates = append(duplicates, name)
+		}
+	}
+	sort.Strings(duplicates)
+	return duplicates, nil
+}
+
+// sectionNames reads every "[...]" section header out of the file at path,
+// returning an empty set (not an error) if path doesn't exist. With
+// stripProfilePrefix, a "profile NAME" header is reported as NAME, matching
+// how the same profile would be named in the credentials file.
+func sectionNames(path string, stripProfilePrefix bool) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]bool{}, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+
+	names := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		m := sectionHeaderRE.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		name := m[1]
+		if stripProfilePrefix {
+			name = strings.TrimPrefix(name, "profile ")
+		}
+		names[name] = true
+	}
+	return names, nil
+}
@@ -0,0 +1,213 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package awsconfigfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProfile(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		profileName      string
+		fields           map[string]string
+		opts             []SetProfileOption
+		existingContents *string
+		errCheck         require.ErrorAssertionFunc
+		expected         string
+	}{
+		{
+			name:        "adds a new profile",
+			profileName: "my-profile",
+			fields:      map[string]string{"role_arn": "arn:aws:iam::1234:role/foo", "source_profile": "default"},
+			errCheck:    require.NoError,
+			expected: `; BEGIN teleport-managed section: profile my-profile
+[profile my-profile]
+role_arn = arn:aws:iam::1234:role/foo
+source_profile = default
+; END teleport-managed section: profile my-profile
+`,
+		},
+		{
+			name:        "default profile renders without the profile prefix",
+			profileName: "default",
+			fields:      map[string]string{"credential_process": "tsh aws credential-process"},
+			errCheck:    require.NoError,
+			expected: `; BEGIN teleport-managed section: default
+[default]
+credential_process = tsh aws credential-process
+; END teleport-managed section: default
+`,
+		},
+		{
+			name:        "sso-session block",
+			profileName: "my-sso",
+			fields:      map[string]string{"sso_start_url": "https://example.awsapps.com/start", "sso_region": "us-east-1"},
+			opts:        []SetProfileOption{WithSectionKind(SectionKindSSOSession)},
+			errCheck:    require.NoError,
+			expected: `; BEGIN teleport-managed section: sso-session my-sso
+[sso-session my-sso]
+sso_region = us-east-1
+sso_start_url = https://example.awsapps.com/start
+; END teleport-managed section: sso-session my-sso
+`,
+		},
+		{
+			name:        "well-known fields render in a stable order",
+			profileName: "chained",
+			fields: map[string]string{
+				"output":         "json",
+				"region":         "us-west-2",
+				"role_arn":       "arn:aws:iam::1234:role/foo",
+				"source_profile": "sso-base",
+				"sso_session":    "my-sso",
+			},
+			errCheck: require.NoError,
+			expected: `; BEGIN teleport-managed section: profile chained
+[profile chained]
+sso_session = my-sso
+role_arn = arn:aws:iam::1234:role/foo
+source_profile = sso-base
+region = us-west-2
+output = json
+; END teleport-managed section: profile chained
+`,
+		},
+		{
+			name:        "preserves unrelated sections",
+			profileName: "my-profile",
+			fields:      map[string]string{"credential_process": "credential_process"},
+			existingContents: strPtr(`[profile other]
+region = us-east-1
+`),
+			errCheck: require.NoError,
+			expected: `[profile other]
+region = us-east-1
+
+; BEGIN teleport-managed section: profile my-profile
+[profile my-profile]
+credential_process = credential_process
+; END teleport-managed section: profile my-profile
+`,
+		},
+		{
+			name:        "upserts its own managed section in place",
+			profileName: "my-profile",
+			fields:      map[string]string{"credential_process": "new process"},
+			existingContents: strPtr(`[profile other]
+region = us-east-1
+
+; BEGIN teleport-managed section: profile my-profile
+[profile my-profile]
+credential_process = old process
+; END teleport-managed section: profile my-profile
+`),
+			errCheck: require.NoError,
+			expected: `[profile other]
+region = us-east-1
+
+; BEGIN teleport-managed section: profile my-profile
+[profile my-profile]
+credential_process = new process
+; END teleport-managed section: profile my-profile
+`,
+		},
+		{
+			name:        "refuses to touch an unmanaged section with the same name",
+			profileName: "my-profile",
+			fields:      map[string]string{"credential_process": "credential_process"},
+			existingContents: strPtr(`[profile my-profile]
+region = us-east-1
+`),
+			errCheck: require.Error,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			configFilePath := filepath.Join(t.TempDir(), "config")
+			if tc.existingContents != nil {
+				err := os.WriteFile(configFilePath, []byte(*tc.existingContents), 0600)
+				require.NoError(t, err)
+			}
+
+			err := SetProfile(configFilePath, tc.profileName, tc.fields, tc.opts...)
+			tc.errCheck(t, err)
+
+			if tc.expected != "" {
+				bs, err := os.ReadFile(configFilePath)
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, string(bs))
+			}
+		})
+	}
+}
+
+func TestRemoveTeleportSections(t *testing.T) {
+	t.Run("no file", func(t *testing.T) {
+		configFilePath := filepath.Join(t.TempDir(), "config")
+		require.NoError(t, RemoveTeleportSections(configFilePath))
+	})
+
+	t.Run("strips managed sections, keeps the rest", func(t *testing.T) {
+		configFilePath := filepath.Join(t.TempDir(), "config")
+		require.NoError(t, os.WriteFile(configFilePath, []byte(`[profile other]
+region = us-east-1
+
+; BEGIN teleport-managed section: profile my-profile
+[profile my-profile]
+credential_process = credential_process
+; END teleport-managed section: profile my-profile
+`), 0600))
+
+		require.NoError(t, RemoveTeleportSections(configFilePath))
+
+		bs, err := os.ReadFile(configFilePath)
+		require.NoError(t, err)
+		require.Equal(t, `[profile other]
+region = us-east-1
+`, string(bs))
+	})
+}
+
+func TestDetectDuplicateProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFilePath := filepath.Join(tmpDir, "config")
+	credentialsFilePath := filepath.Join(tmpDir, "credentials")
+
+	require.NoError(t, os.WriteFile(configFilePath, []byte(`[default]
+region = us-east-1
+
+[profile alice]
+region = us-east-1
+
+[profile bob]
+region = us-east-1
+`), 0600))
+	require.NoError(t, os.WriteFile(credentialsFilePath, []byte(`[default]
+aws_access_key_id = x
+
+[alice]
+aws_access_key_id = x
+`), 0600))
+
+	duplicates, err := DetectDuplicateProfiles(configFilePath, credentialsFilePath)
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "default"}, duplicates)
+}
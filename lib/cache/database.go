@@ -2,6 +2,9 @@ package cache
 
 import (
 	"context"
+	"iter"
+	"sort"
+	"strings"
 
 	"github.com/gravitational/trace"
 
@@ -9,9 +12,62 @@ import (
 	"github.com/gravitational/teleport/api/client/proto"
 	"github.com/gravitational/teleport/api/defaults"
 	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/lib/backend"
 	"github.com/gravitational/teleport/lib/services"
 )
 
+// databaseLabelsIndexKey builds the "by_labels_hash" index key for a set of
+// labels: the labels joined in a canonical, sorted order, so that databases
+// sharing the exact same label set land in the same index bucket.
+func databaseLabelsIndexKey(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(labels[k])
+	}
+	return sb.String()
+}
+
+// databaseAWSAccountRegionIndexKey builds the "by_aws_account_region" index
+// key for db, empty for databases without AWS metadata.
+func databaseAWSAccountRegionIndexKey(db types.Database) string {
+	aws := db.GetAWS()
+	if aws.AccountID == "" && aws.Region == "" {
+		return ""
+	}
+	return aws.AccountID + "/" + aws.Region
+}
+
+// databaseIndexValue recomputes db's key for index, mirroring the key
+// functions registered with newDatabaseCollection's store. Used to confirm
+// a resource read back from a secondary index bucket still belongs to it.
+func databaseIndexValue(db types.Database, index string) string {
+	switch index {
+	case "by_protocol":
+		return db.GetProtocol()
+	case "by_labels_hash":
+		return databaseLabelsIndexKey(db.GetAllLabels())
+	case "by_aws_account_region":
+		return databaseAWSAccountRegionIndexKey(db)
+	default:
+		return db.GetName()
+	}
+}
+
 func newDatabaseCollection(p services.Databases, w types.WatchKind) (*collection[types.Database], error) {
 	if p == nil {
 		return nil, trace.BadParameter("missing parameter Databases")
@@ -22,6 +78,13 @@ func newDatabaseCollection(p services.Databases, w types.WatchKind) (*collection
 			"name": func(u types.Database) string {
 				return u.GetName()
 			},
+			"by_labels_hash": func(u types.Database) string {
+				return databaseLabelsIndexKey(u.GetAllLabels())
+			},
+			"by_aws_account_region": databaseAWSAccountRegionIndexKey,
+			"by_protocol": func(u types.Database) string {
+				return u.GetProtocol()
+			},
 		}),
 		fetcher: func(ctx context.Context, loadSecrets bool) ([]types.Database, error) {
 			return p.GetDatabases(ctx)
@@ -87,6 +150,164 @@ func (c *Cache) GetDatabases(ctx context.Context) ([]types.Database, error) {
 	return dbs, trace.Wrap(err)
 }
 
+// ListDatabasesRequest requests a page of database resources, optionally
+// narrowed by an exact-match label selector, a protocol, and/or AWS
+// account/region.
+type ListDatabasesRequest struct {
+	// Labels, if set, selects only databases whose labels (static and
+	// dynamic) match every key/value pair given (extra labels on the
+	// database are fine).
+	Labels map[string]string
+	// Protocol, if set, selects only databases advertising this protocol.
+	Protocol string
+	// AWSAccountID, if set, selects only databases whose AWS metadata has
+	// this account ID.
+	AWSAccountID string
+	// AWSRegion, if set, selects only databases whose AWS metadata has
+	// this region.
+	AWSRegion string
+	// StartKey is the pagination token from a previous page's NextKey.
+	StartKey string
+	// Limit caps the number of databases returned in one page.
+	Limit int
+}
+
+// databaseListIndex picks the secondary index (and the key to seek to
+// within it) that best narrows req down, so ListDatabases can avoid
+// scanning every cached database when a selective filter is given.
+func databaseListIndex(req ListDatabasesRequest) (index, key string) {
+	switch {
+	case req.Protocol != "":
+		return "by_protocol", req.Protocol
+	case req.AWSAccountID != "" && req.AWSRegion != "":
+		// The index key holds both parts, so only a request naming both
+		// can seek straight to its bucket; a single AWS filter is left to
+		// matchesDatabaseRequest.
+		return "by_aws_account_region", req.AWSAccountID + "/" + req.AWSRegion
+	case len(req.Labels) > 0:
+		return "by_labels_hash", databaseLabelsIndexKey(req.Labels)
+	default:
+		return "name", ""
+	}
+}
+
+// matchesDatabaseRequest reports whether db satisfies every filter set on
+// req. It's applied regardless of which index was used to reach db, since
+// an index bucket only narrows the scan to candidates sharing that index's
+// key and doesn't by itself confirm the other filters.
+func matchesDatabaseRequest(db types.Database, req ListDatabasesRequest) bool {
+	if req.Protocol != "" && db.GetProtocol() != req.Protocol {
+		return false
+	}
+	if req.AWSAccountID != "" || req.AWSRegion != "" {
+		aws := db.GetAWS()
+		if req.AWSAccountID != "" && aws.AccountID != req.AWSAccountID {
+			return false
+		}
+		if req.AWSRegion != "" && aws.Region != req.AWSRegion {
+			return false
+		}
+	}
+	labels := db.GetAllLabels()
+	for k, v := range req.Labels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// filterDatabasesPage applies req's filters and pagination to dbs in
+// memory, for use when the cache can't serve reads (c.Config.Databases was
+// queried directly instead, so there's no index bucket to scan).
+func filterDatabasesPage(dbs []types.Database, req ListDatabasesRequest) *types.ListDatabasesResponse {
+	limit := req.Limit
+	if limit <= 0 || limit > defaults.DefaultChunkSize {
+		limit = defaults.DefaultChunkSize
+	}
+
+	var resp types.ListDatabasesResponse
+	started := req.StartKey == ""
+	for _, db := range dbs {
+		if !started {
+			if db.GetName() == req.StartKey {
+				started = true
+			}
+			continue
+		}
+		if !matchesDatabaseRequest(db, req) {
+			continue
+		}
+		if len(resp.Databases) == limit {
+			resp.NextKey = db.GetName()
+			break
+		}
+		resp.Databases = append(resp.Databases, db)
+	}
+	return &resp
+}
+
+// ListDatabases returns a page of database resources matching req, reading
+// only the index bucket that req's filters narrow it down to (e.g. the
+// "by_protocol" bucket for req.Protocol) rather than the full cached set.
+func (c *Cache) ListDatabases(ctx context.Context, req ListDatabasesRequest) (*types.ListDatabasesResponse, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/ListDatabases")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.dbs)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if !rg.ReadCache() {
+		dbs, err := c.Config.Databases.GetDatabases(ctx)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		return filterDatabasesPage(dbs, req), nil
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > defaults.DefaultChunkSize {
+		limit = defaults.DefaultChunkSize
+	}
+
+	index, indexKey := databaseListIndex(req)
+	startKey := req.StartKey
+	if startKey == "" {
+		startKey = indexKey
+	}
+
+	return scanDatabases(rg.store.resources(index, startKey, ""), index, indexKey, req, limit), nil
+}
+
+// scanDatabases walks seq (a single index bucket's worth of databases,
+// already positioned at startKey) applying req's remaining filters and
+// pagination. It only calls Copy() on databases that make it into the
+// result page, so its cost scales with the page size, not with however
+// many databases the index bucket (or the cache as a whole) holds.
+func scanDatabases(seq iter.Seq[types.Database], index, indexKey string, req ListDatabasesRequest, limit int) *types.ListDatabasesResponse {
+	var resp types.ListDatabasesResponse
+	for db := range seq {
+		if indexKey != "" && databaseIndexValue(db, index) != indexKey {
+			// Sorted traversal of the index means once the key no
+			// longer matches, nothing further in the iteration can
+			// either.
+			break
+		}
+		if !matchesDatabaseRequest(db, req) {
+			continue
+		}
+		if len(resp.Databases) == limit {
+			resp.NextKey = backend.GetPaginationKey(db)
+			break
+		}
+		resp.Databases = append(resp.Databases, db.Copy())
+	}
+	return &resp
+}
+
 func newDatabaseServerCollection(p services.Presence, w types.WatchKind) (*collection[types.DatabaseServer], error) {
 	if p == nil {
 		return nil, trace.BadParameter("missing parameter Presence")
@@ -97,6 +318,9 @@ func newDatabaseServerCollection(p services.Presence, w types.WatchKind) (*colle
 			"name": func(u types.DatabaseServer) string {
 				return u.GetName()
 			},
+			"host_id": func(u types.DatabaseServer) string {
+				return u.GetHostID()
+			},
 		}),
 		fetcher: func(ctx context.Context, loadSecrets bool) ([]types.DatabaseServer, error) {
 			return p.GetDatabaseServers(ctx, defaults.Namespace)
@@ -141,6 +365,53 @@ func (c *Cache) GetDatabaseServers(ctx context.Context, namespace string, opts .
 	return servers, trace.Wrap(err)
 }
 
+// GetDatabaseServersByHostID returns the database proxy servers registered
+// by hostID, reading only the "host_id" index bucket instead of scanning
+// every cached database server. Used by proxy dial paths that fan out per
+// host and would otherwise pay an O(N) scan per host.
+func (c *Cache) GetDatabaseServersByHostID(ctx context.Context, namespace, hostID string) ([]types.DatabaseServer, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetDatabaseServersByHostID")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.dbServers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if rg.ReadCache() {
+		return scanDatabaseServersByHostID(rg.store.resources("host_id", hostID, ""), hostID), nil
+	}
+
+	servers, err := c.Config.Presence.GetDatabaseServers(ctx, namespace)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var out []types.DatabaseServer
+	for _, server := range servers {
+		if server.GetHostID() == hostID {
+			out = append(out, server)
+		}
+	}
+	return out, nil
+}
+
+// scanDatabaseServersByHostID walks seq (the "host_id" index bucket for
+// hostID, already positioned at hostID) and copies out only the matching
+// servers, so its Copy() calls scale with how many servers that host has
+// registered rather than the total cached population.
+func scanDatabaseServersByHostID(seq iter.Seq[types.DatabaseServer], hostID string) []types.DatabaseServer {
+	var out []types.DatabaseServer
+	for ds := range seq {
+		if ds.GetHostID() != hostID {
+			break
+		}
+		out = append(out, ds.Copy())
+	}
+	return out
+}
+
 func newDatabaseServiceCollection(p services.Presence, w types.WatchKind) (*collection[types.DatabaseService], error) {
 	if p == nil {
 		return nil, trace.BadParameter("missing parameter Databases")
@@ -0,0 +1,205 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package cache
+
+import (
+	"fmt"
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+// countingDatabase wraps a types.Database and counts calls to Copy(), so
+// tests can assert how many resources a scan actually materialized.
+type countingDatabase struct {
+	types.Database
+	copies *int
+}
+
+func (d countingDatabase) Copy() types.Database {
+	*d.copies++
+	return countingDatabase{Database: d.Database.Copy(), copies: d.copies}
+}
+
+func newTestDatabase(t *testing.T, name, protocol string, labels map[string]string) types.Database {
+	t.Helper()
+	db, err := types.NewDatabaseV3(types.Metadata{
+		Name:   name,
+		Labels: labels,
+	}, types.DatabaseSpecV3{
+		Protocol: protocol,
+		URI:      "localhost:5432",
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func seqOfDatabases(dbs ...types.Database) iter.Seq[types.Database] {
+	return func(yield func(types.Database) bool) {
+		for _, db := range dbs {
+			if !yield(db) {
+				return
+			}
+		}
+	}
+}
+
+func TestDatabaseListIndex(t *testing.T) {
+	t.Run("protocol takes priority", func(t *testing.T) {
+		index, key := databaseListIndex(ListDatabasesRequest{
+			Protocol: "postgres",
+			Labels:   map[string]string{"env": "prod"},
+		})
+		require.Equal(t, "by_protocol", index)
+		require.Equal(t, "postgres", key)
+	})
+
+	t.Run("aws account/region when no protocol", func(t *testing.T) {
+		index, key := databaseListIndex(ListDatabasesRequest{
+			AWSAccountID: "1234",
+			AWSRegion:    "us-east-1",
+		})
+		require.Equal(t, "by_aws_account_region", index)
+		require.Equal(t, "1234/us-east-1", key)
+	})
+
+	t.Run("labels when no protocol or aws filter", func(t *testing.T) {
+		index, key := databaseListIndex(ListDatabasesRequest{
+			Labels: map[string]string{"env": "prod"},
+		})
+		require.Equal(t, "by_labels_hash", index)
+		require.Equal(t, "env=prod", key)
+	})
+
+	t.Run("falls back to name with no filters", func(t *testing.T) {
+		index, key := databaseListIndex(ListDatabasesRequest{})
+		require.Equal(t, "name", index)
+		require.Empty(t, key)
+	})
+}
+
+func TestMatchesDatabaseRequest(t *testing.T) {
+	db := newTestDatabase(t, "pg", "postgres", map[string]string{"env": "prod", "team": "core"})
+
+	require.True(t, matchesDatabaseRequest(db, ListDatabasesRequest{}))
+	require.True(t, matchesDatabaseRequest(db, ListDatabasesRequest{Protocol: "postgres"}))
+	require.False(t, matchesDatabaseRequest(db, ListDatabasesRequest{Protocol: "mysql"}))
+	require.True(t, matchesDatabaseRequest(db, ListDatabasesRequest{Labels: map[string]string{"env": "prod"}}))
+	require.False(t, matchesDatabaseRequest(db, ListDatabasesRequest{Labels: map[string]string{"env": "staging"}}))
+	require.False(t, matchesDatabaseRequest(db, ListDatabasesRequest{Labels: map[string]string{"missing": "label"}}))
+}
+
+func TestFilterDatabasesPage(t *testing.T) {
+	a := newTestDatabase(t, "a", "postgres", map[string]string{"env": "prod"})
+	b := newTestDatabase(t, "b", "mysql", map[string]string{"env": "prod"})
+	c := newTestDatabase(t, "c", "postgres", map[string]string{"env": "staging"})
+
+	resp := filterDatabasesPage([]types.Database{a, b, c}, ListDatabasesRequest{Protocol: "postgres"})
+	require.Len(t, resp.Databases, 2)
+	require.Equal(t, "a", resp.Databases[0].GetName())
+	require.Equal(t, "c", resp.Databases[1].GetName())
+	require.Empty(t, resp.NextKey)
+
+	resp = filterDatabasesPage([]types.Database{a, b, c}, ListDatabasesRequest{Limit: 1})
+	require.Len(t, resp.Databases, 1)
+	require.Equal(t, "a", resp.Databases[0].GetName())
+	require.Equal(t, "a", resp.NextKey)
+
+	resp = filterDatabasesPage([]types.Database{a, b, c}, ListDatabasesRequest{StartKey: "a"})
+	require.Len(t, resp.Databases, 2)
+	require.Equal(t, "b", resp.Databases[0].GetName())
+	require.Equal(t, "c", resp.Databases[1].GetName())
+}
+
+// TestScanDatabasesCopyCountScalesWithResultSet demonstrates that
+// scanDatabases only calls Copy() on databases that end up in the result
+// page, not on every database in the index bucket it's handed, let alone
+// the full cached population.
+func TestScanDatabasesCopyCountScalesWithResultSet(t *testing.T) {
+	var copies int
+	var dbs []types.Database
+	for i := 0; i < 50; i++ {
+		protocol := "mysql"
+		if i < 5 {
+			protocol = "postgres"
+		}
+		db := newTestDatabase(t, fmt.Sprintf("db-%d", i), protocol, nil)
+		dbs = append(dbs, countingDatabase{Database: db, copies: &copies})
+	}
+
+	// Only the 5 postgres databases are in the "by_protocol" bucket for
+	// "postgres"; scanDatabases should copy exactly those, regardless of
+	// the other 45 entries never reached.
+	resp := scanDatabases(seqOfDatabases(dbs...), "by_protocol", "postgres", ListDatabasesRequest{Protocol: "postgres"}, 10)
+	require.Len(t, resp.Databases, 5)
+	require.Equal(t, 5, copies)
+
+	// A tighter limit copies even fewer, confirming the cost tracks the
+	// page size rather than the bucket size.
+	copies = 0
+	resp = scanDatabases(seqOfDatabases(dbs...), "by_protocol", "postgres", ListDatabasesRequest{Protocol: "postgres"}, 2)
+	require.Len(t, resp.Databases, 2)
+	require.Equal(t, 2, copies)
+	require.NotEmpty(t, resp.NextKey)
+}
+
+func TestScanDatabaseServersByHostIDCopyCountScalesWithResultSet(t *testing.T) {
+	var copies int
+	var servers []types.DatabaseServer
+	for i := 0; i < 20; i++ {
+		hostID := "host-b"
+		if i < 3 {
+			hostID = "host-a"
+		}
+		server, err := types.NewDatabaseServerV3(types.Metadata{
+			Name: fmt.Sprintf("server-%d", i),
+		}, types.DatabaseServerSpecV3{
+			HostID:   hostID,
+			Database: newTestDatabase(t, fmt.Sprintf("db-%d", i), "postgres", nil),
+		})
+		require.NoError(t, err)
+		servers = append(servers, countingDatabaseServer{DatabaseServer: server, copies: &copies})
+	}
+
+	out := scanDatabaseServersByHostID(seqOfDatabaseServers(servers...), "host-a")
+	require.Len(t, out, 3)
+	require.Equal(t, 3, copies)
+}
+
+// countingDatabaseServer wraps a types.DatabaseServer and counts calls to
+// Copy(), mirroring countingDatabase above.
+type countingDatabaseServer struct {
+	types.DatabaseServer
+	copies *int
+}
+
+func (d countingDatabaseServer) Copy() types.DatabaseServer {
+	*d.copies++
+	return countingDatabaseServer{DatabaseServer: d.DatabaseServer.Copy(), copies: d.copies}
+}
+
+func seqOfDatabaseServers(servers ...types.DatabaseServer) iter.Seq[types.DatabaseServer] {
+	return func(yield func(types.DatabaseServer) bool) {
+		for _, s := range servers {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package cache
+
+import (
+	"context"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/lib/services"
+)
+
+func newIdentityCenterAccountCollection(ic services.IdentityCenter, w types.WatchKind) (*collection[types.IdentityCenterAccount], error) {
+	if ic == nil {
+		return nil, trace.BadParameter("missing parameter IdentityCenter")
+	}
+
+	return &collection[types.IdentityCenterAccount]{
+		store: newStore(map[string]func(types.IdentityCenterAccount) string{
+			"name": func(a types.IdentityCenterAccount) string {
+				return a.GetName()
+			},
+		}),
+		fetcher: func(ctx context.Context, loadSecrets bool) ([]types.IdentityCenterAccount, error) {
+			var accounts []types.IdentityCenterAccount
+			var nextToken string
+			for {
+				page, token, err := ic.ListIdentityCenterAccounts(ctx, 0, nextToken)
+				if err != nil {
+					return nil, trace.Wrap(err)
+				}
+				accounts = append(accounts, page...)
+				if token == "" {
+					break
+				}
+				nextToken = token
+			}
+			return accounts, nil
+		},
+		headerTransform: func(hdr *types.ResourceHeader) types.IdentityCenterAccount {
+			return &types.IdentityCenterAccountV1{
+				ResourceHeader: types.ResourceHeader{
+					Kind:    types.KindIdentityCenterAccount,
+					Version: types.V1,
+					Metadata: types.Metadata{
+						Name: hdr.Metadata.Name,
+					},
+				},
+			}
+		},
+		watch: w,
+	}, nil
+}
+
+// GetIdentityCenterAccount returns the specified Identity Center account resource.
+func (c *Cache) GetIdentityCenterAccount(ctx context.Context, name string) (types.IdentityCenterAccount, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetIdentityCenterAccount")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.identityCenterAccounts)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if !rg.ReadCache() {
+		account, err := c.Config.IdentityCenter.GetIdentityCenterAccount(ctx, name)
+		return account, trace.Wrap(err)
+	}
+
+	a, err := rg.store.get("name", name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return a.Clone(), nil
+}
+
+func newIdentityCenterAccountAssignmentCollection(ic services.IdentityCenter, w types.WatchKind) (*collection[types.IdentityCenterAccountAssignment], error) {
+	if ic == nil {
+		return nil, trace.BadParameter("missing parameter IdentityCenter")
+	}
+
+	return &collection[types.IdentityCenterAccountAssignment]{
+		store: newStore(map[string]func(types.IdentityCenterAccountAssignment) string{
+			"name": func(a types.IdentityCenterAccountAssignment) string {
+				return a.GetName()
+			},
+		}),
+		fetcher: func(ctx context.Context, loadSecrets bool) ([]types.IdentityCenterAccountAssignment, error) {
+			var assignments []types.IdentityCenterAccountAssignment
+			var nextToken string
+			for {
+				page, token, err := ic.ListIdentityCenterAccountAssignments(ctx, 0, nextToken)
+				if err != nil {
+					return nil, trace.Wrap(err)
+				}
+				assignments = append(assignments, page...)
+				if token == "" {
+					break
+				}
+				nextToken = token
+			}
+			return assignments, nil
+		},
+		headerTransform: func(hdr *types.ResourceHeader) types.IdentityCenterAccountAssignment {
+			return &types.IdentityCenterAccountAssignmentV1{
+				ResourceHeader: types.ResourceHeader{
+					Kind:    types.KindIdentityCenterAccountAssignment,
+					Version: types.V1,
+					Metadata: types.Metadata{
+						Name: hdr.Metadata.Name,
+					},
+				},
+			}
+		},
+		watch: w,
+	}, nil
+}
+
+// GetIdentityCenterAccountAssignment returns the specified Identity Center account assignment resource.
+func (c *Cache) GetIdentityCenterAccountAssignment(ctx context.Context, name string) (types.IdentityCenterAccountAssignment, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetIdentityCenterAccountAssignment")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.identityCenterAccountAssignments)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if !rg.ReadCache() {
+		assignment, err := c.Config.IdentityCenter.GetIdentityCenterAccountAssignment(ctx, name)
+		return assignment, trace.Wrap(err)
+	}
+
+	a, err := rg.store.get("name", name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return a.Clone(), nil
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"iter"
 
 	"github.com/gravitational/trace"
 
@@ -9,6 +10,14 @@ import (
 	"github.com/gravitational/teleport/lib/services"
 )
 
+// kubeServerClusterHostIndexKey builds the "cluster_name/host_id" composite
+// index key for a kube server, so lookups that know both the cluster and
+// the host can narrow straight to the matching bucket instead of scanning
+// the (potentially much larger) "cluster_name" bucket alone.
+func kubeServerClusterHostIndexKey(s types.KubeServer) string {
+	return s.GetCluster().GetName() + "/" + s.GetHostID()
+}
+
 func newKubernetesServerCollection(p services.Presence, w types.WatchKind) (*collection[types.KubeServer], error) {
 	if p == nil {
 		return nil, trace.BadParameter("missing parameter Presence")
@@ -19,6 +28,13 @@ func newKubernetesServerCollection(p services.Presence, w types.WatchKind) (*col
 			"name": func(u types.KubeServer) string {
 				return u.GetName()
 			},
+			"host_id": func(u types.KubeServer) string {
+				return u.GetHostID()
+			},
+			"cluster_name": func(u types.KubeServer) string {
+				return u.GetCluster().GetName()
+			},
+			"cluster_name/host_id": kubeServerClusterHostIndexKey,
 		}),
 		fetcher: func(ctx context.Context, loadSecrets bool) ([]types.KubeServer, error) {
 			return p.GetKubernetesServers(ctx)
@@ -63,6 +79,86 @@ func (c *Cache) GetKubernetesServers(ctx context.Context) ([]types.KubeServer, e
 	return servers, trace.Wrap(err)
 }
 
+// GetKubernetesServersByCluster returns the kube servers proxying
+// clusterName, reading only the "cluster_name" index bucket instead of
+// scanning every cached kube server. Used by the kube proxy's dial path,
+// which looks servers up per cluster on every connection.
+func (c *Cache) GetKubernetesServersByCluster(ctx context.Context, clusterName string) ([]types.KubeServer, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetKubernetesServersByCluster")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.kubeServers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if rg.ReadCache() {
+		return scanKubeServers(rg.store.resources("cluster_name", clusterName, ""), clusterName, func(s types.KubeServer) string {
+			return s.GetCluster().GetName()
+		}), nil
+	}
+
+	servers, err := c.Config.Presence.GetKubernetesServers(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var out []types.KubeServer
+	for _, server := range servers {
+		if server.GetCluster().GetName() == clusterName {
+			out = append(out, server)
+		}
+	}
+	return out, nil
+}
+
+// GetKubernetesServersByHost returns the kube servers registered by hostID,
+// reading only the "host_id" index bucket instead of scanning every cached
+// kube server.
+func (c *Cache) GetKubernetesServersByHost(ctx context.Context, hostID string) ([]types.KubeServer, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetKubernetesServersByHost")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.kubeServers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if rg.ReadCache() {
+		return scanKubeServers(rg.store.resources("host_id", hostID, ""), hostID, types.KubeServer.GetHostID), nil
+	}
+
+	servers, err := c.Config.Presence.GetKubernetesServers(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var out []types.KubeServer
+	for _, server := range servers {
+		if server.GetHostID() == hostID {
+			out = append(out, server)
+		}
+	}
+	return out, nil
+}
+
+// scanKubeServers walks seq (a single index bucket, already positioned at
+// key) and copies out only the servers whose indexValue still matches key,
+// so its Copy() calls scale with the size of that bucket rather than the
+// total cached population.
+func scanKubeServers(seq iter.Seq[types.KubeServer], key string, indexValue func(types.KubeServer) string) []types.KubeServer {
+	var out []types.KubeServer
+	for s := range seq {
+		if indexValue(s) != key {
+			break
+		}
+		out = append(out, s.Copy())
+	}
+	return out
+}
+
 func newKubernetesClusterCollection(k services.Kubernetes, w types.WatchKind) (*collection[types.KubeCluster], error) {
 	if k == nil {
 		return nil, trace.BadParameter("missing parameter Kubernetes")
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/lib/services"
+)
+
+func newMCPKeyCollection(m services.MCPKeys, w types.WatchKind) (*collection[types.MCPKey], error) {
+	if m == nil {
+		return nil, trace.BadParameter("missing parameter MCPKeys")
+	}
+
+	return &collection[types.MCPKey]{
+		store: newStore(map[string]func(types.MCPKey) string{
+			"name": func(k types.MCPKey) string {
+				return k.GetName()
+			},
+		}),
+		fetcher: func(ctx context.Context, loadSecrets bool) ([]types.MCPKey, error) {
+			return m.GetMCPKeys(ctx)
+		},
+		headerTransform: func(hdr *types.ResourceHeader) types.MCPKey {
+			return &types.MCPKeyV1{
+				Kind:    types.KindMCPKey,
+				Version: types.V1,
+				Metadata: types.Metadata{
+					Name: hdr.Metadata.Name,
+				},
+			}
+		},
+		watch: w,
+	}, nil
+}
+
+// GetMCPKeys returns every MCP key revocation record in the cluster. A
+// record existing here doesn't grant anything by itself -- it's consulted
+// by the auth server's second-line-of-defense check (alongside a Key's own
+// signature and validity window) to reject calls made with a key that's
+// since been revoked, even if the key file itself hasn't expired.
+func (c *Cache) GetMCPKeys(ctx context.Context) ([]types.MCPKey, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetMCPKeys")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.mcpKeys)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if rg.ReadCache() {
+		out := make([]types.MCPKey, 0, rg.store.len())
+		for k := range rg.store.resources("name", "", "") {
+			out = append(out, k.Copy())
+		}
+
+		return out, nil
+	}
+
+	keys, err := c.Config.MCPKeys.GetMCPKeys(ctx)
+	return keys, trace.Wrap(err)
+}
+
+// GetMCPKey returns the specified MCP key revocation record.
+func (c *Cache) GetMCPKey(ctx context.Context, name string) (types.MCPKey, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetMCPKey")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.mcpKeys)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if rg.ReadCache() {
+		k, err := rg.store.get("name", name)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+
+		return k.Copy(), nil
+	}
+
+	key, err := c.Config.MCPKeys.GetMCPKey(ctx, name)
+	return key, trace.Wrap(err)
+}
@@ -105,10 +105,25 @@ func (c *Cache) listResources(ctx context.Context, req proto.ListResourcesReques
 		limit = defaults.DefaultChunkSize
 	}
 
+	// secondaryIndex lets callers sort by an index other than "name", e.g.
+	// SortBy.Field == "description". Collections that don't register the
+	// requested index fall back to "name".
+	index := "name"
+	if req.SortBy.Field != "" {
+		index = req.SortBy.Field
+	}
+	reverse := req.SortBy.IsDesc
+
+	// start/end bound the index range this page's store.resources call
+	// covers, shrinking on every subsequent call so pagination terminates.
+	// See paginationBounds for why forward and reverse need different
+	// bounds.
+	start, end := paginationBounds(req.StartKey, reverse)
+
 	switch req.ResourceType {
 	case types.KindDatabaseServer:
 		resp, err := buildListResourcesResponse(
-			c.collections.dbServers.store.resources("name", req.StartKey, ""),
+			orderedResources(c.collections.dbServers.store.resources(index, start, end), reverse),
 			limit,
 			filter,
 			types.DatabaseServer.CloneResource,
@@ -116,7 +131,7 @@ func (c *Cache) listResources(ctx context.Context, req proto.ListResourcesReques
 		return resp, trace.Wrap(err)
 	case types.KindDatabaseService:
 		resp, err := buildListResourcesResponse(
-			c.collections.dbServices.store.resources("name", req.StartKey, ""),
+			orderedResources(c.collections.dbServices.store.resources(index, start, end), reverse),
 			limit,
 			filter,
 			func(d types.DatabaseService) types.ResourceWithLabels {
@@ -126,7 +141,7 @@ func (c *Cache) listResources(ctx context.Context, req proto.ListResourcesReques
 		return resp, trace.Wrap(err)
 	case types.KindAppServer:
 		resp, err := buildListResourcesResponse(
-			c.collections.appServers.store.resources("name", req.StartKey, ""),
+			orderedResources(c.collections.appServers.store.resources(index, start, end), reverse),
 			limit,
 			filter,
 			types.AppServer.CloneResource,
@@ -134,7 +149,7 @@ func (c *Cache) listResources(ctx context.Context, req proto.ListResourcesReques
 		return resp, trace.Wrap(err)
 	case types.KindNode:
 		resp, err := buildListResourcesResponse(
-			c.collections.nodes.store.resources("name", req.StartKey, ""),
+			orderedResources(c.collections.nodes.store.resources(index, start, end), reverse),
 			limit,
 			filter,
 			types.Server.CloneResource,
@@ -142,7 +157,7 @@ func (c *Cache) listResources(ctx context.Context, req proto.ListResourcesReques
 		return resp, trace.Wrap(err)
 	case types.KindWindowsDesktopService:
 		resp, err := buildListResourcesResponse(
-			c.collections.windowsDesktopServices.store.resources("name", req.StartKey, ""),
+			orderedResources(c.collections.windowsDesktopServices.store.resources(index, start, end), reverse),
 			limit,
 			filter,
 			func(d types.WindowsDesktopService) types.ResourceWithLabels {
@@ -152,23 +167,94 @@ func (c *Cache) listResources(ctx context.Context, req proto.ListResourcesReques
 		return resp, trace.Wrap(err)
 	case types.KindKubeServer:
 		resp, err := buildListResourcesResponse(
-			c.collections.kubeServers.store.resources("name", req.StartKey, ""),
+			orderedResources(c.collections.kubeServers.store.resources(index, start, end), reverse),
 			limit,
 			filter,
 			types.KubeServer.CloneResource,
 		)
 		return resp, trace.Wrap(err)
 	case types.KindUserGroup:
-		return nil, trace.NotImplemented("%s not implemented at ListResources", req.ResourceType)
+		resp, err := buildListResourcesResponse(
+			orderedResources(c.collections.userGroups.store.resources(index, start, end), reverse),
+			limit,
+			filter,
+			func(g types.UserGroup) types.ResourceWithLabels {
+				return g.Clone()
+			},
+		)
+		return resp, trace.Wrap(err)
 	case types.KindIdentityCenterAccount:
-		return nil, trace.NotImplemented("%s not implemented at ListResources", req.ResourceType)
+		resp, err := buildListResourcesResponse(
+			orderedResources(c.collections.identityCenterAccounts.store.resources(index, start, end), reverse),
+			limit,
+			filter,
+			func(a types.IdentityCenterAccount) types.ResourceWithLabels {
+				return a.Clone()
+			},
+		)
+		return resp, trace.Wrap(err)
 	case types.KindIdentityCenterAccountAssignment:
-		return nil, trace.NotImplemented("%s not implemented at ListResources", req.ResourceType)
+		resp, err := buildListResourcesResponse(
+			orderedResources(c.collections.identityCenterAccountAssignments.store.resources(index, start, end), reverse),
+			limit,
+			filter,
+			func(a types.IdentityCenterAccountAssignment) types.ResourceWithLabels {
+				return a.Clone()
+			},
+		)
+		return resp, trace.Wrap(err)
 	default:
 		return nil, trace.NotImplemented("%s not implemented at ListResources", req.ResourceType)
 	}
 }
 
+// paginationBounds returns the [start, end) range a single page's
+// store.resources call should cover for the given index, so consecutive
+// pages shrink the range instead of re-querying it in full each time.
+//
+// Forward iteration resumes at startKey, which GetPaginationKey sets to the
+// next not-yet-returned resource's own index value, and runs unbounded from
+// there.
+//
+// Reverse iteration has to bound the *other* end, then let orderedResources
+// flip the fetched range to descending order: it runs from the beginning of
+// the index up to, and including, startKey. end is exclusive, so startKey's
+// own bucket is kept in range by appending "\x00" - the lowest possible
+// byte, and therefore the immediate lexicographic successor of any string -
+// giving an end bound that excludes everything after startKey without
+// excluding startKey itself.
+func paginationBounds(startKey string, reverse bool) (start, end string) {
+	if reverse {
+		if startKey == "" {
+			return "", ""
+		}
+		return "", startKey + "\x00"
+	}
+	return startKey, ""
+}
+
+// orderedResources optionally reverses seq. The store's native iteration
+// order is ascending by the requested index; reverse==true is used to
+// satisfy SortBy.IsDesc without requiring every collection's store to
+// support descending iteration natively.
+func orderedResources[T any](seq iter.Seq[T], reverse bool) iter.Seq[T] {
+	if !reverse {
+		return seq
+	}
+
+	return func(yield func(T) bool) {
+		var all []T
+		for r := range seq {
+			all = append(all, r)
+		}
+		for i := len(all) - 1; i >= 0; i-- {
+			if !yield(all[i]) {
+				return
+			}
+		}
+	}
+}
+
 func buildListResourcesResponse[T types.ResourceWithLabels](resources iter.Seq[T], limit int, filter services.MatchResourceFilter, cloneFn func(T) types.ResourceWithLabels) (*types.ListResourcesResponse, error) {
 	var resp types.ListResourcesResponse
 	for r := range resources {
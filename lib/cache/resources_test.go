@@ -0,0 +1,197 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package cache
+
+import (
+	"fmt"
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/lib/backend"
+	"github.com/gravitational/teleport/lib/services"
+)
+
+func seqOf(servers ...types.Server) iter.Seq[types.Server] {
+	return func(yield func(types.Server) bool) {
+		for _, s := range servers {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+func newTestServer(t *testing.T, name string, labels map[string]string) types.Server {
+	t.Helper()
+	srv, err := types.NewServerWithLabels(name, types.KindNode, types.ServerSpecV2{}, labels)
+	require.NoError(t, err)
+	return srv
+}
+
+func TestBuildListResourcesResponse(t *testing.T) {
+	a := newTestServer(t, "a", map[string]string{"env": "prod"})
+	b := newTestServer(t, "b", map[string]string{"env": "staging"})
+	c := newTestServer(t, "c", map[string]string{"env": "prod"})
+
+	t.Run("label filter matches subset", func(t *testing.T) {
+		filter := services.MatchResourceFilter{
+			ResourceKind: types.KindNode,
+			Labels:       map[string]string{"env": "prod"},
+		}
+		resp, err := buildListResourcesResponse(seqOf(a, b, c), 10, filter, types.Server.CloneResource)
+		require.NoError(t, err)
+		require.Empty(t, resp.NextKey)
+		require.Len(t, resp.Resources, 2)
+		require.Equal(t, "a", resp.Resources[0].GetName())
+		require.Equal(t, "c", resp.Resources[1].GetName())
+	})
+
+	t.Run("limit sets next key via pagination key", func(t *testing.T) {
+		filter := services.MatchResourceFilter{ResourceKind: types.KindNode}
+		resp, err := buildListResourcesResponse(seqOf(a, b, c), 2, filter, types.Server.CloneResource)
+		require.NoError(t, err)
+		require.Len(t, resp.Resources, 2)
+		require.Equal(t, backend.GetPaginationKey(c), resp.NextKey)
+	})
+
+	t.Run("predicate expression filters resources", func(t *testing.T) {
+		expr, err := services.NewResourceExpression(`labels.env == "staging"`)
+		require.NoError(t, err)
+		filter := services.MatchResourceFilter{
+			ResourceKind:        types.KindNode,
+			PredicateExpression: expr,
+		}
+		resp, err := buildListResourcesResponse(seqOf(a, b, c), 10, filter, types.Server.CloneResource)
+		require.NoError(t, err)
+		require.Len(t, resp.Resources, 1)
+		require.Equal(t, "b", resp.Resources[0].GetName())
+	})
+
+	t.Run("reverse order yields descending results", func(t *testing.T) {
+		filter := services.MatchResourceFilter{ResourceKind: types.KindNode}
+		resp, err := buildListResourcesResponse(orderedResources(seqOf(a, b, c), true), 10, filter, types.Server.CloneResource)
+		require.NoError(t, err)
+		require.Len(t, resp.Resources, 3)
+		require.Equal(t, []string{"c", "b", "a"}, []string{
+			resp.Resources[0].GetName(), resp.Resources[1].GetName(), resp.Resources[2].GetName(),
+		})
+	})
+
+}
+
+// rangeSeq mimics the [start, end) ascending-by-name range a real store's
+// resources("name", start, end) call returns, so
+// TestListResourcesDescendingPagination can drive Cache.listResources's
+// page-boundary logic (paginationBounds) without a real store.
+func rangeSeq(servers []types.Server, start, end string) iter.Seq[types.Server] {
+	return func(yield func(types.Server) bool) {
+		for _, s := range servers {
+			if start != "" && s.GetName() < start {
+				continue
+			}
+			if end != "" && s.GetName() >= end {
+				continue
+			}
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+// TestListResourcesDescendingPagination walks 10k synthetic nodes in
+// descending name order, page by page, reusing the exact bound-selection
+// logic Cache.listResources relies on (paginationBounds). It asserts the
+// walk terminates and visits every node exactly once, in strict descending
+// order - the bug this guards against was every page re-querying the
+// entire unbounded range the first page already covered, which either
+// duplicated resources across page boundaries or never made progress past
+// the first page.
+func TestListResourcesDescendingPagination(t *testing.T) {
+	const total = 10_000
+	servers := make([]types.Server, total)
+	for i := range servers {
+		servers[i] = newTestServer(t, fmt.Sprintf("node-%05d", i), nil)
+	}
+
+	filter := services.MatchResourceFilter{ResourceKind: types.KindNode}
+	const pageSize = 37 // deliberately doesn't divide evenly into total
+
+	var seen []string
+	startKey := ""
+	for page := 0; ; page++ {
+		require.Lessf(t, page, total, "pagination did not terminate")
+
+		start, end := paginationBounds(startKey, true /* reverse */)
+		resp, err := buildListResourcesResponse(
+			orderedResources(rangeSeq(servers, start, end), true), pageSize, filter, types.Server.CloneResource,
+		)
+		require.NoError(t, err)
+
+		for _, r := range resp.Resources {
+			seen = append(seen, r.GetName())
+		}
+
+		if resp.NextKey == "" {
+			break
+		}
+		startKey = resp.NextKey
+	}
+
+	require.Len(t, seen, total)
+
+	wantNames := make([]string, total)
+	for i, s := range servers {
+		wantNames[total-1-i] = s.GetName()
+	}
+	require.Equal(t, wantNames, seen)
+}
+
+func TestOrderedResources(t *testing.T) {
+	a := newTestServer(t, "a", nil)
+	b := newTestServer(t, "b", nil)
+	c := newTestServer(t, "c", nil)
+
+	t.Run("not reversed is a passthrough", func(t *testing.T) {
+		var out []string
+		for s := range orderedResources(seqOf(a, b, c), false) {
+			out = append(out, s.GetName())
+		}
+		require.Equal(t, []string{"a", "b", "c"}, out)
+	})
+
+	t.Run("reversed iterates in descending order", func(t *testing.T) {
+		var out []string
+		for s := range orderedResources(seqOf(a, b, c), true) {
+			out = append(out, s.GetName())
+		}
+		require.Equal(t, []string{"c", "b", "a"}, out)
+	})
+
+	t.Run("reversed stops early when yield returns false", func(t *testing.T) {
+		var out []string
+		for s := range orderedResources(seqOf(a, b, c), true) {
+			out = append(out, s.GetName())
+			if len(out) == 1 {
+				break
+			}
+		}
+		require.Equal(t, []string{"c"}, out)
+	})
+}
@@ -0,0 +1,127 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+package cache
+
+import (
+	"context"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/lib/services"
+)
+
+func newUserGroupCollection(u services.UserGroups, w types.WatchKind) (*collection[types.UserGroup], error) {
+	if u == nil {
+		return nil, trace.BadParameter("missing parameter UserGroups")
+	}
+
+	return &collection[types.UserGroup]{
+		store: newStore(map[string]func(types.UserGroup) string{
+			"name": func(u types.UserGroup) string {
+				return u.GetName()
+			},
+		}),
+		fetcher: func(ctx context.Context, loadSecrets bool) ([]types.UserGroup, error) {
+			var groups []types.UserGroup
+			var nextToken string
+			for {
+				page, token, err := u.ListUserGroups(ctx, 0, nextToken)
+				if err != nil {
+					return nil, trace.Wrap(err)
+				}
+				groups = append(groups, page...)
+				if token == "" {
+					break
+				}
+				nextToken = token
+			}
+			return groups, nil
+		},
+		headerTransform: func(hdr *types.ResourceHeader) types.UserGroup {
+			return &types.UserGroupV1{
+				ResourceHeader: types.ResourceHeader{
+					Kind:    types.KindUserGroup,
+					Version: types.V1,
+					Metadata: types.Metadata{
+						Name: hdr.Metadata.Name,
+					},
+				},
+			}
+		},
+		watch: w,
+	}, nil
+}
+
+// GetUserGroups returns all user group resources.
+func (c *Cache) GetUserGroups(ctx context.Context) ([]types.UserGroup, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetUserGroups")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.userGroups)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if !rg.ReadCache() {
+		var groups []types.UserGroup
+		var nextToken string
+		for {
+			page, token, err := c.Config.UserGroups.ListUserGroups(ctx, 0, nextToken)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+			groups = append(groups, page...)
+			if token == "" {
+				break
+			}
+			nextToken = token
+		}
+		return groups, nil
+	}
+
+	out := make([]types.UserGroup, 0, rg.store.len())
+	for g := range rg.store.resources("name", "", "") {
+		out = append(out, g.Clone())
+	}
+
+	return out, nil
+}
+
+// GetUserGroup returns the specified user group resource.
+func (c *Cache) GetUserGroup(ctx context.Context, name string) (types.UserGroup, error) {
+	ctx, span := c.Tracer.Start(ctx, "cache/GetUserGroup")
+	defer span.End()
+
+	rg, err := acquireReadGuard(c, c.collections.userGroups)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	defer rg.Release()
+
+	if !rg.ReadCache() {
+		group, err := c.Config.UserGroups.GetUserGroup(ctx, name)
+		return group, trace.Wrap(err)
+	}
+
+	g, err := rg.store.get("name", name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	return g.Clone(), nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/gravitational/trace"
+
+	apievents "github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/teleport/lib/events"
+)
+
+const (
+	// asciicastVersion is the only asciicast format version this package
+	// writes. v2's event-stream-of-lines shape maps directly onto a proto
+	// session recording's already-ordered events, unlike v1's single
+	// up-front array.
+	asciicastVersion = 2
+
+	// defaultTermWidth and defaultTermHeight seed the .cast header for a
+	// recording whose first terminal size isn't known up front. A resize
+	// event, if one appears before any output, corrects them via an "r"
+	// record rather than rewriting the header after the fact.
+	defaultTermWidth  = 80
+	defaultTermHeight = 24
+)
+
+// asciicastHeader is the first line of an asciicast v2 recording.
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// ExportAsciicast reads a proto-encoded session recording from r and writes
+// it to w as an asciinema v2 .cast file: a JSON header followed by one
+// `[time, "o", data]` line per terminal output chunk and one
+// `[time, "r", "WxH"]` line per terminal resize, with time measured in
+// seconds elapsed since the start of the recording (derived from each
+// SessionPrint's DelayMilliseconds, the same field the live player uses to
+// pace playback).
+//
+// width and height seed the header for recordings whose terminal size isn't
+// known ahead of the first resize event; pass 0 for Teleport's usual
+// defaults (80x24).
+func ExportAsciicast(ctx context.Context, r io.Reader, w io.Writer, width, height int) error {
+	if width <= 0 {
+		width = defaultTermWidth
+	}
+	if height <= 0 {
+		height = defaultTermHeight
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(asciicastHeader{
+		Version: asciicastVersion,
+		Width:   width,
+		Height:  height,
+	}); err != nil {
+		return trace.Wrap(err, "writing asciicast header")
+	}
+
+	pr := events.NewProtoReader(r)
+	var elapsed time.Duration
+	for {
+		evt, err := pr.Read(ctx)
+		if err != nil {
+			if trace.IsEOF(err) {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+
+		switch e := evt.(type) {
+		case *apievents.SessionPrint:
+			elapsed += time.Duration(e.DelayMilliseconds) * time.Millisecond
+			if err := enc.Encode([]any{elapsed.Seconds(), "o", string(e.Data)}); err != nil {
+				return trace.Wrap(err, "writing asciicast output record")
+			}
+		case *apievents.Resize:
+			if err := enc.Encode([]any{elapsed.Seconds(), "r", e.TerminalSize}); err != nil {
+				return trace.Wrap(err, "writing asciicast resize record")
+			}
+		}
+	}
+}
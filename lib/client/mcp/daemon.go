@@ -0,0 +1,745 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// reloginMarginDefault is how far ahead of a credential's expiry
+	// DaemonConfig.ReloginMargin defaults to re-dialing a tunnel.
+	reloginMarginDefault = 2 * time.Minute
+	// drainTimeoutDefault is how long DaemonConfig.DrainTimeout defaults to
+	// waiting for attached sessions to finish before Shutdown forces them
+	// closed.
+	drainTimeoutDefault = 10 * time.Second
+	// certExpiryPollInterval is how often a tunnel's credential expiry is
+	// checked once DaemonConfig.CertExpiry is set.
+	certExpiryPollInterval = 30 * time.Second
+)
+
+// TunnelKey identifies one warm upstream tunnel a Daemon multiplexes
+// client sessions over: a single MCP app in a single Teleport cluster.
+type TunnelKey struct {
+	Cluster string
+	App     string
+}
+
+func (k TunnelKey) String() string { return k.Cluster + "/" + k.App }
+
+// DialTunnelFunc dials (or re-dials) the warm connection backing a
+// TunnelKey - in practice an alpnproxy.LocalProxy-fronted tunnel to the app
+// - performing whatever app login, cert loading, and ALPN handshake that
+// requires.
+type DialTunnelFunc func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error)
+
+// CertExpiryFunc returns the expiry of the credential backing key's
+// tunnel, so the daemon can proactively re-dial ahead of it rather than
+// waiting for an in-flight call to fail.
+type CertExpiryFunc func(key TunnelKey) (time.Time, error)
+
+// DaemonConfig configures a Daemon.
+type DaemonConfig struct {
+	// SocketPath is the Unix domain socket (a Windows named pipe isn't
+	// implemented here) the daemon listens on for attach requests from
+	// `tsh mcp start --via-daemon`.
+	SocketPath string
+	// DialTunnel dials a tunnel for a TunnelKey the daemon hasn't seen yet,
+	// or whose tunnel is being re-dialed ahead of cert expiry.
+	DialTunnel DialTunnelFunc
+	// CertExpiry, if set, is polled periodically for every open tunnel so
+	// an about-to-expire credential is refreshed before it fails a call
+	// outright. A nil CertExpiry disables proactive re-login; a tunnel is
+	// then only re-dialed the next time a client attaches after it drops.
+	CertExpiry CertExpiryFunc
+	// Logger is used for all daemon logging. Defaults to slog.Default().
+	Logger *slog.Logger
+	// ReloginMargin is how far ahead of a credential's expiry the daemon
+	// re-dials its tunnel. Defaults to reloginMarginDefault.
+	ReloginMargin time.Duration
+	// DrainTimeout bounds how long Shutdown waits for attached sessions to
+	// finish their in-flight requests before forcibly closing them.
+	// Defaults to drainTimeoutDefault.
+	DrainTimeout time.Duration
+}
+
+func (c *DaemonConfig) checkAndSetDefaults() error {
+	if c.SocketPath == "" {
+		return trace.BadParameter("missing socket path")
+	}
+	if c.DialTunnel == nil {
+		return trace.BadParameter("missing tunnel dialer")
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	if c.ReloginMargin <= 0 {
+		c.ReloginMargin = reloginMarginDefault
+	}
+	if c.DrainTimeout <= 0 {
+		c.DrainTimeout = drainTimeoutDefault
+	}
+	return nil
+}
+
+// Daemon is the long-running process behind `tsh mcp daemon`: it listens
+// on a Unix socket for attach requests from thin `tsh mcp start
+// --via-daemon` shims, keeps one warm tunnel per TunnelKey, and
+// multiplexes every attached client's JSON-RPC traffic over it by
+// namespacing request ids, so N concurrent MCP clients for the same app
+// share one login, one cert, and one ALPN tunnel instead of each paying
+// for their own.
+type Daemon struct {
+	cfg DaemonConfig
+
+	mu            sync.Mutex
+	tunnels       map[TunnelKey]*sharedTunnel
+	nextSessionID atomic.Uint64
+
+	listener net.Listener
+	wg       sync.WaitGroup
+	closing  atomic.Bool
+}
+
+// NewDaemon constructs a Daemon from cfg. Call ListenAndServe to start it.
+func NewDaemon(cfg DaemonConfig) (*Daemon, error) {
+	if err := cfg.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Daemon{
+		cfg:     cfg,
+		tunnels: make(map[TunnelKey]*sharedTunnel),
+	}, nil
+}
+
+// ListenAndServe binds cfg.SocketPath and serves attach requests until ctx
+// is done or Shutdown is called, at which point it returns nil. It removes
+// a stale socket file a previous, uncleanly-terminated daemon may have left
+// behind before binding.
+func (d *Daemon) ListenAndServe(ctx context.Context) error {
+	_ = os.Remove(d.cfg.SocketPath)
+	ln, err := net.Listen("unix", d.cfg.SocketPath)
+	if err != nil {
+		return trace.Wrap(err, "listening on %v", d.cfg.SocketPath)
+	}
+	// Every attached client rides the daemon's already-authenticated tunnel
+	// with no re-authentication of its own, so the socket must not be
+	// reachable by any other local user. Restrict it to owner-only as a
+	// first line of defense, on top of the peer-UID check every accepted
+	// connection also gets below.
+	if err := os.Chmod(d.cfg.SocketPath, 0o600); err != nil {
+		ln.Close()
+		return trace.Wrap(err, "restricting permissions on %v", d.cfg.SocketPath)
+	}
+	d.listener = ln
+
+	go func() {
+		<-ctx.Done()
+		d.closing.Store(true)
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if d.closing.Load() {
+				d.wg.Wait()
+				return nil
+			}
+			return trace.Wrap(err, "accepting MCP daemon client")
+		}
+		if err := d.checkPeerUID(ctx, conn); err != nil {
+			d.cfg.Logger.WarnContext(ctx, "Rejecting MCP daemon connection", "error", err)
+			conn.Close()
+			continue
+		}
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			if err := d.handleConn(ctx, conn); err != nil {
+				d.cfg.Logger.DebugContext(ctx, "MCP daemon client session ended", "error", err)
+			}
+		}()
+	}
+}
+
+// checkPeerUID rejects conn unless the connecting process's UID matches the
+// daemon's own. Without this, any other local user could attach to the
+// socket and ride whichever tunnels are already warm, for any app/cluster
+// the daemon's owner is logged into, with no authentication of their own.
+// A platform that can't report peer credentials (see peerUID) only gets a
+// warning, not a rejection, since Chmod(0600) above is still in effect for
+// it.
+func (d *Daemon) checkPeerUID(ctx context.Context, conn net.Conn) error {
+	uid, err := peerUID(conn)
+	if err != nil {
+		d.cfg.Logger.WarnContext(ctx, "Could not verify MCP daemon client's peer credentials, relying on socket permissions alone", "error", err)
+		return nil
+	}
+	if want := uint32(os.Getuid()); uid != want {
+		return trace.AccessDenied("connecting UID %d does not match daemon UID %d", uid, want)
+	}
+	return nil
+}
+
+// Shutdown stops accepting new connections and waits up to
+// cfg.DrainTimeout for attached sessions to finish before forcibly closing
+// every tunnel - a graceful response to SIGTERM.
+func (d *Daemon) Shutdown(ctx context.Context) error {
+	d.closing.Store(true)
+	if d.listener != nil {
+		d.listener.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(d.cfg.DrainTimeout):
+		d.cfg.Logger.WarnContext(ctx, "MCP daemon drain timed out, forcing remaining sessions closed")
+	case <-ctx.Done():
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var errs []error
+	for key, tunnel := range d.tunnels {
+		errs = append(errs, tunnel.conn.Close())
+		delete(d.tunnels, key)
+	}
+	return trace.NewAggregate(errs...)
+}
+
+// TunnelStatus is a point-in-time snapshot of one shared tunnel, for `tsh
+// mcp daemon status`.
+type TunnelStatus struct {
+	Cluster     string
+	App         string
+	Age         time.Duration
+	ClientCount int
+}
+
+// Status returns every currently open tunnel's status.
+func (d *Daemon) Status() []TunnelStatus {
+	d.mu.Lock()
+	tunnels := make([]*sharedTunnel, 0, len(d.tunnels))
+	for _, t := range d.tunnels {
+		tunnels = append(tunnels, t)
+	}
+	d.mu.Unlock()
+
+	out := make([]TunnelStatus, 0, len(tunnels))
+	for _, t := range tunnels {
+		out = append(out, TunnelStatus{
+			Cluster:     t.key.Cluster,
+			App:         t.key.App,
+			Age:         t.age(),
+			ClientCount: t.sessionCount(),
+		})
+	}
+	return out
+}
+
+// attachRequest is the first line a client sends after connecting to the
+// daemon's socket. A QueryStatus request is answered with a single
+// statusResponse line and the connection is then closed; it never attaches
+// a session, and App/Cluster are ignored.
+type attachRequest struct {
+	App         string `json:"app"`
+	Cluster     string `json:"cluster"`
+	QueryStatus bool   `json:"query_status,omitempty"`
+}
+
+// attachResponse is the daemon's reply to an attachRequest. Once OK, every
+// subsequent line on the connection is a JSON-RPC frame.
+type attachResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// statusResponse is the daemon's reply to a QueryStatus attachRequest.
+type statusResponse struct {
+	Tunnels []TunnelStatus `json:"tunnels"`
+}
+
+func (d *Daemon) handleConn(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+
+	scanner := newFrameScanner(conn)
+	if !scanner.Scan() {
+		return trace.Wrap(scanner.Err(), "reading attach request")
+	}
+	var req attachRequest
+	if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+		respondAttach(conn, trace.Wrap(err, "invalid attach request"))
+		return trace.Wrap(err)
+	}
+
+	if req.QueryStatus {
+		data, err := json.Marshal(statusResponse{Tunnels: d.Status()})
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		return trace.Wrap(writeFrame(conn, data))
+	}
+
+	if req.App == "" || req.Cluster == "" {
+		err := trace.BadParameter("attach request requires both app and cluster")
+		respondAttach(conn, err)
+		return trace.Wrap(err)
+	}
+
+	key := TunnelKey{Cluster: req.Cluster, App: req.App}
+	tunnel, err := d.getOrDialTunnel(ctx, key)
+	if err != nil {
+		respondAttach(conn, err)
+		return trace.Wrap(err)
+	}
+	if err := respondAttach(conn, nil); err != nil {
+		return trace.Wrap(err)
+	}
+
+	session := tunnel.attach(d.nextSessionID.Add(1), conn)
+	defer tunnel.detach(session.id)
+
+	d.cfg.Logger.InfoContext(ctx, "MCP client attached", "tunnel", key, "session", session.id)
+	defer d.cfg.Logger.InfoContext(ctx, "MCP client detached", "tunnel", key, "session", session.id)
+
+	return session.pumpFromClient()
+}
+
+func respondAttach(conn net.Conn, cause error) error {
+	resp := attachResponse{OK: cause == nil}
+	if cause != nil {
+		resp.Error = cause.Error()
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return writeFrame(conn, data)
+}
+
+func (d *Daemon) getOrDialTunnel(ctx context.Context, key TunnelKey) (*sharedTunnel, error) {
+	d.mu.Lock()
+	if tunnel, ok := d.tunnels[key]; ok {
+		d.mu.Unlock()
+		return tunnel, nil
+	}
+	d.mu.Unlock()
+
+	conn, err := d.cfg.DialTunnel(ctx, key)
+	if err != nil {
+		return nil, trace.Wrap(err, "dialing MCP tunnel for %v", key)
+	}
+	tunnel := newSharedTunnel(key, conn)
+
+	d.mu.Lock()
+	if existing, ok := d.tunnels[key]; ok {
+		d.mu.Unlock()
+		conn.Close()
+		return existing, nil
+	}
+	d.tunnels[key] = tunnel
+	d.mu.Unlock()
+
+	go d.readTunnel(ctx, tunnel)
+	if d.cfg.CertExpiry != nil {
+		go d.watchCertExpiry(ctx, tunnel)
+	}
+	return tunnel, nil
+}
+
+// readTunnel demultiplexes frames arriving from tunnel's upstream
+// connection to whichever attached session they belong to, for as long as
+// the tunnel exists. A read error ends the loop unless it was caused by
+// watchCertExpiry swapping in a freshly re-dialed connection, in which
+// case it keeps serving the replacement rather than tearing down every
+// attached session.
+func (d *Daemon) readTunnel(ctx context.Context, tunnel *sharedTunnel) {
+	for {
+		conn, generation := tunnel.currentConn()
+		scanner := newFrameScanner(conn)
+		for scanner.Scan() {
+			frame, err := ParseFrame(scanner.Bytes())
+			if err != nil {
+				d.cfg.Logger.WarnContext(ctx, "Discarding malformed frame from MCP tunnel", "tunnel", tunnel.key, "error", err)
+				continue
+			}
+			tunnel.dispatchFromUpstream(frame)
+		}
+
+		if tunnel.generationChangedSince(generation) {
+			continue
+		}
+
+		d.cfg.Logger.WarnContext(ctx, "MCP tunnel closed", "tunnel", tunnel.key, "error", scanner.Err())
+		d.removeTunnel(tunnel.key)
+		tunnel.closeAllSessions()
+		return
+	}
+}
+
+func (d *Daemon) removeTunnel(key TunnelKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.tunnels, key)
+}
+
+// watchCertExpiry re-dials tunnel once its credential is within
+// cfg.ReloginMargin of expiring, then exits; readTunnel picks up the
+// replacement connection on its own.
+func (d *Daemon) watchCertExpiry(ctx context.Context, tunnel *sharedTunnel) {
+	ticker := time.NewTicker(certExpiryPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		expiry, err := d.cfg.CertExpiry(tunnel.key)
+		if err != nil {
+			d.cfg.Logger.WarnContext(ctx, "Failed to check MCP tunnel cert expiry", "tunnel", tunnel.key, "error", err)
+			continue
+		}
+		if time.Until(expiry) > d.cfg.ReloginMargin {
+			continue
+		}
+
+		d.cfg.Logger.InfoContext(ctx, "MCP tunnel credential nearing expiry, re-dialing", "tunnel", tunnel.key, "expiry", expiry)
+		newConn, err := d.cfg.DialTunnel(ctx, tunnel.key)
+		if err != nil {
+			d.cfg.Logger.ErrorContext(ctx, "Failed to re-dial MCP tunnel ahead of cert expiry", "tunnel", tunnel.key, "error", err)
+			continue
+		}
+		if err := tunnel.swapConn(newConn); err != nil {
+			d.cfg.Logger.DebugContext(ctx, "Closing previous MCP tunnel connection after re-dial", "tunnel", tunnel.key, "error", err)
+		}
+		return
+	}
+}
+
+// sharedTunnel is one warm upstream connection multiplexed across every
+// client session attached for its TunnelKey.
+type sharedTunnel struct {
+	key       TunnelKey
+	createdAt time.Time
+
+	mu         sync.Mutex
+	conn       io.ReadWriteCloser
+	generation int
+	writeMu    sync.Mutex
+
+	sessMu   sync.Mutex
+	sessions map[uint64]*mcpSession
+}
+
+func newSharedTunnel(key TunnelKey, conn io.ReadWriteCloser) *sharedTunnel {
+	return &sharedTunnel{
+		key:       key,
+		conn:      conn,
+		createdAt: time.Now(),
+		sessions:  make(map[uint64]*mcpSession),
+	}
+}
+
+func (t *sharedTunnel) age() time.Duration { return time.Since(t.createdAt) }
+
+func (t *sharedTunnel) currentConn() (io.ReadWriteCloser, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.conn, t.generation
+}
+
+func (t *sharedTunnel) generationChangedSince(generation int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.generation != generation
+}
+
+// swapConn installs newConn in place of the tunnel's current connection
+// and closes the old one, which unblocks readTunnel's in-flight read so it
+// can pick up the replacement without tearing down attached sessions.
+func (t *sharedTunnel) swapConn(newConn io.ReadWriteCloser) error {
+	t.mu.Lock()
+	old := t.conn
+	t.conn = newConn
+	t.generation++
+	t.mu.Unlock()
+	return old.Close()
+}
+
+// writeFrame serializes writes from every attached session onto the
+// tunnel's single underlying connection.
+func (t *sharedTunnel) writeFrame(data []byte) error {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return writeFrame(conn, data)
+}
+
+func (t *sharedTunnel) attach(id uint64, conn net.Conn) *mcpSession {
+	session := &mcpSession{
+		id:      id,
+		conn:    conn,
+		tunnel:  t,
+		pending: make(map[string]json.RawMessage),
+	}
+	t.sessMu.Lock()
+	t.sessions[id] = session
+	t.sessMu.Unlock()
+	return session
+}
+
+func (t *sharedTunnel) detach(id uint64) {
+	t.sessMu.Lock()
+	delete(t.sessions, id)
+	t.sessMu.Unlock()
+}
+
+func (t *sharedTunnel) closeAllSessions() {
+	t.sessMu.Lock()
+	defer t.sessMu.Unlock()
+	for _, session := range t.sessions {
+		session.conn.Close()
+	}
+}
+
+func (t *sharedTunnel) sessionCount() int {
+	t.sessMu.Lock()
+	defer t.sessMu.Unlock()
+	return len(t.sessions)
+}
+
+// dispatchFromUpstream routes one frame read off the shared tunnel to
+// whichever attached session it belongs to. A notification (no id) isn't
+// addressed to any one session, so it's broadcast to all of them; a
+// response's namespaced id says exactly which session to deliver it to.
+func (t *sharedTunnel) dispatchFromUpstream(frame *Frame) {
+	if frame.IsNotification() {
+		t.sessMu.Lock()
+		sessions := make([]*mcpSession, 0, len(t.sessions))
+		for _, session := range t.sessions {
+			sessions = append(sessions, session)
+		}
+		t.sessMu.Unlock()
+		for _, session := range sessions {
+			writeFrame(session.conn, frame.raw)
+		}
+		return
+	}
+	if !frame.IsResponse() {
+		return
+	}
+
+	var namespacedID string
+	if err := json.Unmarshal(frame.ID, &namespacedID); err != nil {
+		// Every request this tunnel ever forwards carries a namespaced
+		// string id (see mcpSession.pumpFromClient); anything else isn't
+		// one of ours to route.
+		return
+	}
+	sessionID, ok := parseSessionID(namespacedID)
+	if !ok {
+		return
+	}
+
+	t.sessMu.Lock()
+	session, ok := t.sessions[sessionID]
+	t.sessMu.Unlock()
+	if !ok {
+		return // the session detached before its response arrived
+	}
+	session.resolveResponse(namespacedID, frame.raw)
+}
+
+// mcpSession is one attached client's half of the multiplexed connection:
+// its own socket to the client, plus the bookkeeping needed to namespace
+// its requests onto the shared tunnel and demultiplex their responses back.
+type mcpSession struct {
+	id     uint64
+	conn   net.Conn
+	tunnel *sharedTunnel
+
+	seq atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[string]json.RawMessage // namespaced id -> original client id
+}
+
+// pumpFromClient reads JSON-RPC frames from the attached client, rewrites
+// each request's id to a namespace unique to this session before
+// forwarding it onto the shared tunnel, and forwards notifications
+// unchanged. It returns once the client disconnects or a write to the
+// tunnel fails.
+func (s *mcpSession) pumpFromClient() error {
+	scanner := newFrameScanner(s.conn)
+	for scanner.Scan() {
+		frame, err := ParseFrame(scanner.Bytes())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+
+		if !frame.IsRequest() {
+			if err := s.tunnel.writeFrame(frame.raw); err != nil {
+				return trace.Wrap(err)
+			}
+			continue
+		}
+
+		namespacedID := fmt.Sprintf("%d.%d", s.id, s.seq.Add(1))
+		s.mu.Lock()
+		s.pending[namespacedID] = frame.ID
+		s.mu.Unlock()
+
+		rewritten, err := rewriteFrameID(frame.raw, mustMarshalJSON(namespacedID))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		if err := s.tunnel.writeFrame(rewritten); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(scanner.Err())
+}
+
+// resolveResponse restores a response's original client-assigned id and
+// writes it back to this session's connection.
+func (s *mcpSession) resolveResponse(namespacedID string, raw []byte) {
+	s.mu.Lock()
+	originalID, ok := s.pending[namespacedID]
+	if ok {
+		delete(s.pending, namespacedID)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	rewritten, err := rewriteFrameID(raw, originalID)
+	if err != nil {
+		return
+	}
+	writeFrame(s.conn, rewritten)
+}
+
+// rewriteFrameID returns raw with its top-level "id" field replaced by
+// newID, leaving every other field (including ones this package doesn't
+// model, like "jsonrpc") untouched.
+func rewriteFrameID(raw []byte, newID json.RawMessage) ([]byte, error) {
+	var generic map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, trace.Wrap(err, "decoding JSON-RPC frame to rewrite its id")
+	}
+	generic["id"] = newID
+	data, err := json.Marshal(generic)
+	return data, trace.Wrap(err)
+}
+
+// parseSessionID extracts the session id from a "sessionID.seq" namespaced
+// request id.
+func parseSessionID(namespacedID string) (uint64, bool) {
+	idPart, _, ok := strings.Cut(namespacedID, ".")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+func mustMarshalJSON(v any) json.RawMessage {
+	data, _ := json.Marshal(v) // only ever called with a plain string; can't fail
+	return data
+}
+
+// DialDaemon connects to a running daemon's socket and performs the attach
+// handshake for (cluster, app), returning a connection a thin `tsh mcp
+// start --via-daemon` shim can pump its own stdio through directly - every
+// frame it writes and reads from the result is a plain JSON-RPC frame, the
+// namespacing is entirely the daemon's and this session's concern.
+func DialDaemon(ctx context.Context, socketPath, cluster, app string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, trace.Wrap(err, "connecting to MCP daemon at %v", socketPath)
+	}
+
+	reqData, err := json.Marshal(attachRequest{App: app, Cluster: cluster})
+	if err != nil {
+		conn.Close()
+		return nil, trace.Wrap(err)
+	}
+	if err := writeFrame(conn, reqData); err != nil {
+		conn.Close()
+		return nil, trace.Wrap(err)
+	}
+
+	scanner := newFrameScanner(conn)
+	if !scanner.Scan() {
+		conn.Close()
+		return nil, trace.Wrap(scanner.Err(), "reading attach response")
+	}
+	var resp attachResponse
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		conn.Close()
+		return nil, trace.Wrap(err, "decoding attach response")
+	}
+	if !resp.OK {
+		conn.Close()
+		return nil, trace.Errorf("MCP daemon refused attach: %s", resp.Error)
+	}
+	return conn, nil
+}
+
+// QueryDaemonStatus connects to a running daemon's socket and asks it for
+// every open tunnel's status, for `tsh mcp daemon status`.
+func QueryDaemonStatus(ctx context.Context, socketPath string) ([]TunnelStatus, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, trace.Wrap(err, "connecting to MCP daemon at %v", socketPath)
+	}
+	defer conn.Close()
+
+	reqData, err := json.Marshal(attachRequest{QueryStatus: true})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := writeFrame(conn, reqData); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	scanner := newFrameScanner(conn)
+	if !scanner.Scan() {
+		return nil, trace.Wrap(scanner.Err(), "reading status response")
+	}
+	var resp statusResponse
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		return nil, trace.Wrap(err, "decoding status response")
+	}
+	return resp.Tunnels, nil
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenAndServeRestrictsSocketPermissions(t *testing.T) {
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			t.Fatal("DialTunnel should not be called")
+			return nil, nil
+		},
+	}
+	d := startTestDaemon(t, cfg)
+
+	info, err := os.Stat(d.cfg.SocketPath)
+	require.NoError(t, err)
+	require.Equal(t, fs.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestCheckPeerUIDAllowsOwnUID(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("peer credential checks aren't implemented on this platform")
+	}
+
+	_, tunnelSide := fakeTunnel(t)
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			return tunnelSide, nil
+		},
+	}
+	d := startTestDaemon(t, cfg)
+
+	conn, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err, "a connection from this process's own UID must not be rejected by the peer-credential check")
+	conn.Close()
+}
@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTunnel is an io.ReadWriteCloser backed by a net.Pipe, standing in for
+// a real warm alpnproxy tunnel in tests.
+func fakeTunnel(t *testing.T) (server, client net.Conn) {
+	t.Helper()
+	server, client = net.Pipe()
+	t.Cleanup(func() { server.Close(); client.Close() })
+	return server, client
+}
+
+func startTestDaemon(t *testing.T, cfg DaemonConfig) *Daemon {
+	t.Helper()
+	if cfg.SocketPath == "" {
+		cfg.SocketPath = filepath.Join(t.TempDir(), "mcp.sock")
+	}
+	d, err := NewDaemon(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = d.ListenAndServe(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", cfg.SocketPath)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, 5*time.Millisecond)
+
+	return d
+}
+
+func TestDaemonAttachHandshake(t *testing.T) {
+	upstream, tunnelSide := fakeTunnel(t)
+	_ = upstream
+
+	var dialed atomic.Int32
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			dialed.Add(1)
+			return tunnelSide, nil
+		},
+	}
+	d := startTestDaemon(t, cfg)
+
+	conn, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.EqualValues(t, 1, dialed.Load())
+
+	statuses := d.Status()
+	require.Len(t, statuses, 1)
+	require.Equal(t, "leaf", statuses[0].Cluster)
+	require.Equal(t, "my-app", statuses[0].App)
+	require.Equal(t, 1, statuses[0].ClientCount)
+}
+
+func TestDaemonAttachRejectsIncompleteRequest(t *testing.T) {
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			t.Fatal("DialTunnel should not be called for an invalid attach request")
+			return nil, nil
+		},
+	}
+	d := startTestDaemon(t, cfg)
+
+	_, err := DialDaemon(context.Background(), d.cfg.SocketPath, "", "my-app")
+	require.Error(t, err)
+}
+
+func TestDaemonMultiplexesTwoSessionsOverOneTunnel(t *testing.T) {
+	upstream, tunnelSide := fakeTunnel(t)
+
+	var dialed atomic.Int32
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			dialed.Add(1)
+			return tunnelSide, nil
+		},
+	}
+	d := startTestDaemon(t, cfg)
+
+	connA, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err)
+	defer connA.Close()
+	connB, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err)
+	defer connB.Close()
+
+	require.EqualValues(t, 1, dialed.Load(), "both sessions should share the same tunnel")
+	require.Equal(t, 2, d.Status()[0].ClientCount)
+
+	_, err = connA.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}` + "\n"))
+	require.NoError(t, err)
+	_, err = connB.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}` + "\n"))
+	require.NoError(t, err)
+
+	forwardedA := readDaemonLine(t, upstream)
+	forwardedB := readDaemonLine(t, upstream)
+	require.NotEqual(t, forwardedA, forwardedB, "namespaced ids must not collide")
+
+	// Answer each in turn, by id, and confirm each client gets its own
+	// response back with its own original id restored.
+	for _, forwarded := range []string{forwardedA, forwardedB} {
+		frame, err := ParseFrame([]byte(forwarded))
+		require.NoError(t, err)
+		_, err = upstream.Write(append(append([]byte(`{"jsonrpc":"2.0","id":`), frame.ID...), []byte(`,"result":{}}`+"\n")...))
+		require.NoError(t, err)
+	}
+
+	replyA := readDaemonLine(t, connA)
+	replyB := readDaemonLine(t, connB)
+	require.Contains(t, replyA, `"id":1`)
+	require.Contains(t, replyB, `"id":1`)
+}
+
+func TestDaemonBroadcastsNotificationsToAllSessions(t *testing.T) {
+	upstream, tunnelSide := fakeTunnel(t)
+
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			return tunnelSide, nil
+		},
+	}
+	d := startTestDaemon(t, cfg)
+
+	connA, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err)
+	defer connA.Close()
+	connB, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err)
+	defer connB.Close()
+
+	_, err = upstream.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}` + "\n"))
+	require.NoError(t, err)
+
+	require.Contains(t, readDaemonLine(t, connA), "list_changed")
+	require.Contains(t, readDaemonLine(t, connB), "list_changed")
+}
+
+func TestDaemonShutdownDrainsSessions(t *testing.T) {
+	_, tunnelSide := fakeTunnel(t)
+
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			return tunnelSide, nil
+		},
+		DrainTimeout: 100 * time.Millisecond,
+	}
+	d := startTestDaemon(t, cfg)
+
+	conn, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, d.Shutdown(ctx))
+}
+
+func TestDaemonQueryStatus(t *testing.T) {
+	_, tunnelSide := fakeTunnel(t)
+
+	cfg := DaemonConfig{
+		DialTunnel: func(ctx context.Context, key TunnelKey) (io.ReadWriteCloser, error) {
+			return tunnelSide, nil
+		},
+	}
+	d := startTestDaemon(t, cfg)
+
+	empty, err := QueryDaemonStatus(context.Background(), d.cfg.SocketPath)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	conn, err := DialDaemon(context.Background(), d.cfg.SocketPath, "leaf", "my-app")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	statuses, err := QueryDaemonStatus(context.Background(), d.cfg.SocketPath)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	require.Equal(t, "leaf", statuses[0].Cluster)
+	require.Equal(t, "my-app", statuses[0].App)
+}
+
+func readDaemonLine(t *testing.T, r io.Reader) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
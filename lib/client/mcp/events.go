@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+)
+
+// EventKind identifies the kind of lifecycle event the proxy emits on its
+// event bus.
+type EventKind string
+
+const (
+	// EventUpstreamRegistered fires when a new upstream app server is
+	// discovered and registered, before it has necessarily finished
+	// connecting.
+	EventUpstreamRegistered EventKind = "upstream_registered"
+	// EventUpstreamRemoved fires when an upstream app server is removed.
+	EventUpstreamRemoved EventKind = "upstream_removed"
+	// EventUpstreamUpdated fires when an upstream app server is replaced
+	// in-place (e.g. its spec changed).
+	EventUpstreamUpdated EventKind = "upstream_updated"
+	// EventToolsChanged fires whenever an upstream's advertised tool list
+	// is (re)published, including the initial publish after connecting.
+	EventToolsChanged EventKind = "tools_changed"
+	// EventCallToolInvoked fires when a downstream tools/call is forwarded
+	// to an upstream.
+	EventCallToolInvoked EventKind = "call_tool_invoked"
+	// EventCallToolFailed fires when a downstream tools/call could not be
+	// forwarded or the upstream returned an error.
+	EventCallToolFailed EventKind = "call_tool_failed"
+	// EventInitializeFailed fires when dialing or initializing an upstream
+	// fails, or when a ready upstream fails its health probe; the proxy
+	// will keep retrying with backoff.
+	EventInitializeFailed EventKind = "initialize_failed"
+)
+
+// Event is a single lifecycle notification emitted by the proxy. Not every
+// field is populated for every Kind; see the EventKind docs.
+type Event struct {
+	Kind      EventKind
+	AppName   string
+	ToolCount int
+	Error     error
+}
+
+// eventRingSize bounds how many past events a late subscriber can replay.
+const eventRingSize = 64
+
+// eventSubscriberBuffer bounds how far a slow subscriber can fall behind
+// before new events are dropped for it rather than blocking the proxy.
+const eventSubscriberBuffer = 32
+
+// eventBus is a small fan-out broadcaster with replay, used to let other
+// Teleport subsystems observe MCP proxy lifecycle changes without polling.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[int]chan Event
+	nextID      int
+	ring        []Event
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		subscribers: make(map[int]chan Event),
+	}
+}
+
+func (b *eventBus) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ring = append(b.ring, ev)
+	if len(b.ring) > eventRingSize {
+		b.ring = b.ring[len(b.ring)-eventRingSize:]
+	}
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Slow subscriber; drop rather than block the proxy. The ring
+			// buffer still has recent history for anyone who resubscribes.
+		}
+	}
+}
+
+// subscribe returns a channel of future events, preceded by a replay of
+// whatever is still in the ring buffer. The channel is closed once ctx is
+// done.
+func (b *eventBus) subscribe(ctx context.Context) <-chan Event {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, eventSubscriberBuffer)
+	backlog := append([]Event(nil), b.ring...)
+	b.subscribers[id] = ch
+	b.mu.Unlock()
+
+	out := make(chan Event, eventSubscriberBuffer)
+	go func() {
+		defer close(out)
+		for _, ev := range backlog {
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				b.unsubscribe(id)
+				return
+			}
+		}
+		for {
+			select {
+			case ev, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					b.unsubscribe(id)
+					return
+				}
+			case <-ctx.Done():
+				b.unsubscribe(id)
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (b *eventBus) unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ch, ok := b.subscribers[id]; ok {
+		delete(b.subscribers, id)
+		close(ch)
+	}
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBusPublishSubscribe(t *testing.T) {
+	bus := newEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := bus.subscribe(ctx)
+	bus.publish(Event{Kind: EventUpstreamRegistered, AppName: "my-app"})
+
+	select {
+	case ev := <-ch:
+		require.Equal(t, EventUpstreamRegistered, ev.Kind)
+		require.Equal(t, "my-app", ev.AppName)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestEventBusReplaysBacklogForLateSubscribers(t *testing.T) {
+	bus := newEventBus()
+	bus.publish(Event{Kind: EventToolsChanged, AppName: "my-app", ToolCount: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := bus.subscribe(ctx)
+
+	select {
+	case ev := <-ch:
+		require.Equal(t, EventToolsChanged, ev.Kind)
+		require.Equal(t, 2, ev.ToolCount)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for replayed event")
+	}
+}
+
+func TestEventBusClosesChannelOnContextDone(t *testing.T) {
+	bus := newEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := bus.subscribe(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+}
@@ -0,0 +1,367 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	// bearerTokenByteLength is the amount of randomness GenerateBearerToken
+	// reads, hex-encoded to twice this many characters.
+	bearerTokenByteLength = 32
+	// httpTransportDefaultRequestTimeout bounds how long a POST waits for
+	// the upstream's matching response before failing with 504, when
+	// HTTPTransportConfig.RequestTimeout isn't set.
+	httpTransportDefaultRequestTimeout = 30 * time.Second
+	// sseClientBufferSize bounds how many upstream frames an SSE client can
+	// fall behind by before HTTPTransport drops its connection rather than
+	// letting a slow reader buffer unboundedly.
+	sseClientBufferSize = 64
+)
+
+// GenerateBearerToken returns a fresh, random bearer token for gating an
+// HTTPTransport's endpoint.
+func GenerateBearerToken() (string, error) {
+	buf := make([]byte, bearerTokenByteLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// LoadOrCreateBearerTokenFile reads the bearer token stored at path,
+// creating the file with a freshly generated token (mode 0600, so only
+// this local user can read it) if it doesn't exist yet. This lets
+// `tsh mcp start --transport=http` either mint a new token each run or
+// reuse a stable one across restarts, by pointing multiple invocations at
+// the same --token-file.
+func LoadOrCreateBearerTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", trace.ConvertSystemError(err)
+	}
+
+	token, err := GenerateBearerToken()
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", trace.ConvertSystemError(err)
+	}
+	return token, nil
+}
+
+// HTTPTransportConfig configures an HTTPTransport.
+type HTTPTransportConfig struct {
+	// Addr is the local address to listen on, e.g. "127.0.0.1:8008".
+	Addr string
+	// BearerToken gates every request: a caller must present it as
+	// "Authorization: Bearer <token>", so another local user can't hijack
+	// the endpoint just by knowing its port.
+	BearerToken string
+	// Chain runs over every request, response, and notification crossing
+	// the bridge, exactly as it does for PumpStdio.
+	Chain InterceptorChain
+	// UpIn and UpOut are the upstream MCP connection - the same
+	// alpnproxy-tunneled stdio stream onMCPStart otherwise bridges directly
+	// to the local client's stdio via PumpStdio.
+	UpIn  io.Writer
+	UpOut io.Reader
+	// Logger is used for all transport logging. Defaults to slog.Default().
+	Logger *slog.Logger
+	// RequestTimeout bounds how long a POST request waits for the
+	// upstream's matching response. Defaults to
+	// httpTransportDefaultRequestTimeout.
+	RequestTimeout time.Duration
+}
+
+func (c *HTTPTransportConfig) checkAndSetDefaults() error {
+	if c.Addr == "" {
+		return trace.BadParameter("missing listen address")
+	}
+	if c.BearerToken == "" {
+		return trace.BadParameter("missing bearer token")
+	}
+	if c.UpIn == nil || c.UpOut == nil {
+		return trace.BadParameter("missing upstream connection")
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	if c.RequestTimeout <= 0 {
+		c.RequestTimeout = httpTransportDefaultRequestTimeout
+	}
+	return nil
+}
+
+// HTTPTransport serves the MCP Streamable HTTP transport - POST for
+// JSON-RPC requests/notifications and a GET text/event-stream endpoint for
+// server-initiated notifications - in place of PumpStdio's raw stdio
+// bridge, for hosted MCP clients that speak HTTP rather than a local
+// stdio subprocess. It runs the same InterceptorChain PumpStdio does over
+// every frame, and otherwise leaves the upstream alpnproxy tunnel and cert
+// loading onMCPStart already sets up untouched.
+type HTTPTransport struct {
+	cfg HTTPTransportConfig
+
+	pendingMu sync.Mutex
+	pending   map[string]chan json.RawMessage // JSON-encoded request id -> its waiting POST
+
+	sseMu         sync.Mutex
+	sseClients    map[uint64]chan []byte
+	nextSSEClient uint64
+
+	server *http.Server
+}
+
+// NewHTTPTransport constructs an HTTPTransport from cfg.
+func NewHTTPTransport(cfg HTTPTransportConfig) (*HTTPTransport, error) {
+	if err := cfg.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &HTTPTransport{
+		cfg:        cfg,
+		pending:    make(map[string]chan json.RawMessage),
+		sseClients: make(map[uint64]chan []byte),
+	}, nil
+}
+
+// ListenAndServe serves the transport on cfg.Addr until ctx is done, at
+// which point it shuts the HTTP server down gracefully and returns nil.
+func (t *HTTPTransport) ListenAndServe(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", t.handleMCP)
+	t.server = &http.Server{
+		Addr:    t.cfg.Addr,
+		Handler: t.requireBearerToken(mux),
+	}
+
+	go t.readUpstream(ctx)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		t.server.Shutdown(shutdownCtx)
+	}()
+
+	t.cfg.Logger.InfoContext(ctx, "Starting MCP HTTP transport", "addr", t.cfg.Addr)
+	err := t.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return trace.Wrap(err)
+}
+
+// requireBearerToken rejects any request that doesn't present cfg.BearerToken.
+func (t *HTTPTransport) requireBearerToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "Bearer "
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, prefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, prefix)), []byte(t.cfg.BearerToken)) != 1 {
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *HTTPTransport) handleMCP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			http.Error(w, "GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+			return
+		}
+		t.handleSSE(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost accepts a single JSON-RPC request or notification, runs it
+// through cfg.Chain, and - for a request - blocks until the upstream's
+// matching response arrives (or cfg.RequestTimeout elapses) before writing
+// it back as the HTTP response body.
+func (t *HTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "reading request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	frame, err := ParseFrame(body)
+	if err != nil {
+		http.Error(w, "invalid JSON-RPC frame: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case frame.IsNotification():
+		if err := t.cfg.Chain.onNotification(ctx, frame); err != nil {
+			t.cfg.Logger.WarnContext(ctx, "Blocked MCP notification", "method", frame.Method, "error", err)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if err := writeFrame(t.cfg.UpIn, frame.raw); err != nil {
+			http.Error(w, "forwarding notification upstream: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+	case frame.IsRequest():
+		if err := t.cfg.Chain.onRequest(ctx, frame); err != nil {
+			t.cfg.Logger.WarnContext(ctx, "Blocked MCP request", "method", frame.Method, "error", err)
+			writeJSONResponse(w, mustMarshalDeniedResponse(frame.ID, err))
+			return
+		}
+
+		replyCh := make(chan json.RawMessage, 1)
+		key := string(frame.ID)
+		t.pendingMu.Lock()
+		t.pending[key] = replyCh
+		t.pendingMu.Unlock()
+		defer func() {
+			t.pendingMu.Lock()
+			delete(t.pending, key)
+			t.pendingMu.Unlock()
+		}()
+
+		if err := writeFrame(t.cfg.UpIn, frame.raw); err != nil {
+			http.Error(w, "forwarding request upstream: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		timer := time.NewTimer(t.cfg.RequestTimeout)
+		defer timer.Stop()
+		select {
+		case reply := <-replyCh:
+			writeJSONResponse(w, reply)
+		case <-timer.C:
+			http.Error(w, "timed out waiting for upstream response", http.StatusGatewayTimeout)
+		case <-ctx.Done():
+		}
+
+	default:
+		http.Error(w, "frame is neither a request nor a notification", http.StatusBadRequest)
+	}
+}
+
+// handleSSE streams every notification the upstream sends to one
+// subscriber, for as long as the client stays connected.
+func (t *HTTPTransport) handleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, sseClientBufferSize)
+	t.sseMu.Lock()
+	id := t.nextSSEClient
+	t.nextSSEClient++
+	t.sseClients[id] = ch
+	t.sseMu.Unlock()
+	defer func() {
+		t.sseMu.Lock()
+		delete(t.sseClients, id)
+		t.sseMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				// broadcastSSE dropped this client for falling too far behind.
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// readUpstream demultiplexes frames arriving from the upstream connection:
+// a response is delivered to whichever POST is waiting on its id, and a
+// notification is broadcast to every connected SSE client.
+func (t *HTTPTransport) readUpstream(ctx context.Context) {
+	scanner := newFrameScanner(t.cfg.UpOut)
+	for scanner.Scan() {
+		frame, err := ParseFrame(scanner.Bytes())
+		if err != nil {
+			t.cfg.Logger.WarnContext(ctx, "Discarding malformed frame from MCP upstream", "error", err)
+			continue
+		}
+
+		switch {
+		case frame.IsResponse():
+			if err := t.cfg.Chain.onResponse(ctx, frame); err != nil {
+				t.cfg.Logger.WarnContext(ctx, "MCP response interceptor returned an error", "error", err)
+			}
+			t.pendingMu.Lock()
+			replyCh, ok := t.pending[string(frame.ID)]
+			t.pendingMu.Unlock()
+			if ok {
+				replyCh <- frame.raw
+			}
+		case frame.IsNotification():
+			if err := t.cfg.Chain.onNotification(ctx, frame); err != nil {
+				t.cfg.Logger.WarnContext(ctx, "Blocked MCP notification", "method", frame.Method, "error", err)
+				continue
+			}
+			t.broadcastSSE(ctx, frame.raw)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.cfg.Logger.WarnContext(ctx, "MCP upstream connection closed", "error", err)
+	}
+}
+
+func (t *HTTPTransport) broadcastSSE(ctx context.Context, data []byte) {
+	t.sseMu.Lock()
+	defer t.sseMu.Unlock()
+	for id, ch := range t.sseClients {
+		select {
+		case ch <- data:
+		default:
+			t.cfg.Logger.WarnContext(ctx, "Dropping slow MCP SSE client", "client", id)
+			delete(t.sseClients, id)
+			close(ch)
+		}
+	}
+}
+
+func writeJSONResponse(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes.TrimRight(data, "\n"))
+}
@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTransportUnauthorized(t *testing.T) {
+	upR, toTransport := io.Pipe()
+	fromTransport, upW := io.Pipe()
+	defer toTransport.Close()
+	defer upW.Close()
+	defer upR.Close()
+	defer fromTransport.Close()
+
+	transport, err := NewHTTPTransport(HTTPTransportConfig{
+		Addr:        "127.0.0.1:0",
+		BearerToken: "test-token",
+		Chain:       InterceptorChain{},
+		UpIn:        toTransport,
+		UpOut:       fromTransport,
+	})
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(transport.requireBearerToken(http.HandlerFunc(transport.handleMCP)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/mcp")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHTTPTransportPostRoundTrip(t *testing.T) {
+	upR, toTransport := io.Pipe()
+	fromTransport, upW := io.Pipe()
+	defer toTransport.Close()
+	defer upW.Close()
+
+	transport, err := NewHTTPTransport(HTTPTransportConfig{
+		Addr:           "127.0.0.1:0",
+		BearerToken:    "test-token",
+		Chain:          InterceptorChain{},
+		UpIn:           toTransport,
+		UpOut:          fromTransport,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		RequestTimeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go transport.readUpstream(ctx)
+
+	srv := httptest.NewServer(transport.requireBearerToken(http.HandlerFunc(transport.handleMCP)))
+	defer srv.Close()
+
+	// Simulate the upstream MCP server answering whatever request it
+	// receives.
+	go func() {
+		reader := bufio.NewReader(upR)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		require.Contains(t, line, `"method":"tools/list"`)
+		upW.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}` + "\n"))
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer test-token")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"id":1`)
+	require.Contains(t, string(body), `"result"`)
+}
+
+func TestHTTPTransportPostDeniedByInterceptor(t *testing.T) {
+	upR, toTransport := io.Pipe()
+	fromTransport, upW := io.Pipe()
+	defer toTransport.Close()
+	defer upW.Close()
+	defer upR.Close()
+	defer fromTransport.Close()
+
+	chain := InterceptorChain{&denyingInterceptor{deniedMethod: "tools/call"}}
+	transport, err := NewHTTPTransport(HTTPTransportConfig{
+		Addr:        "127.0.0.1:0",
+		BearerToken: "test-token",
+		Chain:       chain,
+		UpIn:        toTransport,
+		UpOut:       fromTransport,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(transport.requireBearerToken(http.HandlerFunc(transport.handleMCP)))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"x"}}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer test-token")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"id":5`)
+	require.Contains(t, string(body), `"error"`)
+}
+
+func TestHTTPTransportSSEBroadcastsNotifications(t *testing.T) {
+	upR, toTransport := io.Pipe()
+	fromTransport, upW := io.Pipe()
+	defer toTransport.Close()
+
+	transport, err := NewHTTPTransport(HTTPTransportConfig{
+		Addr:        "127.0.0.1:0",
+		BearerToken: "test-token",
+		Chain:       InterceptorChain{},
+		UpIn:        toTransport,
+		UpOut:       fromTransport,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go transport.readUpstream(ctx)
+	defer upR.Close()
+
+	srv := httptest.NewServer(transport.requireBearerToken(http.HandlerFunc(transport.handleMCP)))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/mcp", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = upW.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}` + "\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.Contains(t, line, "data:")
+	require.Contains(t, line, "list_changed")
+}
@@ -0,0 +1,254 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+
+	"github.com/gravitational/trace"
+)
+
+// maxScannerBufferSize is the hard ceiling on a single JSON-RPC frame's
+// size that PumpStdio will ever buffer, regardless of Policy.MaxRequestSize
+// - a backstop against unbounded memory use from a misbehaving peer, not a
+// policy decision. The size-guard interceptor enforces the (usually much
+// tighter) configured limit on top of this.
+const maxScannerBufferSize = 16 << 20 // 16 MiB
+
+// Frame is a minimally-decoded JSON-RPC 2.0 message: just enough structure
+// for an Interceptor to classify it as a request, response, or
+// notification and inspect its method/params, without committing to any
+// particular mcp-go wire type.
+type Frame struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+
+	// raw is the frame's original encoding, reused to forward it unchanged
+	// when no interceptor rewrites it.
+	raw json.RawMessage
+}
+
+// ParseFrame decodes one JSON-RPC message.
+func ParseFrame(data []byte) (*Frame, error) {
+	var f Frame
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, trace.Wrap(err, "decoding JSON-RPC message")
+	}
+	f.raw = append(json.RawMessage(nil), data...)
+	return &f, nil
+}
+
+// IsRequest reports whether the frame is a request: it carries both an ID
+// and a Method.
+func (f *Frame) IsRequest() bool { return len(f.ID) > 0 && f.Method != "" }
+
+// IsNotification reports whether the frame is a notification: it carries a
+// Method but no ID.
+func (f *Frame) IsNotification() bool { return len(f.ID) == 0 && f.Method != "" }
+
+// IsResponse reports whether the frame is a response: it carries an ID but
+// no Method.
+func (f *Frame) IsResponse() bool { return len(f.ID) > 0 && f.Method == "" }
+
+// toolCallParams is the shape of a tools/call request's params.
+type toolCallParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// ToolCallArguments decodes a tools/call frame's tool name and arguments.
+// It returns an error if the frame isn't a tools/call or its params don't
+// decode.
+func (f *Frame) ToolCallArguments() (toolName string, arguments map[string]any, err error) {
+	if f.Method != "tools/call" {
+		return "", nil, trace.BadParameter("frame is a %q message, not tools/call", f.Method)
+	}
+	var params toolCallParams
+	if len(f.Params) > 0 {
+		if err := json.Unmarshal(f.Params, &params); err != nil {
+			return "", nil, trace.Wrap(err, "decoding tools/call params")
+		}
+	}
+	return params.Name, params.Arguments, nil
+}
+
+// Interceptor observes, and may block, JSON-RPC messages flowing between a
+// local MCP client and the upstream server tsh is bridging it to.
+// OnRequest and OnNotification return a non-nil error to block the message
+// instead of forwarding it: for a request, PumpStdio turns the error into a
+// JSON-RPC error response sent back to the client instead of ever
+// forwarding it upstream; for a notification, which has no response to
+// carry an error, PumpStdio simply drops it. OnResponse is observe-only -
+// by the time a response arrives, the request it answers already ran
+// upstream, so an error from OnResponse is logged but never blocks
+// delivery.
+type Interceptor interface {
+	OnRequest(ctx context.Context, f *Frame) error
+	OnResponse(ctx context.Context, f *Frame) error
+	OnNotification(ctx context.Context, f *Frame) error
+}
+
+// InterceptorChain runs a sequence of Interceptors in order, stopping at
+// the first one that blocks a request or notification.
+type InterceptorChain []Interceptor
+
+func (c InterceptorChain) onRequest(ctx context.Context, f *Frame) error {
+	for _, i := range c {
+		if err := i.OnRequest(ctx, f); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (c InterceptorChain) onResponse(ctx context.Context, f *Frame) error {
+	for _, i := range c {
+		if err := i.OnResponse(ctx, f); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+func (c InterceptorChain) onNotification(ctx context.Context, f *Frame) error {
+	for _, i := range c {
+		if err := i.OnNotification(ctx, f); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// jsonrpcErrCodePolicyDenied is the JSON-RPC error code PumpStdio reports
+// for a request an Interceptor blocked. It falls in the -32000..-32099
+// range JSON-RPC 2.0 reserves for implementation-defined server errors.
+const jsonrpcErrCodePolicyDenied = -32001
+
+type jsonrpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonrpcErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   jsonrpcError    `json:"error"`
+}
+
+// PumpStdio bridges a local MCP client's stdio (downIn/downOut) to an
+// upstream MCP connection (upIn/upOut) over newline-delimited JSON-RPC
+// frames, running chain over every request, response, and notification
+// that crosses the bridge. It replaces a byte-oblivious pipe (e.g.
+// utils.ProxyConn) with one that can audit, redact, and enforce policy on
+// the traffic it carries. It blocks until either direction's connection
+// closes, chain blocks an unrecoverable read, or ctx is done, and always
+// returns a non-nil error.
+func PumpStdio(ctx context.Context, log *slog.Logger, chain InterceptorChain, downIn io.Reader, downOut io.Writer, upIn io.Writer, upOut io.Reader) error {
+	errCh := make(chan error, 2)
+	go func() { errCh <- pumpDownstream(ctx, log, chain, downIn, downOut, upIn) }()
+	go func() { errCh <- pumpUpstream(ctx, log, chain, upOut, downOut) }()
+
+	select {
+	case err := <-errCh:
+		return trace.Wrap(err)
+	case <-ctx.Done():
+		return trace.Wrap(ctx.Err())
+	}
+}
+
+// pumpDownstream reads frames from the local MCP client, runs requests and
+// notifications through chain, and forwards whatever isn't blocked
+// upstream.
+func pumpDownstream(ctx context.Context, log *slog.Logger, chain InterceptorChain, downIn io.Reader, downOut io.Writer, upIn io.Writer) error {
+	scanner := newFrameScanner(downIn)
+	for scanner.Scan() {
+		frame, err := ParseFrame(scanner.Bytes())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+
+		switch {
+		case frame.IsRequest():
+			if err := chain.onRequest(ctx, frame); err != nil {
+				log.WarnContext(ctx, "Blocked MCP request", "method", frame.Method, "error", err)
+				if werr := writeFrame(downOut, mustMarshalDeniedResponse(frame.ID, err)); werr != nil {
+					return trace.Wrap(werr)
+				}
+				continue
+			}
+		case frame.IsNotification():
+			if err := chain.onNotification(ctx, frame); err != nil {
+				log.WarnContext(ctx, "Blocked MCP notification", "method", frame.Method, "error", err)
+				continue
+			}
+		}
+
+		if err := writeFrame(upIn, frame.raw); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(scanner.Err())
+}
+
+// pumpUpstream reads frames from the upstream connection, runs responses
+// and notifications through chain for observation, and forwards every
+// frame downstream unchanged - a response, once the upstream has answered
+// it, is never blocked.
+func pumpUpstream(ctx context.Context, log *slog.Logger, chain InterceptorChain, upOut io.Reader, downOut io.Writer) error {
+	scanner := newFrameScanner(upOut)
+	for scanner.Scan() {
+		frame, err := ParseFrame(scanner.Bytes())
+		if err != nil {
+			return trace.Wrap(err)
+		}
+
+		switch {
+		case frame.IsResponse():
+			if err := chain.onResponse(ctx, frame); err != nil {
+				log.WarnContext(ctx, "MCP response interceptor returned an error", "error", err)
+			}
+		case frame.IsNotification():
+			if err := chain.onNotification(ctx, frame); err != nil {
+				log.WarnContext(ctx, "Blocked MCP notification", "method", frame.Method, "error", err)
+				continue
+			}
+		}
+
+		if err := writeFrame(downOut, frame.raw); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return trace.Wrap(scanner.Err())
+}
+
+func newFrameScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScannerBufferSize)
+	return scanner
+}
+
+func mustMarshalDeniedResponse(id json.RawMessage, cause error) []byte {
+	resp := jsonrpcErrorResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: jsonrpcError{
+			Code:    jsonrpcErrCodePolicyDenied,
+			Message: cause.Error(),
+		},
+	}
+	// resp's fields are all trivially marshalable (a json.RawMessage id and
+	// plain strings/ints), so this can never fail.
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+func writeFrame(w io.Writer, data []byte) error {
+	data = append(data, '\n')
+	if _, err := w.Write(data); err != nil {
+		return trace.Wrap(err, "writing MCP frame")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameClassification(t *testing.T) {
+	tests := []struct {
+		name             string
+		data             string
+		wantRequest      bool
+		wantNotification bool
+		wantResponse     bool
+	}{
+		{
+			name:        "request",
+			data:        `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{}}`,
+			wantRequest: true,
+		},
+		{
+			name:             "notification",
+			data:             `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			wantNotification: true,
+		},
+		{
+			name:         "response",
+			data:         `{"jsonrpc":"2.0","id":1,"result":{}}`,
+			wantResponse: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frame, err := ParseFrame([]byte(test.data))
+			require.NoError(t, err)
+			require.Equal(t, test.wantRequest, frame.IsRequest())
+			require.Equal(t, test.wantNotification, frame.IsNotification())
+			require.Equal(t, test.wantResponse, frame.IsResponse())
+		})
+	}
+}
+
+func TestFrameToolCallArguments(t *testing.T) {
+	frame, err := ParseFrame([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"teleport_list_nodes","arguments":{"search":"db1"}}}`))
+	require.NoError(t, err)
+
+	name, args, err := frame.ToolCallArguments()
+	require.NoError(t, err)
+	require.Equal(t, "teleport_list_nodes", name)
+	require.Equal(t, map[string]any{"search": "db1"}, args)
+
+	notToolCall, err := ParseFrame([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	require.NoError(t, err)
+	_, _, err = notToolCall.ToolCallArguments()
+	require.Error(t, err)
+}
+
+// denyingInterceptor blocks every request/notification whose method matches
+// deniedMethod, for exercising PumpStdio's blocking path without depending
+// on the built-in policy interceptors.
+type denyingInterceptor struct {
+	deniedMethod string
+}
+
+func (d *denyingInterceptor) OnRequest(_ context.Context, f *Frame) error {
+	if f.Method == d.deniedMethod {
+		return trace.AccessDenied("denied by test interceptor")
+	}
+	return nil
+}
+
+func (d *denyingInterceptor) OnResponse(context.Context, *Frame) error { return nil }
+
+func (d *denyingInterceptor) OnNotification(_ context.Context, f *Frame) error {
+	if f.Method == d.deniedMethod {
+		return trace.AccessDenied("denied by test interceptor")
+	}
+	return nil
+}
+
+func TestPumpStdio(t *testing.T) {
+	// clientR/clientW is the local MCP client's side of the bridge; tsh
+	// reads requests from clientR and writes responses to clientW.
+	clientR, toTsh := io.Pipe()
+	fromTsh, clientW := io.Pipe()
+
+	// upR/upW is the upstream's side; tsh writes requests to upW and reads
+	// responses from upR.
+	upR, toUpstream := io.Pipe()
+	fromUpstream, upW := io.Pipe()
+
+	chain := InterceptorChain{&denyingInterceptor{deniedMethod: "tools/call"}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go PumpStdio(ctx, log, chain, clientR, clientW, toUpstream, fromUpstream)
+
+	// A denied request should get an error response back without ever
+	// reaching the upstream.
+	_, err := toTsh.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"x"}}` + "\n"))
+	require.NoError(t, err)
+
+	resp := readLine(t, fromTsh)
+	require.Contains(t, resp, `"id":1`)
+	require.Contains(t, resp, `"error"`)
+
+	// An allowed request should be forwarded upstream unchanged, and the
+	// upstream's response forwarded back downstream unchanged.
+	_, err = toTsh.Write([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}` + "\n"))
+	require.NoError(t, err)
+
+	forwarded := readLine(t, upR)
+	require.Contains(t, forwarded, `"method":"tools/list"`)
+
+	_, err = upW.Write([]byte(`{"jsonrpc":"2.0","id":2,"result":{}}` + "\n"))
+	require.NoError(t, err)
+
+	reply := readLine(t, fromTsh)
+	require.Contains(t, reply, `"id":2`)
+	require.Contains(t, reply, `"result"`)
+}
+
+func readLine(t *testing.T, r io.Reader) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
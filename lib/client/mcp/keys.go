@@ -0,0 +1,221 @@
+package mcp
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// ToolArgConstraint restricts the values a single argument of a tool call is
+// allowed to take. An argument not listed here is unconstrained; an argument
+// listed with an empty AllowedValues can never be satisfied and always
+// rejects the call, which lets a key author explicitly forbid an argument
+// rather than merely failing to restrict it.
+type ToolArgConstraint struct {
+	// AllowedValues is the set of values the argument may take. Values are
+	// compared against the JSON-decoded argument's string form, so this only
+	// constrains string and string-array arguments (e.g. event_types, roles);
+	// numeric or object arguments aren't constrainable this way.
+	AllowedValues []string `json:"allowed_values"`
+}
+
+// KeyConstraints is everything a capability-limited MCP key grants: which
+// tools may be called at all, what resource-name arguments those calls may
+// touch, per-tool per-argument value restrictions, and a validity window.
+// It's the payload that gets signed to produce a Key.
+type KeyConstraints struct {
+	// AllowedTools is the set of tool names the key may call. The MCP server
+	// only registers tools on this list with mcpServer.AddTool; every other
+	// tool is simply absent from the session, rather than present-but-denied.
+	AllowedTools []string `json:"allowed_tools"`
+	// NamePrefix restricts every resource-name-shaped argument (node names,
+	// session IDs, app names, etc.) a call may reference to this prefix. An
+	// empty NamePrefix places no restriction.
+	NamePrefix string `json:"name_prefix,omitempty"`
+	// ToolArgConstraints restricts specific arguments of specific tools, e.g.
+	// {"teleport_search_events": {"event_types": {AllowedValues: [...]}}}.
+	ToolArgConstraints map[string]map[string]ToolArgConstraint `json:"tool_arg_constraints,omitempty"`
+	// NotBefore and NotAfter bound the key's validity window. A zero
+	// NotAfter never expires, matching time.Time's zero-value meaning "unset"
+	// elsewhere in this codebase.
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after,omitempty"`
+}
+
+// checkValidAt returns an error if now falls outside the key's validity
+// window.
+func (c *KeyConstraints) checkValidAt(now time.Time) error {
+	if now.Before(c.NotBefore) {
+		return trace.AccessDenied("MCP key is not valid until %s", c.NotBefore.Format(time.RFC3339))
+	}
+	if !c.NotAfter.IsZero() && now.After(c.NotAfter) {
+		return trace.AccessDenied("MCP key expired at %s", c.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkTool returns an error if toolName isn't on the key's allow-list.
+func (c *KeyConstraints) checkTool(toolName string) error {
+	if !slices.Contains(c.AllowedTools, toolName) {
+		return trace.AccessDenied("MCP key does not permit tool %q", toolName)
+	}
+	return nil
+}
+
+// CheckResourceName returns an error if name doesn't satisfy the key's
+// NamePrefix restriction. Exported so the MCP server can check resource-name
+// arguments (node names, session IDs, etc.) that AuthorizeToolCall's generic
+// argument-value check can't distinguish from an ordinary string argument.
+func (c *KeyConstraints) CheckResourceName(name string) error {
+	if c.NamePrefix == "" || strings.HasPrefix(name, c.NamePrefix) {
+		return nil
+	}
+	return trace.AccessDenied("MCP key restricts resource names to prefix %q, got %q", c.NamePrefix, name)
+}
+
+// checkToolArgs returns an error if any argument of a call to toolName
+// violates that tool's constraints. Arguments not mentioned in the
+// constraint set are unrestricted; args not present in the call are
+// skipped (a required-argument check is the tool handler's job, not the
+// key's).
+func (c *KeyConstraints) checkToolArgs(toolName string, args map[string]any) error {
+	constraints, ok := c.ToolArgConstraints[toolName]
+	if !ok {
+		return nil
+	}
+	for argName, constraint := range constraints {
+		rawValue, present := args[argName]
+		if !present {
+			continue
+		}
+		if len(constraint.AllowedValues) == 0 {
+			return trace.AccessDenied("MCP key forbids argument %s.%s", toolName, argName)
+		}
+		for _, value := range argValues(rawValue) {
+			if !slices.Contains(constraint.AllowedValues, value) {
+				return trace.AccessDenied("MCP key restricts %s.%s to %v, got %q", toolName, argName, constraint.AllowedValues, value)
+			}
+		}
+	}
+	return nil
+}
+
+// argValues normalizes a JSON-decoded tool argument (a bare string or an
+// array of them, the two shapes every constrainable argument in this file
+// takes) into a flat list of strings to check against an allow-list.
+func argValues(raw any) []string {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, elem := range v {
+			if s, ok := elem.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// Key is a signed, capability-limited MCP credential: KeyConstraints plus a
+// Subject identifying the Teleport identity that minted it and a Signature
+// over the constraints, so that both the local `tsh mcp start-teleport`
+// process and (as a second line of defense) the auth server can
+// independently verify a key wasn't tampered with or forged.
+//
+// Keys are signed with ed25519, matching the key type Teleport's user CA
+// already issues SSH certificates with, so `tsh mcp keys create` can sign
+// with the same private key material `tsh` holds for the logged-in user
+// rather than minting or trusting a separate key type.
+type Key struct {
+	KeyConstraints
+	// Subject is the Teleport username the key was signed on behalf of. The
+	// auth server's second-line-of-defense check rejects any call a key
+	// authorizes if Subject doesn't match the identity on the connection the
+	// key arrived over, so a stolen key file can't be replayed as another
+	// user.
+	Subject string `json:"subject"`
+	// Signature is over the JSON encoding of every other field of Key with
+	// Signature itself left empty.
+	Signature []byte `json:"signature"`
+}
+
+// signingPayload returns the bytes a Key's signature is computed over: the
+// JSON encoding of k with Signature cleared, so the signature never signs
+// itself.
+func (k *Key) signingPayload() ([]byte, error) {
+	unsigned := *k
+	unsigned.Signature = nil
+	payload, err := json.Marshal(unsigned)
+	return payload, trace.Wrap(err)
+}
+
+// SignKey signs constraints on behalf of subject using the ed25519 private
+// key backing the caller's own Teleport identity.
+func SignKey(constraints KeyConstraints, subject string, signer ed25519.PrivateKey) (*Key, error) {
+	key := &Key{
+		KeyConstraints: constraints,
+		Subject:        subject,
+	}
+	payload, err := key.signingPayload()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	key.Signature = ed25519.Sign(signer, payload)
+	return key, nil
+}
+
+// Verify checks that k's signature was produced by the private key
+// corresponding to pub, and that now falls within k's validity window.
+func (k *Key) Verify(pub ed25519.PublicKey, now time.Time) error {
+	if err := k.checkValidAt(now); err != nil {
+		return trace.Wrap(err)
+	}
+	payload, err := k.signingPayload()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if !ed25519.Verify(pub, payload, k.Signature) {
+		return trace.AccessDenied("MCP key signature is invalid for subject %q", k.Subject)
+	}
+	return nil
+}
+
+// AuthorizeToolCall is the single check the MCP server runs on every
+// CallToolRequest before it reaches authClient: the key must still be
+// valid, must permit the tool, and the call's arguments must satisfy that
+// tool's constraints.
+func (k *Key) AuthorizeToolCall(now time.Time, toolName string, args map[string]any) error {
+	if err := k.checkValidAt(now); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := k.checkTool(toolName); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(k.checkToolArgs(toolName, args))
+}
+
+// ParseKeyFile decodes a Key previously written by WriteKeyFile.
+func ParseKeyFile(r io.Reader) (*Key, error) {
+	var key Key
+	if err := json.NewDecoder(r).Decode(&key); err != nil {
+		return nil, trace.Wrap(err, "parsing MCP key file")
+	}
+	return &key, nil
+}
+
+// WriteKeyFile encodes key for `tsh mcp keys create` to write to --out, and
+// for `tsh mcp start-teleport --mcp-key-file` to later read back.
+func WriteKeyFile(w io.Writer, key *Key) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return trace.Wrap(enc.Encode(key))
+}
@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(t *testing.T, constraints KeyConstraints) (*Key, ed25519.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	key, err := SignKey(constraints, "alice", priv)
+	require.NoError(t, err)
+
+	return key, pub
+}
+
+func TestKey_VerifyRejectsTamperedConstraints(t *testing.T) {
+	key, pub := testKey(t, KeyConstraints{AllowedTools: []string{"teleport_list_nodes"}})
+	require.NoError(t, key.Verify(pub, time.Now()))
+
+	key.AllowedTools = append(key.AllowedTools, "teleport_get_session_recording")
+	require.Error(t, key.Verify(pub, time.Now()))
+}
+
+func TestKey_VerifyChecksValidityWindow(t *testing.T) {
+	now := time.Now()
+	key, pub := testKey(t, KeyConstraints{
+		AllowedTools: []string{"teleport_list_nodes"},
+		NotBefore:    now.Add(time.Hour),
+	})
+	require.Error(t, key.Verify(pub, now))
+
+	key, pub = testKey(t, KeyConstraints{
+		AllowedTools: []string{"teleport_list_nodes"},
+		NotAfter:     now.Add(-time.Hour),
+	})
+	require.Error(t, key.Verify(pub, now))
+}
+
+func TestKey_AuthorizeToolCall(t *testing.T) {
+	key, _ := testKey(t, KeyConstraints{
+		AllowedTools: []string{"teleport_search_events"},
+		ToolArgConstraints: map[string]map[string]ToolArgConstraint{
+			"teleport_search_events": {
+				"event_types": {AllowedValues: []string{"session.start", "session.end"}},
+			},
+		},
+	})
+	now := time.Now()
+
+	require.NoError(t, key.AuthorizeToolCall(now, "teleport_search_events", map[string]any{
+		"event_types": []any{"session.start"},
+	}))
+
+	require.Error(t, key.AuthorizeToolCall(now, "teleport_list_nodes", nil),
+		"tool not on the allow-list must be rejected")
+
+	require.Error(t, key.AuthorizeToolCall(now, "teleport_search_events", map[string]any{
+		"event_types": []any{"role.created"},
+	}), "event type outside the constraint's allowed values must be rejected")
+}
+
+func TestKeyConstraints_CheckResourceName(t *testing.T) {
+	c := KeyConstraints{NamePrefix: "staging-"}
+	require.NoError(t, c.CheckResourceName("staging-node-1"))
+	require.Error(t, c.CheckResourceName("prod-node-1"))
+
+	unrestricted := KeyConstraints{}
+	require.NoError(t, unrestricted.CheckResourceName("anything"))
+}
+
+func TestKeyFile_RoundTrip(t *testing.T) {
+	key, _ := testKey(t, KeyConstraints{AllowedTools: []string{"teleport_list_nodes"}})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteKeyFile(&buf, key))
+
+	parsed, err := ParseKeyFile(&buf)
+	require.NoError(t, err)
+	require.Equal(t, key, parsed)
+}
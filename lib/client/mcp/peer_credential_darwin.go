@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"net"
+
+	"github.com/gravitational/trace"
+	"golang.org/x/sys/unix"
+)
+
+// peerUID returns the UID of the process on the other end of conn, which
+// must be a *net.UnixConn, via the LOCAL_PEERCRED socket option.
+func peerUID(conn net.Conn) (uint32, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, trace.BadParameter("expected a *net.UnixConn, got %T", conn)
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, trace.Wrap(err)
+	}
+
+	var uid uint32
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		xucred, err := unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+		if err != nil {
+			sockErr = err
+			return
+		}
+		uid = xucred.Uid
+	}); err != nil {
+		return 0, trace.Wrap(err)
+	}
+	return uid, trace.Wrap(sockErr)
+}
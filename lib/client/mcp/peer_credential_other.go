@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package mcp
+
+import (
+	"net"
+
+	"github.com/gravitational/trace"
+)
+
+// peerUID isn't implemented for this platform: neither SO_PEERCRED nor
+// LOCAL_PEERCRED exist outside Linux/Darwin, and a Windows named pipe isn't
+// implemented here (see DaemonConfig.SocketPath) for ListenAndServe's
+// caller to fall back on instead.
+func peerUID(conn net.Conn) (uint32, error) {
+	return 0, trace.NotImplemented("MCP daemon peer credential checks aren't supported on this platform")
+}
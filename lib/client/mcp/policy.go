@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultMaxRequestSize bounds a single JSON-RPC message the size-guard
+// interceptor forwards upstream when Policy.MaxRequestSize isn't set.
+const defaultMaxRequestSize = 1 << 20 // 1 MiB
+
+// Policy is the local MCP middleware configuration an administrator can
+// distribute as ~/.tsh/mcp.yaml, without recompiling tsh, to control what
+// `tsh mcp start`'s stdio bridge allows and logs.
+type Policy struct {
+	// AllowTools, if non-empty, restricts tools/call to tool names matching
+	// one of these filepath.Match patterns (e.g. "teleport/*/read_*"). A
+	// call matching neither AllowTools nor DenyTools is permitted only if
+	// AllowTools is empty.
+	AllowTools []string `yaml:"allow_tools,omitempty"`
+	// DenyTools is checked before AllowTools; a match always denies the
+	// call regardless of AllowTools.
+	DenyTools []string `yaml:"deny_tools,omitempty"`
+	// RedactArgs lists tool argument names the audit interceptor logs as a
+	// short hash instead of in full.
+	RedactArgs []string `yaml:"redact_args,omitempty"`
+	// MaxRequestSize bounds, in bytes, the size of a single JSON-RPC
+	// message the size-guard interceptor will forward upstream. Zero uses
+	// defaultMaxRequestSize.
+	MaxRequestSize int `yaml:"max_request_size,omitempty"`
+}
+
+// DefaultPolicyPath returns the conventional location of the local MCP
+// policy file, ~/.tsh/mcp.yaml under the user's tsh home directory.
+func DefaultPolicyPath(homeDir string) string {
+	return filepath.Join(homeDir, ".tsh", "mcp.yaml")
+}
+
+// LoadPolicyFile reads and parses the policy file at path. A missing file
+// is not an error; it returns the zero Policy, which permits every tool and
+// logs arguments unredacted, i.e. today's behavior.
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Policy{}, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, trace.Wrap(err, "parsing MCP policy file %v", path)
+	}
+	return &policy, nil
+}
+
+func (p *Policy) maxRequestSize() int {
+	if p.MaxRequestSize > 0 {
+		return p.MaxRequestSize
+	}
+	return defaultMaxRequestSize
+}
+
+// BuiltinInterceptors assembles the chain `tsh mcp start` runs by default:
+// a max-request-size guard, a tool allow/deny list, and structured audit
+// logging of every tools/call, in that order, so an oversized or denied
+// call is rejected before it ever reaches the audit log. A nil policy
+// behaves like the zero Policy.
+func BuiltinInterceptors(log *slog.Logger, policy *Policy) InterceptorChain {
+	if policy == nil {
+		policy = &Policy{}
+	}
+	return InterceptorChain{
+		&sizeGuardInterceptor{maxSize: policy.maxRequestSize()},
+		&toolFilterInterceptor{allow: policy.AllowTools, deny: policy.DenyTools},
+		&auditInterceptor{log: log, redactArgs: policy.RedactArgs},
+	}
+}
+
+// sizeGuardInterceptor rejects any request larger than maxSize, so a
+// malicious or buggy client can't force tsh to buffer and forward an
+// arbitrarily large payload.
+type sizeGuardInterceptor struct {
+	maxSize int
+}
+
+func (s *sizeGuardInterceptor) OnRequest(_ context.Context, f *Frame) error {
+	if len(f.raw) > s.maxSize {
+		return trace.LimitExceeded("MCP request is %d bytes, which exceeds the %d byte limit", len(f.raw), s.maxSize)
+	}
+	return nil
+}
+
+func (s *sizeGuardInterceptor) OnResponse(context.Context, *Frame) error { return nil }
+
+func (s *sizeGuardInterceptor) OnNotification(context.Context, *Frame) error { return nil }
+
+// toolFilterInterceptor enforces a local tool allow/deny list against
+// tools/call requests.
+type toolFilterInterceptor struct {
+	allow []string
+	deny  []string
+}
+
+func (t *toolFilterInterceptor) OnRequest(_ context.Context, f *Frame) error {
+	if f.Method != "tools/call" {
+		return nil
+	}
+	toolName, _, err := f.ToolCallArguments()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if matchesAny(t.deny, toolName) {
+		return trace.AccessDenied("local MCP policy denies tool %q", toolName)
+	}
+	if len(t.allow) > 0 && !matchesAny(t.allow, toolName) {
+		return trace.AccessDenied("local MCP policy only permits %v, got %q", t.allow, toolName)
+	}
+	return nil
+}
+
+func (t *toolFilterInterceptor) OnResponse(context.Context, *Frame) error { return nil }
+
+func (t *toolFilterInterceptor) OnNotification(context.Context, *Frame) error { return nil }
+
+// matchesAny reports whether name matches any of patterns, using
+// filepath.Match syntax. An invalid pattern is treated as not matching
+// rather than as an error, since a policy file typo should fail closed
+// only for the tool it was meant to restrict, not abort every call.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// auditInterceptor logs every tools/call request, redacting configured
+// argument names.
+type auditInterceptor struct {
+	log        *slog.Logger
+	redactArgs []string
+}
+
+func (a *auditInterceptor) OnRequest(ctx context.Context, f *Frame) error {
+	if f.Method != "tools/call" {
+		return nil
+	}
+	toolName, args, err := f.ToolCallArguments()
+	if err != nil {
+		// Malformed params are the upstream's problem to reject, not this
+		// interceptor's to block.
+		return nil
+	}
+	a.log.InfoContext(ctx, "MCP tool call",
+		"tool", toolName,
+		"arguments", redactArguments(args, a.redactArgs),
+	)
+	return nil
+}
+
+func (a *auditInterceptor) OnResponse(context.Context, *Frame) error { return nil }
+
+func (a *auditInterceptor) OnNotification(context.Context, *Frame) error { return nil }
+
+// redactArguments returns a copy of args with every key named in redact
+// replaced by a short sha256 digest of its JSON-encoded value, so audit
+// logs can show that an argument was present, and detect when it changes,
+// without recording a secret or sensitive value in full.
+func redactArguments(args map[string]any, redact []string) map[string]any {
+	if len(redact) == 0 || len(args) == 0 {
+		return args
+	}
+	redactSet := make(map[string]bool, len(redact))
+	for _, name := range redact {
+		redactSet[name] = true
+	}
+
+	out := make(map[string]any, len(args))
+	for key, value := range args {
+		if !redactSet[key] {
+			out[key] = value
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			out[key] = "<redacted>"
+			continue
+		}
+		sum := sha256.Sum256(encoded)
+		out[key] = "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return out
+}
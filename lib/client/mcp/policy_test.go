@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPolicyFile(t *testing.T) {
+	t.Run("missing file returns the zero policy", func(t *testing.T) {
+		policy, err := LoadPolicyFile(filepath.Join(t.TempDir(), "mcp.yaml"))
+		require.NoError(t, err)
+		require.Equal(t, &Policy{}, policy)
+	})
+
+	t.Run("parses a policy file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mcp.yaml")
+		contents := "allow_tools:\n  - \"teleport_*\"\ndeny_tools:\n  - \"teleport_exec\"\nredact_args:\n  - password\nmax_request_size: 2048\n"
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+		policy, err := LoadPolicyFile(path)
+		require.NoError(t, err)
+		require.Equal(t, &Policy{
+			AllowTools:     []string{"teleport_*"},
+			DenyTools:      []string{"teleport_exec"},
+			RedactArgs:     []string{"password"},
+			MaxRequestSize: 2048,
+		}, policy)
+	})
+}
+
+func TestToolFilterInterceptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		tool    string
+		wantErr bool
+	}{
+		{name: "no lists configured permits everything", tool: "teleport_exec"},
+		{name: "deny list blocks a match", deny: []string{"teleport_exec"}, tool: "teleport_exec", wantErr: true},
+		{name: "deny list doesn't block other tools", deny: []string{"teleport_exec"}, tool: "teleport_list_nodes"},
+		{name: "allow list permits a match", allow: []string{"teleport_list_*"}, tool: "teleport_list_nodes"},
+		{name: "allow list blocks a non-match", allow: []string{"teleport_list_*"}, tool: "teleport_exec", wantErr: true},
+		{name: "deny takes precedence over allow", allow: []string{"teleport_*"}, deny: []string{"teleport_exec"}, tool: "teleport_exec", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interceptor := &toolFilterInterceptor{allow: test.allow, deny: test.deny}
+			frame, err := ParseFrame([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + test.tool + `"}}`))
+			require.NoError(t, err)
+
+			err = interceptor.OnRequest(context.Background(), frame)
+			if test.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSizeGuardInterceptor(t *testing.T) {
+	interceptor := &sizeGuardInterceptor{maxSize: 16}
+	small, err := ParseFrame([]byte(`{"id":1}`))
+	require.NoError(t, err)
+	require.NoError(t, interceptor.OnRequest(context.Background(), small))
+
+	large, err := ParseFrame([]byte(`{"id":1,"method":"tools/call","params":{"name":"padding-to-exceed-the-limit"}}`))
+	require.NoError(t, err)
+	require.Error(t, interceptor.OnRequest(context.Background(), large))
+}
+
+func TestRedactArguments(t *testing.T) {
+	args := map[string]any{
+		"username": "alice",
+		"password": "hunter2",
+	}
+
+	redacted := redactArguments(args, []string{"password"})
+	require.Equal(t, "alice", redacted["username"])
+	require.NotEqual(t, "hunter2", redacted["password"])
+	require.Contains(t, redacted["password"], "sha256:")
+
+	// Redacting the same value twice is deterministic.
+	require.Equal(t, redacted["password"], redactArguments(args, []string{"password"})["password"])
+
+	// No redaction list leaves args untouched (same map, not a copy).
+	unredacted := redactArguments(args, nil)
+	require.Equal(t, args["password"], unredacted["password"])
+}
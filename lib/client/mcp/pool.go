@@ -0,0 +1,474 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/api/utils/retryutils"
+)
+
+// SelectionPolicy chooses which pool member serves the next upstream call
+// when more than one types.AppServer backs the same MCP app - the same
+// problem a signaling gateway solves fanning out to multiple media
+// servers, just one layer up, at the MCP tool-call level.
+type SelectionPolicy string
+
+const (
+	// SelectionPolicyRoundRobin cycles through healthy members in turn.
+	// This is the default.
+	SelectionPolicyRoundRobin SelectionPolicy = "round_robin"
+	// SelectionPolicyLeastInFlight sends each call to the healthy member
+	// with the fewest calls currently in flight.
+	SelectionPolicyLeastInFlight SelectionPolicy = "least_in_flight"
+	// SelectionPolicyStickyBySession pins every call for a given downstream
+	// session to the same member, falling back to least-in-flight the
+	// first time a session is seen or after its pinned member faults.
+	SelectionPolicyStickyBySession SelectionPolicy = "sticky_by_session"
+)
+
+// defaultSessionKey is used by SelectionPolicyStickyBySession until the
+// proxy tracks more than one concurrent downstream MCP session; a single
+// tsh-side forward proxy process today only ever serves one, so stickiness
+// degenerates to "pin to the first healthy member for this process's
+// lifetime", which is still the behavior that matters in practice.
+const defaultSessionKey = ""
+
+// pingInterval is how often a ready pool member's tunnel is probed with a
+// JSON-RPC ping while otherwise idle.
+const pingInterval = 30 * time.Second
+
+// pingTimeout bounds how long a single ping probe may take before its
+// member is considered faulted.
+const pingTimeout = 10 * time.Second
+
+// PoolMemberStats is a single backing instance's point-in-time state within
+// an app's pool, as returned by proxy.PoolStats().
+type PoolMemberStats struct {
+	HostID    string
+	Status    UpstreamStatus
+	InFlight  int
+	LastError error
+}
+
+// PoolStats is one MCP app's full pool state, as returned by
+// proxy.PoolStats(), for tsh to print (e.g. `tsh mcp status`).
+type PoolStats struct {
+	AppName string
+	Policy  SelectionPolicy
+	Members []PoolMemberStats
+}
+
+// poolMember is one types.AppServer instance backing a pooled MCP app: its
+// own upstreamEntry (client + health), plus the in-flight accounting
+// SelectionPolicyLeastInFlight needs.
+type poolMember struct {
+	hostID string
+	entry  *upstreamEntry
+
+	mu       sync.Mutex
+	inFlight int
+}
+
+func (m *poolMember) begin() {
+	m.mu.Lock()
+	m.inFlight++
+	m.mu.Unlock()
+}
+
+func (m *poolMember) end() {
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+}
+
+func (m *poolMember) inFlightCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inFlight
+}
+
+// upstreamPool is every known backing instance (one per types.AppServer,
+// keyed by host ID) of one MCP app, load balanced and failed over between
+// according to its SelectionPolicy.
+type upstreamPool struct {
+	appName string
+	policy  SelectionPolicy
+
+	mu      sync.Mutex
+	members map[string]*poolMember // host ID -> member
+	order   []string               // host IDs, iteration/round-robin order
+	next    int
+	sticky  map[string]string // session key -> host ID
+	callSeq uint64
+}
+
+func newUpstreamPool(appName string, policy SelectionPolicy) *upstreamPool {
+	if policy == "" {
+		policy = SelectionPolicyRoundRobin
+	}
+	return &upstreamPool{
+		appName: appName,
+		policy:  policy,
+		members: make(map[string]*poolMember),
+		sticky:  make(map[string]string),
+	}
+}
+
+// addMember returns hostID's member, creating it if this is the first time
+// hostID has been seen; the bool reports whether it was created.
+func (p *upstreamPool) addMember(hostID string) (*poolMember, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m, ok := p.members[hostID]; ok {
+		return m, false
+	}
+	m := &poolMember{hostID: hostID, entry: newUpstreamEntry(p.appName)}
+	p.members[hostID] = m
+	p.order = append(p.order, hostID)
+	return m, true
+}
+
+// removeMember drops hostID from the pool, closing its client if any, and
+// reports whether the pool is now empty.
+func (p *upstreamPool) removeMember(hostID string) (empty bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m, ok := p.members[hostID]; ok {
+		if client := m.entry.getClient(); client != nil {
+			client.Close()
+		}
+		delete(p.members, hostID)
+	}
+	for i, id := range p.order {
+		if id == hostID {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	return len(p.members) == 0
+}
+
+func (p *upstreamPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.members)
+}
+
+func (p *upstreamPool) nextCallID() uint64 {
+	return atomic.AddUint64(&p.callSeq, 1)
+}
+
+// pick selects a healthy, not-yet-excluded member according to p.policy.
+// exclude is nil-able and consulted so callWithFailover can rule out
+// members it already tried for this call.
+func (p *upstreamPool) pick(sessionKey string, exclude map[string]bool) (*poolMember, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	healthy := func(id string) bool {
+		if exclude[id] {
+			return false
+		}
+		m := p.members[id]
+		return m != nil && m.entry.getStatus() == UpstreamStatusReady
+	}
+
+	switch p.policy {
+	case SelectionPolicyStickyBySession:
+		if id, ok := p.sticky[sessionKey]; ok && healthy(id) {
+			return p.members[id], true
+		}
+		member, ok := p.pickLeastInFlightLocked(healthy)
+		if ok {
+			p.sticky[sessionKey] = member.hostID
+		}
+		return member, ok
+	case SelectionPolicyLeastInFlight:
+		return p.pickLeastInFlightLocked(healthy)
+	default: // SelectionPolicyRoundRobin
+		n := len(p.order)
+		for i := 0; i < n; i++ {
+			id := p.order[p.next%n]
+			p.next++
+			if healthy(id) {
+				return p.members[id], true
+			}
+		}
+		return nil, false
+	}
+}
+
+func (p *upstreamPool) pickLeastInFlightLocked(healthy func(string) bool) (*poolMember, bool) {
+	var best *poolMember
+	for _, id := range p.order {
+		if !healthy(id) {
+			continue
+		}
+		m := p.members[id]
+		if best == nil || m.inFlightCount() < best.inFlightCount() {
+			best = m
+		}
+	}
+	return best, best != nil
+}
+
+// memberStats is the pool's full per-member state, for PoolStats().
+func (p *upstreamPool) memberStats() []PoolMemberStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]PoolMemberStats, 0, len(p.order))
+	for _, id := range p.order {
+		m := p.members[id]
+		state := m.entry.state()
+		out = append(out, PoolMemberStats{
+			HostID:    id,
+			Status:    state.Status,
+			InFlight:  m.inFlightCount(),
+			LastError: state.LastError,
+		})
+	}
+	return out
+}
+
+// aggregateState collapses the pool down to a single UpstreamState, the
+// shape Status() already returns, by reporting its healthiest member:
+// PoolStats gives the full per-member picture.
+func (p *upstreamPool) aggregateState() UpstreamState {
+	rank := func(s UpstreamStatus) int {
+		switch s {
+		case UpstreamStatusReady:
+			return 3
+		case UpstreamStatusDegraded:
+			return 2
+		case UpstreamStatusConnecting:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best := UpstreamState{AppName: p.appName, Status: UpstreamStatusFailed}
+	for _, id := range p.order {
+		state := p.members[id].entry.state()
+		if rank(state.Status) > rank(best.Status) {
+			best = state
+		}
+	}
+	return best
+}
+
+// closeAll closes every member's client, collecting any errors.
+func (p *upstreamPool) closeAll() []error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for _, id := range p.order {
+		if client := p.members[id].entry.getClient(); client != nil {
+			if err := client.Close(); err != nil {
+				errs = append(errs, trace.Wrap(err, "failed to close upstream client %v/%v", p.appName, id))
+			}
+		}
+	}
+	return errs
+}
+
+// callWithFailover picks a healthy pool member and forwards req to it,
+// retrying against the pool's other healthy members if the picked one
+// turns out to be faulted mid-call, up to once per member. callID
+// correlates every attempt for the same logical downstream call across log
+// lines and events - the proxy's stand-in for deduping by JSON-RPC request
+// ID: Upstream.CallTool owns the wire-level id when it reframes req for
+// the upstream transport, so the proxy can't dedupe on that id directly;
+// instead it only ever acts on the first attempt that succeeds and
+// abandons the rest, so a client never sees more than one result for a
+// given call.
+func (p *proxy) callWithFailover(ctx context.Context, pool *upstreamPool, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	callID := pool.nextCallID()
+	tried := map[string]bool{}
+
+	var lastErr error
+	for attempt := 0; attempt < pool.size(); attempt++ {
+		member, ok := pool.pick(defaultSessionKey, tried)
+		if !ok {
+			break
+		}
+		tried[member.hostID] = true
+
+		client := member.entry.getClient()
+		if client == nil {
+			continue
+		}
+
+		member.begin()
+		result, err := client.CallTool(ctx, req)
+		member.end()
+		if err == nil {
+			return result, nil
+		}
+		if !isConnectionFault(err) {
+			return nil, trace.Wrap(err)
+		}
+
+		p.log.WarnContext(ctx, "Upstream MCP pool member faulted mid-call, failing over",
+			"upstream", pool.appName, "host_id", member.hostID, "call_id", callID, "error", err,
+		)
+		member.entry.setFailed(err)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = trace.ConnectionProblem(nil, "no healthy upstream MCP pool member for %v", pool.appName)
+	}
+	return nil, trace.Wrap(lastErr)
+}
+
+// isConnectionFault reports whether err looks like a transport-level fault
+// (the underlying alpnproxy.LocalProxy tunnel dropped, or a probe/call
+// timed out) as opposed to a tool-level error the upstream itself returned,
+// which callWithFailover shouldn't paper over by trying another member.
+func isConnectionFault(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return trace.IsConnectionProblem(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// monitorPoolMember dials and initializes app's backing member, then keeps
+// it healthy for as long as it stays registered: once ready, it probes the
+// tunnel with a JSON-RPC ping every pingInterval, and on a failed dial,
+// failed probe, or a faulted mid-call (see callWithFailover) demotes the
+// member and restarts the dial-with-backoff loop. A fault on one member
+// only ever affects its own slot in the pool; upstreamPool.pick routes new
+// calls to its healthy siblings in the meantime.
+func (p *proxy) monitorPoolMember(ctx context.Context, app types.AppServer, member *poolMember) {
+	name := app.GetApp().GetName()
+	entry := member.entry
+
+	for {
+		upstream, err := p.dialPoolMemberWithBackoff(ctx, app, member)
+		if err != nil {
+			return // ctx done
+		}
+
+		if !p.poolMemberStillRegistered(name, member.hostID) {
+			upstream.Close()
+			return
+		}
+
+		entry.setReady(upstream)
+		upstream.OnNotification(func(n mcp.JSONRPCNotification) {
+			if n.Method == NotificationMethodToolsListChanged {
+				p.refreshUpstreamTools(ctx, name, upstream, entry)
+			}
+		})
+		p.refreshUpstreamTools(ctx, name, upstream, entry)
+
+		if !p.pingUntilFailure(ctx, name, member, upstream) {
+			return
+		}
+		// a ping failed; loop back around and reconnect with backoff
+	}
+}
+
+// dialPoolMemberWithBackoff dials and initializes app, retrying with
+// exponential backoff until it succeeds or ctx is done.
+func (p *proxy) dialPoolMemberWithBackoff(ctx context.Context, app types.AppServer, member *poolMember) (*Upstream, error) {
+	name := app.GetApp().GetName()
+	retry, err := retryutils.NewRetryV2(retryutils.RetryV2Config{
+		First:  time.Second,
+		Driver: retryutils.NewExponentialDriver(time.Second),
+		Max:    30 * time.Second,
+	})
+	if err != nil {
+		p.log.ErrorContext(ctx, "Failed to create upstream reconnect backoff", "error", err)
+		member.entry.setFailed(err)
+		return nil, trace.Wrap(err)
+	}
+
+	for {
+		upstream, err := p.dialPoolMemberOnce(ctx, app)
+		if err == nil {
+			return upstream, nil
+		}
+
+		p.log.WarnContext(ctx, "Failed to initialize upstream MCP pool member, retrying",
+			"upstream", name, "host_id", member.hostID, "error", err,
+		)
+		member.entry.setFailed(err)
+		p.events.publish(Event{Kind: EventInitializeFailed, AppName: name, Error: err})
+
+		select {
+		case <-retry.After():
+			retry.Inc()
+		case <-ctx.Done():
+			return nil, trace.Wrap(ctx.Err())
+		}
+	}
+}
+
+// dialPoolMemberOnce makes a single dial+initialize attempt, bounded by
+// initLimiter so a large pool doesn't dial every member at once.
+func (p *proxy) dialPoolMemberOnce(ctx context.Context, app types.AppServer) (*Upstream, error) {
+	select {
+	case p.initLimiter <- struct{}{}:
+		defer func() { <-p.initLimiter }()
+	case <-ctx.Done():
+		return nil, trace.Wrap(ctx.Err())
+	}
+	return p.dialAndInitUpstream(ctx, app)
+}
+
+// pingUntilFailure probes upstream with a JSON-RPC ping every pingInterval
+// until one fails or times out, or until a tick finds the member already
+// demoted to failed by a faulted mid-call (returning true, so the caller
+// reconnects), or ctx is done (returning false).
+func (p *proxy) pingUntilFailure(ctx context.Context, appName string, member *poolMember, upstream *Upstream) bool {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if member.entry.getStatus() == UpstreamStatusFailed {
+				return true
+			}
+			pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+			err := upstream.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				p.log.WarnContext(ctx, "Upstream MCP pool member failed health probe, failing over",
+					"upstream", appName, "host_id", member.hostID, "error", err,
+				)
+				member.entry.setFailed(err)
+				p.events.publish(Event{Kind: EventInitializeFailed, AppName: appName, Error: err})
+				return true
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// poolMemberStillRegistered reports whether hostID is still a member of
+// appName's pool, i.e. it wasn't removed while a connect attempt was in
+// flight.
+func (p *proxy) poolMemberStillRegistered(appName, hostID string) bool {
+	p.mu.RLock()
+	pool, ok := p.upstreams[appName]
+	p.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	_, ok = pool.members[hostID]
+	return ok
+}
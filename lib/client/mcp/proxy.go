@@ -19,16 +19,61 @@ import (
 	"github.com/gravitational/teleport/lib/services"
 )
 
+// maxConcurrentUpstreamInit bounds how many upstream MCP servers the proxy
+// dials and initializes at once, so one slow deployment doesn't stall the
+// rest of the fleet behind a small worker pool.
+const maxConcurrentUpstreamInit = 8
+
 // AppDialerFunc dials an MCP application server and returns two ends of a pipe
 // that can be used to read/write bytes to the app server.
 type AppDialerFunc func(ctx context.Context, appServer types.AppServer) (io.ReadCloser, io.WriteCloser, error)
 
+// AppHTTPDialerFunc dials an upstream MCP app server exposed over the
+// Streamable HTTP or SSE transport and returns a ready-to-use MCP client,
+// rather than the pipe halves AppDialerFunc returns for stdio apps.
+type AppHTTPDialerFunc func(ctx context.Context, appServer types.AppServer, transport mcpUpstreamTransport) (mcpclient.MCPClient, error)
+
+// ToolFilterFunc filters the tools an upstream MCP app server advertised
+// before they're re-exported to the downstream client, so that tools the
+// caller's roles don't permit (e.g. via an `mcp.tools: ["read_*", "!write_*"]`
+// role rule) are never advertised in the first place.
+type ToolFilterFunc func(appName string, tools []mcp.Tool) []mcp.Tool
+
+// CallToolAuthorizerFunc authorizes a single CallTool invocation against an
+// upstream app's tool, returning a non-nil error to deny it. Implementations
+// are expected to also audit the decision.
+type CallToolAuthorizerFunc func(ctx context.Context, appName, toolName string, arguments any) error
+
 type ProxyConfig struct {
 	// AppDialerFn is a callback that abstracts away dialing an upstream MCP app server
 	AppDialerFn AppDialerFunc
+	// AppHTTPDialerFn dials upstream MCP app servers that use the
+	// Streamable HTTP or SSE transport (see detectUpstreamTransport). It is
+	// only required if such an app is present; stdio-only deployments can
+	// leave it nil.
+	AppHTTPDialerFn AppHTTPDialerFunc
 	// Events is used to watch app servers
 	Events           types.Events
 	AppServersGetter services.AppServersGetter
+	// ToolFilter, if set, is applied to every upstream's tool list before
+	// it's re-exported. A nil ToolFilter re-exports everything, preserving
+	// today's behavior. tsh itself leaves this nil: it has no local RBAC
+	// decision point to build a filter from (no AccessChecker - the proxy
+	// only ever sees one app's AppServer roster at a time, via
+	// AppServersGetter). The real mcp.tools enforcement for every upstream
+	// app still happens at that app's own server, in
+	// lib/srv/app/mcpserver.go's authorizedReader, which this proxy's
+	// AppDialerFn connects through; a nil ToolFilter here only means tsh
+	// doesn't also pre-hide denied tools in its own aggregated view.
+	ToolFilter ToolFilterFunc
+	// CallToolAuthorizer, if set, gates and audits every downstream
+	// tools/call before it's forwarded upstream. See the ToolFilter comment
+	// above for why tsh leaves this nil too.
+	CallToolAuthorizer CallToolAuthorizerFunc
+	// SelectionPolicy chooses which types.AppServer backs the next call
+	// when more than one proxies the same MCP app. Defaults to
+	// SelectionPolicyRoundRobin.
+	SelectionPolicy SelectionPolicy
 }
 
 func (c *ProxyConfig) check() error {
@@ -47,6 +92,18 @@ func (c *ProxyConfig) check() error {
 type MCPProxy interface {
 	Listen(ctx context.Context, stdin io.Reader, stdout io.Writer) error
 	Close() error
+	// Status returns a point-in-time snapshot of every known upstream's
+	// health, keyed by app name.
+	Status() map[string]UpstreamState
+	// PoolStats returns a point-in-time snapshot of every app's pool of
+	// backing instances - selection policy, and each member's host ID,
+	// health, and in-flight call count - for tsh to print.
+	PoolStats() map[string]PoolStats
+	// Subscribe returns a channel of proxy lifecycle events, preceded by a
+	// replay of recent history, so a late subscriber doesn't miss events
+	// that happened before it called Subscribe. The channel closes when
+	// ctx is done.
+	Subscribe(ctx context.Context) <-chan Event
 }
 
 func NewProxy(ctx context.Context, cfg ProxyConfig) (MCPProxy, error) {
@@ -59,11 +116,13 @@ func NewProxy(ctx context.Context, cfg ProxyConfig) (MCPProxy, error) {
 		server.WithToolCapabilities(true),
 	)
 	p := &proxy{
-		cfg:        cfg,
-		log:        slog.With(teleport.ComponentKey, teleport.ComponentMCP),
-		server:     srv,
-		appServers: make(map[string]types.AppServer),
-		clients:    make(map[string]mcpclient.MCPClient),
+		cfg:         cfg,
+		log:         slog.With(teleport.ComponentKey, teleport.ComponentMCP),
+		server:      srv,
+		appServers:  make(map[string]types.AppServer),
+		upstreams:   make(map[string]*upstreamPool),
+		initLimiter: make(chan struct{}, maxConcurrentUpstreamInit),
+		events:      newEventBus(),
 	}
 	if err := p.start(ctx); err != nil {
 		return nil, trace.Wrap(err)
@@ -75,10 +134,25 @@ type proxy struct {
 	cfg ProxyConfig
 	log *slog.Logger
 
-	mu         sync.RWMutex
+	mu sync.RWMutex
+	// appServers is keyed by appServerKey (app name + host ID), since more
+	// than one types.AppServer can back the same app name.
 	appServers map[string]types.AppServer
-	clients    map[string]mcpclient.MCPClient
-	server     *server.MCPServer
+	// upstreams is keyed by app name; each pool holds every backing
+	// types.AppServer currently registered for that app.
+	upstreams map[string]*upstreamPool
+	server    *server.MCPServer
+
+	// initLimiter bounds how many upstreams are dialed/initialized
+	// concurrently; acquire a slot by sending, release by receiving.
+	initLimiter chan struct{}
+
+	events *eventBus
+}
+
+// Subscribe returns a channel of proxy lifecycle events; see MCPProxy.
+func (p *proxy) Subscribe(ctx context.Context) <-chan Event {
+	return p.events.subscribe(ctx)
 }
 
 func (p *proxy) Listen(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
@@ -90,16 +164,44 @@ func (p *proxy) Listen(ctx context.Context, stdin io.Reader, stdout io.Writer) e
 }
 
 func (p *proxy) Close() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	var errors []error
-	for name, c := range p.clients {
-		if err := c.Close(); err != nil {
-			err = trace.Wrap(err, "failed to close upstream client %v", name)
-			errors = append(errors, err)
-		}
+	for _, pool := range p.upstreams {
+		errors = append(errors, pool.closeAll()...)
 	}
 	return trace.NewAggregate(errors...)
 }
 
+// Status returns a point-in-time snapshot of every known upstream's health,
+// keyed by app name. When an app's pool has more than one backing member,
+// this reports its healthiest one; see PoolStats for the full picture.
+func (p *proxy) Status() map[string]UpstreamState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make(map[string]UpstreamState, len(p.upstreams))
+	for name, pool := range p.upstreams {
+		out[name] = pool.aggregateState()
+	}
+	return out
+}
+
+// PoolStats returns a point-in-time snapshot of every app's pool; see
+// MCPProxy.
+func (p *proxy) PoolStats() map[string]PoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make(map[string]PoolStats, len(p.upstreams))
+	for name, pool := range p.upstreams {
+		out[name] = PoolStats{
+			AppName: name,
+			Policy:  pool.policy,
+			Members: pool.memberStats(),
+		}
+	}
+	return out
+}
+
 func (p *proxy) start(ctx context.Context) error {
 	p.log.InfoContext(ctx, "Starting MCP forward proxy")
 	if err := p.watchAppServers(ctx); err != nil {
@@ -148,7 +250,7 @@ func (p *proxy) watchAppServers(ctx context.Context) error {
 		GetNewResources: func() map[string]types.AppServer {
 			out := map[string]types.AppServer{}
 			for _, r := range newResources {
-				out[r.GetApp().GetName()] = r
+				out[appServerKey(r)] = r
 			}
 			return out
 		},
@@ -202,6 +304,9 @@ func (p *proxy) getCurrentResources() map[string]types.AppServer {
 	return p.getAppServers()
 }
 
+// getAppServers returns every currently known types.AppServer, keyed by
+// appServerKey - app name plus host ID, since more than one AppServer can
+// back the same app name (see upstreamPool).
 func (p *proxy) getAppServers() map[string]types.AppServer {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -210,39 +315,64 @@ func (p *proxy) getAppServers() map[string]types.AppServer {
 	return out
 }
 
+// appServerKey uniquely identifies one types.AppServer backing an MCP app,
+// so multiple instances proxying the same app (e.g. several app service
+// replicas for HA) are each tracked individually rather than collapsing to
+// the last one reconciled.
+func appServerKey(app types.AppServer) string {
+	return app.GetApp().GetName() + "/" + app.GetHostID()
+}
+
+// registerAppServer records app as a backing member of its app's pool
+// (creating the pool if this is the first member seen for that app name)
+// and kicks off a background goroutine to dial, initialize, and then keep
+// monitoring it, bounded by initLimiter. It returns immediately without
+// waiting for the dial to complete so that one slow upstream never delays
+// the reconciler from processing the rest of the batch.
 func (p *proxy) registerAppServer(ctx context.Context, app types.AppServer) error {
 	name := app.GetApp().GetName()
-	upstream, err := p.newUpstreamClient(ctx, app)
-	if err != nil {
-		return trace.Wrap(err, "failed to create upstream MCP client")
-	}
+	hostID := app.GetHostID()
+
 	p.mu.Lock()
-	defer p.mu.Unlock()
-	if _, ok := p.clients[name]; ok {
-		p.log.DebugContext(ctx, "upstream MCP client already exists",
-			"name", name,
+	pool, ok := p.upstreams[name]
+	if !ok {
+		pool = newUpstreamPool(name, p.cfg.SelectionPolicy)
+		p.upstreams[name] = pool
+	}
+	p.appServers[appServerKey(app)] = app
+	p.mu.Unlock()
+
+	member, isNew := pool.addMember(hostID)
+	if !isNew {
+		p.log.DebugContext(ctx, "upstream MCP pool member already exists",
+			"name", name, "host_id", hostID,
 		)
 		return nil
 	}
-	p.clients[name] = upstream
-	p.appServers[name] = app
+
+	p.events.publish(Event{Kind: EventUpstreamRegistered, AppName: name})
+	go p.monitorPoolMember(ctx, app, member)
 	return nil
 }
 
+// removeAppServer drops app's entry from its pool, closing its client if
+// any. EventUpstreamRemoved only fires once the pool has no members left,
+// i.e. this was app's last backing instance.
 func (p *proxy) removeAppServer(ctx context.Context, app types.AppServer) error {
 	name := app.GetApp().GetName()
+	hostID := app.GetHostID()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if upstream, ok := p.clients[name]; ok {
-		if err := upstream.Close(); err != nil {
-			p.log.DebugContext(ctx, "failed to close upstream MCP client",
-				"error", err,
-			)
-		}
-		delete(p.clients, name)
+	delete(p.appServers, appServerKey(app))
+
+	pool, ok := p.upstreams[name]
+	if !ok {
+		return nil
 	}
-	if _, ok := p.appServers[name]; ok {
-		delete(p.appServers, name)
+	if pool.removeMember(hostID) {
+		delete(p.upstreams, name)
+		p.events.publish(Event{Kind: EventUpstreamRemoved, AppName: name})
 	}
 	return nil
 }
@@ -254,16 +384,46 @@ func (p *proxy) updateAppServer(ctx context.Context, new, old types.AppServer) e
 	if err := p.registerAppServer(ctx, new); err != nil {
 		return trace.Wrap(err, "failed to add new tool for app %s", new.GetName())
 	}
+	p.events.publish(Event{Kind: EventUpstreamUpdated, AppName: new.GetApp().GetName()})
 	return nil
 }
 
-func (p *proxy) newUpstreamClient(ctx context.Context, app types.AppServer) (*Upstream, error) {
-	// TODO(gavin): async connect to upstream
-	reader, writer, err := p.dialAppServer(ctx, app)
+// refreshUpstreamTools fetches the upstream's current tool list and
+// re-advertises it. A fetch failure demotes an already-ready upstream to
+// degraded rather than discarding its last known-good tools.
+func (p *proxy) refreshUpstreamTools(ctx context.Context, name string, upstream *Upstream, entry *upstreamEntry) {
+	p.log.DebugContext(ctx, "Fetching tools from upstream", "upstream", name)
+	tools, err := p.fetchTools(ctx, upstream)
 	if err != nil {
-		return nil, trace.Wrap(err)
+		p.log.WarnContext(ctx, "Failed to fetch tools from upstream, keeping last known list",
+			"upstream", name, "error", err,
+		)
+		entry.setDegraded(err)
+		return
+	}
+	if err := p.addTools(name, tools); err != nil {
+		p.log.DebugContext(ctx, "Failed to update MCP server tools list",
+			"upstream", name, "error", err,
+		)
+		entry.setDegraded(err)
+		return
+	}
+	entry.setReady(nil)
+	entry.setToolCount(len(tools))
+	p.events.publish(Event{Kind: EventToolsChanged, AppName: name, ToolCount: len(tools)})
+}
+
+// dialAndInitUpstream dials app over whichever transport it's configured
+// for and performs the MCP initialize handshake.
+func (p *proxy) dialAndInitUpstream(ctx context.Context, app types.AppServer) (*Upstream, error) {
+	var upstream *Upstream
+	var err error
+	switch transportKind := detectUpstreamTransport(app.GetApp()); transportKind {
+	case mcpUpstreamTransportSSE, mcpUpstreamTransportStreamableHTTP:
+		upstream, err = p.dialHTTPUpstream(ctx, app, transportKind)
+	default:
+		upstream, err = p.dialStdioUpstream(ctx, app)
 	}
-	upstream, err := newUpstream(reader, writer)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -271,44 +431,11 @@ func (p *proxy) newUpstreamClient(ctx context.Context, app types.AppServer) (*Up
 	initReq.Params.ProtocolVersion = "2024-11-05"
 	initReq.Params.ClientInfo.Name = "tsh"
 	initReq.Params.ClientInfo.Version = teleport.Version
-	_, err = upstream.Initialize(ctx, initReq)
-	if err != nil {
+	if _, err := upstream.Initialize(ctx, initReq); err != nil {
 		return nil, trace.Wrap(err,
 			"failed to initialize upstream %v", app.GetApp().GetName(),
 		)
 	}
-	upstream.OnNotification(func(n mcp.JSONRPCNotification) {
-		switch n.Method {
-		case NotificationMethodToolsListChanged:
-			p.mu.Lock()
-			defer p.mu.Unlock()
-			tools := p.fetchTools(ctx, upstream)
-			p.log.DebugContext(ctx, "Fetching updated tools from upstream",
-				"upstream", app.GetApp().GetName(),
-			)
-			if err := p.addTools(app.GetApp().GetName(), tools); err != nil {
-				p.log.DebugContext(ctx,
-					"Failed to update MCP server tools list.",
-					"upstream", app.GetApp().GetName(),
-					"error", err,
-				)
-				return
-			}
-		}
-	})
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.log.DebugContext(ctx, "Fetching initial tools from upstream",
-		"upstream", app.GetApp().GetName(),
-	)
-	tools := p.fetchTools(ctx, upstream)
-	if err := p.addTools(app.GetApp().GetName(), tools); err != nil {
-		p.log.DebugContext(ctx,
-			"Failed to initialize MCP server tools list.",
-			"upstream", app.GetApp().GetName(),
-			"error", err,
-		)
-	}
 	return upstream, nil
 }
 
@@ -316,7 +443,34 @@ func (p *proxy) dialAppServer(ctx context.Context, appServer types.AppServer) (i
 	return p.cfg.AppDialerFn(ctx, appServer)
 }
 
-func (p *proxy) fetchTools(ctx context.Context, upstream *Upstream) []mcp.Tool {
+// dialStdioUpstream dials app over a pipe and wraps it in an Upstream that
+// speaks MCP framed over that pipe's stdin/stdout.
+func (p *proxy) dialStdioUpstream(ctx context.Context, app types.AppServer) (*Upstream, error) {
+	reader, writer, err := p.dialAppServer(ctx, app)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	upstream, err := newUpstream(reader, writer)
+	return upstream, trace.Wrap(err)
+}
+
+// dialHTTPUpstream dials app over the Streamable HTTP or SSE transport using
+// ProxyConfig.AppHTTPDialerFn and wraps the resulting client in an Upstream.
+func (p *proxy) dialHTTPUpstream(ctx context.Context, app types.AppServer, transport mcpUpstreamTransport) (*Upstream, error) {
+	if p.cfg.AppHTTPDialerFn == nil {
+		return nil, trace.BadParameter(
+			"app %v uses the %v MCP transport, but no HTTP dialer is configured",
+			app.GetApp().GetName(), transport,
+		)
+	}
+	client, err := p.cfg.AppHTTPDialerFn(ctx, app, transport)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return newUpstreamFromClient(client), nil
+}
+
+func (p *proxy) fetchTools(ctx context.Context, upstream *Upstream) ([]mcp.Tool, error) {
 	var cursor mcp.Cursor
 	var tools []mcp.Tool
 	for {
@@ -324,11 +478,7 @@ func (p *proxy) fetchTools(ctx context.Context, upstream *Upstream) []mcp.Tool {
 		req.Params.Cursor = cursor
 		res, err := upstream.ListTools(ctx, req)
 		if err != nil {
-			p.log.DebugContext(ctx,
-				"Failed to fetch tools for upstream MCP server",
-				"error", err,
-			)
-			return nil
+			return nil, trace.Wrap(err, "failed to fetch tools for upstream MCP server")
 		}
 		tools = append(tools, res.Tools...)
 		cursor = res.NextCursor
@@ -336,10 +486,13 @@ func (p *proxy) fetchTools(ctx context.Context, upstream *Upstream) []mcp.Tool {
 			break
 		}
 	}
-	return tools
+	return tools, nil
 }
 
 func (p *proxy) addTools(upstreamName string, upstreamTools []mcp.Tool) error {
+	if p.cfg.ToolFilter != nil {
+		upstreamTools = p.cfg.ToolFilter(upstreamName, upstreamTools)
+	}
 	p.log.Info("Adding tools from upstream",
 		"upstream", upstreamName,
 		"tool_count", len(upstreamTools),
@@ -354,7 +507,7 @@ func (p *proxy) addTools(upstreamName string, upstreamTools []mcp.Tool) error {
 		downTool.Name = p.translateUpstreamTool(upstreamName, upTool.Name)
 		serverTools = append(serverTools, server.ServerTool{
 			Tool:    downTool,
-			Handler: p.callUpstreamTool,
+			Handler: p.withPanicRecovery(p.callUpstreamTool),
 		})
 	}
 	if len(serverTools) == 0 {
@@ -365,17 +518,35 @@ func (p *proxy) addTools(upstreamName string, upstreamTools []mcp.Tool) error {
 }
 
 func (p *proxy) callUpstreamTool(ctx context.Context, downReq mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	upstreamName, upToolName := p.translateDownstreamTool(downReq.Params.Name)
+	upstreamName, upToolName, err := p.translateDownstreamTool(downReq.Params.Name)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if p.cfg.CallToolAuthorizer != nil {
+		if err := p.cfg.CallToolAuthorizer(ctx, upstreamName, upToolName, downReq.Params.Arguments); err != nil {
+			p.events.publish(Event{Kind: EventCallToolFailed, AppName: upstreamName, Error: err})
+			return nil, trace.Wrap(err)
+		}
+	}
 	var upReq mcp.CallToolRequest
 	upReq.Params = downReq.Params
 	upReq.Params.Name = upToolName
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	upstream, ok := p.clients[upstreamName]
+
+	p.mu.RLock()
+	pool, ok := p.upstreams[upstreamName]
+	p.mu.RUnlock()
 	if !ok {
-		return nil, trace.NotFound("upstream MCP server %v not found", upstreamName)
+		err := trace.NotFound("upstream MCP server %v not found", upstreamName)
+		p.events.publish(Event{Kind: EventCallToolFailed, AppName: upstreamName, Error: err})
+		return nil, err
+	}
+
+	p.events.publish(Event{Kind: EventCallToolInvoked, AppName: upstreamName})
+	result, err := p.callWithFailover(ctx, pool, upReq)
+	if err != nil {
+		p.events.publish(Event{Kind: EventCallToolFailed, AppName: upstreamName, Error: err})
 	}
-	return upstream.CallTool(ctx, upReq)
+	return result, err
 }
 
 // translateUpstreamTool renames an upstream tool in the format "teleport/$app/$name"
@@ -383,20 +554,19 @@ func (p *proxy) translateUpstreamTool(upstreamName, toolName string) string {
 	return fmt.Sprintf("teleport/%s/%s", upstreamName, toolName)
 }
 
-func (p *proxy) translateDownstreamTool(toolName string) (string, string) {
+func (p *proxy) translateDownstreamTool(toolName string) (string, string, error) {
 	parts := strings.SplitN(toolName, "/", 3)
 	if len(parts) != 3 {
-		// should never happen
 		p.log.Error("unexpected tool call from downstream client",
 			"tool", toolName,
 			"parts", parts,
 		)
-		msg := fmt.Sprintf("the server only advertises namespaced tools, but accepted a tool call (name: %v, parts: %v) that is not namespaced",
+		return "", "", trace.BadParameter(
+			"the server only advertises namespaced tools, but received a tool call (name: %v, parts: %v) that is not namespaced",
 			toolName, parts,
 		)
-		panic(msg)
 	}
 	upstreamName := parts[1]
 	upstreamToolName := parts[2]
-	return upstreamName, upstreamToolName
+	return upstreamName, upstreamToolName, nil
 }
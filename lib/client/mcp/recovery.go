@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// mcpToolPanicsTotal counts panics recovered from upstream tool dispatch, by
+// the downstream (namespaced) tool name that triggered them. A misbehaving
+// upstream MCP server should never be able to crash the forward proxy.
+var mcpToolPanicsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: teleportNamespace,
+		Subsystem: "mcp",
+		Name:      "tool_panics_total",
+		Help:      "Number of panics recovered while dispatching an MCP tool call.",
+	},
+	[]string{"tool"},
+)
+
+const teleportNamespace = "teleport"
+
+// withPanicRecovery wraps a server.ToolHandlerFunc so that a panic anywhere
+// in the dispatch path (translating the tool name, calling upstream,
+// decoding its response, etc.) is converted into a JSON-RPC tool error
+// instead of taking down the whole stdio proxy. This mirrors the
+// recovery.UnaryServerInterceptor pattern used for gRPC servers: recover,
+// log with a stack trace, count it, and return a stable error to the caller.
+func (p *proxy) withPanicRecovery(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				mcpToolPanicsTotal.WithLabelValues(req.Params.Name).Inc()
+				p.log.ErrorContext(ctx, "Recovered from panic while dispatching MCP tool call",
+					"tool", req.Params.Name,
+					"panic", r,
+					"stack", string(debug.Stack()),
+				)
+				result = mcp.NewToolResultError(fmt.Sprintf(
+					"internal error dispatching tool %q", req.Params.Name,
+				))
+				err = nil
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateDownstreamTool(t *testing.T) {
+	p := &proxy{log: slog.Default()}
+
+	upstreamName, toolName, err := p.translateDownstreamTool("teleport/my-app/my-tool")
+	require.NoError(t, err)
+	require.Equal(t, "my-app", upstreamName)
+	require.Equal(t, "my-tool", toolName)
+
+	_, _, err = p.translateDownstreamTool("not-namespaced")
+	require.True(t, trace.IsBadParameter(err))
+}
+
+func TestWithPanicRecovery(t *testing.T) {
+	p := &proxy{log: slog.Default()}
+
+	var panicking server.ToolHandlerFunc = func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		panic("boom")
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "teleport/my-app/my-tool"
+
+	result, err := p.withPanicRecovery(panicking)(context.Background(), req)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.True(t, result.IsError)
+}
@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/gravitational/trace"
+)
+
+// routerDomainSuffix is the fake TLS SNI domain suffix a Router maps to a
+// local app name: a ClientHello for "github.mcp.local" routes to the
+// Teleport app named "github".
+const routerDomainSuffix = ".mcp.local"
+
+// RouterAppDialerFunc dials the ALPN backend for a single MCP app by name,
+// using the same cert/loader path as onMCPForward's dialAppServer - a
+// Router's only difference from that function is it picks which app to
+// dial from the inbound connection's TLS SNI rather than a single fixed
+// app. It is distinct from the proxy's AppDialerFunc, which dials a
+// types.AppServer and returns pipe halves.
+type RouterAppDialerFunc func(ctx context.Context, appName string) (io.ReadWriteCloser, error)
+
+// RouterConfig configures a Router.
+type RouterConfig struct {
+	// DialApp dials the backend for an app name extracted from an inbound
+	// connection's ClientHello SNI.
+	DialApp RouterAppDialerFunc
+	// Logger is used for all router logging. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+func (c *RouterConfig) checkAndSetDefaults() error {
+	if c.DialApp == nil {
+		return trace.BadParameter("missing app dialer")
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	return nil
+}
+
+// Router accepts inbound TLS connections on a single listener, reads just
+// enough of each ClientHello to learn its SNI, and splices the connection
+// through to the backend for the app that SNI names - without terminating
+// TLS itself, so the real handshake still happens end-to-end between the
+// original client and whichever backend onMCPForward-style dialing stood
+// up, exactly as if the client had dialed that backend directly. This lets
+// `tsh mcp route` expose every app a user is logged into behind a single
+// local port, addressed by hostname instead of one stdio subprocess per
+// app.
+type Router struct {
+	cfg RouterConfig
+}
+
+// NewRouter constructs a Router from cfg.
+func NewRouter(cfg RouterConfig) (*Router, error) {
+	if err := cfg.checkAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Router{cfg: cfg}, nil
+}
+
+// Serve accepts connections from ln and routes each until ctx is done.
+func (r *Router) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			return trace.Wrap(err)
+		}
+		go r.handleConn(ctx, conn)
+	}
+}
+
+func (r *Router) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	if err := r.routeConn(ctx, conn); err != nil {
+		r.cfg.Logger.WarnContext(ctx, "Failed to route MCP connection", "error", err)
+	}
+}
+
+func (r *Router) routeConn(ctx context.Context, conn net.Conn) error {
+	sni, peeked, err := peekClientHelloServerName(conn)
+	if err != nil {
+		return trace.Wrap(err, "reading TLS ClientHello")
+	}
+	appName, ok := appNameFromSNI(sni)
+	if !ok {
+		return trace.BadParameter("SNI %q doesn't match the %q routing domain", sni, routerDomainSuffix)
+	}
+
+	backend, err := r.cfg.DialApp(ctx, appName)
+	if err != nil {
+		return trace.Wrap(err, "dialing MCP app %q", appName)
+	}
+	defer backend.Close()
+
+	if len(peeked) > 0 {
+		if _, err := backend.Write(peeked); err != nil {
+			return trace.Wrap(err, "forwarding buffered ClientHello to %q", appName)
+		}
+	}
+
+	return trace.Wrap(splice(conn, backend))
+}
+
+// appNameFromSNI extracts the app name from a ClientHello SNI of the form
+// "<app>.mcp.local".
+func appNameFromSNI(sni string) (string, bool) {
+	appName, ok := strings.CutSuffix(sni, routerDomainSuffix)
+	if !ok || appName == "" {
+		return "", false
+	}
+	return appName, true
+}
+
+// splice copies bytes in both directions between a and b until either
+// side's copy returns, and reports that error.
+func splice(a, b io.ReadWriteCloser) error {
+	errCh := make(chan error, 2)
+	go func() { _, err := io.Copy(b, a); errCh <- err }()
+	go func() { _, err := io.Copy(a, b); errCh <- err }()
+	return trace.Wrap(<-errCh)
+}
+
+// errServerNameExtracted is returned by peekClientHelloServerName's
+// GetConfigForClient callback the instant the ClientHello's SNI is known,
+// to abort the sniffing handshake below before it ever completes.
+var errServerNameExtracted = errors.New("server name extracted")
+
+// recordingConn wraps a net.Conn, recording every byte read through it so
+// peekClientHelloServerName's aborted handshake can be replayed byte for
+// byte to whichever backend the Router routes the connection to. Writes
+// are discarded - the sniffing handshake below is never meant to reach the
+// real client, only to read its ClientHello.
+type recordingConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) { return len(p), nil }
+
+// peekClientHelloServerName reads just enough of conn to learn an inbound
+// TLS ClientHello's SNI, deliberately aborting before the handshake
+// completes, and returns every byte it consumed from conn so the caller
+// can forward them unchanged to the real backend, which performs the
+// actual handshake.
+func peekClientHelloServerName(conn net.Conn) (serverName string, peeked []byte, err error) {
+	rec := &recordingConn{Conn: conn}
+	tlsConn := tls.Server(rec, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, errServerNameExtracted
+		},
+	})
+	if err := tlsConn.Handshake(); !errors.Is(err, errServerNameExtracted) {
+		return "", rec.buf.Bytes(), trace.Wrap(err, "sniffing handshake did not abort as expected")
+	}
+	if serverName == "" {
+		return "", rec.buf.Bytes(), trace.BadParameter("ClientHello did not include a server name (SNI)")
+	}
+	return serverName, rec.buf.Bytes(), nil
+}
@@ -0,0 +1,147 @@
+package mcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppNameFromSNI(t *testing.T) {
+	tests := []struct {
+		sni     string
+		appName string
+		ok      bool
+	}{
+		{sni: "github.mcp.local", appName: "github", ok: true},
+		{sni: "my-app.mcp.local", appName: "my-app", ok: true},
+		{sni: ".mcp.local", ok: false},
+		{sni: "mcp.local", ok: false},
+		{sni: "github.example.com", ok: false},
+	}
+	for _, test := range tests {
+		appName, ok := appNameFromSNI(test.sni)
+		require.Equal(t, test.ok, ok, test.sni)
+		require.Equal(t, test.appName, appName, test.sni)
+	}
+}
+
+// selfSignedCert returns a minimal self-signed certificate for commonName,
+// for standing in as an app backend's TLS identity in tests.
+func selfSignedCert(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestPeekClientHelloServerName(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	serverDone := make(chan struct {
+		sni     string
+		peeked  []byte
+		readErr error
+	}, 1)
+	go func() {
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		sni, peeked, err := peekClientHelloServerName(conn)
+		serverDone <- struct {
+			sni     string
+			peeked  []byte
+			readErr error
+		}{sni, peeked, err}
+	}()
+
+	clientConn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	// The client's real handshake will fail - the router's sniffing
+	// handshake above never sends a ServerHello back - but by then the
+	// ClientHello has already reached the server.
+	tlsClient := tls.Client(clientConn, &tls.Config{ServerName: "my-app.mcp.local", InsecureSkipVerify: true})
+	_ = tlsClient.Handshake()
+
+	result := <-serverDone
+	require.NoError(t, result.readErr)
+	require.Equal(t, "my-app.mcp.local", result.sni)
+	require.NotEmpty(t, result.peeked)
+}
+
+// TestRouterRoutesBySNI exercises a Router end to end: the original
+// client's real TLS handshake completes against an app backend chosen
+// purely from the peeked SNI, with the Router never terminating TLS
+// itself - it only splices raw bytes between the two.
+func TestRouterRoutesBySNI(t *testing.T) {
+	cert := selfSignedCert(t, "my-app.mcp.local")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	var dialedApp string
+	router, err := NewRouter(RouterConfig{
+		DialApp: func(ctx context.Context, appName string) (io.ReadWriteCloser, error) {
+			dialedApp = appName
+			routerSide, appSide := net.Pipe()
+			go func() {
+				tlsApp := tls.Server(appSide, &tls.Config{Certificates: []tls.Certificate{cert}})
+				buf := make([]byte, 4)
+				if _, err := io.ReadFull(tlsApp, buf); err != nil {
+					return
+				}
+				tlsApp.Write(buf) // echo what the client sent, to prove the handshake and splice both worked
+			}()
+			return routerSide, nil
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go router.Serve(ctx, ln)
+
+	rawConn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer rawConn.Close()
+
+	tlsClient := tls.Client(rawConn, &tls.Config{ServerName: "my-app.mcp.local", InsecureSkipVerify: true})
+	require.NoError(t, tlsClient.Handshake())
+
+	_, err = tlsClient.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(tlsClient, buf)
+	require.NoError(t, err)
+
+	require.Equal(t, "my-app", dialedApp)
+}
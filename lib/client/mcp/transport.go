@@ -0,0 +1,57 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mcp
+
+import (
+	"strings"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+// mcpUpstreamTransport identifies how the forward proxy should reach an
+// upstream MCP app server.
+type mcpUpstreamTransport string
+
+const (
+	// mcpUpstreamTransportStdio dials the app server for a ReadCloser/
+	// WriteCloser pipe via ProxyConfig.AppDialerFn, as teleport has always
+	// done.
+	mcpUpstreamTransportStdio mcpUpstreamTransport = "stdio"
+	// mcpUpstreamTransportStreamableHTTP speaks the MCP "Streamable HTTP"
+	// transport against app.GetURI().
+	mcpUpstreamTransportStreamableHTTP mcpUpstreamTransport = "streamable-http"
+	// mcpUpstreamTransportSSE speaks the older HTTP+SSE transport against
+	// app.GetURI().
+	mcpUpstreamTransportSSE mcpUpstreamTransport = "sse"
+)
+
+// detectUpstreamTransport inspects app's configuration to decide which
+// transport the forward proxy should use to reach the upstream MCP server.
+// Apps configured with an MCP command always use stdio; apps configured
+// with a URI instead use one of the two HTTP-based transports, chosen by
+// the conventional "/sse" suffix used by the older transport.
+func detectUpstreamTransport(app types.Application) mcpUpstreamTransport {
+	if app.GetMCPCommand() != "" {
+		return mcpUpstreamTransportStdio
+	}
+	if strings.HasSuffix(strings.TrimSuffix(app.GetURI(), "/"), "/sse") {
+		return mcpUpstreamTransportSSE
+	}
+	return mcpUpstreamTransportStreamableHTTP
+}
@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"sync"
+)
+
+// UpstreamStatus describes the current health of a single upstream MCP app
+// server, as tracked by the forward proxy.
+type UpstreamStatus string
+
+const (
+	// UpstreamStatusConnecting means the proxy is dialing and initializing
+	// the upstream for the first time; no tools have been advertised yet.
+	UpstreamStatusConnecting UpstreamStatus = "connecting"
+	// UpstreamStatusReady means the upstream initialized successfully and
+	// its tools are advertised to downstream clients.
+	UpstreamStatusReady UpstreamStatus = "ready"
+	// UpstreamStatusDegraded means the upstream was ready at some point but
+	// a subsequent operation (e.g. refreshing its tool list) failed; its
+	// last known-good tools remain advertised.
+	UpstreamStatusDegraded UpstreamStatus = "degraded"
+	// UpstreamStatusFailed means the upstream has no usable connection (it
+	// could not be initialized, or a health probe or call on it faulted)
+	// and the proxy is reconnecting with backoff. Tools advertised before
+	// the failure stay advertised.
+	UpstreamStatusFailed UpstreamStatus = "failed"
+)
+
+// UpstreamState is a point-in-time snapshot of a single upstream's health,
+// returned by Status().
+type UpstreamState struct {
+	// AppName is the Teleport application name the upstream is registered
+	// under.
+	AppName string
+	// Status is the upstream's current state.
+	Status UpstreamStatus
+	// ToolCount is the number of tools currently advertised on behalf of
+	// this upstream. It is stale (reflects the last healthy fetch) while
+	// Status is Degraded or Failed.
+	ToolCount int
+	// LastError is the error from the most recent failed operation, if any.
+	LastError error
+}
+
+// upstreamEntry tracks a single upstream's client and health state. Each
+// poolMember owns one, and it is mutated by that member's own
+// monitorPoolMember goroutine.
+type upstreamEntry struct {
+	mu        sync.Mutex
+	appName   string
+	client    *Upstream
+	status    UpstreamStatus
+	toolCount int
+	lastErr   error
+}
+
+func newUpstreamEntry(appName string) *upstreamEntry {
+	return &upstreamEntry{
+		appName: appName,
+		status:  UpstreamStatusConnecting,
+	}
+}
+
+func (e *upstreamEntry) state() UpstreamState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return UpstreamState{
+		AppName:   e.appName,
+		Status:    e.status,
+		ToolCount: e.toolCount,
+		LastError: e.lastErr,
+	}
+}
+
+func (e *upstreamEntry) setConnecting() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status = UpstreamStatusConnecting
+}
+
+// setReady records a successful (re)connect. client is nil-able so that a
+// successful tool refresh on an already-ready upstream can reuse this
+// without re-supplying the client.
+func (e *upstreamEntry) setReady(client *Upstream) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if client != nil {
+		e.client = client
+	}
+	e.status = UpstreamStatusReady
+	e.lastErr = nil
+}
+
+// setDegraded records that a previously-ready upstream failed a follow-up
+// operation (e.g. a tools/list refresh). Its last known tool count and
+// client are left untouched so in-flight calls keep working.
+func (e *upstreamEntry) setDegraded(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status = UpstreamStatusDegraded
+	e.lastErr = err
+}
+
+// setFailed records that the upstream has no usable client: a dial or
+// initialize attempt failed, a health probe failed, or a call on it hit a
+// connection fault.
+func (e *upstreamEntry) setFailed(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status = UpstreamStatusFailed
+	e.lastErr = err
+}
+
+func (e *upstreamEntry) setToolCount(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.toolCount = n
+}
+
+func (e *upstreamEntry) getClient() *Upstream {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.client
+}
+
+func (e *upstreamEntry) getStatus() UpstreamStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status
+}
@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamEntryStateTransitions(t *testing.T) {
+	entry := newUpstreamEntry("my-app")
+	require.Equal(t, UpstreamStatusConnecting, entry.state().Status)
+
+	entry.setReady(nil)
+	state := entry.state()
+	require.Equal(t, UpstreamStatusReady, state.Status)
+	require.NoError(t, state.LastError)
+
+	failure := errors.New("tools/list failed")
+	entry.setDegraded(failure)
+	state = entry.state()
+	require.Equal(t, UpstreamStatusDegraded, state.Status)
+	require.Equal(t, failure, state.LastError)
+
+	entry.setToolCount(3)
+	require.Equal(t, 3, entry.state().ToolCount)
+
+	reconnectErr := errors.New("connection refused")
+	entry.setFailed(reconnectErr)
+	state = entry.state()
+	require.Equal(t, UpstreamStatusFailed, state.Status)
+	require.Equal(t, reconnectErr, state.LastError)
+}
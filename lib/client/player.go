@@ -20,7 +20,6 @@ package client
 
 import (
 	"context"
-	"fmt"
 	"os"
 
 	"github.com/gravitational/trace"
@@ -60,35 +59,21 @@ func (p *playFromFileStreamer) StreamSessionEvents(
 				return
 			}
 
-			if printEvt, ok := evt.(*apievents.SessionPrint); ok {
-				fmt.Fprintf(
-					os.Stderr,
-					"i=%d: Decoded print event: ci=%d offset=%d delay=%d data=[%s]\n",
-					i, printEvt.ChunkIndex, printEvt.Offset, printEvt.DelayMilliseconds,
-					printEvt.Data,
-				)
-				if b1, b2 := int(printEvt.Bytes), len(printEvt.Data); b1 != b2 {
-					fmt.Fprintf(os.Stderr, "BYTES DIFFER! %d vs %d\n", b1, b2)
-				}
-			} else {
-				fmt.Fprintf(
-					os.Stderr,
-					"i=%d: Decoded event: type=%q, code=%q\n",
-					i, evt.GetType(), evt.GetCode(),
-				)
+			switch evt.(type) {
+			case *apievents.SessionPrint, *apievents.Resize:
+			default:
+				continue
 			}
 
-			if true {
+			if i < startIndex {
 				continue
 			}
 
-			if i >= startIndex {
-				select {
-				case evts <- evt:
-				case <-ctx.Done():
-					errs <- trace.Wrap(ctx.Err())
-					return
-				}
+			select {
+			case evts <- evt:
+			case <-ctx.Done():
+				errs <- trace.Wrap(ctx.Err())
+				return
 			}
 		}
 	}()
@@ -0,0 +1,125 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package b2sessions implements events.MultipartHandler against Backblaze
+// B2's native large-file API, as a sibling to filesessions.Handler (which
+// implements the same contract against the local filesystem) and to the
+// various S3-compatible-gateway backends, for operators who'd rather talk
+// to B2 directly than stand up a gateway in front of it.
+package b2sessions
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport"
+	"github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/session"
+)
+
+// sessionFilePrefix is the well-known B2 filename prefix every Teleport
+// session recording upload is created under. ListUploads filters on it so a
+// bucket shared with other B2 use isn't mistaken for abandoned Teleport
+// uploads.
+const sessionFilePrefix = "sessions/"
+
+// Config configures a B2-backed Handler.
+type Config struct {
+	// Bucket is the name of the B2 bucket session recordings are uploaded
+	// to. The configured ApplicationKey may be scoped to just this bucket.
+	Bucket string
+	// KeyID and ApplicationKey are a B2 application key pair. ApplicationKey
+	// may be a bucket-scoped key restricted to Bucket.
+	KeyID          string
+	ApplicationKey string
+	// APIURL overrides B2's account-authorization endpoint. Only ever set
+	// in tests; production deployments always start from defaultAPIURL.
+	APIURL string
+	// HTTPClient is the client used for all B2 API calls. Defaults to an
+	// http.Client with a conservative timeout.
+	HTTPClient *http.Client
+	// OnBeforeComplete, if set, is called with the completed upload just
+	// before its B2 large file is finished.
+	OnBeforeComplete func(ctx context.Context, upload events.StreamUpload) error
+}
+
+// CheckAndSetDefaults validates cfg and fills in defaults.
+func (cfg *Config) CheckAndSetDefaults() error {
+	if cfg.Bucket == "" {
+		return trace.BadParameter("Bucket is required")
+	}
+	if cfg.KeyID == "" {
+		return trace.BadParameter("KeyID is required")
+	}
+	if cfg.ApplicationKey == "" {
+		return trace.BadParameter("ApplicationKey is required")
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultAPIURL
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.OnBeforeComplete == nil {
+		cfg.OnBeforeComplete = func(context.Context, events.StreamUpload) error { return nil }
+	}
+	return nil
+}
+
+// Handler implements events.MultipartHandler against Backblaze B2. Each
+// Teleport upload ID is a B2 fileId: CreateUpload opens a B2 large file and
+// returns its fileId as the upload's ID, and every later call
+// (UploadPart/ListParts/CompleteUpload) addresses that same large file by
+// it. Account authorization is resolved lazily, on first API call, and
+// transparently refreshed whenever B2 responds 401.
+type Handler struct {
+	Config
+	logger *slog.Logger
+
+	authMu sync.Mutex
+	auth   *accountAuth
+}
+
+// NewHandler returns a Handler uploading session recordings to cfg.Bucket.
+func NewHandler(cfg Config) (*Handler, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Handler{
+		Config: cfg,
+		logger: slog.With(teleport.ComponentKey, "b2sessions"),
+	}, nil
+}
+
+// GetUploadMetadata returns metadata for a session's upload, synchronously
+// and without any B2 API call, matching filesessions.Handler's contract.
+func (h *Handler) GetUploadMetadata(s session.ID) events.UploadMetadata {
+	return events.UploadMetadata{
+		URL:       "b2://" + h.Bucket + "/" + sessionFileName(s),
+		SessionID: s,
+	}
+}
+
+func sessionFileName(s session.ID) string {
+	return sessionFilePrefix + string(s)
+}
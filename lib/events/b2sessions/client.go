@@ -0,0 +1,488 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package b2sessions
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/session"
+)
+
+// defaultAPIURL is where account authorization is always requested from;
+// b2_authorize_account's response then redirects every subsequent call to
+// the account's assigned API/upload hosts.
+const defaultAPIURL = "https://api.backblazeb2.com"
+
+// accountAuth is the cached result of a b2_authorize_account call.
+type accountAuth struct {
+	token    string
+	apiURL   string
+	bucketID string
+}
+
+// CreateUpload opens a new B2 large file and returns its fileId as the
+// upload's ID.
+func (h *Handler) CreateUpload(ctx context.Context, sessionID session.ID) (*events.StreamUpload, error) {
+	var resp startLargeFileResponse
+	if err := h.doJSON(ctx, "/b2api/v2/b2_start_large_file", startLargeFileRequest{
+		FileName:    sessionFileName(sessionID),
+		ContentType: "application/octet-stream",
+	}, &resp); err != nil {
+		return nil, trace.Wrap(err, "starting B2 large file for session %s", sessionID)
+	}
+
+	upload := events.StreamUpload{
+		SessionID: sessionID,
+		ID:        resp.FileID,
+		Initiated: time.Now(),
+	}
+	return &upload, trace.Wrap(upload.CheckAndSetDefaults())
+}
+
+// UploadPart uploads partBody as part partNumber of upload's B2 large file.
+// B2 requires every part to carry its SHA1 in the X-Bz-Content-Sha1 header
+// and rejects the part on a mismatch, so the hash is computed locally first.
+func (h *Handler) UploadPart(ctx context.Context, upload events.StreamUpload, partNumber int64, partBody io.ReadSeeker) (*events.StreamPart, error) {
+	size, err := partBody.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if _, err := partBody.Seek(0, io.SeekStart); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	hasher := sha1.New()
+	if _, err := io.Copy(hasher, partBody); err != nil {
+		return nil, trace.Wrap(err, "hashing part %d of upload %s", partNumber, upload.ID)
+	}
+	contentSHA1 := hex.EncodeToString(hasher.Sum(nil))
+	if _, err := partBody.Seek(0, io.SeekStart); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	var uploadURLResp getUploadPartURLResponse
+	if err := h.doJSON(ctx, "/b2api/v2/b2_get_upload_part_url", getUploadPartURLRequest{
+		FileID: upload.ID,
+	}, &uploadURLResp); err != nil {
+		return nil, trace.Wrap(err, "getting B2 upload-part URL for upload %s", upload.ID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURLResp.UploadURL, partBody)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req.Header.Set("Authorization", uploadURLResp.AuthorizationToken)
+	req.Header.Set("X-Bz-Part-Number", strconv.FormatInt(partNumber, 10))
+	req.Header.Set("X-Bz-Content-Sha1", contentSHA1)
+	req.ContentLength = size
+
+	var partResp uploadPartResponse
+	if err := h.do(ctx, req, &partResp); err != nil {
+		return nil, trace.Wrap(err, "uploading part %d of upload %s", partNumber, upload.ID)
+	}
+
+	return &events.StreamPart{
+		Number:       partNumber,
+		LastModified: time.Now(),
+	}, nil
+}
+
+// ReserveUploadPart is a no-op: B2 has no concept of reserving space for a
+// part ahead of uploading it, unlike the local-filesystem backend which
+// pre-truncates a reservation file to guard against running out of disk
+// mid-upload.
+func (h *Handler) ReserveUploadPart(ctx context.Context, upload events.StreamUpload, partNumber int64) error {
+	return nil
+}
+
+// ListParts lists the parts uploaded so far for upload's B2 large file,
+// ordered by part number.
+func (h *Handler) ListParts(ctx context.Context, upload events.StreamUpload) ([]events.StreamPart, error) {
+	b2Parts, err := h.listB2Parts(ctx, upload.ID)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	parts := make([]events.StreamPart, len(b2Parts))
+	for i, p := range b2Parts {
+		parts[i] = events.StreamPart{
+			Number:       p.PartNumber,
+			LastModified: time.Unix(0, p.UploadTimestamp*int64(time.Millisecond)),
+		}
+	}
+	return parts, nil
+}
+
+// listB2Parts pages through b2_list_parts in full, returning every part of
+// fileID ordered by part number.
+func (h *Handler) listB2Parts(ctx context.Context, fileID string) ([]b2Part, error) {
+	var all []b2Part
+	startPartNumber := int64(0)
+	for {
+		var resp listPartsResponse
+		if err := h.doJSON(ctx, "/b2api/v2/b2_list_parts", listPartsRequest{
+			FileID:       fileID,
+			StartPartNum: startPartNumber,
+			MaxPartCount: 1000,
+		}, &resp); err != nil {
+			return nil, trace.Wrap(err, "listing B2 parts for upload %s", fileID)
+		}
+		all = append(all, resp.Parts...)
+		if resp.NextPartNumber == 0 {
+			break
+		}
+		startPartNumber = resp.NextPartNumber
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].PartNumber < all[j].PartNumber })
+	return all, nil
+}
+
+// CompleteUpload finishes upload's B2 large file, assembling the ordered
+// SHA1 array b2_finish_large_file requires from b2_list_parts' per-part
+// hashes.
+func (h *Handler) CompleteUpload(ctx context.Context, upload events.StreamUpload, parts []events.StreamPart) error {
+	if err := h.Config.OnBeforeComplete(ctx, upload); err != nil {
+		return trace.Wrap(err)
+	}
+
+	b2Parts, err := h.listB2Parts(ctx, upload.ID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	sha1ByPart := make(map[int64]string, len(b2Parts))
+	for _, p := range b2Parts {
+		sha1ByPart[p.PartNumber] = p.ContentSHA1
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	partSHA1Array := make([]string, 0, len(parts))
+	for _, part := range parts {
+		sha1, ok := sha1ByPart[part.Number]
+		if !ok {
+			return trace.NotFound("B2 has no uploaded part %d for upload %s", part.Number, upload.ID)
+		}
+		partSHA1Array = append(partSHA1Array, sha1)
+	}
+
+	return trace.Wrap(h.doJSON(ctx, "/b2api/v2/b2_finish_large_file", finishLargeFileRequest{
+		FileID:        upload.ID,
+		PartSHA1Array: partSHA1Array,
+	}, nil), "finishing B2 large file for upload %s", upload.ID)
+}
+
+// ListUploads lists large files started under sessionFilePrefix that
+// haven't been finished (or canceled) yet, earliest first.
+func (h *Handler) ListUploads(ctx context.Context) ([]events.StreamUpload, error) {
+	var uploads []events.StreamUpload
+	startFileID := ""
+	for {
+		var resp listUnfinishedLargeFilesResponse
+		if err := h.doJSON(ctx, "/b2api/v2/b2_list_unfinished_large_files", listUnfinishedLargeFilesRequest{
+			NamePrefix:   sessionFilePrefix,
+			StartFileID:  startFileID,
+			MaxFileCount: 100,
+		}, &resp); err != nil {
+			return nil, trace.Wrap(err, "listing unfinished B2 large files")
+		}
+		for _, f := range resp.Files {
+			sessionID, ok := strings.CutPrefix(f.FileName, sessionFilePrefix)
+			if !ok {
+				continue
+			}
+			uploads = append(uploads, events.StreamUpload{
+				SessionID: session.ID(sessionID),
+				ID:        f.FileID,
+				Initiated: time.Unix(0, f.UploadTimestamp*int64(time.Millisecond)),
+			})
+		}
+		if resp.NextFileID == "" {
+			break
+		}
+		startFileID = resp.NextFileID
+	}
+
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Initiated.Before(uploads[j].Initiated) })
+	return uploads, nil
+}
+
+// authorize performs b2_authorize_account and resolves Bucket to its
+// bucketID via b2_list_buckets, caching both on h.auth.
+func (h *Handler) authorize(ctx context.Context) (*accountAuth, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.APIURL+"/b2api/v2/b2_authorize_account", nil)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	req.SetBasicAuth(h.KeyID, h.ApplicationKey)
+
+	var authResp authorizeAccountResponse
+	if err := h.do(ctx, req, &authResp); err != nil {
+		return nil, trace.Wrap(err, "authorizing B2 account")
+	}
+
+	auth := &accountAuth{
+		token:  authResp.AuthorizationToken,
+		apiURL: authResp.APIInfo.StorageAPI.APIURL,
+	}
+
+	listReq, err := json.Marshal(listBucketsRequest{
+		AccountID:  authResp.AccountID,
+		BucketName: h.Bucket,
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bucketsHTTPReq, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.apiURL+"/b2api/v2/b2_list_buckets", bytes.NewReader(listReq))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	bucketsHTTPReq.Header.Set("Authorization", auth.token)
+	bucketsHTTPReq.Header.Set("Content-Type", "application/json")
+
+	var bucketsResp listBucketsResponse
+	if err := h.do(ctx, bucketsHTTPReq, &bucketsResp); err != nil {
+		return nil, trace.Wrap(err, "resolving B2 bucket %q", h.Bucket)
+	}
+	if len(bucketsResp.Buckets) == 0 {
+		return nil, trace.NotFound("B2 bucket %q not found or not visible to this key", h.Bucket)
+	}
+	auth.bucketID = bucketsResp.Buckets[0].BucketID
+
+	return auth, nil
+}
+
+// currentAuth returns the cached account authorization, authorizing for the
+// first time if needed.
+func (h *Handler) currentAuth(ctx context.Context) (*accountAuth, error) {
+	h.authMu.Lock()
+	defer h.authMu.Unlock()
+	if h.auth != nil {
+		return h.auth, nil
+	}
+	auth, err := h.authorize(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	h.auth = auth
+	return auth, nil
+}
+
+// refreshAuth discards the cached account authorization and re-authorizes,
+// called after a 401 response.
+func (h *Handler) refreshAuth(ctx context.Context) (*accountAuth, error) {
+	h.authMu.Lock()
+	defer h.authMu.Unlock()
+	auth, err := h.authorize(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	h.auth = auth
+	return auth, nil
+}
+
+// doJSON issues a POST to path (resolved against the current API host) with
+// body marshaled as JSON, decoding the response into out (ignored if nil).
+func (h *Handler) doJSON(ctx context.Context, path string, body, out any) error {
+	auth, err := h.currentAuth(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.apiURL+path, bytes.NewReader(data))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Header.Set("Authorization", auth.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	return trace.Wrap(h.doWithReauth(ctx, req, func() ([]byte, error) { return data, nil }, out))
+}
+
+// do issues req as-is (used for requests, like part uploads, whose
+// Authorization header isn't the account token), decoding the response into
+// out. Unlike doJSON it never retries: a 401 is returned as an error, since
+// re-authorizing the account doesn't refresh an upload-part URL's own
+// token.
+func (h *Handler) do(ctx context.Context, req *http.Request, out any) error {
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return trace.Wrap(readB2Error(resp))
+	}
+	if out != nil {
+		return trace.Wrap(json.NewDecoder(resp.Body).Decode(out))
+	}
+	return nil
+}
+
+// doWithReauth is doJSON's retry loop: on a 401 it re-authorizes the
+// account once and retries the request with the refreshed token.
+func (h *Handler) doWithReauth(ctx context.Context, req *http.Request, rebody func() ([]byte, error), out any) error {
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+
+		auth, err := h.refreshAuth(ctx)
+		if err != nil {
+			return trace.Wrap(err, "refreshing B2 account authorization after 401")
+		}
+		data, err := rebody()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		retryReq, err := http.NewRequestWithContext(ctx, req.Method, strings.Replace(req.URL.String(), req.Host, "", 1), bytes.NewReader(data))
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		retryReq.URL = req.URL
+		retryReq.Header = req.Header.Clone()
+		retryReq.Header.Set("Authorization", auth.token)
+		resp, err = h.HTTPClient.Do(retryReq)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return trace.Wrap(readB2Error(resp))
+	}
+	if out != nil {
+		return trace.Wrap(json.NewDecoder(resp.Body).Decode(out))
+	}
+	return nil
+}
+
+func readB2Error(resp *http.Response) error {
+	var apiErr b2Error
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+		return trace.Errorf("B2 API error: HTTP %d", resp.StatusCode)
+	}
+	return trace.Errorf("B2 API error: HTTP %d: %s: %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+}
+
+type b2Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type authorizeAccountResponse struct {
+	AuthorizationToken string `json:"authorizationToken"`
+	AccountID          string `json:"accountId"`
+	APIInfo            struct {
+		StorageAPI struct {
+			APIURL string `json:"apiUrl"`
+		} `json:"storageApi"`
+	} `json:"apiInfo"`
+}
+
+type listBucketsRequest struct {
+	AccountID  string `json:"accountId"`
+	BucketName string `json:"bucketName"`
+}
+
+type listBucketsResponse struct {
+	Buckets []struct {
+		BucketID string `json:"bucketId"`
+	} `json:"buckets"`
+}
+
+type startLargeFileRequest struct {
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+}
+
+type startLargeFileResponse struct {
+	FileID string `json:"fileId"`
+}
+
+type getUploadPartURLRequest struct {
+	FileID string `json:"fileId"`
+}
+
+type getUploadPartURLResponse struct {
+	UploadURL          string `json:"uploadUrl"`
+	AuthorizationToken string `json:"authorizationToken"`
+}
+
+type uploadPartResponse struct {
+	PartNumber  int64  `json:"partNumber"`
+	ContentSHA1 string `json:"contentSha1"`
+}
+
+type listPartsRequest struct {
+	FileID       string `json:"fileId"`
+	StartPartNum int64  `json:"startPartNumber,omitempty"`
+	MaxPartCount int64  `json:"maxPartCount,omitempty"`
+}
+
+type listPartsResponse struct {
+	Parts          []b2Part `json:"parts"`
+	NextPartNumber int64    `json:"nextPartNumber"`
+}
+
+type b2Part struct {
+	PartNumber      int64  `json:"partNumber"`
+	ContentSHA1     string `json:"contentSha1"`
+	UploadTimestamp int64  `json:"uploadTimestamp"`
+}
+
+type finishLargeFileRequest struct {
+	FileID        string   `json:"fileId"`
+	PartSHA1Array []string `json:"partSha1Array"`
+}
+
+type listUnfinishedLargeFilesRequest struct {
+	NamePrefix   string `json:"namePrefix,omitempty"`
+	StartFileID  string `json:"startFileId,omitempty"`
+	MaxFileCount int64  `json:"maxFileCount,omitempty"`
+}
+
+type listUnfinishedLargeFilesResponse struct {
+	Files []struct {
+		FileID          string `json:"fileId"`
+		FileName        string `json:"fileName"`
+		UploadTimestamp int64  `json:"uploadTimestamp"`
+	} `json:"files"`
+	NextFileID string `json:"nextFileId"`
+}
@@ -0,0 +1,219 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filesessions
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"filippo.io/age"
+	"github.com/gravitational/trace"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// dekSize is the size, in bytes, of the random per-session data encryption
+// key (DEK) used to encrypt a session recording's body.
+const dekSize = chacha20poly1305.KeySize
+
+// streamChunkSize is the plaintext chunk size used when encrypting a
+// session body, matching age's own STREAM chunk size.
+const streamChunkSize = 64 * 1024
+
+// streamChunkOverhead is the per-chunk AEAD tag overhead.
+const streamChunkOverhead = chacha20poly1305.Overhead
+
+// generateDEK returns a fresh random per-session data encryption key.
+func generateDEK() ([]byte, error) {
+	dek := make([]byte, dekSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return dek, nil
+}
+
+// wrapDEK encrypts dek to recipients using age, so it (and thus the
+// session body it protects) can only be recovered by the holder of a
+// matching identity.
+func wrapDEK(dek []byte, recipients []age.Recipient) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if _, err := w.Write(dek); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// unwrapDEK recovers the DEK wrapped by wrapDEK, given any one of the
+// identities it was wrapped to.
+func unwrapDEK(wrapped []byte, identities ...age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(wrapped), identities...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	dek, err := io.ReadAll(r)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(dek) != dekSize {
+		return nil, trace.BadParameter("unexpected data key length: %d", len(dek))
+	}
+	return dek, nil
+}
+
+// streamNonce builds the 12-byte per-chunk nonce, setting the final byte
+// when final is true, in the spirit of age's STREAM chunking: an
+// incrementing counter plus a last-chunk flag, so truncating, reordering,
+// or splicing chunks is detected at decryption time.
+func streamNonce(chunk uint64, final bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], chunk)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// streamEncryptor chunks and AEAD-encrypts a session body under a DEK,
+// writing one ciphertext chunk (plus its streamChunkOverhead tag) per
+// streamChunkSize-or-smaller run of plaintext as it accumulates. Close must be called to flush and emit the final chunk.
+type streamEncryptor struct {
+	aead  cipher.AEAD
+	dst   io.Writer
+	buf   []byte
+	chunk uint64
+	err   error
+}
+
+func newStreamEncryptor(dst io.Writer, dek []byte) (*streamEncryptor, error) {
+	aead, err := chacha20poly1305.New(dek)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &streamEncryptor{aead: aead, dst: dst}, nil
+}
+
+func (e *streamEncryptor) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n := len(p)
+	e.buf = append(e.buf, p...)
+	// Keep the last chunk buffered (even if it's a full chunk) until
+	// Close, since we don't know it's final until then.
+	for len(e.buf) > streamChunkSize {
+		if err := e.writeChunk(e.buf[:streamChunkSize], false); err != nil {
+			e.err = err
+			return 0, err
+		}
+		e.buf = e.buf[streamChunkSize:]
+	}
+	return n, nil
+}
+
+func (e *streamEncryptor) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.writeChunk(e.buf, true)
+}
+
+func (e *streamEncryptor) writeChunk(plaintext []byte, final bool) error {
+	nonce := streamNonce(e.chunk, final)
+	ciphertext := e.aead.Seal(nil, nonce, plaintext, nil)
+	if _, err := e.dst.Write(ciphertext); err != nil {
+		return trace.Wrap(err)
+	}
+	e.chunk++
+	return nil
+}
+
+// streamDecryptor reverses streamEncryptor: it reads and authenticates one
+// ciphertext chunk at a time, using look-ahead on src to tell whether the
+// chunk just read was the final one.
+type streamDecryptor struct {
+	aead  cipher.AEAD
+	src   *bufio.Reader
+	chunk uint64
+	buf   []byte
+	done  bool
+}
+
+func newStreamDecryptor(src io.Reader, dek []byte) (*streamDecryptor, error) {
+	aead, err := chacha20poly1305.New(dek)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &streamDecryptor{aead: aead, src: bufio.NewReader(src)}, nil
+}
+
+func (d *streamDecryptor) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+
+		ciphertext := make([]byte, streamChunkSize+streamChunkOverhead)
+		n, err := io.ReadFull(d.src, ciphertext)
+		switch {
+		case err == nil:
+			// A full-size chunk was read; peek ahead to see whether more
+			// data follows, which is the only way to know if this chunk
+			// was the final one.
+			_, peekErr := d.src.Peek(1)
+			final := peekErr != nil
+			plaintext, decErr := d.aead.Open(nil, streamNonce(d.chunk, final), ciphertext[:n], nil)
+			if decErr != nil {
+				return 0, trace.Wrap(decErr, "decrypting chunk %d", d.chunk)
+			}
+			d.buf, d.done = plaintext, final
+
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			plaintext, decErr := d.aead.Open(nil, streamNonce(d.chunk, true), ciphertext[:n], nil)
+			if decErr != nil {
+				return 0, trace.Wrap(decErr, "decrypting final chunk %d", d.chunk)
+			}
+			d.buf, d.done = plaintext, true
+
+		case errors.Is(err, io.EOF):
+			// Reached cleanly between chunks; only valid once we've
+			// already emitted the final chunk.
+			if !d.done {
+				return 0, trace.Wrap(io.ErrUnexpectedEOF, "truncated ciphertext stream")
+			}
+			return 0, io.EOF
+
+		default:
+			return 0, trace.Wrap(err)
+		}
+		d.chunk++
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
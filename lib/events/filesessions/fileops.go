@@ -17,14 +17,21 @@
 package filesessions
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
 	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"filippo.io/age"
 	"github.com/gravitational/trace"
 
+	"github.com/gravitational/teleport"
 	"github.com/gravitational/teleport/lib/utils"
 )
 
@@ -33,12 +40,57 @@ const (
 	combinedFilePerm    = reservationFilePerm
 )
 
+const (
+	// ageChunkSize is age/STREAM's fixed plaintext chunk size.
+	ageChunkSize = 64 * 1024
+	// ageChunkOverhead is the per-chunk ChaCha20-Poly1305 tag age/STREAM
+	// appends to every chunk, including a final, possibly empty, chunk.
+	ageChunkOverhead = 16
+	// ageHeaderOverheadEstimate is a generous upper bound on an age file's
+	// header size (recipient stanzas plus the header MAC). A native X25519
+	// stanza is under 100 bytes, but plugin-backed recipients (YubiKey,
+	// Secure Enclave, TPM, KMS) can be larger, and a reservation is
+	// encrypted to the reservation recipient plus every configured
+	// Recipients entry, so this headroom comfortably covers a handful of
+	// mixed recipients without needing to size it exactly.
+	ageHeaderOverheadEstimate = 4096
+
+	// reservationIdentityFileName is where encryptedFileOps persists the
+	// node-local reservation identity, relative to ReservationIdentityDir.
+	reservationIdentityFileName = "reservation_identity.age-key"
+)
+
+// ageEncryptedSize estimates the on-disk size of an age-encrypted stream
+// given its plaintext size, so CreateReservation can pre-truncate reservation
+// files large enough to hold their eventual ciphertext.
+func ageEncryptedSize(plaintextSize int64) int64 {
+	numChunks := plaintextSize / ageChunkSize
+	if plaintextSize%ageChunkSize != 0 || plaintextSize == 0 {
+		// Round up to a final, possibly short or empty, chunk: age/STREAM
+		// always emits at least one chunk, even for zero-length input.
+		numChunks++
+	}
+	return plaintextSize + numChunks*ageChunkOverhead + ageHeaderOverheadEstimate
+}
+
 // FileOps captures file operations done by filesessions, allowing both
 // plaintext and encrypted implementations to co-exist.
 type FileOps interface {
 	CreateReservation(name string, size int64) error
 	WriteReservation(name string, data io.Reader) error
-	CombineParts(dst io.Writer, parts []string) error
+	CombineParts(dst io.Writer, parts []PartRef) error
+}
+
+// PartRef identifies a single on-disk part CombineParts reads, plus the
+// SHA-256 UploadPart recorded for its plaintext content when it was
+// written. CombineParts re-hashes the part's plaintext (decrypting first,
+// for an encrypted FileOps) as it copies it to dst and fails with
+// trace.CompareFailed on a mismatch, rather than silently assembling a
+// corrupt session recording. A zero-length SHA256 skips verification for
+// that part, which lets parts uploaded before this existed keep combining.
+type PartRef struct {
+	Path   string
+	SHA256 []byte
 }
 
 type plainFileOps struct {
@@ -99,7 +151,7 @@ func (p *plainFileOps) WriteReservation(name string, data io.Reader) (err error)
 	return trace.Wrap(f.Close())
 }
 
-func (p *plainFileOps) CombineParts(dst io.Writer, parts []string) (err error) {
+func (p *plainFileOps) CombineParts(dst io.Writer, parts []PartRef) (err error) {
 	if err := combineParts(dst, parts, p.OpenFile, p.Logger); err != nil {
 		return trace.ConvertSystemError(err)
 	}
@@ -107,31 +159,98 @@ func (p *plainFileOps) CombineParts(dst io.Writer, parts []string) (err error) {
 }
 
 type encryptedFileOps struct {
-	Logger     *slog.Logger
-	OpenFile   utils.OpenFileWithFlagsFunc
+	Logger   *slog.Logger
+	OpenFile utils.OpenFileWithFlagsFunc
+	// Recipients is the parsed form of a RecipientsConfig; see
+	// ParseRecipients.
 	Recipients []age.Recipient
+	// ReservationIdentityDir is the local directory the reservation
+	// identity (see reservationIdentity) is persisted under. It must be
+	// scoped to this node's upload directory: the key never leaves disk
+	// and is never a Recipients entry, so it grants no one other than this
+	// node the ability to read a reservation before CombineParts runs.
+	ReservationIdentityDir string
+
+	reservationIdentityOnce   sync.Once
+	reservationIdentityCached *age.X25519Identity
+	reservationIdentityErr    error
 }
 
 var _ FileOps = &encryptedFileOps{}
 
 func (e *encryptedFileOps) CreateReservation(name string, size int64) error {
-	return e.plaintext().CreateReservation(name, size)
+	return e.plaintext().CreateReservation(name, ageEncryptedSize(size))
 }
 
-func (e *encryptedFileOps) WriteReservation(name string, data io.Reader) error {
-	// TODO(codingllama): Encrypt reservations with an additional reservation recipient,
-	//  then decrypt to combine?
-	return e.plaintext().WriteReservation(name, data)
+// WriteReservation encrypts data to both the node-local reservation
+// identity's recipient and e.Recipients before writing it to name. Including
+// e.Recipients isn't strictly required for CombineParts (which only ever
+// needs the reservation identity to open a part), but it means a part
+// stranded by a crash mid-upload is still recoverable by the operator's own
+// Recipients, not just by this node.
+func (e *encryptedFileOps) WriteReservation(name string, data io.Reader) (err error) {
+	defer func() {
+		if err != nil {
+			err = trace.ConvertSystemError(err)
+		}
+	}()
+
+	identity, err := e.reservationIdentity()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	recipients := append([]age.Recipient{identity.Recipient()}, e.Recipients...)
+
+	f, err := e.OpenFile(name, os.O_WRONLY|os.O_CREATE, reservationFilePerm)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	encWriter, err := age.Encrypt(f, recipients...)
+	if err != nil {
+		loggingClose(f, e.Logger, "Failed to close file (age.Encrypt error flow)", "name", name)
+		return trace.Wrap(err)
+	}
+
+	if _, err := io.Copy(encWriter, data); err != nil {
+		loggingClose(f, e.Logger, "Failed to close file (io.Copy error flow)", "name", name)
+		return trace.Wrap(err)
+	}
+	if err := encWriter.Close(); err != nil {
+		loggingClose(f, e.Logger, "Failed to close file (age writer Close error flow)", "name", name)
+		return trace.Wrap(err)
+	}
+
+	// Reservations are pre-truncated to a generous upper bound by
+	// CreateReservation; now that the real ciphertext size is known,
+	// shrink to it. f's current offset is the true ciphertext size since
+	// encWriter wrote directly into f.
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		loggingClose(f, e.Logger, "Failed to close file (Seek error flow)", "name", name)
+		return trace.Wrap(err)
+	}
+	if err := f.Truncate(offset); err != nil {
+		loggingClose(f, e.Logger, "Failed to close file (Truncate error flow)", "name", name)
+		return trace.Wrap(err)
+	}
+
+	return trace.Wrap(f.Close())
 }
 
-func (e *encryptedFileOps) CombineParts(dst io.Writer, parts []string) (err error) {
+func (e *encryptedFileOps) CombineParts(dst io.Writer, parts []PartRef) (err error) {
+	identity, err := e.reservationIdentity()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	encWriter, err := age.Encrypt(dst, e.Recipients...)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 	// No need to "defer encWriter.Close()" on failures.
 
-	if err := combineParts(encWriter, parts, e.OpenFile, e.Logger); err != nil {
+	if err := combineDecryptedParts(encWriter, parts, identity, e.OpenFile, e.Logger); err != nil {
 		return trace.ConvertSystemError(err)
 	}
 
@@ -139,6 +258,17 @@ func (e *encryptedFileOps) CombineParts(dst io.Writer, parts []string) (err erro
 	return trace.Wrap(encWriter.Close())
 }
 
+// reservationIdentity returns this node's persistent reservation identity,
+// generating and persisting one under ReservationIdentityDir on first use.
+func (e *encryptedFileOps) reservationIdentity() (*age.X25519Identity, error) {
+	e.reservationIdentityOnce.Do(func() {
+		e.reservationIdentityCached, e.reservationIdentityErr = loadOrCreateReservationIdentity(
+			e.ReservationIdentityDir, e.OpenFile,
+		)
+	})
+	return e.reservationIdentityCached, e.reservationIdentityErr
+}
+
 func (e *encryptedFileOps) plaintext() *plainFileOps {
 	return &plainFileOps{
 		Logger:   e.Logger,
@@ -152,17 +282,35 @@ func (e *encryptedFileOps) plaintext() *plainFileOps {
 // It does not wraps errors with trace.ConvertSystemError.
 //
 // Do not use this directly, use a [FileOps] implementation instead.
-func combineParts(dst io.Writer, parts []string, openFile utils.OpenFileWithFlagsFunc, logger *slog.Logger) (err error) {
+func combineParts(dst io.Writer, parts []PartRef, openFile utils.OpenFileWithFlagsFunc, logger *slog.Logger) (err error) {
 	for _, part := range parts {
-		partFile, err := openFile(part, os.O_RDONLY, 0 /* perm */)
+		partFile, err := openFile(part.Path, os.O_RDONLY, 0 /* perm */)
 		if err != nil {
 			return trace.Wrap(err)
 		}
-		if _, err := io.Copy(dst, partFile); err != nil {
-			loggingClose(partFile, logger, "Failed to close part (io.Copy error flow)", "name", part)
+		if err := copyAndVerify(dst, partFile, part); err != nil {
+			loggingClose(partFile, logger, "Failed to close part (io.Copy error flow)", "name", part.Path)
 			return trace.Wrap(err)
 		}
-		loggingClose(partFile, logger, "Failed to close part", "name", part)
+		loggingClose(partFile, logger, "Failed to close part", "name", part.Path)
+	}
+	return nil
+}
+
+// copyAndVerify copies src to dst, and, if part.SHA256 is set, fails with
+// trace.CompareFailed if the copied bytes don't hash to it.
+func copyAndVerify(dst io.Writer, src io.Reader, part PartRef) error {
+	if len(part.SHA256) == 0 {
+		_, err := io.Copy(dst, src)
+		return trace.Wrap(err)
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(dst, io.TeeReader(src, hasher)); err != nil {
+		return trace.Wrap(err)
+	}
+	if sum := hasher.Sum(nil); !bytes.Equal(sum, part.SHA256) {
+		return trace.CompareFailed("checksum mismatch for upload part %v: expected %x, got %x", part.Path, part.SHA256, sum)
 	}
 	return nil
 }
@@ -175,3 +323,78 @@ func loggingClose(closer io.Closer, logger *slog.Logger, msg string, args ...any
 		)
 	}
 }
+
+// combineDecryptedParts is combineParts for encryptedFileOps: each part is
+// opened with age.Decrypt using identity before being copied to dst, so dst
+// sees the plaintext stream rather than each part's own ciphertext.
+//
+// It does not wrap errors with trace.ConvertSystemError.
+func combineDecryptedParts(dst io.Writer, parts []PartRef, identity age.Identity, openFile utils.OpenFileWithFlagsFunc, logger *slog.Logger) (err error) {
+	for _, part := range parts {
+		partFile, err := openFile(part.Path, os.O_RDONLY, 0 /* perm */)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+
+		decReader, err := age.Decrypt(partFile, identity)
+		if err != nil {
+			loggingClose(partFile, logger, "Failed to close part (age.Decrypt error flow)", "name", part.Path)
+			return trace.Wrap(err)
+		}
+		if err := copyAndVerify(dst, decReader, part); err != nil {
+			loggingClose(partFile, logger, "Failed to close part (io.Copy error flow)", "name", part.Path)
+			return trace.Wrap(err)
+		}
+		loggingClose(partFile, logger, "Failed to close part", "name", part.Path)
+	}
+	return nil
+}
+
+// loadOrCreateReservationIdentity loads the reservation identity persisted
+// at <dir>/reservationIdentityFileName, generating and persisting a new one
+// on first use. The identity never leaves dir: it is not a Recipients entry
+// and has no purpose beyond letting this node open its own in-flight
+// reservations, including ones stranded by a crash mid-upload across
+// restarts.
+func loadOrCreateReservationIdentity(dir string, openFile utils.OpenFileWithFlagsFunc) (*age.X25519Identity, error) {
+	path := filepath.Join(dir, reservationIdentityFileName)
+
+	if data, err := os.ReadFile(path); err == nil {
+		identity, err := age.ParseX25519Identity(strings.TrimSpace(string(data)))
+		return identity, trace.Wrap(err, "parsing reservation identity %v", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, trace.ConvertSystemError(err)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, trace.Wrap(err, "generating reservation identity")
+	}
+
+	if err := os.MkdirAll(dir, teleport.PrivateDirMode); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+
+	// Create exclusively: if another process races us to generate its own
+	// identity first, lose the race gracefully and read back whatever it
+	// wrote, rather than persisting two different identities for the same
+	// directory.
+	f, err := openFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, trace.ConvertSystemError(err)
+		}
+		identity, err := age.ParseX25519Identity(strings.TrimSpace(string(data)))
+		return identity, trace.Wrap(err, "parsing reservation identity %v", path)
+	}
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(identity.String() + "\n"); err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	return identity, nil
+}
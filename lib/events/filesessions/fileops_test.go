@@ -0,0 +1,148 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filesessions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestAgeEncryptedSize_upperBound(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	for _, size := range []int64{0, 1, 100, ageChunkSize, ageChunkSize + 1, ageChunkSize*3 + 42} {
+		var ciphertext bytes.Buffer
+		w, err := age.Encrypt(&ciphertext, identity.Recipient())
+		require.NoError(t, err)
+		_, err = io.CopyN(w, zeroReader{}, size)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		require.LessOrEqualf(t, int64(ciphertext.Len()), ageEncryptedSize(size),
+			"ageEncryptedSize(%d) underestimated the real ciphertext size", size)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestEncryptedFileOps_reservationRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	fileOps := &encryptedFileOps{
+		Logger:                 discardLogger(),
+		OpenFile:               os.OpenFile,
+		Recipients:             []age.Recipient{identity.Recipient()},
+		ReservationIdentityDir: filepath.Join(dir, "keys"),
+	}
+
+	const part1, part2 = "hello, ", "session recording"
+	part1Path := filepath.Join(dir, "1.reservation")
+	part2Path := filepath.Join(dir, "2.reservation")
+
+	require.NoError(t, fileOps.CreateReservation(part1Path, int64(len(part1))))
+	require.NoError(t, fileOps.WriteReservation(part1Path, bytes.NewBufferString(part1)))
+	require.NoError(t, fileOps.CreateReservation(part2Path, int64(len(part2))))
+	require.NoError(t, fileOps.WriteReservation(part2Path, bytes.NewBufferString(part2)))
+
+	// Parts on disk must not contain the plaintext; they're only readable
+	// via the node-local reservation identity.
+	rawPart1, err := os.ReadFile(part1Path)
+	require.NoError(t, err)
+	require.NotContains(t, string(rawPart1), part1)
+
+	var combined bytes.Buffer
+	require.NoError(t, fileOps.CombineParts(&combined, []PartRef{{Path: part1Path}, {Path: part2Path}}))
+
+	r, err := age.Decrypt(&combined, identity)
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, part1+part2, string(got))
+}
+
+func TestEncryptedFileOps_reservationIdentityPersists(t *testing.T) {
+	t.Parallel()
+
+	keyDir := filepath.Join(t.TempDir(), "keys")
+
+	first := &encryptedFileOps{Logger: discardLogger(), OpenFile: os.OpenFile, ReservationIdentityDir: keyDir}
+	id1, err := first.reservationIdentity()
+	require.NoError(t, err)
+
+	second := &encryptedFileOps{Logger: discardLogger(), OpenFile: os.OpenFile, ReservationIdentityDir: keyDir}
+	id2, err := second.reservationIdentity()
+	require.NoError(t, err)
+
+	require.Equal(t, id1.String(), id2.String(),
+		"a second encryptedFileOps pointed at the same directory must reuse the persisted identity, not generate a new one")
+}
+
+func TestPlainFileOps_combinePartsVerifiesChecksum(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fileOps := &plainFileOps{Logger: discardLogger(), OpenFile: os.OpenFile}
+
+	const part1, part2 = "hello, ", "session recording"
+	part1Path := filepath.Join(dir, "1.part")
+	part2Path := filepath.Join(dir, "2.part")
+	require.NoError(t, os.WriteFile(part1Path, []byte(part1), 0o600))
+	require.NoError(t, os.WriteFile(part2Path, []byte(part2), 0o600))
+
+	part1Sum := sha256.Sum256([]byte(part1))
+	part2Sum := sha256.Sum256([]byte(part2))
+
+	var combined bytes.Buffer
+	require.NoError(t, fileOps.CombineParts(&combined, []PartRef{
+		{Path: part1Path, SHA256: part1Sum[:]},
+		{Path: part2Path, SHA256: part2Sum[:]},
+	}))
+	require.Equal(t, part1+part2, combined.String())
+
+	combined.Reset()
+	err := fileOps.CombineParts(&combined, []PartRef{
+		{Path: part1Path, SHA256: part2Sum[:]},
+		{Path: part2Path, SHA256: part2Sum[:]},
+	})
+	require.Error(t, err)
+	require.True(t, trace.IsCompareFailed(err), "expected a CompareFailed error, got %T: %v", err, err)
+}
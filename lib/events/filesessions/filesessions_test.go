@@ -76,6 +76,27 @@ func TestNonModifyingUpload_plaintext(t *testing.T) {
 			Decrypter:  decrypter,
 		})
 	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		t.Parallel()
+
+		const passphrase = "correct-horse-battery-staple"
+
+		recipients, err := ParseRecipients(RecipientsConfig{
+			Passphrase:       passphrase,
+			ScryptWorkFactor: 10, // low work factor, this is a test
+		})
+		require.NoError(t, err, "ParseRecipients()")
+
+		identity, err := age.NewScryptIdentity(passphrase)
+		require.NoError(t, err, "age.NewScryptIdentity()")
+
+		decrypter := func(src io.Reader) (io.Reader, error) { return age.Decrypt(src, identity) }
+		runTestNonModifyingUpload(t, testNonModifyingUploadOpts{
+			Recipients: recipients,
+			Decrypter:  decrypter,
+		})
+	})
 }
 
 type testNonModifyingUploadOpts struct {
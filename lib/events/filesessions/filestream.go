@@ -20,6 +20,8 @@ package filesessions
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -109,14 +111,20 @@ func (h *Handler) UploadPart(ctx context.Context, upload events.StreamUpload, pa
 		return nil, trace.Wrap(err)
 	}
 
+	// Hash the part as it's written rather than re-reading it afterwards, so
+	// CompleteUpload can later detect a partial write, bit rot, or a bad
+	// fileOps implementation instead of silently assembling a corrupt
+	// session recording.
+	hasher := sha256.New()
 	reservationPath := h.reservationPath(upload, partNumber)
-	if err := h.fileOps.WriteReservation(reservationPath, partBody); err != nil {
+	if err := h.fileOps.WriteReservation(reservationPath, io.TeeReader(partBody, hasher)); err != nil {
 		// TODO(codingllama): Move Remove into fileOps?
 		if rmErr := os.Remove(reservationPath); rmErr != nil {
 			h.logger.WarnContext(ctx, "Failed to remove part file", "file", reservationPath, "error", rmErr)
 		}
 		return nil, trace.Wrap(err)
 	}
+	sha256Sum := hasher.Sum(nil)
 
 	// Rename reservation to part file.
 	partPath := h.partPath(upload, partNumber)
@@ -124,12 +132,16 @@ func (h *Handler) UploadPart(ctx context.Context, upload events.StreamUpload, pa
 		return nil, trace.ConvertSystemError(err)
 	}
 
+	if err := h.writePartChecksum(partPath, sha256Sum); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
 	var lastModified time.Time
 	fi, err := os.Stat(partPath)
 	if err == nil {
 		lastModified = fi.ModTime()
 	}
-	return &events.StreamPart{Number: partNumber, LastModified: lastModified}, nil
+	return &events.StreamPart{Number: partNumber, LastModified: lastModified, SHA256: sha256Sum}, nil
 }
 
 // CompleteUpload completes the upload
@@ -196,13 +208,19 @@ Loop:
 	sort.Slice(parts, func(i, j int) bool {
 		return parts[i].Number < parts[j].Number
 	})
-	partNames := make([]string, len(parts))
+	partRefs := make([]PartRef, len(parts))
 	for i, part := range parts {
-		partNames[i] = h.partPath(upload, part.Number)
+		partRefs[i] = PartRef{Path: h.partPath(upload, part.Number), SHA256: part.SHA256}
 	}
 
-	// Combine parts into f.
-	if err := h.fileOps.CombineParts(f, partNames); err != nil {
+	// Combine parts into f, re-verifying each part's checksum as it's
+	// combined.
+	if err := h.fileOps.CombineParts(f, partRefs); err != nil {
+		// Don't leave a truncated or corrupt session recording behind for a
+		// checksum mismatch or a combine error partway through.
+		if rmErr := os.Remove(uploadPath); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+			h.logger.ErrorContext(ctx, "Failed to remove incomplete upload output", "file", uploadPath, "error", rmErr)
+		}
 		return trace.Wrap(err)
 	}
 
@@ -241,9 +259,17 @@ func (h *Handler) ListParts(ctx context.Context, upload events.StreamUpload) ([]
 
 			return nil
 		}
+		sha256Sum, err := readPartChecksum(path)
+		if err != nil {
+			// Older parts uploaded before checksums existed have no sidecar
+			// file; readPartChecksum only returns an error for a corrupt or
+			// unreadable one, which shouldn't silently skip the part.
+			h.logger.WarnContext(ctx, "Failed to read part checksum", "file", path, "error", err)
+		}
 		parts = append(parts, events.StreamPart{
 			Number:       part,
 			LastModified: info.ModTime(),
+			SHA256:       sha256Sum,
 		})
 		return nil
 	})
@@ -327,6 +353,64 @@ func (h *Handler) GetUploadMetadata(s session.ID) events.UploadMetadata {
 	}
 }
 
+// VerifyUpload walks upload's parts and reports whether each part's current
+// on-disk content (decrypted first, for an encrypted FileOps) still matches
+// the SHA-256 UploadPart recorded for it, without combining or completing
+// the upload. UploadCompleter does not call it; it finalizes a stale upload
+// through CompleteUpload, which runs the same check while combining parts
+// and fails on a mismatch without quarantining the upload.
+func (h *Handler) VerifyUpload(ctx context.Context, upload events.StreamUpload) error {
+	parts, err := h.ListParts(ctx, upload)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	partRefs := make([]PartRef, len(parts))
+	for i, part := range parts {
+		partRefs[i] = PartRef{Path: h.partPath(upload, part.Number), SHA256: part.SHA256}
+	}
+
+	return trace.Wrap(h.fileOps.CombineParts(io.Discard, partRefs))
+}
+
+// writePartChecksum persists sum next to partPath as "<n>.part.sha256", for
+// ListParts and VerifyUpload to read back later.
+func (h *Handler) writePartChecksum(partPath string, sum []byte) (err error) {
+	f, err := h.openFile(partChecksumPath(partPath), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = trace.ConvertSystemError(closeErr)
+		}
+	}()
+
+	_, err = f.WriteString(hex.EncodeToString(sum))
+	return trace.ConvertSystemError(err)
+}
+
+// readPartChecksum reads back the checksum writePartChecksum persisted for
+// partPath. Returns (nil, nil) if no sidecar file exists, e.g. for a part
+// uploaded before checksums existed.
+func readPartChecksum(partPath string) ([]byte, error) {
+	data, err := os.ReadFile(partChecksumPath(partPath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, trace.ConvertSystemError(err)
+	}
+	sum, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	return sum, trace.Wrap(err, "corrupt checksum file %v", partChecksumPath(partPath))
+}
+
+// partChecksumPath returns where writePartChecksum/readPartChecksum store
+// partPath's checksum, e.g. "<n>.part" -> "<n>.part.sha256".
+func partChecksumPath(partPath string) string {
+	return partPath + checksumExt
+}
+
 // ReserveUploadPart reserves an upload part.
 func (h *Handler) ReserveUploadPart(ctx context.Context, upload events.StreamUpload, partNumber int64) error {
 	reservationPath := h.reservationPath(upload, partNumber)
@@ -419,4 +503,7 @@ const (
 	errorExt = ".error"
 	// reservationExt is part reservation extension.
 	reservationExt = ".reservation"
+	// checksumExt is the extension for a part's SHA-256 checksum sidecar
+	// file, e.g. "3.part.sha256" for part 3's checksum.
+	checksumExt = ".sha256"
 )
@@ -0,0 +1,148 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filesessions
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"filippo.io/age"
+	"github.com/gravitational/trace"
+)
+
+// headerLengthSize is the byte width of the length prefix written in front
+// of a wrapped-key header, so RotateRecipients can read and replace the
+// header on its own, without ever reading the (potentially huge) session
+// body that follows it.
+const headerLengthSize = 4
+
+// KeyManager wraps and unwraps the per-session data key (DEK) used to
+// envelope-encrypt a session recording, so that rotating which recipients
+// can read a recording only rewrites the small wrapped-key header rather
+// than re-encrypting the recording body.
+//
+// The header produced by EncryptSession can either be prepended directly
+// to the session body (a single self-contained file) or kept alongside it
+// as a "<sessionid>.keys" sidecar; KeyManager itself is agnostic to which
+// layout the caller chooses, since header and body are handled separately.
+type KeyManager struct{}
+
+// NewKeyManager returns a ready-to-use KeyManager.
+func NewKeyManager() *KeyManager {
+	return &KeyManager{}
+}
+
+// EncryptSession generates a fresh DEK, writes its wrapped (age-encrypted)
+// form to header, and returns a WriteCloser that encrypts the session body
+// under that DEK to body. Close must be called on the returned WriteCloser
+// to flush the final chunk.
+func (m *KeyManager) EncryptSession(header io.Writer, body io.Writer, recipients []age.Recipient) (io.WriteCloser, error) {
+	dek, err := generateDEK()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	wrapped, err := wrapDEK(dek, recipients)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if err := writeHeader(header, wrapped); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	enc, err := newStreamEncryptor(body, dek)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return enc, nil
+}
+
+// DecryptSession reads a wrapped-key header, recovers the DEK using
+// identities, and returns a Reader that decrypts body under it.
+func (m *KeyManager) DecryptSession(header io.Reader, body io.Reader, identities ...age.Identity) (io.Reader, error) {
+	wrapped, err := readHeader(header)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	dek, err := unwrapDEK(wrapped, identities...)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	dec, err := newStreamDecryptor(body, dek)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return dec, nil
+}
+
+// RotateRecipients reads the wrapped-key header in oldHeader, recovers the
+// DEK using identity (which must correspond to one of the recipients the
+// header was originally wrapped to), and returns a new wrapped-key header
+// for newRecipients.
+//
+// The session body is never read, decrypted, or re-encrypted: the caller
+// only needs to replace the old header bytes (prepended-header layout) or
+// overwrite the ".keys" sidecar (sidecar layout) with the returned bytes,
+// leaving the ciphertext body untouched.
+func (m *KeyManager) RotateRecipients(oldHeader io.Reader, identity age.Identity, newRecipients []age.Recipient) ([]byte, error) {
+	wrapped, err := readHeader(oldHeader)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	dek, err := unwrapDEK(wrapped, identity)
+	if err != nil {
+		return nil, trace.Wrap(err, "recovering data key during rotation")
+	}
+
+	newWrapped, err := wrapDEK(dek, newRecipients)
+	if err != nil {
+		return nil, trace.Wrap(err, "wrapping data key for new recipients")
+	}
+
+	var buf bytes.Buffer
+	if err := writeHeader(&buf, newWrapped); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeHeader(w io.Writer, wrapped []byte) error {
+	var lenBuf [headerLengthSize]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(wrapped)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return trace.Wrap(err)
+	}
+	_, err := w.Write(wrapped)
+	return trace.Wrap(err)
+}
+
+func readHeader(r io.Reader) ([]byte, error) {
+	var lenBuf [headerLengthSize]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, trace.Wrap(err, "reading wrapped-key header length")
+	}
+
+	wrapped := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(r, wrapped); err != nil {
+		return nil, trace.Wrap(err, "reading wrapped-key header")
+	}
+	return wrapped, nil
+}
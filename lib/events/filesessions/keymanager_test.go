@@ -0,0 +1,123 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filesessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyManager_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	identity1, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	identity2, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	recipients := []age.Recipient{identity1.Recipient(), identity2.Recipient()}
+
+	// Exercise a body spanning multiple streamChunkSize-sized chunks, plus
+	// a partial final chunk.
+	plaintext := make([]byte, streamChunkSize*2+123)
+	_, err = rand.Read(plaintext)
+	require.NoError(t, err)
+
+	km := NewKeyManager()
+
+	var header, body bytes.Buffer
+	enc, err := km.EncryptSession(&header, &body, recipients)
+	require.NoError(t, err, "EncryptSession()")
+	_, err = io.Copy(enc, bytes.NewReader(plaintext))
+	require.NoError(t, err, "writing plaintext")
+	require.NoError(t, enc.Close(), "enc.Close()")
+
+	for _, identity := range []age.Identity{identity1, identity2} {
+		dec, err := km.DecryptSession(bytes.NewReader(header.Bytes()), bytes.NewReader(body.Bytes()), identity)
+		require.NoError(t, err, "DecryptSession()")
+		got, err := io.ReadAll(dec)
+		require.NoError(t, err, "reading decrypted body")
+		require.Equal(t, plaintext, got)
+	}
+}
+
+func TestKeyManager_rotateRecipients(t *testing.T) {
+	t.Parallel()
+
+	oldIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	newIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	const plaintext = "session recording body, never re-encrypted"
+
+	km := NewKeyManager()
+
+	var header, body bytes.Buffer
+	enc, err := km.EncryptSession(&header, &body, []age.Recipient{oldIdentity.Recipient()})
+	require.NoError(t, err, "EncryptSession()")
+	_, err = io.WriteString(enc, plaintext)
+	require.NoError(t, err, "writing plaintext")
+	require.NoError(t, enc.Close(), "enc.Close()")
+
+	bodyBeforeRotation := append([]byte(nil), body.Bytes()...)
+
+	// Rotate away from oldIdentity to newIdentity, using oldIdentity to
+	// recover the DEK.
+	newHeader, err := km.RotateRecipients(bytes.NewReader(header.Bytes()), oldIdentity, []age.Recipient{newIdentity.Recipient()})
+	require.NoError(t, err, "RotateRecipients()")
+	require.NotEqual(t, header.Bytes(), newHeader, "rotated header should differ from the original")
+
+	// The body must be untouched by rotation.
+	require.Equal(t, bodyBeforeRotation, body.Bytes(), "rotation must not touch the ciphertext body")
+
+	// oldIdentity can no longer decrypt the (rotated) header.
+	_, err = km.DecryptSession(bytes.NewReader(newHeader), bytes.NewReader(body.Bytes()), oldIdentity)
+	require.Error(t, err, "old identity should be removed after rotation")
+
+	// newIdentity can decrypt the untouched body through the new header.
+	dec, err := km.DecryptSession(bytes.NewReader(newHeader), bytes.NewReader(body.Bytes()), newIdentity)
+	require.NoError(t, err, "DecryptSession() with new identity")
+	got, err := io.ReadAll(dec)
+	require.NoError(t, err, "reading decrypted body")
+	require.Equal(t, plaintext, string(got))
+}
+
+func TestKeyManager_decryptWrongIdentity(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	km := NewKeyManager()
+
+	var header, body bytes.Buffer
+	enc, err := km.EncryptSession(&header, &body, []age.Recipient{identity.Recipient()})
+	require.NoError(t, err, "EncryptSession()")
+	_, err = io.WriteString(enc, "top secret")
+	require.NoError(t, err)
+	require.NoError(t, enc.Close())
+
+	_, err = km.DecryptSession(bytes.NewReader(header.Bytes()), bytes.NewReader(body.Bytes()), other)
+	require.Error(t, err, "decryption with an unrelated identity must fail")
+}
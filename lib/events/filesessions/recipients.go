@@ -0,0 +1,116 @@
+// Teleport
+// Copyright (C) 2025 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package filesessions
+
+import (
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/plugin"
+	"github.com/gravitational/trace"
+)
+
+// defaultScryptWorkFactor is the scrypt work factor (as log2(N)) used for
+// passphrase-encrypted recordings when RecipientsConfig doesn't set one
+// explicitly. It matches age's own CLI default.
+const defaultScryptWorkFactor = 18
+
+// RecipientsConfig describes the recipients recordings should be encrypted
+// to, in the operator-facing string form (config file, CLI flag, etc.)
+// rather than as parsed age.Recipient values.
+type RecipientsConfig struct {
+	// Recipients are age recipient strings. A native X25519 recipient
+	// ("age1...") is parsed directly; any other "age1<plugin>1..." form is
+	// dispatched to the matching "age-plugin-<plugin>" binary on PATH,
+	// so hardware-backed recipients (YubiKey, Secure Enclave, TPM, KMS,
+	// etc.) work without Teleport linking those backends directly.
+	Recipients []string
+	// Passphrase, if set, adds a scrypt-derived passphrase recipient. age
+	// refuses to encrypt to a passphrase recipient together with any other
+	// recipient, so encryption fails if Recipients is also set.
+	Passphrase string
+	// ScryptWorkFactor is the scrypt work factor (log2(N)) applied to
+	// Passphrase. Defaults to defaultScryptWorkFactor when Passphrase is
+	// set and this is <= 0.
+	ScryptWorkFactor int
+	// PluginStderr receives diagnostic output written by invoked
+	// age-plugin-* binaries. Defaults to io.Discard.
+	PluginStderr io.Writer
+}
+
+// ParseRecipients turns cfg into the age.Recipient values encryptedFileOps
+// encrypts recordings to.
+func ParseRecipients(cfg RecipientsConfig) ([]age.Recipient, error) {
+	stderr := cfg.PluginStderr
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	var recipients []age.Recipient
+	for _, spec := range cfg.Recipients {
+		recipient, err := parseRecipient(spec, stderr)
+		if err != nil {
+			return nil, trace.Wrap(err, "parsing recipient %q", spec)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if cfg.Passphrase != "" {
+		scryptRecipient, err := age.NewScryptRecipient(cfg.Passphrase)
+		if err != nil {
+			return nil, trace.Wrap(err, "creating passphrase recipient")
+		}
+		workFactor := cfg.ScryptWorkFactor
+		if workFactor <= 0 {
+			workFactor = defaultScryptWorkFactor
+		}
+		scryptRecipient.SetWorkFactor(workFactor)
+		recipients = append(recipients, scryptRecipient)
+	}
+
+	if len(recipients) == 0 {
+		return nil, trace.BadParameter("no recipients configured")
+	}
+	return recipients, nil
+}
+
+// parseRecipient parses a single recipient spec, trying the native X25519
+// form first and falling back to the age-plugin-* binary protocol for
+// every other "age1..." prefix.
+func parseRecipient(spec string, pluginStderr io.Writer) (age.Recipient, error) {
+	if recipient, err := age.ParseX25519Recipient(spec); err == nil {
+		return recipient, nil
+	}
+
+	recipient, err := plugin.NewRecipient(spec, &plugin.ClientUI{Stderr: pluginStderr})
+	if err != nil {
+		return nil, trace.Wrap(err, "invoking age-plugin-%v", pluginNameFromRecipient(spec))
+	}
+	return recipient, nil
+}
+
+// pluginNameFromRecipient extracts the plugin name segment from an
+// "age1<plugin>1..." recipient string, for error messages only; the actual
+// parsing and dispatch is done by the plugin package itself.
+func pluginNameFromRecipient(spec string) string {
+	rest := strings.TrimPrefix(spec, "age1")
+	if idx := strings.Index(rest, "1"); idx != -1 {
+		return rest[:idx]
+	}
+	return "unknown"
+}
@@ -0,0 +1,213 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package awsra
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"golang.org/x/sync/singleflight"
+)
+
+// CredentialsCacheConfig is the config for creating a CredentialsCache.
+type CredentialsCacheConfig struct {
+	// Generator generates new AWS Roles Anywhere credentials on a cache miss
+	// or proactive refresh. Defaults to GenerateAWSRACredentials.
+	Generator func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error)
+	// RefreshBeforeExpiry is how long before the cached credentials expire
+	// that they are proactively refreshed in the background.
+	RefreshBeforeExpiry time.Duration
+	// Clock is used to control time.
+	Clock clockwork.Clock
+}
+
+// CheckAndSetDefaults validates the config and sets default values.
+func (c *CredentialsCacheConfig) CheckAndSetDefaults() error {
+	if c.Generator == nil {
+		c.Generator = GenerateAWSRACredentials
+	}
+	if c.RefreshBeforeExpiry <= 0 {
+		c.RefreshBeforeExpiry = 5 * time.Minute
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	return nil
+}
+
+// credentialsCacheKey identifies a unique AWS Roles Anywhere credentials
+// request in the cache. NotAfter is intentionally excluded: it doesn't
+// change the role/profile/identity being assumed, and including it would
+// defeat caching since callers typically compute it relative to the current
+// time on every call.
+type credentialsCacheKey struct {
+	trustAnchorARN    string
+	profileARN        string
+	roleARN           string
+	subjectCommonName string
+}
+
+// groupKey returns the string key used to coalesce concurrent misses for
+// key onto a single Generator call via CredentialsCache.group.
+func (k credentialsCacheKey) groupKey() string {
+	return fmt.Sprintf("%s|%s|%s|%s", k.trustAnchorARN, k.profileARN, k.roleARN, k.subjectCommonName)
+}
+
+type credentialsCacheEntry struct {
+	resp       *GenerateAWSRACredentialsResponse
+	expiresAt  time.Time
+	refreshing bool
+}
+
+// CredentialsCache caches AWS IAM Roles Anywhere credentials returned by
+// GenerateAWSRACredentials and proactively refreshes them in the background
+// before they expire. Without it, every caller on the hot path pays the cost
+// of a fresh certificate issuance and rolesanywhere:CreateSession call.
+// Concurrent callers that miss the cache for the same key are coalesced
+// onto a single Generator call.
+type CredentialsCache struct {
+	cfg CredentialsCacheConfig
+
+	mu      sync.Mutex
+	entries map[credentialsCacheKey]*credentialsCacheEntry
+	group   singleflight.Group
+}
+
+// NewCredentialsCache returns a new CredentialsCache.
+func NewCredentialsCache(cfg CredentialsCacheConfig) (*CredentialsCache, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &CredentialsCache{
+		cfg:     cfg,
+		entries: make(map[credentialsCacheKey]*credentialsCacheEntry),
+	}, nil
+}
+
+// Get returns cached AWS Roles Anywhere credentials for req, generating them
+// if no usable entry exists yet. Once cached, credentials are refreshed in
+// the background RefreshBeforeExpiry before they expire, so callers only
+// block on a fresh CreateSession call on the first request (or first
+// concurrent burst of requests) for a given trust anchor, profile, role and
+// subject common name.
+func (c *CredentialsCache) Get(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+	key := credentialsCacheKey{
+		trustAnchorARN:    req.TrustAnchorARN,
+		profileARN:        req.ProfileARN,
+		roleARN:           req.RoleARN,
+		subjectCommonName: req.SubjectCommonName,
+	}
+
+	now := c.cfg.Clock.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && now.Before(entry.expiresAt) {
+		resp := entry.resp
+		if !entry.refreshing && now.Add(c.cfg.RefreshBeforeExpiry).After(entry.expiresAt) {
+			entry.refreshing = true
+			go c.refreshInBackground(key, req)
+		}
+		c.mu.Unlock()
+		return resp, nil
+	}
+	c.mu.Unlock()
+
+	return c.refresh(ctx, key, req)
+}
+
+// refresh calls the generator, stores the result in the cache and returns
+// it, coalescing concurrent callers for the same key onto a single
+// Generator call so a refresh doesn't stampede rolesanywhere:CreateSession.
+func (c *CredentialsCache) refresh(ctx context.Context, key credentialsCacheKey, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+	v, err, _ := c.group.Do(key.groupKey(), func() (any, error) {
+		req.Clock = c.cfg.Clock
+		resp, err := c.cfg.Generator(ctx, req)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+
+		expiresAt, err := time.Parse(time.RFC3339, resp.Expiration)
+		if err != nil {
+			return nil, trace.Wrap(err, "parsing AWS Roles Anywhere credentials expiration")
+		}
+
+		c.mu.Lock()
+		c.entries[key] = &credentialsCacheEntry{resp: resp, expiresAt: expiresAt}
+		c.mu.Unlock()
+
+		return resp, nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return v.(*GenerateAWSRACredentialsResponse), nil
+}
+
+// refreshInBackground regenerates the credentials for key outside of the
+// caller's request context, clearing the in-progress flag so a later Get can
+// retry if it fails.
+func (c *CredentialsCache) refreshInBackground(key credentialsCacheKey, req GenerateAWSRACredentialsRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := c.refresh(ctx, key, req); err != nil {
+		slog.ErrorContext(ctx, "failed to proactively refresh AWS Roles Anywhere credentials",
+			"error", err,
+			"profile_arn", req.ProfileARN,
+			"role_arn", req.RoleARN,
+		)
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok {
+			entry.refreshing = false
+		}
+		c.mu.Unlock()
+	}
+}
+
+// CachingCredentialGenerator adapts a CredentialsCache to the same function
+// signature as GenerateAWSRACredentials, so a caller that holds a generator
+// function (e.g. for the `credential_process` path) can opt into caching by
+// constructing one and using its Generate method in its place.
+type CachingCredentialGenerator struct {
+	cache *CredentialsCache
+}
+
+// NewCachingCredentialGenerator returns a CachingCredentialGenerator backed
+// by a new CredentialsCache built from cfg.
+func NewCachingCredentialGenerator(cfg CredentialsCacheConfig) (*CachingCredentialGenerator, error) {
+	cache, err := NewCredentialsCache(cfg)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &CachingCredentialGenerator{cache: cache}, nil
+}
+
+// Generate returns cached AWS Roles Anywhere credentials for req. It has the
+// same signature as GenerateAWSRACredentials, so it can be used as a
+// drop-in, caching replacement wherever that function is expected.
+func (g *CachingCredentialGenerator) Generate(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+	resp, err := g.cache.Get(ctx, req)
+	return resp, trace.Wrap(err)
+}
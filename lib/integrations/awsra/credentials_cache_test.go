@@ -0,0 +1,197 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package awsra
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCredentialsCache(t *testing.T, clock clockwork.Clock, generate func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error)) *CredentialsCache {
+	t.Helper()
+	cache, err := NewCredentialsCache(CredentialsCacheConfig{
+		Generator:           generate,
+		RefreshBeforeExpiry: time.Minute,
+		Clock:               clock,
+	})
+	require.NoError(t, err)
+	return cache
+}
+
+func TestCredentialsCacheHit(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var calls atomic.Int32
+	cache := newTestCredentialsCache(t, clock, func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+		calls.Add(1)
+		return &GenerateAWSRACredentialsResponse{
+			AccessKeyID: "key",
+			Expiration:  clock.Now().Add(time.Hour).Format(time.RFC3339),
+		}, nil
+	})
+
+	req := GenerateAWSRACredentialsRequest{TrustAnchorARN: "ta", ProfileARN: "profile", RoleARN: "role"}
+
+	resp1, err := cache.Get(context.Background(), req)
+	require.NoError(t, err)
+	resp2, err := cache.Get(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Same(t, resp1, resp2)
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestCredentialsCacheRefetchesAfterExpiry(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var calls atomic.Int32
+	cache := newTestCredentialsCache(t, clock, func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+		calls.Add(1)
+		return &GenerateAWSRACredentialsResponse{
+			AccessKeyID: "key",
+			Expiration:  clock.Now().Add(time.Hour).Format(time.RFC3339),
+		}, nil
+	})
+
+	req := GenerateAWSRACredentialsRequest{TrustAnchorARN: "ta", ProfileARN: "profile", RoleARN: "role"}
+
+	_, err := cache.Get(context.Background(), req)
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Hour)
+
+	_, err = cache.Get(context.Background(), req)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestCredentialsCacheDistinctKeys(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var calls atomic.Int32
+	cache := newTestCredentialsCache(t, clock, func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+		calls.Add(1)
+		return &GenerateAWSRACredentialsResponse{
+			AccessKeyID: req.RoleARN,
+			Expiration:  clock.Now().Add(time.Hour).Format(time.RFC3339),
+		}, nil
+	})
+
+	_, err := cache.Get(context.Background(), GenerateAWSRACredentialsRequest{TrustAnchorARN: "ta", ProfileARN: "profile", RoleARN: "role-a"})
+	require.NoError(t, err)
+	_, err = cache.Get(context.Background(), GenerateAWSRACredentialsRequest{TrustAnchorARN: "ta", ProfileARN: "profile", RoleARN: "role-b"})
+	require.NoError(t, err)
+
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestCredentialsCacheDistinctSubjectCommonNames(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var calls atomic.Int32
+	cache := newTestCredentialsCache(t, clock, func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+		calls.Add(1)
+		return &GenerateAWSRACredentialsResponse{
+			AccessKeyID: req.SubjectCommonName,
+			Expiration:  clock.Now().Add(time.Hour).Format(time.RFC3339),
+		}, nil
+	})
+
+	base := GenerateAWSRACredentialsRequest{TrustAnchorARN: "ta", ProfileARN: "profile", RoleARN: "role"}
+
+	reqA := base
+	reqA.SubjectCommonName = "alice"
+	respA, err := cache.Get(context.Background(), reqA)
+	require.NoError(t, err)
+
+	reqB := base
+	reqB.SubjectCommonName = "bob"
+	respB, err := cache.Get(context.Background(), reqB)
+	require.NoError(t, err)
+
+	require.Equal(t, "alice", respA.AccessKeyID)
+	require.Equal(t, "bob", respB.AccessKeyID)
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestCredentialsCacheCoalescesConcurrentMisses(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	cache := newTestCredentialsCache(t, clock, func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+		calls.Add(1)
+		close(started)
+		<-release
+		return &GenerateAWSRACredentialsResponse{
+			AccessKeyID: "key",
+			Expiration:  clock.Now().Add(time.Hour).Format(time.RFC3339),
+		}, nil
+	})
+
+	req := GenerateAWSRACredentialsRequest{TrustAnchorARN: "ta", ProfileARN: "profile", RoleARN: "role"}
+
+	var wg sync.WaitGroup
+	results := make([]*GenerateAWSRACredentialsResponse, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := cache.Get(context.Background(), req)
+			require.NoError(t, err)
+			results[i] = resp
+		}(i)
+	}
+
+	<-started
+	close(release)
+	wg.Wait()
+
+	require.Same(t, results[0], results[1])
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestCachingCredentialGenerator(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	var calls atomic.Int32
+	gen, err := NewCachingCredentialGenerator(CredentialsCacheConfig{
+		Generator: func(ctx context.Context, req GenerateAWSRACredentialsRequest) (*GenerateAWSRACredentialsResponse, error) {
+			calls.Add(1)
+			return &GenerateAWSRACredentialsResponse{
+				AccessKeyID: "key",
+				Expiration:  clock.Now().Add(time.Hour).Format(time.RFC3339),
+			}, nil
+		},
+		RefreshBeforeExpiry: time.Minute,
+		Clock:               clock,
+	})
+	require.NoError(t, err)
+
+	req := GenerateAWSRACredentialsRequest{TrustAnchorARN: "ta", ProfileARN: "profile", RoleARN: "role"}
+
+	resp1, err := gen.Generate(context.Background(), req)
+	require.NoError(t, err)
+	resp2, err := gen.Generate(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Same(t, resp1, resp2)
+	require.EqualValues(t, 1, calls.Load())
+}
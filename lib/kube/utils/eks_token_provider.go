@@ -0,0 +1,325 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+const teleportNamespace = "teleport"
+
+var (
+	eksTokenCacheHitsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: teleportNamespace,
+			Subsystem: "kube_eks_token",
+			Name:      "cache_hits_total",
+			Help:      "Number of EKS token requests served from cache without presigning STS.",
+		},
+		[]string{"cluster_id"},
+	)
+	eksTokenRefreshFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: teleportNamespace,
+			Subsystem: "kube_eks_token",
+			Name:      "refresh_failures_total",
+			Help:      "Number of failed attempts to refresh a cached EKS token.",
+		},
+		[]string{"cluster_id"},
+	)
+	eksTokenThrottledTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: teleportNamespace,
+			Subsystem: "kube_eks_token",
+			Name:      "throttled_total",
+			Help:      "Number of EKS token refreshes that failed due to AWS STS throttling.",
+		},
+		[]string{"cluster_id"},
+	)
+)
+
+// EKSTokenCredentialOption configures which AWS credentials a single
+// TokenProvider.GetToken call presigns the STS request with.
+type EKSTokenCredentialOption func(*eksTokenCredentialOptions)
+
+type eksTokenCredentialOptions struct {
+	assumeRoleARN        string
+	webIdentityRoleARN   string
+	webIdentityTokenFile string
+}
+
+// roleARN returns the cache-key role component for these options: the role,
+// if any, credentials are ultimately scoped to.
+func (o eksTokenCredentialOptions) roleARN() string {
+	if o.webIdentityRoleARN != "" {
+		return o.webIdentityRoleARN
+	}
+	return o.assumeRoleARN
+}
+
+// WithAssumeRoleARN presigns using credentials obtained by assuming roleARN
+// on top of the provider's base (ambient default-chain) credentials.
+func WithAssumeRoleARN(roleARN string) EKSTokenCredentialOption {
+	return func(o *eksTokenCredentialOptions) {
+		o.assumeRoleARN = roleARN
+	}
+}
+
+// WithWebIdentityToken presigns using AssumeRoleWithWebIdentity against
+// roleARN with the OIDC token at tokenFile. This is the mechanism behind
+// both IRSA and EKS Pod Identity's projected service account token flow;
+// callers pick it explicitly rather than relying on ambient discovery so
+// the resulting token is cached under the role it was actually scoped to.
+func WithWebIdentityToken(roleARN, tokenFile string) EKSTokenCredentialOption {
+	return func(o *eksTokenCredentialOptions) {
+		o.webIdentityRoleARN = roleARN
+		o.webIdentityTokenFile = tokenFile
+	}
+}
+
+// STSPresignClientFactory builds an STSPresignClient for a single GetToken
+// call, honoring the credential source selected by opts.
+type STSPresignClientFactory func(ctx context.Context, opt EKSTokenCredentialOption) (STSPresignClient, error)
+
+// defaultSTSPresignClientFactory builds an STSPresignClient from the AWS
+// SDK's default credential chain, additionally assuming a role or exchanging
+// a web identity token when the caller asked for one via opts.
+func defaultSTSPresignClientFactory(ctx context.Context, opt EKSTokenCredentialOption) (STSPresignClient, error) {
+	var opts eksTokenCredentialOptions
+	if opt != nil {
+		opt(&opts)
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	switch {
+	case opts.webIdentityRoleARN != "":
+		awsCfg.Credentials = aws.NewCredentialsCache(stscreds.NewWebIdentityRoleProvider(
+			sts.NewFromConfig(awsCfg), opts.webIdentityRoleARN,
+			stscreds.IdentityTokenFile(opts.webIdentityTokenFile),
+		))
+	case opts.assumeRoleARN != "":
+		awsCfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(
+			sts.NewFromConfig(awsCfg), opts.assumeRoleARN,
+		))
+	}
+
+	return sts.NewPresignClient(sts.NewFromConfig(awsCfg)), nil
+}
+
+// TokenProviderConfig is the config for creating a TokenProvider.
+type TokenProviderConfig struct {
+	// NewSTSPresignClient builds the STSPresignClient used for a cache miss
+	// or background refresh. Defaults to defaultSTSPresignClientFactory.
+	NewSTSPresignClient STSPresignClientFactory
+	// RefreshBeforeExpiry is how long before a cached token expires that it
+	// is proactively refreshed in the background. Defaults to 5 minutes,
+	// approximately the last third of an EKS token's ~14 minute lifetime.
+	RefreshBeforeExpiry time.Duration
+	// Clock is used to control time.
+	Clock clockwork.Clock
+}
+
+// CheckAndSetDefaults validates the config and sets default values.
+func (c *TokenProviderConfig) CheckAndSetDefaults() error {
+	if c.NewSTSPresignClient == nil {
+		c.NewSTSPresignClient = defaultSTSPresignClientFactory
+	}
+	if c.RefreshBeforeExpiry <= 0 {
+		c.RefreshBeforeExpiry = 5 * time.Minute
+	}
+	if c.Clock == nil {
+		c.Clock = clockwork.NewRealClock()
+	}
+	return nil
+}
+
+// tokenCacheKey identifies a unique EKS token in the cache: the cluster and
+// the role the presigning credentials are scoped to (empty for the ambient
+// default credential source).
+type tokenCacheKey struct {
+	clusterID string
+	roleARN   string
+}
+
+type tokenCacheEntry struct {
+	token      string
+	expiresAt  time.Time
+	refreshing bool
+}
+
+// TokenProvider caches AWS EKS bearer tokens returned by GenAWSEKSToken,
+// keyed by (cluster ID, assumed role ARN), and proactively refreshes them in
+// the background before they expire. Without it, every kube request against
+// an EKS cluster pays the cost of a fresh STS presign, even though the
+// resulting token stays valid for about 14 minutes. Concurrent callers that
+// miss the cache for the same key are coalesced onto a single presign.
+type TokenProvider struct {
+	cfg TokenProviderConfig
+
+	mu      sync.Mutex
+	entries map[tokenCacheKey]*tokenCacheEntry
+	group   singleflight.Group
+}
+
+// NewTokenProvider returns a new TokenProvider.
+func NewTokenProvider(cfg TokenProviderConfig) (*TokenProvider, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &TokenProvider{
+		cfg:     cfg,
+		entries: make(map[tokenCacheKey]*tokenCacheEntry),
+	}, nil
+}
+
+// GetToken returns a cached EKS bearer token for clusterID, presigning a new
+// one if no usable entry exists yet. Once cached, the token is refreshed in
+// the background RefreshBeforeExpiry before it expires, so callers only
+// block on a fresh STS presign for the first request (or first concurrent
+// burst of requests) for a given cluster and credential source.
+func (p *TokenProvider) GetToken(ctx context.Context, clusterID string, opts ...EKSTokenCredentialOption) (string, time.Time, error) {
+	var merged eksTokenCredentialOptions
+	for _, opt := range opts {
+		opt(&merged)
+	}
+	key := tokenCacheKey{clusterID: clusterID, roleARN: merged.roleARN()}
+
+	now := p.cfg.Clock.Now()
+
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	if ok && now.Before(entry.expiresAt) {
+		token, expiresAt := entry.token, entry.expiresAt
+		if !entry.refreshing && now.Add(p.cfg.RefreshBeforeExpiry).After(entry.expiresAt) {
+			entry.refreshing = true
+			go p.refreshInBackground(key, opts)
+		}
+		p.mu.Unlock()
+		eksTokenCacheHitsTotal.WithLabelValues(clusterID).Inc()
+		return token, expiresAt, nil
+	}
+	p.mu.Unlock()
+
+	token, expiresAt, err := p.refresh(ctx, key, opts)
+	if err != nil {
+		return "", time.Time{}, trace.Wrap(err)
+	}
+	return token, expiresAt, nil
+}
+
+// refresh presigns a fresh token for key and stores it in the cache,
+// coalescing concurrent callers for the same key onto a single presign.
+func (p *TokenProvider) refresh(ctx context.Context, key tokenCacheKey, opts []EKSTokenCredentialOption) (string, time.Time, error) {
+	type result struct {
+		token     string
+		expiresAt time.Time
+	}
+
+	v, err, _ := p.group.Do(fmt.Sprintf("%s|%s", key.clusterID, key.roleARN), func() (any, error) {
+		var opt EKSTokenCredentialOption
+		if len(opts) > 0 {
+			opt = func(o *eksTokenCredentialOptions) {
+				for _, apply := range opts {
+					apply(o)
+				}
+			}
+		}
+
+		stsClient, err := p.cfg.NewSTSPresignClient(ctx, opt)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+
+		token, expiresAt, err := GenAWSEKSToken(ctx, stsClient, key.clusterID, p.cfg.Clock)
+		if err != nil {
+			if isThrottlingError(err) {
+				eksTokenThrottledTotal.WithLabelValues(key.clusterID).Inc()
+			}
+			return nil, trace.Wrap(err)
+		}
+
+		p.mu.Lock()
+		p.entries[key] = &tokenCacheEntry{token: token, expiresAt: expiresAt}
+		p.mu.Unlock()
+
+		return result{token: token, expiresAt: expiresAt}, nil
+	})
+	if err != nil {
+		eksTokenRefreshFailuresTotal.WithLabelValues(key.clusterID).Inc()
+		return "", time.Time{}, trace.Wrap(err)
+	}
+
+	r := v.(result)
+	return r.token, r.expiresAt, nil
+}
+
+// refreshInBackground re-presigns the token for key outside of any caller's
+// request context, clearing the in-progress flag so a later GetToken can
+// retry if it fails.
+func (p *TokenProvider) refreshInBackground(key tokenCacheKey, opts []EKSTokenCredentialOption) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, _, err := p.refresh(ctx, key, opts); err != nil {
+		slog.ErrorContext(ctx, "failed to proactively refresh EKS token",
+			"error", err,
+			"cluster_id", key.clusterID,
+			"role_arn", key.roleARN,
+		)
+		p.mu.Lock()
+		if entry, ok := p.entries[key]; ok {
+			entry.refreshing = false
+		}
+		p.mu.Unlock()
+	}
+}
+
+// isThrottlingError reports whether err is an AWS STS throttling error.
+func isThrottlingError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "TooManyRequestsException", "RequestLimitExceeded":
+		return true
+	default:
+		return false
+	}
+}
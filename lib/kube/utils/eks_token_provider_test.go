@@ -0,0 +1,147 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSTSPresignClient returns a deterministic presigned URL (or a fixed
+// error) instead of calling AWS.
+type fakeSTSPresignClient struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (f *fakeSTSPresignClient) PresignGetCallerIdentity(_ context.Context, _ *sts.GetCallerIdentityInput, _ ...func(*sts.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	n := f.calls.Add(1)
+	return &v4.PresignedHTTPRequest{URL: fmt.Sprintf("https://sts.example.com/?call=%d", n)}, nil
+}
+
+func newTestTokenProvider(t *testing.T, clock clockwork.Clock, byRoleARN map[string]*fakeSTSPresignClient) *TokenProvider {
+	t.Helper()
+	if byRoleARN["default"] == nil {
+		byRoleARN["default"] = &fakeSTSPresignClient{}
+	}
+	provider, err := NewTokenProvider(TokenProviderConfig{
+		Clock:               clock,
+		RefreshBeforeExpiry: time.Minute,
+		NewSTSPresignClient: func(_ context.Context, opt EKSTokenCredentialOption) (STSPresignClient, error) {
+			var opts eksTokenCredentialOptions
+			if opt != nil {
+				opt(&opts)
+			}
+			key := opts.roleARN()
+			if key == "" {
+				key = "default"
+			}
+			client, ok := byRoleARN[key]
+			if !ok {
+				client = &fakeSTSPresignClient{}
+				byRoleARN[key] = client
+			}
+			return client, nil
+		},
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestTokenProviderCacheHit(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	clients := map[string]*fakeSTSPresignClient{}
+	provider := newTestTokenProvider(t, clock, clients)
+
+	token1, _, err := provider.GetToken(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	token2, _, err := provider.GetToken(context.Background(), "my-cluster")
+	require.NoError(t, err)
+
+	require.Equal(t, token1, token2)
+	require.EqualValues(t, 1, clients["default"].calls.Load())
+}
+
+func TestTokenProviderBackgroundRefresh(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	clients := map[string]*fakeSTSPresignClient{}
+	provider := newTestTokenProvider(t, clock, clients)
+
+	token1, _, err := provider.GetToken(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, clients["default"].calls.Load())
+
+	// Still outside the RefreshBeforeExpiry window: served from cache, no
+	// background refresh triggered.
+	clock.Advance(10 * time.Minute)
+	token2, _, err := provider.GetToken(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	require.Equal(t, token1, token2)
+	require.EqualValues(t, 1, clients["default"].calls.Load())
+
+	// Now inside the last minute before expiry (token TTL is ~14 minutes):
+	// the cached token is still returned immediately, but a background
+	// refresh is kicked off.
+	clock.Advance(4 * time.Minute)
+	token3, _, err := provider.GetToken(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	require.Equal(t, token1, token3, "stale-but-valid token should still be served immediately")
+
+	require.Eventually(t, func() bool {
+		return clients["default"].calls.Load() == 2
+	}, time.Second, time.Millisecond, "background refresh should have presigned a new token")
+}
+
+func TestTokenProviderEvictsOnCredentialChange(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	clients := map[string]*fakeSTSPresignClient{}
+	provider := newTestTokenProvider(t, clock, clients)
+
+	_, _, err := provider.GetToken(context.Background(), "my-cluster")
+	require.NoError(t, err)
+	_, _, err = provider.GetToken(context.Background(), "my-cluster", WithAssumeRoleARN("arn:aws:iam::123456789012:role/other"))
+	require.NoError(t, err)
+
+	require.EqualValues(t, 1, clients["default"].calls.Load())
+	require.EqualValues(t, 1, clients["arn:aws:iam::123456789012:role/other"].calls.Load())
+}
+
+func TestTokenProviderThrottling(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	clients := map[string]*fakeSTSPresignClient{
+		"default": {err: &smithy.GenericAPIError{Code: "ThrottlingException", Message: "slow down"}},
+	}
+	provider := newTestTokenProvider(t, clock, clients)
+
+	_, _, err := provider.GetToken(context.Background(), "my-cluster")
+	require.Error(t, err)
+	require.True(t, isThrottlingError(err))
+}
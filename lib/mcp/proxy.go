@@ -24,24 +24,22 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/tls"
-	"io"
 	"log/slog"
 	"net"
-	"os"
-	"os/exec"
 
+	"github.com/google/uuid"
 	"github.com/gravitational/trace"
 	"github.com/mark3labs/mcp-go/server"
-	"github.com/mattn/go-shellwords"
 
 	"github.com/gravitational/teleport"
 	clientproto "github.com/gravitational/teleport/api/client/proto"
 	"github.com/gravitational/teleport/api/constants"
+	apitypes "github.com/gravitational/teleport/api/types"
+	apievents "github.com/gravitational/teleport/api/types/events"
 	"github.com/gravitational/teleport/api/utils/keys"
 	"github.com/gravitational/teleport/lib/auth"
 	"github.com/gravitational/teleport/lib/auth/authclient"
 	"github.com/gravitational/teleport/lib/authz"
-	pgmcp "github.com/gravitational/teleport/lib/client/db/mcp/postgres"
 	alpncommon "github.com/gravitational/teleport/lib/srv/alpnproxy/common"
 	"github.com/gravitational/teleport/lib/utils"
 )
@@ -51,6 +49,9 @@ type ProxyServerConfig struct {
 	AuthClient  authclient.ClientI
 	AccessPoint authclient.ProxyAccessPoint
 	ALPNHandler func(ctx context.Context, conn net.Conn) error
+	// Emitter emits audit events for RBAC-gated upstream tool calls. A nil
+	// Emitter disables CallTool auditing but not authorization.
+	Emitter apievents.Emitter
 }
 
 func (c *ProxyServerConfig) Check() error {
@@ -69,6 +70,8 @@ func (c *ProxyServerConfig) Check() error {
 type ProxyServer struct {
 	cfg        *ProxyServerConfig
 	middleware *auth.Middleware
+	router     *Router
+	toolCalls  ToolCallMiddleware
 	logger     *slog.Logger
 }
 
@@ -86,10 +89,17 @@ func NewProxyServer(ctx context.Context, cfg *ProxyServerConfig) (*ProxyServer,
 		ClusterName: clusterName.GetClusterName(),
 	}
 
+	logger := slog.With(teleport.ComponentKey, teleport.Component(teleport.ComponentProxy, "mcp"))
+
 	return &ProxyServer{
 		cfg:        cfg,
 		middleware: middleware,
-		logger:     slog.With(teleport.ComponentKey, teleport.Component(teleport.ComponentProxy, "mcp")),
+		router:     NewRouter(),
+		toolCalls: ToolCallMiddlewareChain{
+			newAuditToolCallMiddleware(cfg.Emitter, logger),
+			newRateLimitToolCallMiddleware(defaultToolCallQuota),
+		},
+		logger: logger,
 	}, nil
 }
 
@@ -114,30 +124,16 @@ func (s *ProxyServer) HandleConnection(ctx context.Context, conn net.Conn) error
 		return s.handleOneDB(ctx, conn, authCtx)
 	}
 
-	// TODO replace me with real impl
-	cmdToRun := os.Getenv("TELEPORT_MCP_RUN_POSTGRES")
-	s.logger.DebugContext(ctx, "=== MCP server authorized", "user", authCtx.User, "cmd", cmdToRun)
-	if cmdToRun != "" {
-		parts, err := shellwords.Parse(cmdToRun)
-		if err != nil {
-			return trace.BadParameter("cannot parse mcp.run: %v", err)
-		}
-		s.logger.DebugContext(ctx, "=== running tmp postgres server ", "command", parts)
-		cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
-		cmd.Stdin = tlsConn
-		cmd.Stdout = tlsConn
-		cmd.Stderr = io.Discard
-		if err := cmd.Start(); err != nil {
-			return trace.Wrap(err)
-		}
-		return cmd.Wait()
-	} else {
-		_, err := tlsConn.Write([]byte("hello teleport"))
-		return trace.Wrap(err)
-	}
+	// The client didn't present a route, so there's no database (and thus
+	// no protocol) to dispatch to yet. Discovering a route from the MCP
+	// initialize handshake itself isn't supported yet.
+	return trace.NotImplemented("MCP sessions require a RouteToDatabase; discovering a route from the initialize handshake is not yet supported")
 }
 
 func (s *ProxyServer) handleOneDB(ctx context.Context, clientConn net.Conn, authCtx *authz.Context) error {
+	sessionID := uuid.NewString()
+	s.logger.DebugContext(ctx, "Starting MCP database session", "session", sessionID)
+
 	// What the hell am i doing
 	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
@@ -211,15 +207,34 @@ func (s *ProxyServer) handleOneDB(ctx context.Context, clientConn net.Conn, auth
 
 	mcpServer := server.NewMCPServer("teleport", teleport.Version)
 
-	// Add PostgreSQL MCP stuff.
-	sess, err := pgmcp.NewSession(ctx, pgmcp.NewSessionConfig{
-		MCPServer: mcpServer,
-		RawDBConn: serverConn,
-		Route:     certsReq.RouteToDatabase,
-	})
+	sess, dispatchErr := s.router.Dispatch(ctx, mcpServer, serverConn, certsReq.RouteToDatabase)
+	check := s.router.HealthCheck(dispatchErr)
+	s.logger.DebugContext(ctx, "MCP session health", "status", check.Status, "protocol", identity.RouteToDatabase.Protocol)
+	if dispatchErr != nil {
+		return trace.Wrap(dispatchErr)
+	}
 	defer sess.Close(ctx)
 
-	err = server.NewStdioServer(mcpServer).Listen(ctx, clientConn, clientConn)
+	var db apitypes.Database
+	db, err = s.cfg.AccessPoint.GetDatabase(ctx, identity.RouteToDatabase.ServiceName)
+	if err != nil {
+		return trace.Wrap(err, "resolving database %q for MCP tool authorization", identity.RouteToDatabase.ServiceName)
+	}
+	toolAuthz := newToolAuthorizer(authCtx, db, s.cfg.Emitter, s.logger)
+
+	// RBAC is its own stage, combined with the server's standing
+	// audit/rate-limit chain per session, since it (unlike those two) needs
+	// this session's authCtx and routed database to decide anything.
+	sessionToolCalls := ToolCallMiddlewareChain{s.toolCalls, newRBACToolCallMiddleware(toolAuthz)}
+
+	auditedConn := WrapToolCallSession(ctx, clientConn, sessionToolCalls, ToolCallInfo{
+		SessionID:    sessionID,
+		Route:        certsReq.RouteToDatabase,
+		UserMetadata: authCtx.Identity.GetIdentity().GetUserMetadata(),
+	}, toolAuthz.FilterTools, s.logger)
+	defer auditedConn.Close()
+
+	err = server.NewStdioServer(mcpServer).Listen(ctx, auditedConn, auditedConn)
 	s.logger.DebugContext(ctx, "MCP session terminated", "error", err)
 	return trace.Wrap(err)
 }
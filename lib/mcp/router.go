@@ -0,0 +1,122 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"net"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/gravitational/teleport"
+	clientproto "github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/defaults"
+	"github.com/gravitational/teleport/api/types"
+	pgmcp "github.com/gravitational/teleport/lib/client/db/mcp/postgres"
+	"github.com/gravitational/teleport/lib/srv/db/common"
+)
+
+// RouteToDatabase identifies the database a client's MCP session was routed
+// to, e.g. via an issued certificate's RouteToDatabase field.
+type RouteToDatabase = clientproto.RouteToDatabase
+
+// Session is a live, protocol-specific MCP upstream session. Closing it
+// tears down the underlying database connection and releases any resources
+// the ProtocolHandler allocated in Register.
+type Session interface {
+	Close(ctx context.Context) error
+}
+
+// ProtocolHandler registers a database protocol's MCP tools on mcpServer
+// and wires them to conn, the already-established raw connection to the
+// target database.
+type ProtocolHandler interface {
+	Register(ctx context.Context, mcpServer *server.MCPServer, conn net.Conn, route RouteToDatabase) (Session, error)
+}
+
+// Router dispatches an MCP session to the ProtocolHandler registered for
+// the session's database protocol, mirroring how handleOneDB already picks
+// an ALPN protocol via alpncommon.ToALPNProtocol for the underlying
+// connection.
+type Router struct {
+	handlers map[string]ProtocolHandler
+	logger   *slog.Logger
+}
+
+// NewRouter returns a Router with the built-in protocol handlers
+// registered.
+func NewRouter() *Router {
+	r := &Router{
+		handlers: make(map[string]ProtocolHandler),
+		logger:   slog.With(teleport.ComponentKey, teleport.Component(teleport.ComponentProxy, "mcp", "router")),
+	}
+	r.Register(defaults.ProtocolPostgres, postgresHandler{})
+	return r
+}
+
+// Register associates protocol (e.g. "postgres") with handler. Registering
+// under a protocol that already has a handler replaces it; this is mainly
+// useful for tests.
+func (r *Router) Register(protocol string, handler ProtocolHandler) {
+	r.handlers[protocol] = handler
+}
+
+// Dispatch looks up the ProtocolHandler for route.Protocol and registers its
+// tools on mcpServer, wiring them to conn.
+func (r *Router) Dispatch(ctx context.Context, mcpServer *server.MCPServer, conn net.Conn, route RouteToDatabase) (Session, error) {
+	handler, ok := r.handlers[route.Protocol]
+	if !ok {
+		return nil, trace.NotImplemented("MCP is not supported for database protocol %q yet", route.Protocol)
+	}
+
+	r.logger.DebugContext(ctx, "Dispatching MCP session", "protocol", route.Protocol, "db_service", route.ServiceName)
+	sess, err := handler.Register(ctx, mcpServer, conn, route)
+	return sess, trace.Wrap(err)
+}
+
+// HealthCheck builds a DatabaseHealthCheckV1 reflecting the outcome of
+// dispatching (or failing to dispatch) an MCP session, so callers can
+// surface per-protocol MCP session health the same way
+// common.NewConnectivityHealthcheck surfaces regular connection health on
+// the DatabaseServer status.
+func (r *Router) HealthCheck(dispatchErr error) types.DatabaseHealthCheckV1 {
+	return common.NewConnectivityHealthcheck(dispatchErr)
+}
+
+// postgresHandler adapts pgmcp.NewSession to the ProtocolHandler interface.
+type postgresHandler struct{}
+
+// Register implements ProtocolHandler.
+//
+// pgmcp has no hook of its own for filtering addTools/callUpstreamTool, so
+// mcp.tools RBAC isn't enforced here - it's enforced one layer up, in
+// ProxyServer.handleOneDB, which wraps every session's connection (whatever
+// protocol handler it's registered to) in a toolAuthorizer-backed
+// ToolCallMiddleware that gates and filters tools/call and tools/list on
+// the wire, regardless of what the handler underneath advertised.
+func (postgresHandler) Register(ctx context.Context, mcpServer *server.MCPServer, conn net.Conn, route RouteToDatabase) (Session, error) {
+	sess, err := pgmcp.NewSession(ctx, pgmcp.NewSessionConfig{
+		MCPServer: mcpServer,
+		RawDBConn: conn,
+		Route:     route,
+	})
+	return sess, trace.Wrap(err)
+}
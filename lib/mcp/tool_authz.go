@@ -0,0 +1,141 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	apitypes "github.com/gravitational/teleport/api/types"
+	apievents "github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/teleport/lib/authz"
+	"github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/services"
+)
+
+// toolAccessChecker is the subset of services.AccessChecker that
+// toolAuthorizer needs. Narrowing to it, rather than embedding the full
+// *authz.Context, lets tests fake access decisions without standing up a
+// real AccessChecker.
+type toolAccessChecker interface {
+	CheckAccess(resource apitypes.ResourceWithLabels, state services.AccessState, matchers ...services.RoleMatcher) error
+}
+
+// toolAuthorizer filters the set of upstream MCP tools advertised to a
+// client and authorizes individual CallTool invocations, based on the
+// caller's Teleport roles and the mcp.tools allow/deny rules attached to
+// resource (e.g. `mcp.tools: ["read_*", "!write_*"]`). It reuses the same
+// services.MCPToolsMatcher that lib/srv/app uses to gate tools/call for
+// stdio/HTTP MCP apps, so a single role option shape covers both proxies.
+type toolAuthorizer struct {
+	checker      toolAccessChecker
+	userMetadata apievents.UserMetadata
+	resource     apitypes.ResourceWithLabels
+	emitter      apievents.Emitter
+	logger       *slog.Logger
+}
+
+// newToolAuthorizer returns a toolAuthorizer scoped to a single upstream
+// session. resource is whatever Teleport resource the caller was routed to
+// (e.g. the database or application); its labels are what mcp.tools rules
+// match against.
+func newToolAuthorizer(authCtx *authz.Context, resource apitypes.ResourceWithLabels, emitter apievents.Emitter, logger *slog.Logger) *toolAuthorizer {
+	return &toolAuthorizer{
+		checker:      authCtx.Checker,
+		userMetadata: authCtx.Identity.GetIdentity().GetUserMetadata(),
+		resource:     resource,
+		emitter:      emitter,
+		logger:       logger,
+	}
+}
+
+// FilterTools returns the subset of tools the caller's roles permit to call,
+// in the order the upstream server advertised them.
+func (a *toolAuthorizer) FilterTools(tools []mcp.Tool) []mcp.Tool {
+	allowed := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if a.checkToolAccess(tool.Name) == nil {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
+
+// AuthorizeCallTool checks whether the caller may invoke the named upstream
+// tool and emits an AppSessionMCPRequest-style audit event recording the
+// decision, mirroring how tools/call is audited for stdio/HTTP MCP apps.
+func (a *toolAuthorizer) AuthorizeCallTool(ctx context.Context, sessionID, name string, arguments any) error {
+	authErr := a.checkToolAccess(name)
+	a.emitCallToolEvent(ctx, sessionID, name, arguments, authErr)
+	return trace.Wrap(authErr)
+}
+
+func (a *toolAuthorizer) checkToolAccess(name string) error {
+	accessState := services.AccessState{
+		MFAVerified:    true,
+		DeviceVerified: true,
+	}
+	return trace.Wrap(a.checker.CheckAccess(a.resource, accessState, &services.MCPToolsMatcher{Name: name}))
+}
+
+func (a *toolAuthorizer) emitCallToolEvent(ctx context.Context, sessionID, name string, arguments any, authErr error) {
+	if a.emitter == nil {
+		return
+	}
+
+	status := apievents.Status{Success: true}
+	if authErr != nil {
+		status.Success = false
+		status.Error = authErr.Error()
+	}
+
+	var argsStruct *apievents.Struct
+	if argsJSON, err := json.Marshal(arguments); err != nil {
+		a.logger.WarnContext(ctx, "Failed to encode MCP tool call arguments for audit event", "error", err)
+	} else {
+		argsStruct = &apievents.Struct{}
+		if err := json.Unmarshal(argsJSON, argsStruct); err != nil {
+			a.logger.WarnContext(ctx, "Failed to encode MCP tool call arguments for audit event", "error", err)
+			argsStruct = nil
+		}
+	}
+
+	event := &apievents.AppSessionMCPRequest{
+		UserMetadata:    a.userMetadata,
+		SessionMetadata: apievents.SessionMetadata{SessionID: sessionID},
+		Metadata: apievents.Metadata{
+			Type: events.AppSessionMCPRequestEvent,
+			Code: events.AppSessionMCPRequestCode,
+		},
+		RPCMethod: "tools/call",
+		RPCParams: argsStruct,
+		Status:    status,
+	}
+	if authErr != nil {
+		event.Metadata.Code = events.AppSessionMCPRequestFailureCode
+	}
+
+	if err := a.emitter.EmitAuditEvent(ctx, event); err != nil {
+		a.logger.WarnContext(ctx, "Failed to emit MCP CallTool audit event", "error", err, "tool", name)
+	}
+}
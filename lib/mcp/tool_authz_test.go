@@ -0,0 +1,105 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+
+	apitypes "github.com/gravitational/teleport/api/types"
+	apievents "github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/teleport/lib/services"
+)
+
+// fakeToolAccessChecker denies every tool name in denied and allows
+// everything else, regardless of the resource it's asked about.
+type fakeToolAccessChecker struct {
+	denied map[string]bool
+}
+
+func (f *fakeToolAccessChecker) CheckAccess(_ apitypes.ResourceWithLabels, _ services.AccessState, matchers ...services.RoleMatcher) error {
+	for _, m := range matchers {
+		if tm, ok := m.(*services.MCPToolsMatcher); ok && f.denied[tm.Name] {
+			return trace.AccessDenied("tool %q is denied by role rules", tm.Name)
+		}
+	}
+	return nil
+}
+
+// fakeEmitter records every audit event it's asked to emit.
+type fakeEmitter struct {
+	events []apievents.AuditEvent
+}
+
+func (f *fakeEmitter) EmitAuditEvent(_ context.Context, event apievents.AuditEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func newTestToolAuthorizer(denied ...string) (*toolAuthorizer, *fakeEmitter) {
+	deniedSet := make(map[string]bool, len(denied))
+	for _, name := range denied {
+		deniedSet[name] = true
+	}
+	emitter := &fakeEmitter{}
+	return &toolAuthorizer{
+		checker: &fakeToolAccessChecker{denied: deniedSet},
+		emitter: emitter,
+		logger:  slog.Default(),
+	}, emitter
+}
+
+func TestToolAuthorizerFilterTools(t *testing.T) {
+	authorizer, _ := newTestToolAuthorizer("write_file")
+
+	tools := []mcp.Tool{{Name: "read_file"}, {Name: "write_file"}, {Name: "list_files"}}
+	allowed := authorizer.FilterTools(tools)
+
+	require.Len(t, allowed, 2)
+	require.Equal(t, "read_file", allowed[0].Name)
+	require.Equal(t, "list_files", allowed[1].Name)
+}
+
+func TestToolAuthorizerFilterToolsAllowsEverythingByDefault(t *testing.T) {
+	authorizer, _ := newTestToolAuthorizer()
+
+	tools := []mcp.Tool{{Name: "read_file"}, {Name: "write_file"}}
+	require.Equal(t, tools, authorizer.FilterTools(tools))
+}
+
+func TestToolAuthorizerAuthorizeCallTool(t *testing.T) {
+	authorizer, emitter := newTestToolAuthorizer("write_file")
+
+	require.NoError(t, authorizer.AuthorizeCallTool(context.Background(), "sess-1", "read_file", nil))
+	require.Len(t, emitter.events, 1)
+
+	err := authorizer.AuthorizeCallTool(context.Background(), "sess-1", "write_file", map[string]any{"path": "/etc/passwd"})
+	require.Error(t, err)
+	require.True(t, trace.IsAccessDenied(err))
+	require.Len(t, emitter.events, 2)
+
+	denied, ok := emitter.events[1].(*apievents.AppSessionMCPRequest)
+	require.True(t, ok)
+	require.False(t, denied.Status.Success)
+}
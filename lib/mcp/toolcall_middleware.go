@@ -0,0 +1,536 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	apievents "github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/teleport/lib/events"
+)
+
+// toolsCallMethod is the JSON-RPC method name for an MCP tool invocation.
+const toolsCallMethod = "tools/call"
+
+// toolsListMethod is the JSON-RPC method name for listing available tools.
+const toolsListMethod = "tools/list"
+
+// defaultToolCallQuota caps the number of tools/call invocations a single
+// database-routed MCP session may make, as a coarse guard against a
+// runaway or compromised client hammering the upstream database through
+// repeated tool calls.
+const defaultToolCallQuota = 1000
+
+// ToolCallInfo describes a single tools/call invocation observed on the
+// wire between a client and the in-process MCP server for a
+// database-routed MCP session.
+type ToolCallInfo struct {
+	// SessionID identifies the MCP session the call belongs to.
+	SessionID string
+	// Route is the database the session was routed to.
+	Route RouteToDatabase
+	// UserMetadata identifies the Teleport user driving the session.
+	UserMetadata apievents.UserMetadata
+
+	// ToolName is the invoked tool's name.
+	ToolName string
+	// Arguments are the tool's unredacted call arguments.
+	Arguments map[string]any
+}
+
+// ToolCallMiddleware observes, and may reject, a tools/call invocation.
+// BeforeCall runs before the request reaches the in-process MCP server; an
+// error stops the call from reaching it and is returned to the client
+// instead. AfterCall runs once the call's outcome is known (the server's
+// response, or a BeforeCall rejection) and cannot reject anything.
+type ToolCallMiddleware interface {
+	BeforeCall(ctx context.Context, info ToolCallInfo) error
+	AfterCall(ctx context.Context, info ToolCallInfo, resultErr error)
+}
+
+// ToolCallMiddlewareChain composes several ToolCallMiddleware stages into
+// one. BeforeCall stops at the first stage that rejects the call; AfterCall
+// always runs every stage, in order, regardless of what BeforeCall decided.
+type ToolCallMiddlewareChain []ToolCallMiddleware
+
+// BeforeCall implements ToolCallMiddleware.
+func (c ToolCallMiddlewareChain) BeforeCall(ctx context.Context, info ToolCallInfo) error {
+	for _, mw := range c {
+		if err := mw.BeforeCall(ctx, info); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// AfterCall implements ToolCallMiddleware.
+func (c ToolCallMiddlewareChain) AfterCall(ctx context.Context, info ToolCallInfo, resultErr error) {
+	for _, mw := range c {
+		mw.AfterCall(ctx, info, resultErr)
+	}
+}
+
+// toolCallRedactionRules maps a tool name to the argument field names that
+// must be stripped before a call is audited. The "*" entry applies to every
+// tool regardless of name. This is intentionally a flat, declarative rule
+// set rather than full JSONSchema validation, since the upstream tool
+// schemas (e.g. pgmcp's) aren't known to this package; add an entry here
+// for any tool whose schema exposes a sensitive field this default list
+// doesn't already cover.
+var toolCallRedactionRules = map[string][]string{
+	"*": {"password", "dsn", "connection_string", "secret", "token"},
+}
+
+// redactToolCallArguments returns a copy of args with every field named in
+// toolCallRedactionRules (for "*" and toolName, case-insensitively)
+// replaced with a placeholder.
+func redactToolCallArguments(toolName string, args map[string]any) map[string]any {
+	if len(args) == 0 {
+		return args
+	}
+
+	redactedFields := make(map[string]bool, len(toolCallRedactionRules["*"]))
+	for _, field := range toolCallRedactionRules["*"] {
+		redactedFields[field] = true
+	}
+	for _, field := range toolCallRedactionRules[toolName] {
+		redactedFields[field] = true
+	}
+
+	redacted := make(map[string]any, len(args))
+	for k, v := range args {
+		if redactedFields[strings.ToLower(k)] {
+			redacted[k] = "<redacted>"
+			continue
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// auditToolCallMiddleware emits a db.mcp.tool.call audit event for every
+// tools/call invocation on a database-routed MCP session. It does the same
+// job as toolAuthorizer.emitCallToolEvent (tool_authz.go), but as a
+// standalone ToolCallMiddleware stage and
+// scoped to the DatabaseSessionMCPToolCall event family rather than
+// AppSessionMCPRequest, since this session has no application resource to
+// attach app-session events to.
+type auditToolCallMiddleware struct {
+	emitter apievents.Emitter
+	logger  *slog.Logger
+}
+
+// newAuditToolCallMiddleware returns a ToolCallMiddleware that audits every
+// call. A nil emitter disables emission; BeforeCall still always succeeds.
+func newAuditToolCallMiddleware(emitter apievents.Emitter, logger *slog.Logger) *auditToolCallMiddleware {
+	return &auditToolCallMiddleware{emitter: emitter, logger: logger}
+}
+
+// BeforeCall implements ToolCallMiddleware. Auditing only observes calls, so
+// it never rejects one.
+func (a *auditToolCallMiddleware) BeforeCall(context.Context, ToolCallInfo) error {
+	return nil
+}
+
+// AfterCall implements ToolCallMiddleware.
+func (a *auditToolCallMiddleware) AfterCall(ctx context.Context, info ToolCallInfo, resultErr error) {
+	if a.emitter == nil {
+		return
+	}
+
+	status := apievents.Status{Success: true}
+	code := events.DatabaseSessionMCPToolCallCode
+	if resultErr != nil {
+		status.Success = false
+		status.Error = resultErr.Error()
+		code = events.DatabaseSessionMCPToolCallFailureCode
+	}
+
+	var argsStruct *apievents.Struct
+	redacted := redactToolCallArguments(info.ToolName, info.Arguments)
+	if argsJSON, err := json.Marshal(redacted); err != nil {
+		a.logger.WarnContext(ctx, "Failed to encode MCP tool call arguments for audit event", "error", err)
+	} else {
+		argsStruct = &apievents.Struct{}
+		if err := json.Unmarshal(argsJSON, argsStruct); err != nil {
+			a.logger.WarnContext(ctx, "Failed to encode MCP tool call arguments for audit event", "error", err)
+			argsStruct = nil
+		}
+	}
+
+	event := &apievents.DatabaseSessionMCPToolCall{
+		UserMetadata:    info.UserMetadata,
+		SessionMetadata: apievents.SessionMetadata{SessionID: info.SessionID},
+		Metadata: apievents.Metadata{
+			Type: events.DatabaseSessionMCPToolCallEvent,
+			Code: code,
+		},
+		DatabaseMetadata: apievents.DatabaseMetadata{
+			DatabaseService:  info.Route.ServiceName,
+			DatabaseProtocol: info.Route.Protocol,
+			DatabaseName:     info.Route.Database,
+			DatabaseUser:     info.Route.Username,
+		},
+		RPCMethod: toolsCallMethod,
+		ToolName:  info.ToolName,
+		RPCParams: argsStruct,
+		Status:    status,
+	}
+
+	if err := a.emitter.EmitAuditEvent(ctx, event); err != nil {
+		a.logger.WarnContext(ctx, "Failed to emit MCP tool call audit event", "error", err, "tool", info.ToolName)
+	}
+}
+
+// rateLimitToolCallMiddleware enforces a simple per-session tool call
+// quota. It's the second built-in middleware, alongside
+// auditToolCallMiddleware, so the chain demonstrates both an observe-only
+// stage and a stage that can actually reject a call.
+type rateLimitToolCallMiddleware struct {
+	maxCalls int
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// newRateLimitToolCallMiddleware returns a ToolCallMiddleware rejecting any
+// call past the maxCalls'th for a given session.
+func newRateLimitToolCallMiddleware(maxCalls int) *rateLimitToolCallMiddleware {
+	return &rateLimitToolCallMiddleware{
+		maxCalls: maxCalls,
+		counts:   make(map[string]int),
+	}
+}
+
+// BeforeCall implements ToolCallMiddleware.
+func (r *rateLimitToolCallMiddleware) BeforeCall(_ context.Context, info ToolCallInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[info.SessionID]++
+	if r.counts[info.SessionID] > r.maxCalls {
+		return trace.LimitExceeded("MCP session %s exceeded its tool call quota of %d", info.SessionID, r.maxCalls)
+	}
+	return nil
+}
+
+// AfterCall implements ToolCallMiddleware. The quota is enforced entirely
+// in BeforeCall, so there's nothing to do once the call has happened.
+func (r *rateLimitToolCallMiddleware) AfterCall(context.Context, ToolCallInfo, error) {}
+
+// rbacToolCallMiddleware rejects a tools/call invocation the caller's
+// Teleport roles don't permit, using toolAuthorizer (tool_authz.go), which
+// checks the same services.MCPToolsMatcher lib/srv/app uses to gate
+// tools/call for stdio/HTTP MCP apps, so database-routed MCP sessions get
+// the same mcp.tools enforcement.
+type rbacToolCallMiddleware struct {
+	authorizer *toolAuthorizer
+}
+
+// newRBACToolCallMiddleware returns a ToolCallMiddleware that denies any
+// tools/call authorizer doesn't permit. It deliberately doesn't audit the
+// decision itself - auditToolCallMiddleware already covers every call on
+// this chain, including ones this stage rejects.
+func newRBACToolCallMiddleware(authorizer *toolAuthorizer) *rbacToolCallMiddleware {
+	return &rbacToolCallMiddleware{authorizer: authorizer}
+}
+
+// BeforeCall implements ToolCallMiddleware.
+func (r *rbacToolCallMiddleware) BeforeCall(_ context.Context, info ToolCallInfo) error {
+	return trace.Wrap(r.authorizer.checkToolAccess(info.ToolName))
+}
+
+// AfterCall implements ToolCallMiddleware. Authorization is decided entirely
+// in BeforeCall, so there's nothing to do once the call has happened.
+func (r *rbacToolCallMiddleware) AfterCall(context.Context, ToolCallInfo, error) {}
+
+// jsonRPCBaseMessage is the minimal shape of a JSON-RPC 2.0 message needed
+// to recognize tools/call requests and correlate their responses.
+type jsonRPCBaseMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      any             `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// toolCallParams is the params shape of a tools/call request.
+type toolCallParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// pendingToolCalls correlates a tools/call response back to the request
+// that triggered it, so AfterCall can be given the same ToolCallInfo
+// BeforeCall saw, keyed by JSON-RPC ID.
+type pendingToolCalls struct {
+	mu    sync.Mutex
+	calls map[string]ToolCallInfo
+}
+
+func newPendingToolCalls() *pendingToolCalls {
+	return &pendingToolCalls{calls: make(map[string]ToolCallInfo)}
+}
+
+func (p *pendingToolCalls) put(id string, info ToolCallInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls[id] = info
+}
+
+func (p *pendingToolCalls) take(id string) (ToolCallInfo, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info, ok := p.calls[id]
+	if ok {
+		delete(p.calls, id)
+	}
+	return info, ok
+}
+
+// WrapToolCallSession returns the reader/writer pair that should be handed
+// to server.NewStdioServer(...).Listen in place of clientConn directly. It
+// sits between the client and the in-process MCP server: every tools/call
+// request read from clientConn runs through chain.BeforeCall before being
+// forwarded, and every matching response runs through chain.AfterCall once
+// the result is known. A call chain.BeforeCall rejects is answered directly
+// on clientConn and never reaches the MCP server. This mirrors how
+// lib/srv/app's authorizedReader/dumpWriter intercept the same JSON-RPC
+// methods for stdio/HTTP MCP apps, adapted to an in-process server with no
+// separate upstream transport to forward through.
+//
+// toolFilter, if non-nil, is applied to every tools/list response before
+// it's forwarded, so a tool chain would reject is never advertised to the
+// client in the first place - not just blocked when called. A nil
+// toolFilter forwards tools/list responses unchanged.
+func WrapToolCallSession(ctx context.Context, clientConn net.Conn, chain ToolCallMiddleware, info ToolCallInfo, toolFilter func([]mcp.Tool) []mcp.Tool, logger *slog.Logger) io.ReadWriteCloser {
+	serverSide, relaySide := net.Pipe()
+
+	pending := newPendingToolCalls()
+	pendingLists := newPendingListIDs()
+
+	go relayClientRequests(ctx, clientConn, relaySide, chain, info, pending, pendingLists, logger)
+	go relayServerMessages(ctx, relaySide, clientConn, chain, pending, pendingLists, toolFilter, logger)
+
+	return serverSide
+}
+
+// relayClientRequests reads line-delimited JSON-RPC messages from
+// clientConn, running each tools/call request through chain.BeforeCall
+// before forwarding it to relaySide (read by the in-process MCP server). A
+// rejected call is answered on clientConn and never forwarded. tools/list
+// requests are forwarded unfiltered - relayServerMessages filters their
+// response instead, once the full tool list is known.
+func relayClientRequests(ctx context.Context, clientConn net.Conn, relaySide net.Conn, chain ToolCallMiddleware, sessionInfo ToolCallInfo, pending *pendingToolCalls, pendingLists *pendingListIDs, logger *slog.Logger) {
+	defer relaySide.Close()
+
+	scanner := bufio.NewScanner(clientConn)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg jsonRPCBaseMessage
+		if err := json.Unmarshal(line, &msg); err != nil || (msg.Method != toolsCallMethod && msg.Method != toolsListMethod) {
+			if _, err := fmt.Fprintf(relaySide, "%s\n", line); err != nil {
+				return
+			}
+			continue
+		}
+
+		if msg.Method == toolsListMethod {
+			pendingLists.add(fmt.Sprintf("%v", msg.ID))
+			if _, err := fmt.Fprintf(relaySide, "%s\n", line); err != nil {
+				return
+			}
+			continue
+		}
+
+		var params toolCallParams
+		_ = json.Unmarshal(msg.Params, &params)
+
+		callInfo := sessionInfo
+		callInfo.ToolName = params.Name
+		callInfo.Arguments = params.Arguments
+
+		if err := chain.BeforeCall(ctx, callInfo); err != nil {
+			logger.WarnContext(ctx, "Rejected MCP tool call", "tool", params.Name, "error", err)
+			writeToolCallRejection(clientConn, msg.ID, err)
+			chain.AfterCall(ctx, callInfo, err)
+			continue
+		}
+
+		pending.put(fmt.Sprintf("%v", msg.ID), callInfo)
+		if _, err := fmt.Fprintf(relaySide, "%s\n", line); err != nil {
+			return
+		}
+	}
+}
+
+// relayServerMessages reads line-delimited JSON-RPC messages from relaySide
+// (written by the in-process MCP server), running chain.AfterCall for any
+// response that correlates to a pending tools/call and toolFilter for any
+// response that correlates to a pending tools/list, then forwards the
+// (possibly filtered) message to clientConn.
+func relayServerMessages(ctx context.Context, relaySide net.Conn, clientConn net.Conn, chain ToolCallMiddleware, pending *pendingToolCalls, pendingLists *pendingListIDs, toolFilter func([]mcp.Tool) []mcp.Tool, logger *slog.Logger) {
+	defer clientConn.Close()
+
+	scanner := bufio.NewScanner(relaySide)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg jsonRPCBaseMessage
+		if err := json.Unmarshal(line, &msg); err == nil && msg.ID != nil {
+			idKey := fmt.Sprintf("%v", msg.ID)
+			if callInfo, ok := pending.take(idKey); ok {
+				var resultErr error
+				if msg.Error != nil {
+					resultErr = trace.Errorf("%s", msg.Error.Message)
+				}
+				chain.AfterCall(ctx, callInfo, resultErr)
+			} else if pendingLists.take(idKey) && toolFilter != nil {
+				if filtered, ok := filterToolsListResponse(line, toolFilter); ok {
+					line = filtered
+				}
+			}
+		}
+
+		if _, err := fmt.Fprintf(clientConn, "%s\n", line); err != nil {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.WarnContext(ctx, "MCP tool call interceptor stopped relaying server messages", "error", err)
+	}
+}
+
+// pendingListIDs tracks the JSON-RPC IDs of in-flight tools/list requests,
+// so relayServerMessages knows which responses to run through toolFilter.
+type pendingListIDs struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+func newPendingListIDs() *pendingListIDs {
+	return &pendingListIDs{ids: make(map[string]bool)}
+}
+
+func (p *pendingListIDs) add(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ids[id] = true
+}
+
+// take reports whether id was a pending tools/list request, removing it
+// either way.
+func (p *pendingListIDs) take(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ok := p.ids[id]
+	delete(p.ids, id)
+	return ok
+}
+
+// filterToolsListResponse applies filter to the Tools in a tools/list
+// response line, re-marshaling the result with every other field (e.g.
+// nextCursor) left untouched. ok is false, and line should be forwarded
+// unchanged, if line doesn't have the expected result shape.
+func filterToolsListResponse(line []byte, filter func([]mcp.Tool) []mcp.Tool) (filtered []byte, ok bool) {
+	var msg struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      any             `json:"id,omitempty"`
+		Result  json.RawMessage `json:"result,omitempty"`
+	}
+	if err := json.Unmarshal(line, &msg); err != nil || len(msg.Result) == 0 {
+		return line, false
+	}
+
+	var resultFields map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Result, &resultFields); err != nil {
+		return line, false
+	}
+	var result struct {
+		Tools []mcp.Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		return line, false
+	}
+
+	toolsJSON, err := json.Marshal(filter(result.Tools))
+	if err != nil {
+		return line, false
+	}
+	resultFields["tools"] = toolsJSON
+
+	newResult, err := json.Marshal(resultFields)
+	if err != nil {
+		return line, false
+	}
+	msg.Result = newResult
+
+	out, err := json.Marshal(msg)
+	if err != nil {
+		return line, false
+	}
+	return out, true
+}
+
+// writeToolCallRejection writes a JSON-RPC error response for id directly
+// to conn, standing in for the response the MCP server would have sent had
+// the call been forwarded to it.
+func writeToolCallRejection(conn net.Conn, id any, rejectErr error) {
+	resp := struct {
+		JSONRPC string `json:"jsonrpc"`
+		ID      any    `json:"id,omitempty"`
+		Error   struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}{
+		JSONRPC: "2.0",
+		ID:      id,
+	}
+	resp.Error.Code = -32000
+	resp.Error.Message = rejectErr.Error()
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(conn, "%s\n", data)
+}
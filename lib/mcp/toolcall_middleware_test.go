@@ -0,0 +1,204 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactToolCallArguments(t *testing.T) {
+	args := map[string]any{
+		"Password": "hunter2",
+		"dsn":      "postgres://...",
+		"query":    "select 1",
+	}
+
+	redacted := redactToolCallArguments("run_query", args)
+	require.Equal(t, "<redacted>", redacted["Password"])
+	require.Equal(t, "<redacted>", redacted["dsn"])
+	require.Equal(t, "select 1", redacted["query"])
+
+	// The original map is untouched.
+	require.Equal(t, "hunter2", args["Password"])
+}
+
+// countingMiddleware records every BeforeCall/AfterCall invocation it sees,
+// so tests can assert on chain ordering.
+type countingMiddleware struct {
+	name      string
+	before    []string
+	after     []string
+	beforeErr error
+}
+
+func (c *countingMiddleware) BeforeCall(_ context.Context, _ ToolCallInfo) error {
+	c.before = append(c.before, c.name)
+	return c.beforeErr
+}
+
+func (c *countingMiddleware) AfterCall(_ context.Context, _ ToolCallInfo, _ error) {
+	c.after = append(c.after, c.name)
+}
+
+func TestToolCallMiddlewareChainStopsAtFirstRejection(t *testing.T) {
+	first := &countingMiddleware{name: "first", beforeErr: trace.LimitExceeded("quota exceeded")}
+	second := &countingMiddleware{name: "second"}
+	chain := ToolCallMiddlewareChain{first, second}
+
+	err := chain.BeforeCall(context.Background(), ToolCallInfo{SessionID: "sess-1"})
+	require.True(t, trace.IsLimitExceeded(err))
+	require.Equal(t, []string{"first"}, first.before)
+	require.Empty(t, second.before)
+
+	// AfterCall always runs every stage, regardless of what BeforeCall did.
+	chain.AfterCall(context.Background(), ToolCallInfo{SessionID: "sess-1"}, nil)
+	require.Equal(t, []string{"first"}, first.after)
+	require.Equal(t, []string{"second"}, second.after)
+}
+
+func TestRateLimitToolCallMiddleware(t *testing.T) {
+	mw := newRateLimitToolCallMiddleware(2)
+	info := ToolCallInfo{SessionID: "sess-1"}
+
+	require.NoError(t, mw.BeforeCall(context.Background(), info))
+	require.NoError(t, mw.BeforeCall(context.Background(), info))
+	err := mw.BeforeCall(context.Background(), info)
+	require.True(t, trace.IsLimitExceeded(err))
+
+	// A different session has its own quota.
+	require.NoError(t, mw.BeforeCall(context.Background(), ToolCallInfo{SessionID: "sess-2"}))
+}
+
+func TestRBACToolCallMiddlewareRejectsDeniedTool(t *testing.T) {
+	authorizer, _ := newTestToolAuthorizer("write_file")
+	mw := newRBACToolCallMiddleware(authorizer)
+
+	require.NoError(t, mw.BeforeCall(context.Background(), ToolCallInfo{ToolName: "read_file"}))
+
+	err := mw.BeforeCall(context.Background(), ToolCallInfo{ToolName: "write_file"})
+	require.True(t, trace.IsAccessDenied(err))
+}
+
+func TestFilterToolsListResponse(t *testing.T) {
+	line := []byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"read_file"},{"name":"write_file"}],"nextCursor":"abc"}}`)
+
+	onlyReadFile := func(tools []mcp.Tool) []mcp.Tool {
+		var kept []mcp.Tool
+		for _, tool := range tools {
+			if tool.Name == "read_file" {
+				kept = append(kept, tool)
+			}
+		}
+		return kept
+	}
+
+	filtered, ok := filterToolsListResponse(line, onlyReadFile)
+	require.True(t, ok)
+
+	var decoded struct {
+		Result struct {
+			Tools      []mcp.Tool `json:"tools"`
+			NextCursor string     `json:"nextCursor"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(filtered, &decoded))
+	require.Len(t, decoded.Result.Tools, 1)
+	require.Equal(t, "read_file", decoded.Result.Tools[0].Name)
+	// Unrelated fields (e.g. pagination cursors) survive the round-trip.
+	require.Equal(t, "abc", decoded.Result.NextCursor)
+}
+
+func TestFilterToolsListResponseLeavesUnrecognizedLinesUnchanged(t *testing.T) {
+	line := []byte(`not json`)
+	filtered, ok := filterToolsListResponse(line, func(tools []mcp.Tool) []mcp.Tool { return tools })
+	require.False(t, ok)
+	require.Equal(t, line, filtered)
+}
+
+// TestWrapToolCallSessionBlocksAndHidesDeniedTool proves a tool denied by
+// the RBAC middleware is both rejected on tools/call and hidden from
+// tools/list, end to end through WrapToolCallSession.
+func TestWrapToolCallSessionBlocksAndHidesDeniedTool(t *testing.T) {
+	authorizer, _ := newTestToolAuthorizer("write_file")
+	chain := ToolCallMiddlewareChain{newRBACToolCallMiddleware(authorizer)}
+
+	clientConn, wrappedConn := net.Pipe()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serverSide := WrapToolCallSession(context.Background(), wrappedConn, chain, ToolCallInfo{SessionID: "sess-1"}, authorizer.FilterTools, logger)
+	defer serverSide.Close()
+	defer clientConn.Close()
+
+	// Fake "in-process MCP server" on the other end of serverSide: echoes a
+	// canned tools/list result for any tools/list request, and a canned
+	// success result for any tools/call request that reaches it.
+	go func() {
+		scanner := bufio.NewScanner(serverSide)
+		for scanner.Scan() {
+			var msg jsonRPCBaseMessage
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				continue
+			}
+			switch msg.Method {
+			case toolsListMethod:
+				fmt.Fprintf(serverSide, `{"jsonrpc":"2.0","id":%v,"result":{"tools":[{"name":"read_file"},{"name":"write_file"}]}}`+"\n", msg.ID)
+			case toolsCallMethod:
+				fmt.Fprintf(serverSide, `{"jsonrpc":"2.0","id":%v,"result":{"content":[]}}`+"\n", msg.ID)
+			}
+		}
+	}()
+
+	clientReader := bufio.NewScanner(clientConn)
+	readLine := func() map[string]any {
+		require.True(t, clientReader.Scan())
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(clientReader.Bytes(), &decoded))
+		return decoded
+	}
+
+	// tools/list: write_file must be filtered out of the result.
+	fmt.Fprintln(clientConn, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	listResp := readLine()
+	result := listResp["result"].(map[string]any)
+	tools := result["tools"].([]any)
+	require.Len(t, tools, 1)
+	require.Equal(t, "read_file", tools[0].(map[string]any)["name"])
+
+	// tools/call on the allowed tool reaches the fake server and succeeds.
+	fmt.Fprintln(clientConn, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"read_file"}}`)
+	allowedResp := readLine()
+	require.Nil(t, allowedResp["error"])
+
+	// tools/call on the denied tool never reaches the fake server - it's
+	// answered directly with an error.
+	fmt.Fprintln(clientConn, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"write_file"}}`)
+	deniedResp := readLine()
+	require.Nil(t, deniedResp["result"])
+	require.NotNil(t, deniedResp["error"])
+}
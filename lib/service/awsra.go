@@ -48,6 +48,13 @@ func (process *TeleportProcess) initAWSRAProfileSync() error {
 		return trace.Errorf("instance client not yet initialized")
 	}
 
+	credentialsCache, err := awsra.NewCredentialsCache(awsra.CredentialsCacheConfig{
+		Clock: process.Clock,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
 	for {
 		pollInterval := time.Second * 20
 		resourceLifetime := pollInterval * 2
@@ -102,7 +109,7 @@ func (process *TeleportProcess) initAWSRAProfileSync() error {
 				return trace.Wrap(err)
 			}
 
-			resp, err := awsra.GenerateAWSRACredentials(ctx, awsra.GenerateAWSRACredentialsRequest{
+			resp, err := credentialsCache.Get(ctx, awsra.GenerateAWSRACredentialsRequest{
 				Clock:                process.Clock,
 				TrustAnchorARN:       trustAnchorARN,
 				ProfileARN:           profileSyncProfileARN,
@@ -0,0 +1,146 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	apievents "github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/tlsca"
+)
+
+// mcpMessageDirection identifies which side of the MCP stdio pipe a recorded
+// message travelled on.
+type mcpMessageDirection string
+
+const (
+	// mcpMessageDirectionClient marks a message sent by the client (requests
+	// and notifications).
+	mcpMessageDirectionClient mcpMessageDirection = "client"
+	// mcpMessageDirectionServer marks a message sent by the upstream MCP
+	// server (responses, notifications and tool-call results).
+	mcpMessageDirectionServer mcpMessageDirection = "server"
+)
+
+// defaultMCPRedactedMethods lists JSON-RPC methods whose params are replaced
+// with a placeholder in the session recording because they are likely to
+// carry sensitive data, such as sampling prompts.
+var defaultMCPRedactedMethods = map[string]bool{
+	string(mcp.MethodSamplingCreateMessage): true,
+}
+
+// MCPSessionRecorder records every JSON-RPC message exchanged over an MCP
+// session, in order, to the session recording storage so it can later be
+// replayed with `tsh mcp sessions play`. It writes one
+// AppSessionMCPSessionChunk audit event per message to stream, reusing the
+// same session chunks uploader as SSH and Kubernetes session recording.
+type MCPSessionRecorder struct {
+	stream          events.StreamWriter
+	identity        *tlsca.Identity
+	sessionID       string
+	redactedMethods map[string]bool
+	clock           clockwork.Clock
+	log             *slog.Logger
+}
+
+// NewMCPSessionRecorder returns a recorder that writes to stream. A nil
+// redactedMethods keeps the default redaction list; pass an empty, non-nil
+// slice to disable redaction entirely.
+func NewMCPSessionRecorder(stream events.StreamWriter, identity *tlsca.Identity, sessionID string, redactedMethods []string, clock clockwork.Clock, log *slog.Logger) *MCPSessionRecorder {
+	redacted := defaultMCPRedactedMethods
+	if redactedMethods != nil {
+		redacted = make(map[string]bool, len(redactedMethods))
+		for _, method := range redactedMethods {
+			redacted[method] = true
+		}
+	}
+	if clock == nil {
+		clock = clockwork.NewRealClock()
+	}
+	return &MCPSessionRecorder{
+		stream:          stream,
+		identity:        identity,
+		sessionID:       sessionID,
+		redactedMethods: redacted,
+		clock:           clock,
+		log:             log,
+	}
+}
+
+// Record appends a single JSON-RPC message to the recording. It is safe to
+// call on a nil recorder (recording disabled).
+func (r *MCPSessionRecorder) Record(ctx context.Context, direction mcpMessageDirection, raw []byte) {
+	if r == nil || r.stream == nil {
+		return
+	}
+
+	payload := r.maybeRedact(raw)
+	event := &apievents.AppSessionMCPSessionChunk{
+		Metadata: apievents.Metadata{
+			Type: events.AppSessionMCPSessionChunkEvent,
+			Time: r.clock.Now().UTC(),
+		},
+		SessionMetadata: apievents.SessionMetadata{SessionID: r.sessionID},
+		UserMetadata:    r.identity.GetUserMetadata(),
+		Direction:       string(direction),
+		Payload:         payload,
+	}
+
+	if err := r.stream.EmitAuditEvent(ctx, event); err != nil {
+		r.log.WarnContext(ctx, "Failed to record MCP session chunk", "error", err)
+	}
+}
+
+// maybeRedact replaces the params of raw with a placeholder if its method is
+// in the redaction list, leaving the message unchanged otherwise.
+func (r *MCPSessionRecorder) maybeRedact(raw []byte) []byte {
+	var base struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(raw, &base); err != nil || !r.redactedMethods[base.Method] {
+		return raw
+	}
+
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return raw
+	}
+	msg["params"] = json.RawMessage(`"<redacted>"`)
+	redacted, err := json.Marshal(msg)
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
+
+// Close flushes and closes the underlying recording stream. It is safe to
+// call on a nil recorder.
+func (r *MCPSessionRecorder) Close(ctx context.Context) error {
+	if r == nil || r.stream == nil {
+		return nil
+	}
+	return trace.Wrap(r.stream.Close(ctx))
+}
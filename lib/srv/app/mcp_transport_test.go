@@ -0,0 +1,64 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apitypes "github.com/gravitational/teleport/api/types"
+)
+
+func TestDetectUpstreamTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     apitypes.AppSpecV3
+		expected mcpUpstreamTransport
+	}{
+		{
+			name:     "stdio command",
+			spec:     apitypes.AppSpecV3{MCP: &apitypes.MCP{Command: "npx", Args: []string{"server"}}},
+			expected: mcpUpstreamTransportStdio,
+		},
+		{
+			name:     "sse uri",
+			spec:     apitypes.AppSpecV3{URI: "https://upstream.example.com/sse"},
+			expected: mcpUpstreamTransportSSE,
+		},
+		{
+			name:     "sse uri with trailing slash",
+			spec:     apitypes.AppSpecV3{URI: "https://upstream.example.com/sse/"},
+			expected: mcpUpstreamTransportSSE,
+		},
+		{
+			name:     "streamable http uri",
+			spec:     apitypes.AppSpecV3{URI: "https://upstream.example.com/mcp"},
+			expected: mcpUpstreamTransportStreamableHTTP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := apitypes.NewAppV3(apitypes.Metadata{Name: "test-app"}, tt.spec)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, detectUpstreamTransport(app))
+		})
+	}
+}
@@ -3,7 +3,11 @@ package app
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gravitational/trace"
 	"github.com/mark3labs/mcp-go/mcp"
 
@@ -11,17 +15,138 @@ import (
 	"github.com/gravitational/teleport/lib/events"
 )
 
-// mcpMessageToEvent handles a single JSON-RPC message and either returns audit event (possibly empty) or error.
-func mcpMessageToEvent(line string, userMeta apievents.UserMetadata, sessionMeta apievents.SessionMetadata, authErr error) (apievents.AuditEvent, bool, error) {
+// pendingRequestTTL bounds how long an unanswered request is tracked before
+// it's evicted, so a client that sends a request and never gets (or never
+// sends) a matching response doesn't leak memory for the life of a
+// long-running session.
+const pendingRequestTTL = 5 * time.Minute
+
+// pendingRequest is what pendingRequestTracker remembers about an in-flight
+// JSON-RPC request so a later response can be correlated back to it.
+type pendingRequest struct {
+	method    string
+	startedAt time.Time
+}
+
+type pendingRequestKey struct {
+	sessionID string
+	rpcID     string
+}
+
+// pendingRequestTracker correlates JSON-RPC responses back to the request
+// that triggered them, keyed by session ID and RPC ID, so that
+// AppSessionMCPResponse events can record the originating method and
+// elapsed latency instead of just a bare result/error payload.
+type pendingRequestTracker struct {
+	mu      sync.Mutex
+	pending map[pendingRequestKey]pendingRequest
+}
+
+func newPendingRequestTracker() *pendingRequestTracker {
+	return &pendingRequestTracker{
+		pending: make(map[pendingRequestKey]pendingRequest),
+	}
+}
+
+// start records that sessionID sent a request with rpcID for method.
+func (t *pendingRequestTracker) start(sessionID, rpcID, method string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.evictExpiredLocked()
+	t.pending[pendingRequestKey{sessionID, rpcID}] = pendingRequest{
+		method:    method,
+		startedAt: time.Now(),
+	}
+}
+
+// finish removes and returns the pending request for sessionID/rpcID, if
+// still tracked.
+func (t *pendingRequestTracker) finish(sessionID, rpcID string) (pendingRequest, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := pendingRequestKey{sessionID, rpcID}
+	req, ok := t.pending[key]
+	if ok {
+		delete(t.pending, key)
+	}
+	return req, ok
+}
+
+func (t *pendingRequestTracker) evictExpiredLocked() {
+	if len(t.pending) == 0 {
+		return
+	}
+	cutoff := time.Now().Add(-pendingRequestTTL)
+	for key, req := range t.pending {
+		if req.startedAt.Before(cutoff) {
+			delete(t.pending, key)
+		}
+	}
+}
+
+// mcpMessagesToEvents parses a single JSON-RPC message or, per the JSON-RPC
+// 2.0 spec, a batch of them (a top-level array), and returns the audit
+// events that should be emitted for it. Batch elements share a generated
+// BatchID so they can be correlated back to the same wire message in the
+// audit log. tracker may be nil, in which case response events are still
+// emitted but without a correlated method/latency.
+func mcpMessagesToEvents(direction mcpMessageDirection, raw string, userMeta apievents.UserMetadata, sessionMeta apievents.SessionMetadata, authErr error, tracker *pendingRequestTracker) ([]apievents.AuditEvent, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	var rawMessages []json.RawMessage
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal([]byte(trimmed), &rawMessages); err != nil {
+			return nil, trace.Wrap(err, "failed to parse MCP batch message")
+		}
+	} else {
+		rawMessages = []json.RawMessage{json.RawMessage(trimmed)}
+	}
+
+	var batchID string
+	if len(rawMessages) > 1 {
+		batchID = uuid.NewString()
+	}
+
+	var out []apievents.AuditEvent
+	for _, rawMessage := range rawMessages {
+		event, emit, err := mcpMessageToEvent(direction, string(rawMessage), batchID, userMeta, sessionMeta, authErr, tracker)
+		if err != nil {
+			return out, trace.Wrap(err)
+		}
+		if emit {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+// mcpMessageToEvent handles a single JSON-RPC message (never a batch; see
+// mcpMessagesToEvents) and either returns an audit event (possibly none) or
+// an error. direction distinguishes a client request/notification from a
+// server response/notification, since both share the same JSON-RPC shape
+// and can only be told apart by which side sent them and whether the
+// message carries a method or a result/error.
+func mcpMessageToEvent(direction mcpMessageDirection, line string, batchID string, userMeta apievents.UserMetadata, sessionMeta apievents.SessionMetadata, authErr error, tracker *pendingRequestTracker) (apievents.AuditEvent, bool, error) {
 	var baseMessage struct {
 		JSONRPC string            `json:"jsonrpc"`
 		Method  string            `json:"method"`
 		ID      any               `json:"id,omitempty"`
 		Params  *apievents.Struct `json:"params,omitempty"`
+		Result  *apievents.Struct `json:"result,omitempty"`
+		Error   *apievents.Struct `json:"error,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(line), &baseMessage); err != nil {
 		return nil, false, trace.Wrap(err, "failed to parse MCP message")
 	}
+
+	// A response has an ID but no method; only the server sends those.
+	if direction == mcpMessageDirectionServer && baseMessage.Method == "" && baseMessage.ID != nil {
+		return mcpResponseToEvent(baseMessage.ID, baseMessage.Result, baseMessage.Error, batchID, userMeta, sessionMeta, tracker)
+	}
+
 	shouldEmit := shouldEmitMCPEvent(mcp.MCPMethod(baseMessage.Method))
 	if baseMessage.ID == nil {
 		return &apievents.AppSessionMCPNotification{
@@ -34,9 +159,15 @@ func mcpMessageToEvent(line string, userMeta apievents.UserMetadata, sessionMeta
 			JSONRPC:   baseMessage.JSONRPC,
 			RPCMethod: baseMessage.Method,
 			RPCParams: baseMessage.Params,
+			BatchID:   batchID,
 		}, shouldEmit, nil
 	}
 
+	rpcID := fmt.Sprintf("%v", baseMessage.ID)
+	if tracker != nil {
+		tracker.start(sessionMeta.SessionID, rpcID, baseMessage.Method)
+	}
+
 	code := events.AppSessionMCPRequestCode
 	status := apievents.Status{
 		Success: true,
@@ -55,12 +186,59 @@ func mcpMessageToEvent(line string, userMeta apievents.UserMetadata, sessionMeta
 		},
 		JSONRPC:   baseMessage.JSONRPC,
 		RPCMethod: baseMessage.Method,
-		RPCID:     fmt.Sprintf("%v", baseMessage.ID),
+		RPCID:     rpcID,
 		RPCParams: baseMessage.Params,
+		BatchID:   batchID,
 		Status:    status,
 	}, shouldEmit, nil
 }
 
+// mcpResponseToEvent builds an AppSessionMCPResponse event for a JSON-RPC
+// response, correlating it back to the originating request (for its method
+// and elapsed latency) via tracker when possible.
+func mcpResponseToEvent(id any, result, respErr *apievents.Struct, batchID string, userMeta apievents.UserMetadata, sessionMeta apievents.SessionMetadata, tracker *pendingRequestTracker) (apievents.AuditEvent, bool, error) {
+	rpcID := fmt.Sprintf("%v", id)
+
+	var method string
+	var elapsed time.Duration
+	if tracker != nil {
+		if req, ok := tracker.finish(sessionMeta.SessionID, rpcID); ok {
+			method = req.method
+			elapsed = time.Since(req.startedAt)
+		}
+	}
+
+	// Only filter out responses we can positively correlate to a
+	// low-value method (e.g. tools/list); an uncorrelated response is
+	// always emitted since we can't know whether it matters.
+	if method != "" && !shouldEmitMCPEvent(mcp.MCPMethod(method)) {
+		return nil, false, nil
+	}
+
+	code := events.AppSessionMCPResponseCode
+	status := apievents.Status{Success: true}
+	if respErr != nil {
+		status.Success = false
+		code = events.AppSessionMCPResponseFailureCode
+	}
+
+	return &apievents.AppSessionMCPResponse{
+		UserMetadata:    userMeta,
+		SessionMetadata: sessionMeta,
+		Metadata: apievents.Metadata{
+			Type: events.AppSessionMCPResponseEvent,
+			Code: code,
+		},
+		RPCID:               rpcID,
+		RPCMethod:           method,
+		RPCResult:           result,
+		RPCError:            respErr,
+		BatchID:             batchID,
+		ElapsedMilliseconds: elapsed.Milliseconds(),
+		Status:              status,
+	}, true, nil
+}
+
 func shouldEmitMCPEvent(method mcp.MCPMethod) bool {
 	switch method {
 	case mcp.MethodPing,
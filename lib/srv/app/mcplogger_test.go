@@ -0,0 +1,97 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apievents "github.com/gravitational/teleport/api/types/events"
+)
+
+func TestMCPMessagesToEventsRequestResponseCorrelation(t *testing.T) {
+	userMeta := apievents.UserMetadata{User: "alice"}
+	sessionMeta := apievents.SessionMetadata{SessionID: "session-1"}
+	tracker := newPendingRequestTracker()
+
+	reqEvents, err := mcpMessagesToEvents(mcpMessageDirectionClient,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"read_file"}}`,
+		userMeta, sessionMeta, nil, tracker,
+	)
+	require.NoError(t, err)
+	require.Len(t, reqEvents, 1)
+	req, ok := reqEvents[0].(*apievents.AppSessionMCPRequest)
+	require.True(t, ok)
+	require.Equal(t, "tools/call", req.RPCMethod)
+	require.Equal(t, "1", req.RPCID)
+
+	respEvents, err := mcpMessagesToEvents(mcpMessageDirectionServer,
+		`{"jsonrpc":"2.0","id":1,"result":{"ok":true}}`,
+		userMeta, sessionMeta, nil, tracker,
+	)
+	require.NoError(t, err)
+	require.Len(t, respEvents, 1)
+	resp, ok := respEvents[0].(*apievents.AppSessionMCPResponse)
+	require.True(t, ok)
+	require.Equal(t, "1", resp.RPCID)
+	require.Equal(t, "tools/call", resp.RPCMethod)
+	require.True(t, resp.Status.Success)
+}
+
+func TestMCPMessagesToEventsBatch(t *testing.T) {
+	userMeta := apievents.UserMetadata{User: "alice"}
+	sessionMeta := apievents.SessionMetadata{SessionID: "session-1"}
+	tracker := newPendingRequestTracker()
+
+	reqEvents, err := mcpMessagesToEvents(mcpMessageDirectionClient,
+		`[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"a"}},`+
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"b"}}]`,
+		userMeta, sessionMeta, nil, tracker,
+	)
+	require.NoError(t, err)
+	require.Len(t, reqEvents, 2)
+
+	first := reqEvents[0].(*apievents.AppSessionMCPRequest)
+	second := reqEvents[1].(*apievents.AppSessionMCPRequest)
+	require.NotEmpty(t, first.BatchID)
+	require.Equal(t, first.BatchID, second.BatchID)
+}
+
+func TestMCPMessagesToEventsFiltersUninterestingMethods(t *testing.T) {
+	userMeta := apievents.UserMetadata{User: "alice"}
+	sessionMeta := apievents.SessionMetadata{SessionID: "session-1"}
+	tracker := newPendingRequestTracker()
+
+	events, err := mcpMessagesToEvents(mcpMessageDirectionClient,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		userMeta, sessionMeta, nil, tracker,
+	)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// The paired response should also be filtered once correlated back to
+	// tools/list.
+	events, err = mcpMessagesToEvents(mcpMessageDirectionServer,
+		`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`,
+		userMeta, sessionMeta, nil, tracker,
+	)
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
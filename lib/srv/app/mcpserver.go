@@ -19,6 +19,7 @@
 package app
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -30,21 +31,25 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 
 	apitypes "github.com/gravitational/teleport/api/types"
 	apievents "github.com/gravitational/teleport/api/types/events"
 	"github.com/gravitational/teleport/lib/authz"
+	"github.com/gravitational/teleport/lib/events"
 	"github.com/gravitational/teleport/lib/services"
+	"github.com/gravitational/teleport/lib/session"
 	"github.com/gravitational/teleport/lib/tlsca"
 	"github.com/gravitational/teleport/lib/utils"
 	logutils "github.com/gravitational/teleport/lib/utils/log"
 )
 
 type mcpServer struct {
-	emitter apievents.Emitter
-	hostID  string
-	log     *slog.Logger
+	emitter  apievents.Emitter
+	streamer events.Streamer
+	hostID   string
+	log      *slog.Logger
 }
 
 // handleConnection handles connection from an MCP application.
@@ -54,17 +59,30 @@ func (s *mcpServer) handleConnection(ctx context.Context, clientConn net.Conn, a
 
 	log := s.log.With("session", sessionID)
 
+	recorder, err := s.maybeStartRecording(ctx, authCtx, app, sessionID, &identity, log)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer recorder.Close(ctx)
+
+	switch transportKind := detectUpstreamTransport(app); transportKind {
+	case mcpUpstreamTransportStreamableHTTP, mcpUpstreamTransportSSE:
+		log.DebugContext(ctx, "Running mcp", "app", app.GetName(), "transport", transportKind, "uri", app.GetURI())
+		return s.handleHTTPConnection(ctx, clientConn, authCtx, app, transportKind, sessionID, log, recorder)
+	}
+
 	log.DebugContext(ctx, "Running mcp",
 		"app", app.GetName(),
 		"cmd", app.GetMCPCommand(),
 		"args", app.GetMCPArgs(),
 	)
 
-	mkWriter := func(handleName string, emitEvents bool) *dumpWriter {
+	tracker := newPendingRequestTracker()
+	mkWriter := func(handleName string, direction mcpMessageDirection, emitEvents bool) *dumpWriter {
 		if emitEvents {
-			return newDumpWriter(ctx, handleName, s.emitter, log, &identity, sessionID)
+			return newDumpWriter(ctx, handleName, s.emitter, log, &identity, sessionID, recorder, direction, tracker)
 		}
-		return newDumpWriter(ctx, handleName, nil, log, &identity, sessionID)
+		return newDumpWriter(ctx, handleName, nil, log, &identity, sessionID, recorder, direction, tracker)
 	}
 
 	responseWriter := utils.NewSyncWriter(clientConn)
@@ -73,96 +91,317 @@ func (s *mcpServer) handleConnection(ctx context.Context, clientConn net.Conn, a
 	cmd.Stdin = &authorizedReader{
 		ctx:            ctx,
 		clientConn:     clientConn,
-		authCtx:        authCtx,
+		checker:        authCtx.Checker,
 		app:            app,
 		responseWriter: responseWriter,
 		log:            s.log,
-		dumpWriter:     mkWriter("in", true),
+		dumpWriter:     mkWriter("in", mcpMessageDirectionClient, true),
 	}
-	cmd.Stdout = io.MultiWriter(responseWriter, mkWriter("out", false))
-	cmd.Stderr = mkWriter("err", false)
+	cmd.Stdout = io.MultiWriter(responseWriter, mkWriter("out", mcpMessageDirectionServer, true))
+	cmd.Stderr = mkWriter("err", mcpMessageDirectionServer, false)
 	if err := cmd.Start(); err != nil {
 		return trace.Wrap(err)
 	}
 	return cmd.Wait()
 }
 
+// maybeStartRecording returns a recorder for sessionID if the effective
+// role set for authCtx enables MCP session recording, or a nil recorder
+// (which silently no-ops) otherwise.
+func (s *mcpServer) maybeStartRecording(ctx context.Context, authCtx *authz.Context, app apitypes.Application, sessionID string, identity *tlsca.Identity, log *slog.Logger) (*MCPSessionRecorder, error) {
+	if s.streamer == nil || !authCtx.Checker.RecordMCPSession() {
+		return nil, nil
+	}
+
+	stream, err := s.streamer.CreateAuditStream(ctx, session.ID(sessionID))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return NewMCPSessionRecorder(stream, identity, sessionID, app.GetMCPRedactedMethods(), nil, log), nil
+}
+
+// handleHTTPConnection bridges a client speaking line-delimited JSON-RPC
+// over clientConn (the same stdio-style framing tsh uses locally) to an
+// upstream MCP server reachable over HTTP, using either the Streamable HTTP
+// or SSE transport. RBAC enforcement and audit logging reuse the same
+// authorizedReader/dumpWriter machinery as the stdio path.
+func (s *mcpServer) handleHTTPConnection(ctx context.Context, clientConn net.Conn, authCtx *authz.Context, app apitypes.Application, transportKind mcpUpstreamTransport, sessionID string, log *slog.Logger, recorder *MCPSessionRecorder) error {
+	identity := authCtx.Identity.GetIdentity()
+	responseWriter := utils.NewSyncWriter(clientConn)
+	tracker := newPendingRequestTracker()
+	dumpWriter := newDumpWriter(ctx, "in", s.emitter, log, &identity, sessionID, recorder, mcpMessageDirectionClient, tracker)
+	outWriter := newDumpWriter(ctx, "out", s.emitter, log, &identity, sessionID, recorder, mcpMessageDirectionServer, tracker)
+
+	var upstream transport.Interface
+	var err error
+	switch transportKind {
+	case mcpUpstreamTransportSSE:
+		upstream, err = transport.NewSSE(app.GetURI())
+	default:
+		upstream, err = transport.NewStreamableHTTP(app.GetURI())
+	}
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	upstream.SetNotificationHandler(func(notification mcp.JSONRPCNotification) {
+		if notifBytes, err := json.Marshal(notification); err == nil {
+			fmt.Fprintf(responseWriter, "%s\n", notifBytes)
+			outWriter.Write(notifBytes)
+		} else {
+			log.ErrorContext(ctx, "Failed to marshal upstream MCP notification", "error", err)
+		}
+	})
+
+	if err := upstream.Start(ctx); err != nil {
+		return trace.Wrap(err)
+	}
+	defer upstream.Close()
+
+	reader := &authorizedReader{
+		ctx:            ctx,
+		clientConn:     clientConn,
+		checker:        authCtx.Checker,
+		app:            app,
+		responseWriter: responseWriter,
+		log:            s.log,
+		dumpWriter:     dumpWriter,
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var baseMessage struct {
+			ID any `json:"id,omitempty"`
+		}
+		if err := json.Unmarshal(line, &baseMessage); err != nil {
+			log.WarnContext(ctx, "Failed to parse client MCP message", "error", err)
+			continue
+		}
+
+		if baseMessage.ID == nil {
+			var notification mcp.JSONRPCNotification
+			if err := json.Unmarshal(line, &notification); err != nil {
+				log.WarnContext(ctx, "Failed to parse client MCP notification", "error", err)
+				continue
+			}
+			if err := upstream.SendNotification(ctx, notification); err != nil {
+				log.WarnContext(ctx, "Failed to forward notification to upstream MCP server", "error", err)
+			}
+			continue
+		}
+
+		var request transport.JSONRPCRequest
+		if err := json.Unmarshal(line, &request); err != nil {
+			log.WarnContext(ctx, "Failed to parse client MCP request", "error", err)
+			continue
+		}
+
+		resp, err := upstream.SendRequest(ctx, request)
+		if err != nil {
+			log.WarnContext(ctx, "Upstream MCP request failed", "error", err, "method", request.Method)
+			continue
+		}
+		if respBytes, err := json.Marshal(resp); err == nil {
+			fmt.Fprintf(responseWriter, "%s\n", respBytes)
+			outWriter.Write(respBytes)
+		} else {
+			log.ErrorContext(ctx, "Failed to marshal upstream MCP response", "error", err)
+		}
+	}
+
+	return trace.Wrap(scanner.Err())
+}
+
+// accessChecker is the subset of services.AccessChecker that authorizedReader
+// needs to gate a single MCP call. Narrowing to it, rather than holding the
+// full *authz.Context, lets tests fake access decisions without standing up
+// a real AccessChecker.
+type accessChecker interface {
+	CheckAccess(resource apitypes.ResourceWithLabels, state services.AccessState, matchers ...services.RoleMatcher) error
+}
+
 type authorizedReader struct {
 	ctx            context.Context
 	clientConn     io.Reader
-	authCtx        *authz.Context
+	checker        accessChecker
 	app            apitypes.Application
 	responseWriter io.Writer
 	log            *slog.Logger
 	dumpWriter     *dumpWriter
+
+	// scanner splits clientConn into newline-delimited JSON-RPC messages, so
+	// RBAC authorization always sees one complete message at a time. Built
+	// lazily on the first Read, since clientConn isn't necessarily readable
+	// yet at construction.
+	scanner *bufio.Scanner
+	// pending holds the already-authorized bytes of the message currently
+	// being drained to the caller, since a single Read(p) may be too small
+	// to return a whole message at once.
+	pending []byte
+}
+
+// mcpAccessMatcher builds the services.RoleMatcher that should gate a given
+// JSON-RPC method/params pair, or nil if the method isn't RBAC-gated.
+func mcpAccessMatcher(method string, params struct {
+	Name string `json:"name"`
+	URI  string `json:"uri"`
+}) services.RoleMatcher {
+	switch method {
+	case string(mcp.MethodToolsCall):
+		return &services.MCPToolsMatcher{Name: params.Name}
+	case string(mcp.MethodResourcesRead):
+		return &services.MCPResourcesMatcher{URI: params.URI}
+	case string(mcp.MethodPromptsGet):
+		return &services.MCPPromptsMatcher{Name: params.Name}
+	case string(mcp.MethodSamplingCreateMessage):
+		return &services.MCPSamplingMatcher{}
+	default:
+		return nil
+	}
 }
 
+// deniedResultFor builds the JSON-RPC result content returned to the client
+// in place of the upstream's response when authErr denies access to method.
+func deniedResultFor(method string, authErr error) any {
+	text := fmt.Sprintf("Access denied to this MCP %s: %v. RBAC is enforced by your Teleport roles.", method, authErr)
+	switch method {
+	case string(mcp.MethodResourcesRead):
+		return mcp.ReadResourceResult{
+			Contents: []mcp.ResourceContents{mcp.TextResourceContents{Text: text}},
+		}
+	case string(mcp.MethodPromptsGet):
+		return mcp.GetPromptResult{
+			Messages: []mcp.PromptMessage{{
+				Role:    mcp.RoleAssistant,
+				Content: mcp.TextContent{Type: "text", Text: text},
+			}},
+		}
+	case string(mcp.MethodSamplingCreateMessage):
+		return mcp.CreateMessageResult{
+			SamplingMessage: mcp.SamplingMessage{
+				Role:    mcp.RoleAssistant,
+				Content: mcp.TextContent{Type: "text", Text: text},
+			},
+		}
+	default:
+		return mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: text}},
+			IsError: false,
+		}
+	}
+}
+
+// Read returns authorized MCP messages, one newline-delimited JSON-RPC
+// message at a time, buffered through a bufio.Scanner so authorizeLine
+// always sees a complete message - never a partial message split across two
+// reads, nor several messages concatenated into one. Forwarding raw,
+// unparsed bytes here would let a client smuggle an ungated call past RBAC
+// simply by timing its writes to straddle a read boundary, so any message
+// that doesn't parse is dropped rather than forwarded: fail closed, not
+// open.
 func (r *authorizedReader) Read(p []byte) (n int, err error) {
-	temp := make([]byte, len(p))
-	n, err = r.clientConn.Read(temp)
-	if err != nil {
-		return n, trace.Wrap(err)
+	for len(r.pending) == 0 {
+		if r.scanner == nil {
+			r.scanner = bufio.NewScanner(r.clientConn)
+			r.scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		}
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return 0, trace.Wrap(err)
+			}
+			return 0, io.EOF
+		}
+
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		r.pending = r.authorizeLine(line)
 	}
-	if len(temp) != 0 {
-		var baseMessage struct {
-			ID     any    `json:"id,omitempty"`
-			Method string `json:"method"`
-			Params struct {
-				Name string `json:"name"`
-			} `json:"params"`
-		}
-		if err := json.Unmarshal(bytes.TrimSpace(temp[:n]), &baseMessage); err == nil {
-			if baseMessage.ID != nil && baseMessage.Method == string(mcp.MethodToolsCall) {
-				r.log.DebugContext(r.ctx, "Tools call", "msg", baseMessage)
-				accessState := services.AccessState{
-					MFAVerified:    true,
-					DeviceVerified: true,
-				}
-				toolMatcher := &services.MCPToolsMatcher{
-					Name: baseMessage.Params.Name,
-				}
-				authErr := r.authCtx.Checker.CheckAccess(r.app, accessState, toolMatcher)
-				if authErr != nil {
-					// Send a response.
-					result := mcp.CallToolResult{
-						Content: []mcp.Content{mcp.TextContent{
-							Type: "text",
-							Text: fmt.Sprintf("Access denied to this MCP tool: %v. RBAC is enforced by your Teleport roles.", authErr),
-						}},
-						IsError: false,
-					}
-					resp := mcp.JSONRPCResponse{
-						JSONRPC: mcp.JSONRPC_VERSION,
-						ID:      baseMessage.ID,
-						Result:  result,
-					}
-					if respBytes, err := json.Marshal(resp); err == nil {
-						// Write response followed by newline
-						if _, err := fmt.Fprintf(r.responseWriter, "%s\n", respBytes); err != nil {
-							r.log.ErrorContext(r.ctx, "Failed to send JSON RPC response", "error", err, "resp", resp)
-						}
-					} else {
-						r.log.ErrorContext(r.ctx, "Failed to marshal JSON RPC response", "error", err, "resp", resp)
-					}
-					r.dumpWriter.emitAuditEvent(string(temp[:n]), authErr)
-					// Do NOT fail this otherwise the connection will be killed.
-					return n, nil
-				}
+
+	n = copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// authorizeLine checks a single complete JSON-RPC message against the
+// caller's RBAC role set, answering a denied message directly on
+// r.responseWriter and dropping an unparseable one (logged, unanswered)
+// instead of forwarding either. It returns the
+// bytes that should be forwarded to the upstream MCP server, or nil if line
+// must not be forwarded at all.
+func (r *authorizedReader) authorizeLine(line []byte) []byte {
+	var baseMessage struct {
+		ID     any    `json:"id,omitempty"`
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+			URI  string `json:"uri"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(line, &baseMessage); err != nil {
+		r.log.WarnContext(r.ctx, "Dropping unparseable MCP client message", "error", err)
+		return nil
+	}
+
+	if baseMessage.ID != nil {
+		if matcher := mcpAccessMatcher(baseMessage.Method, baseMessage.Params); matcher != nil {
+			r.log.DebugContext(r.ctx, "MCP RBAC-gated call", "msg", baseMessage)
+			accessState := services.AccessState{
+				MFAVerified:    true,
+				DeviceVerified: true,
+			}
+			authErr := r.checker.CheckAccess(r.app, accessState, matcher)
+			if authErr != nil {
+				r.denyCall(baseMessage.ID, baseMessage.Method, authErr, line)
+				return nil
 			}
 		}
 	}
-	copy(p, temp)
-	r.dumpWriter.Write(temp[:n])
-	return n, err
+
+	out := make([]byte, 0, len(line)+1)
+	out = append(out, line...)
+	out = append(out, '\n')
+	r.dumpWriter.Write(out)
+	return out
+}
+
+// denyCall answers id directly on r.responseWriter with the denied result
+// for method, instead of forwarding the call upstream, and audits the
+// decision.
+func (r *authorizedReader) denyCall(id any, method string, authErr error, line []byte) {
+	result := deniedResultFor(method, authErr)
+	resp := mcp.JSONRPCResponse{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      id,
+		Result:  result,
+	}
+	if respBytes, err := json.Marshal(resp); err == nil {
+		if _, err := fmt.Fprintf(r.responseWriter, "%s\n", respBytes); err != nil {
+			r.log.ErrorContext(r.ctx, "Failed to send JSON RPC response", "error", err, "resp", resp)
+		}
+	} else {
+		r.log.ErrorContext(r.ctx, "Failed to marshal JSON RPC response", "error", err, "resp", resp)
+	}
+	r.dumpWriter.emitAuditEvent(string(line), authErr)
 }
 
-func newDumpWriter(ctx context.Context, handleName string, emitter apievents.Emitter, log *slog.Logger, identity *tlsca.Identity, sessionID string) *dumpWriter {
+func newDumpWriter(ctx context.Context, handleName string, emitter apievents.Emitter, log *slog.Logger, identity *tlsca.Identity, sessionID string, recorder *MCPSessionRecorder, direction mcpMessageDirection, tracker *pendingRequestTracker) *dumpWriter {
 	return &dumpWriter{
 		ctx:       ctx,
 		logger:    log.With("stdio", handleName),
 		emitter:   emitter,
 		identity:  identity,
 		sessionID: sessionID,
+		recorder:  recorder,
+		direction: direction,
+		tracker:   tracker,
 	}
 }
 
@@ -172,6 +411,9 @@ type dumpWriter struct {
 	identity  *tlsca.Identity
 	emitter   apievents.Emitter
 	sessionID string
+	recorder  *MCPSessionRecorder
+	direction mcpMessageDirection
+	tracker   *pendingRequestTracker
 }
 
 func (d *dumpWriter) emitAuditEvent(msg string, authError error) {
@@ -181,23 +423,22 @@ func (d *dumpWriter) emitAuditEvent(msg string, authError error) {
 
 	userMeta := d.identity.GetUserMetadata()
 	sessionMeta := apievents.SessionMetadata{SessionID: d.sessionID}
-	event, emit, err := mcpMessageToEvent(msg, userMeta, sessionMeta, authError)
+	auditEvents, err := mcpMessagesToEvents(d.direction, msg, userMeta, sessionMeta, authError, d.tracker)
 	if err != nil {
 		d.logger.WarnContext(d.ctx, "Failed to parse RPC message", "error", err)
 		return
 	}
-	if !emit {
-		return
-	}
-	d.logger.InfoContext(d.ctx, "event", "val", event)
-
-	if err := d.emitter.EmitAuditEvent(d.ctx, event); err != nil {
-		d.logger.WarnContext(d.ctx, "Failed to emit MCP call event.", "error", err)
+	for _, event := range auditEvents {
+		d.logger.InfoContext(d.ctx, "event", "val", event)
+		if err := d.emitter.EmitAuditEvent(d.ctx, event); err != nil {
+			d.logger.WarnContext(d.ctx, "Failed to emit MCP call event.", "error", err)
+		}
 	}
 }
 
 func (d *dumpWriter) Write(p []byte) (int, error) {
 	d.emitAuditEvent(string(p), nil)
+	d.recorder.Record(d.ctx, d.direction, p)
 	d.logger.Log(d.ctx, logutils.TraceLevel, "=== dump", "data", string(p))
 	return len(p), nil
 }
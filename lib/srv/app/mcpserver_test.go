@@ -0,0 +1,142 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apitypes "github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/lib/services"
+)
+
+// fakeAccessChecker denies every tools/call tool name in denied and allows
+// everything else.
+type fakeAccessChecker struct {
+	denied map[string]bool
+}
+
+func (f *fakeAccessChecker) CheckAccess(_ apitypes.ResourceWithLabels, _ services.AccessState, matchers ...services.RoleMatcher) error {
+	for _, m := range matchers {
+		if tm, ok := m.(*services.MCPToolsMatcher); ok && f.denied[tm.Name] {
+			return &deniedError{tool: tm.Name}
+		}
+	}
+	return nil
+}
+
+type deniedError struct{ tool string }
+
+func (e *deniedError) Error() string { return "tool " + e.tool + " is denied" }
+
+func newTestAuthorizedReader(t *testing.T, clientConn io.Reader, denied ...string) (*authorizedReader, *bytes.Buffer) {
+	t.Helper()
+	deniedSet := make(map[string]bool, len(denied))
+	for _, name := range denied {
+		deniedSet[name] = true
+	}
+
+	var responses bytes.Buffer
+	return &authorizedReader{
+		ctx:            context.Background(),
+		clientConn:     clientConn,
+		checker:        &fakeAccessChecker{denied: deniedSet},
+		app:            nil,
+		responseWriter: &responses,
+		log:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		dumpWriter: newDumpWriter(context.Background(), "in", nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+			nil, "sess-1", nil, mcpMessageDirectionClient, newPendingRequestTracker()),
+	}, &responses
+}
+
+// TestAuthorizedReaderFailsClosedOnUnparseableMessage proves that a line
+// that isn't valid JSON is dropped, unanswered, rather than forwarded raw.
+// Forwarding unparsed bytes would let an attacker smuggle a call past the
+// RBAC check.
+func TestAuthorizedReaderFailsClosedOnUnparseableMessage(t *testing.T) {
+	// Deliberately not valid JSON-RPC on its own: a naive per-Read parse
+	// would fail to unmarshal this and, under the old behavior, forward it
+	// verbatim anyway.
+	garbage := []byte("not json at all\n")
+	reader, responses := newTestAuthorizedReader(t, bytes.NewReader(garbage), "write_file")
+
+	out := make([]byte, 4096)
+	n, err := reader.Read(out)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 0, n)
+	require.Empty(t, responses.Bytes())
+}
+
+// TestAuthorizedReaderHandlesConcatenatedMessages proves two JSON-RPC
+// messages delivered in a single underlying read (as can happen over TCP)
+// are each authorized independently, rather than being treated as one
+// unparseable blob and forwarded raw.
+func TestAuthorizedReaderHandlesConcatenatedMessages(t *testing.T) {
+	concatenated := []byte(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"read_file"}}` + "\n" +
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"write_file"}}` + "\n",
+	)
+	reader, responses := newTestAuthorizedReader(t, bytes.NewReader(concatenated), "write_file")
+
+	forwarded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	// Only the allowed call is forwarded upstream.
+	require.Contains(t, string(forwarded), `"read_file"`)
+	require.NotContains(t, string(forwarded), `"write_file"`)
+
+	// The denied call got an answer on the response writer instead.
+	require.Contains(t, responses.String(), `"id":2`)
+}
+
+// TestAuthorizedReaderReassemblesSplitMessage proves a single JSON-RPC
+// message delivered across two small underlying reads - so that neither
+// fragment parses on its own - is still authorized correctly once
+// reassembled, rather than forwarded as two raw, ungated fragments.
+func TestAuthorizedReaderReassemblesSplitMessage(t *testing.T) {
+	full := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"write_file"}}` + "\n"
+	split := &splitReader{chunks: [][]byte{[]byte(full[:20]), []byte(full[20:])}}
+
+	reader, responses := newTestAuthorizedReader(t, split, "write_file")
+
+	forwarded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Empty(t, forwarded)
+	require.Contains(t, responses.String(), `"id":1`)
+}
+
+// splitReader returns its configured chunks one at a time, one per Read
+// call, to simulate a message arriving split across multiple reads.
+type splitReader struct {
+	chunks [][]byte
+}
+
+func (s *splitReader) Read(p []byte) (int, error) {
+	if len(s.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, s.chunks[0])
+	s.chunks = s.chunks[1:]
+	return n, nil
+}
@@ -0,0 +1,223 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+// Label keys consulted by ClassifierConfigFromLabels to build a
+// ClassifierConfig for a database resource, so health classification can be
+// tuned per-resource without a code change.
+const (
+	LabelHealthWindow             = "teleport.dev/health-window"
+	LabelHealthUnhealthyThreshold = "teleport.dev/health-unhealthy-threshold"
+	LabelHealthRecoveryThreshold  = "teleport.dev/health-recovery-threshold"
+	LabelHealthLatencyThreshold   = "teleport.dev/health-latency-threshold"
+)
+
+// LatencyFunc extracts the observed latency for a health check, if any was
+// recorded. The default ClassifierConfig leaves this nil, so latency never
+// affects classification until a caller supplies one.
+type LatencyFunc func(check *types.DatabaseHealthCheckV1) (time.Duration, bool)
+
+// ClassifierConfig configures a HealthClassifier's thresholds.
+type ClassifierConfig struct {
+	// Window bounds how many of the most recent health checks (newest
+	// first) the classifier considers; older checks are ignored.
+	Window int
+	// UnhealthyThreshold is how many consecutive failures, counting back
+	// from the most recent check, demote a server to Unhealthy.
+	UnhealthyThreshold int
+	// RecoveryThreshold is how many consecutive successes, counting back
+	// from the most recent check, are required before a server that was
+	// Unhealthy or mid-recovery is reported Healthy again. This hysteresis
+	// keeps an isolated success from flapping the status straight back to
+	// Healthy.
+	RecoveryThreshold int
+	// LatencyThreshold, if set alongside LatencyFunc, promotes an
+	// otherwise-Healthy server to Warning when the p95 latency across the
+	// window exceeds it.
+	LatencyThreshold time.Duration
+	// LatencyFunc extracts a check's observed latency. A nil LatencyFunc
+	// disables latency-based Warning promotion regardless of
+	// LatencyThreshold.
+	LatencyFunc LatencyFunc
+}
+
+// CheckAndSetDefaults fills in zero-valued fields with the package defaults:
+// a 3-check window, Unhealthy after 2 consecutive failures, and Healthy
+// again after 1 success.
+func (c *ClassifierConfig) CheckAndSetDefaults() error {
+	if c.Window <= 0 {
+		c.Window = 3
+	}
+	if c.UnhealthyThreshold <= 0 {
+		c.UnhealthyThreshold = 2
+	}
+	if c.RecoveryThreshold <= 0 {
+		c.RecoveryThreshold = 1
+	}
+	if c.UnhealthyThreshold > c.Window {
+		return trace.BadParameter("UnhealthyThreshold (%d) cannot exceed Window (%d)", c.UnhealthyThreshold, c.Window)
+	}
+	if c.RecoveryThreshold > c.Window {
+		return trace.BadParameter("RecoveryThreshold (%d) cannot exceed Window (%d)", c.RecoveryThreshold, c.Window)
+	}
+	return nil
+}
+
+// ClassifierConfigFromLabels builds a ClassifierConfig from a resource's
+// labels, leaving any key that's absent or fails to parse at its zero value
+// (CheckAndSetDefaults then fills it in with the package default).
+func ClassifierConfigFromLabels(labels map[string]string) ClassifierConfig {
+	var cfg ClassifierConfig
+	if v, ok := labels[LabelHealthWindow]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Window = n
+		}
+	}
+	if v, ok := labels[LabelHealthUnhealthyThreshold]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.UnhealthyThreshold = n
+		}
+	}
+	if v, ok := labels[LabelHealthRecoveryThreshold]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.RecoveryThreshold = n
+		}
+	}
+	if v, ok := labels[LabelHealthLatencyThreshold]; ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.LatencyThreshold = d
+		}
+	}
+	return cfg
+}
+
+// HealthClassifier turns a database server's recent health checks into a
+// DatabaseServerStatus. It applies hysteresis (separate thresholds for
+// tripping Unhealthy and recovering to Healthy) so a single blip doesn't
+// flap the reported status, and can optionally promote Healthy to Warning
+// when observed latency is elevated.
+type HealthClassifier struct {
+	cfg ClassifierConfig
+}
+
+// NewHealthClassifier returns a HealthClassifier for cfg, applying defaults
+// to any unset fields.
+func NewHealthClassifier(cfg ClassifierConfig) (*HealthClassifier, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &HealthClassifier{cfg: cfg}, nil
+}
+
+// defaultHealthClassifier is the classifier built from the zero-value
+// ClassifierConfig, used by ServerStatus. It classifies by runs of
+// consecutive results counting back from the newest check, not by the
+// number of successes in the window, so newest-first [failure, success,
+// success] is Warning and [success, failure, failure] is Healthy.
+var defaultHealthClassifier = func() *HealthClassifier {
+	classifier, err := NewHealthClassifier(ClassifierConfig{})
+	if err != nil {
+		panic(err) // the zero-value config is always valid once defaulted
+	}
+	return classifier
+}()
+
+// Classify returns the DatabaseServerStatus for checks, which must be
+// ordered newest-first, as types.Database.GetStatusHealth().Checks is.
+func (h *HealthClassifier) Classify(checks []*types.DatabaseHealthCheckV1) DatabaseServerStatus {
+	if len(checks) == 0 {
+		return DatabaseServerStatusUnknown
+	}
+
+	window := checks
+	if len(window) > h.cfg.Window {
+		window = window[:h.cfg.Window]
+	}
+
+	// With a single data point there's no window to apply hysteresis
+	// over; report exactly what that one check says.
+	if len(window) == 1 {
+		if window[0].IsSuccess() {
+			return DatabaseServerStatusHealthy
+		}
+		return DatabaseServerStatusUnhealthy
+	}
+
+	consecutiveFailures := 0
+	for _, check := range window {
+		if check.IsSuccess() {
+			break
+		}
+		consecutiveFailures++
+	}
+	if consecutiveFailures >= h.cfg.UnhealthyThreshold {
+		return DatabaseServerStatusUnhealthy
+	}
+
+	consecutiveSuccesses := 0
+	for _, check := range window {
+		if !check.IsSuccess() {
+			break
+		}
+		consecutiveSuccesses++
+	}
+	if consecutiveSuccesses < h.cfg.RecoveryThreshold {
+		// Recovering from a recent failure but hasn't cleared the
+		// hysteresis bar yet.
+		return DatabaseServerStatusWarning
+	}
+
+	if h.cfg.LatencyFunc != nil && h.cfg.LatencyThreshold > 0 {
+		if p95, ok := p95Latency(window, h.cfg.LatencyFunc); ok && p95 > h.cfg.LatencyThreshold {
+			return DatabaseServerStatusWarning
+		}
+	}
+
+	return DatabaseServerStatusHealthy
+}
+
+// p95Latency returns the 95th-percentile latency across checks, using
+// latencyFunc to extract a sample from each check that has one.
+func p95Latency(checks []*types.DatabaseHealthCheckV1, latencyFunc LatencyFunc) (time.Duration, bool) {
+	samples := make([]time.Duration, 0, len(checks))
+	for _, check := range checks {
+		if d, ok := latencyFunc(check); ok {
+			samples = append(samples, d)
+		}
+	}
+	if len(samples) == 0 {
+		return 0, false
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := int(float64(len(samples)) * 0.95)
+	if idx >= len(samples) {
+		idx = len(samples) - 1
+	}
+	return samples[idx], true
+}
@@ -0,0 +1,122 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+func checkResult(success bool) *types.DatabaseHealthCheckV1 {
+	return &types.DatabaseHealthCheckV1{
+		Diagnostic: &types.ConnectionDiagnosticSpecV1{
+			Success: success,
+		},
+	}
+}
+
+func TestHealthClassifierWarningOnElevatedLatency(t *testing.T) {
+	checks := []*types.DatabaseHealthCheckV1{checkResult(true), checkResult(true), checkResult(true)}
+	latencies := map[*types.DatabaseHealthCheckV1]time.Duration{
+		checks[0]: 600 * time.Millisecond,
+		checks[1]: 50 * time.Millisecond,
+		checks[2]: 40 * time.Millisecond,
+	}
+
+	classifier, err := NewHealthClassifier(ClassifierConfig{
+		LatencyThreshold: 500 * time.Millisecond,
+		LatencyFunc: func(c *types.DatabaseHealthCheckV1) (time.Duration, bool) {
+			d, ok := latencies[c]
+			return d, ok
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, DatabaseServerStatusWarning, classifier.Classify(checks))
+
+	// Without a LatencyFunc, elevated latency is invisible and the server
+	// is reported Healthy on the same checks.
+	noLatency, err := NewHealthClassifier(ClassifierConfig{})
+	require.NoError(t, err)
+	require.Equal(t, DatabaseServerStatusHealthy, noLatency.Classify(checks))
+}
+
+func TestHealthClassifierSuppressesFlapping(t *testing.T) {
+	// Newest-first: one success right after a failure shouldn't be enough
+	// to report Healthy again once RecoveryThreshold > 1.
+	checks := []*types.DatabaseHealthCheckV1{checkResult(true), checkResult(false), checkResult(true), checkResult(true)}
+
+	classifier, err := NewHealthClassifier(ClassifierConfig{
+		Window:             4,
+		UnhealthyThreshold: 3,
+		RecoveryThreshold:  2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, DatabaseServerStatusWarning, classifier.Classify(checks))
+
+	// A second consecutive success clears the recovery bar.
+	recovered := []*types.DatabaseHealthCheckV1{checkResult(true), checkResult(true), checkResult(false), checkResult(true)}
+	require.Equal(t, DatabaseServerStatusHealthy, classifier.Classify(recovered))
+}
+
+func TestHealthClassifierWindowLargerThanThree(t *testing.T) {
+	// Only the most recent 5 of 7 checks matter; the 3 consecutive
+	// failures among them should trip Unhealthy even though older checks
+	// (outside the window) all succeeded.
+	checks := []*types.DatabaseHealthCheckV1{
+		checkResult(false), checkResult(false), checkResult(false), checkResult(true), checkResult(true),
+		checkResult(true), checkResult(true),
+	}
+
+	classifier, err := NewHealthClassifier(ClassifierConfig{
+		Window:             5,
+		UnhealthyThreshold: 3,
+		RecoveryThreshold:  1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, DatabaseServerStatusUnhealthy, classifier.Classify(checks))
+}
+
+func TestHealthClassifierInvalidConfig(t *testing.T) {
+	_, err := NewHealthClassifier(ClassifierConfig{Window: 2, UnhealthyThreshold: 3})
+	require.True(t, trace.IsBadParameter(err))
+}
+
+func TestClassifierConfigFromLabels(t *testing.T) {
+	cfg := ClassifierConfigFromLabels(map[string]string{
+		LabelHealthWindow:             "5",
+		LabelHealthUnhealthyThreshold: "3",
+		LabelHealthRecoveryThreshold:  "2",
+		LabelHealthLatencyThreshold:   "250ms",
+		"unrelated-label":             "ignored",
+	})
+	require.Equal(t, 5, cfg.Window)
+	require.Equal(t, 3, cfg.UnhealthyThreshold)
+	require.Equal(t, 2, cfg.RecoveryThreshold)
+	require.Equal(t, 250*time.Millisecond, cfg.LatencyThreshold)
+
+	// Malformed values are left at the zero value for
+	// CheckAndSetDefaults to fill in.
+	cfg = ClassifierConfigFromLabels(map[string]string{LabelHealthWindow: "not-a-number"})
+	require.Zero(t, cfg.Window)
+}
@@ -30,51 +30,17 @@ const (
 	DatabaseServerStatusUnhealthy
 )
 
-// TODO: make it easier to read through
-//
-// assuming we'll have only 3 checks:
-// - HEALTHY:
-//   - last two checks succeeded
-//   - last and third checks succeeded (TODO)
-//
-// - WARNING:
-//   - last succeeded but all other two failed
-//
-// - UNHEALTHY:
-//   - all failed
+// ServerStatus classifies server's recent health checks into a
+// DatabaseServerStatus using the package's default HealthClassifier (a
+// rolling 3-check window with hysteresis). Use ServerStatusWithClassifier
+// to tune the window, thresholds, or latency sensitivity per resource,
+// e.g. via ClassifierConfigFromLabels.
 func ServerStatus(server types.DatabaseServer) DatabaseServerStatus {
-	checks := server.GetDatabase().GetHealthchecks()
-	totalChecks := len(checks)
-	if totalChecks == 0 {
-		return DatabaseServerStatusUnknown
-	}
-
-	lastSucceded := checks[0].IsSuccess()
-	if totalChecks == 1 {
-		if lastSucceded {
-			return DatabaseServerStatusHealthy
-		}
-
-		return DatabaseServerStatusUnhealthy
-	}
-
-	totalSucceeded := 0
-	if lastSucceded {
-		totalSucceeded += 1
-	}
-
-	for _, check := range checks[1:] {
-		if check.IsSuccess() {
-			totalSucceeded += 1
-		}
-	}
-
-	switch {
-	case lastSucceded && totalSucceeded == 0:
-		return DatabaseServerStatusWarning
-	case totalSucceeded < 2:
-		return DatabaseServerStatusUnhealthy
-	}
+	return ServerStatusWithClassifier(server, defaultHealthClassifier)
+}
 
-	return DatabaseServerStatusHealthy
+// ServerStatusWithClassifier is like ServerStatus but classifies server's
+// health checks with classifier instead of the package default.
+func ServerStatusWithClassifier(server types.DatabaseServer, classifier *HealthClassifier) DatabaseServerStatus {
+	return classifier.Classify(server.GetDatabase().GetHealthchecks())
 }
@@ -19,71 +19,71 @@
 package debug
 
 import (
-	"encoding/json"
-	"log/slog"
-	"net/http"
-)
-
-func handleGetDatabaseHealthCheck(logger *slog.Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		service := getDatabaseServicePlugin()
-		if service == nil {
-			http.Error(w, "database service is not registered", http.StatusServiceUnavailable)
-			return
-		}
+	"context"
 
-		name := r.URL.Query().Get("name")
-		if name == "" {
-			http.Error(w, "database name not provided", http.StatusBadRequest)
-			return
-		}
+	"github.com/gravitational/trace"
 
-		db, err := service.GetProxiedDatabase(r.Context(), name)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	"github.com/gravitational/teleport/api/types"
+)
 
-		health := db.GetStatusHealth()
-		data, err := json.MarshalIndent(health, "", "  ")
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+// DatabaseServicePlugin is the database service's original, db-specific
+// view of a health-check plugin, predating HealthCheckPlugin. It's kept so
+// the database service doesn't have to change its own return type (the
+// full types.Database, useful to more than just this package) just because
+// this package's HTTP surface generalized to cover every proxied resource
+// kind.
+type DatabaseServicePlugin interface {
+	GetProxiedDatabase(ctx context.Context, name string) (types.Database, error)
+	RunHealthCheck(ctx context.Context, name string) (types.Database, error)
+}
 
-		w.Write(data)
-		return
-	}
+// DatabaseServicePluginWithNames additionally lets the database service
+// advertise every database it currently proxies, for the /health index.
+// Optional: a DatabaseServicePlugin that doesn't implement it is still
+// queryable at /health/db, it just can't be summarized at /health without
+// already knowing database names.
+type DatabaseServicePluginWithNames interface {
+	DatabaseServicePlugin
+	ProxiedDatabaseNames(ctx context.Context) ([]string, error)
 }
 
-func handleRunDatabaseHealthCheck(logger *slog.Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		service := getDatabaseServicePlugin()
-		if service == nil {
-			http.Error(w, "database service is not registered", http.StatusServiceUnavailable)
-			return
-		}
+// RegisterDatabaseServicePlugin registers p as the ResourceKindDatabase
+// HealthCheckPlugin, adapting its types.Database-returning methods down to
+// HealthCheckPlugin's types.TargetHealth-only surface.
+func RegisterDatabaseServicePlugin(p DatabaseServicePlugin) {
+	RegisterHealthCheckPlugin(ResourceKindDatabase, databasePlugin{p})
+}
 
-		name := r.URL.Query().Get("name")
-		if name == "" {
-			http.Error(w, "database name not provided", http.StatusBadRequest)
-			return
-		}
+// databasePlugin adapts a DatabaseServicePlugin to HealthCheckPlugin (and,
+// when p also implements DatabaseServicePluginWithNames, to
+// ProxiedNamesLister).
+type databasePlugin struct {
+	DatabaseServicePlugin
+}
 
-		db, err := service.RunHealthCheck(r.Context(), name)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+func (d databasePlugin) GetProxied(ctx context.Context, name string) (types.TargetHealth, error) {
+	db, err := d.GetProxiedDatabase(ctx, name)
+	if err != nil {
+		return types.TargetHealth{}, trace.Wrap(err)
+	}
+	return db.GetStatusHealth(), nil
+}
 
-		health := db.GetStatusHealth()
-		data, err := json.MarshalIndent(health, "", "  ")
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+func (d databasePlugin) RunHealthCheck(ctx context.Context, name string) (types.TargetHealth, error) {
+	db, err := d.DatabaseServicePlugin.RunHealthCheck(ctx, name)
+	if err != nil {
+		return types.TargetHealth{}, trace.Wrap(err)
+	}
+	return db.GetStatusHealth(), nil
+}
 
-		w.Write(data)
-		return
+func (d databasePlugin) ProxiedNames(ctx context.Context) ([]string, error) {
+	withNames, ok := d.DatabaseServicePlugin.(DatabaseServicePluginWithNames)
+	if !ok {
+		return nil, trace.NotImplemented("database service plugin does not support listing proxied database names")
 	}
+	names, err := withNames.ProxiedDatabaseNames(ctx)
+	return names, trace.Wrap(err)
 }
+
+var _ ProxiedNamesLister = databasePlugin{}
@@ -0,0 +1,145 @@
+/*
+ * Teleport
+ * Copyright (C) 2024  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gravitational/teleport/api/types"
+)
+
+// RegisterHealthRoutes mounts the health-check surface on mux:
+// GET  /health/{kind}      - the named resource's last known health
+// GET  /health/{kind}/run  - runs an on-demand health check and returns it
+// GET  /health             - a summary of every resource every registered
+//
+//	plugin proxies, across all kinds
+//
+// {kind} is one of the ResourceKind constants (db, app, kube, desktop,
+// node); a kind with no registered plugin (that service isn't running on
+// this instance, or hasn't called RegisterHealthCheckPlugin yet) answers
+// 503.
+func RegisterHealthRoutes(mux *http.ServeMux, logger *slog.Logger) {
+	mux.HandleFunc("GET /health/{kind}", handleGetHealthCheck(logger))
+	mux.HandleFunc("GET /health/{kind}/run", handleRunHealthCheck(logger))
+	mux.HandleFunc("GET /health", handleHealthIndex(logger))
+}
+
+// handleGetHealthCheck returns the resource's last known health, as
+// determined by its service's ordinary (usually periodic) health checking.
+func handleGetHealthCheck(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, logger, func(p HealthCheckPlugin, name string) (types.TargetHealth, error) {
+			return p.GetProxied(r.Context(), name)
+		})
+	}
+}
+
+// handleRunHealthCheck runs an on-demand health check against the named
+// resource and returns its resulting health.
+func handleRunHealthCheck(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, logger, func(p HealthCheckPlugin, name string) (types.TargetHealth, error) {
+			return p.RunHealthCheck(r.Context(), name)
+		})
+	}
+}
+
+// writeHealth resolves {kind} and ?name= from r, calls get against the
+// registered plugin for that kind, and writes the result as JSON.
+func writeHealth(w http.ResponseWriter, r *http.Request, logger *slog.Logger, get func(p HealthCheckPlugin, name string) (types.TargetHealth, error)) {
+	kind := ResourceKind(r.PathValue("kind"))
+	plugin := getHealthCheckPlugin(kind)
+	if plugin == nil {
+		http.Error(w, string(kind)+" service is not registered", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name not provided", http.StatusBadRequest)
+		return
+	}
+
+	health, err := get(plugin, name)
+	if err != nil {
+		logger.WarnContext(r.Context(), "Health check failed", "kind", kind, "name", name, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, logger, health)
+}
+
+// healthSummary is the /health index's response: every proxied resource's
+// last known health, grouped by ResourceKind and then resource name.
+type healthSummary struct {
+	Kinds map[ResourceKind]map[string]types.TargetHealth `json:"kinds"`
+}
+
+// handleHealthIndex summarizes every proxied resource's last known health
+// across every registered plugin. A kind whose plugin doesn't implement
+// ProxiedNamesLister (can't enumerate its resources) is included with an
+// empty map rather than failing the whole summary.
+func handleHealthIndex(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		summary := healthSummary{Kinds: map[ResourceKind]map[string]types.TargetHealth{}}
+
+		for _, kind := range registeredKinds() {
+			plugin := getHealthCheckPlugin(kind)
+			summary.Kinds[kind] = map[string]types.TargetHealth{}
+
+			lister, ok := plugin.(ProxiedNamesLister)
+			if !ok {
+				continue
+			}
+
+			names, err := lister.ProxiedNames(r.Context())
+			if err != nil {
+				logger.WarnContext(r.Context(), "Failed to list proxied resources", "kind", kind, "error", err)
+				continue
+			}
+
+			for _, name := range names {
+				health, err := plugin.GetProxied(r.Context(), name)
+				if err != nil {
+					logger.WarnContext(r.Context(), "Failed to get proxied resource health", "kind", kind, "name", name, "error", err)
+					continue
+				}
+				summary.Kinds[kind][name] = health
+			}
+		}
+
+		writeJSON(w, logger, summary)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, logger *slog.Logger, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := w.Write(data); err != nil {
+		logger.DebugContext(context.Background(), "Failed to write health check response", "error", err)
+	}
+}
@@ -20,28 +20,81 @@ package debug
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/gravitational/teleport/api/types"
 )
 
-type DatabaseServicePlugin interface {
-	GetProxiedDatabase(ctx context.Context, name string) (types.Database, error)
-	RunHealthCheck(ctx context.Context, name string) (types.Database, error)
+// ResourceKind identifies which class of proxied resource a
+// HealthCheckPlugin speaks for, and the {kind} path segment /health routes
+// requests to it under (e.g. /health/db, /health/kube/run).
+type ResourceKind string
+
+// Resource kinds with a registered HealthCheckPlugin.
+const (
+	ResourceKindDatabase       ResourceKind = "db"
+	ResourceKindApp            ResourceKind = "app"
+	ResourceKindKube           ResourceKind = "kube"
+	ResourceKindWindowsDesktop ResourceKind = "desktop"
+	ResourceKindNode           ResourceKind = "node"
+)
+
+// HealthCheckPlugin lets a service that proxies resources (db, app, kube,
+// desktop, node) expose their on-demand and last-known health through the
+// debug service's HTTP surface, without this package needing any direct
+// knowledge of each service's internals.
+type HealthCheckPlugin interface {
+	// GetProxied returns name's last known health, as determined by the
+	// service's ordinary (usually periodic) health checking.
+	GetProxied(ctx context.Context, name string) (types.TargetHealth, error)
+	// RunHealthCheck runs an on-demand health check against name and
+	// returns its resulting health.
+	RunHealthCheck(ctx context.Context, name string) (types.TargetHealth, error)
 }
 
-var dbServicePlugin DatabaseServicePlugin
-var pluginMutex = sync.RWMutex{}
+// ProxiedNamesLister is an optional extension to HealthCheckPlugin. A
+// plugin that implements it lets the /health index enumerate and summarize
+// every resource it proxies, rather than requiring each resource's name be
+// known ahead of time by whoever is querying /health/{kind}.
+type ProxiedNamesLister interface {
+	ProxiedNames(ctx context.Context) ([]string, error)
+}
 
-// TODO maybe move this to lib/srv/debug/common or lib/srv/debug/plugins
-func RegisterDatabaseServicePlugin(p DatabaseServicePlugin) {
-	pluginMutex.Lock()
-	defer pluginMutex.Unlock()
-	dbServicePlugin = p
+var (
+	pluginMu sync.RWMutex
+	plugins  = map[ResourceKind]HealthCheckPlugin{}
+)
+
+// RegisterHealthCheckPlugin registers p as the HealthCheckPlugin for kind,
+// replacing any previously registered plugin for it. Services normally call
+// this once, from their own startup, as soon as they have something capable
+// of answering GetProxied/RunHealthCheck.
+func RegisterHealthCheckPlugin(kind ResourceKind, p HealthCheckPlugin) {
+	pluginMu.Lock()
+	defer pluginMu.Unlock()
+	plugins[kind] = p
+}
+
+// getHealthCheckPlugin returns the plugin registered for kind, or nil if
+// none has registered yet (e.g. that service isn't running on this
+// instance).
+func getHealthCheckPlugin(kind ResourceKind) HealthCheckPlugin {
+	pluginMu.RLock()
+	defer pluginMu.RUnlock()
+	return plugins[kind]
 }
 
-func getDatabaseServicePlugin() DatabaseServicePlugin {
-	pluginMutex.RLock()
-	defer pluginMutex.RUnlock()
-	return dbServicePlugin
+// registeredKinds returns every ResourceKind with a currently registered
+// plugin, sorted for a stable /health index ordering.
+func registeredKinds() []ResourceKind {
+	pluginMu.RLock()
+	defer pluginMu.RUnlock()
+
+	kinds := make([]ResourceKind, 0, len(plugins))
+	for kind := range plugins {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
 }
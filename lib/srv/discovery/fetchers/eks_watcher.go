@@ -0,0 +1,515 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package fetchers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/smithy-go"
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/api/utils/retryutils"
+	"github.com/gravitational/teleport/lib/utils"
+)
+
+// eksWatcherEventBuffer is how many EKSClusterEvents EKSWatcher.Events can
+// hold before a region's sync loop blocks waiting for a consumer.
+const eksWatcherEventBuffer = 256
+
+// defaultEKSResyncInterval is how often each region re-lists and re-diffs
+// its clusters as a safety net, independent of EKSWatcherConfig.StateChangeSource.
+const defaultEKSResyncInterval = 5 * time.Minute
+
+// EKSClusterEventType identifies what changed about a cluster between two
+// observations.
+type EKSClusterEventType string
+
+const (
+	EKSClusterEventAdded   EKSClusterEventType = "Added"
+	EKSClusterEventUpdated EKSClusterEventType = "Updated"
+	EKSClusterEventDeleted EKSClusterEventType = "Deleted"
+)
+
+// EKSClusterEvent is published on EKSWatcher.Events whenever a cluster is
+// first observed, changes, or disappears from a watched region. Cluster is
+// the last known state, even for EKSClusterEventDeleted.
+type EKSClusterEvent struct {
+	Type    EKSClusterEventType
+	Cluster *ekstypes.Cluster
+}
+
+// EKSStateChangeSource notifies an EKSWatcher of out-of-band EKS cluster
+// state changes, so it can refresh a single cluster immediately instead of
+// waiting for its next periodic resync. This package provides no
+// implementation; the caller supplies one, and a nil source means the
+// watcher relies entirely on its resync interval.
+type EKSStateChangeSource interface {
+	// Next blocks until one or more clusters have changed or ctx is done,
+	// returning their ARNs.
+	Next(ctx context.Context) ([]string, error)
+}
+
+// EKSWatcherConfig configures an EKSWatcher.
+type EKSWatcherConfig struct {
+	// ClientGetter builds the per-region EKS API client.
+	ClientGetter EKSClientGetter
+	// FilterLabels restricts the watcher to clusters whose tags match.
+	// Defaults to matching everything.
+	FilterLabels types.Labels
+	// Regions lists the AWS regions to shard across; each gets its own
+	// sync goroutine.
+	Regions []string
+	// ResyncInterval is how often each region re-lists and re-diffs its
+	// clusters. Defaults to defaultEKSResyncInterval.
+	ResyncInterval time.Duration
+	// StateChangeSource optionally delivers EKS cluster state-change
+	// notifications out of band, so changed clusters are refreshed without
+	// waiting for the next resync.
+	StateChangeSource EKSStateChangeSource
+	// Logger is used for background sync errors and diagnostics.
+	Logger *slog.Logger
+}
+
+// CheckAndSetDefaults validates the config and applies defaults.
+func (c *EKSWatcherConfig) CheckAndSetDefaults() error {
+	if c.ClientGetter == nil {
+		return trace.BadParameter("missing ClientGetter")
+	}
+	if len(c.Regions) == 0 {
+		return trace.BadParameter("missing Regions")
+	}
+	if c.FilterLabels == nil {
+		c.FilterLabels = types.Labels{types.Wildcard: []string{types.Wildcard}}
+	}
+	if c.ResyncInterval <= 0 {
+		c.ResyncInterval = defaultEKSResyncInterval
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	return nil
+}
+
+// EKSWatcher maintains an in-memory, ARN-indexed store of EKS clusters
+// across one or more regions, populated by an initial ListClusters plus
+// DescribeCluster sync per region and kept fresh by a periodic resync (the
+// safety net) and, when configured, an EKSStateChangeSource (the fast
+// path). It publishes Add/Update/Delete notifications on Events so the
+// discovery service can push resources to the auth server as soon as they
+// change, instead of waiting for a full poll to notice.
+//
+// A single EKSWatcher's store can back multiple Matchers: List and the
+// underlying indexer are safe for concurrent readers.
+type EKSWatcher struct {
+	cfg   EKSWatcherConfig
+	store *clusterIndexer
+
+	eventsCh chan EKSClusterEvent
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewEKSWatcher creates an EKSWatcher and starts its background sync
+// goroutines. Callers must call Close when done to stop them.
+func NewEKSWatcher(ctx context.Context, cfg EKSWatcherConfig) (*EKSWatcher, error) {
+	if err := cfg.CheckAndSetDefaults(); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &EKSWatcher{
+		cfg:      cfg,
+		store:    newClusterIndexer(),
+		eventsCh: make(chan EKSClusterEvent, eksWatcherEventBuffer),
+		cancel:   cancel,
+	}
+
+	for _, region := range cfg.Regions {
+		w.wg.Add(1)
+		go w.runRegion(ctx, region)
+	}
+	if cfg.StateChangeSource != nil {
+		w.wg.Add(1)
+		go w.consumeStateChanges(ctx)
+	}
+
+	return w, nil
+}
+
+// Events returns the channel Add/Update/Delete notifications are published
+// to. It is closed once Close has stopped every background goroutine.
+func (w *EKSWatcher) Events() <-chan EKSClusterEvent {
+	return w.eventsCh
+}
+
+// List returns a point-in-time snapshot of every cluster currently in the
+// store, for callers that want a baseline before consuming Events.
+func (w *EKSWatcher) List() []*ekstypes.Cluster {
+	return w.store.list()
+}
+
+// Close stops every region and state-change goroutine, waits for them to
+// exit, and closes Events.
+func (w *EKSWatcher) Close() {
+	w.cancel()
+	w.wg.Wait()
+	close(w.eventsCh)
+}
+
+// runRegion is the per-region shard: it resyncs on cfg.ResyncInterval,
+// backing off exponentially while a region's API calls keep failing so one
+// throttled or unreachable region can't busy-loop.
+func (w *EKSWatcher) runRegion(ctx context.Context, region string) {
+	defer w.wg.Done()
+
+	backoff, err := retryutils.NewExponential(retryutils.ExponentialConfig{
+		First: time.Second,
+		Step:  2,
+		Max:   time.Minute,
+	})
+	if err != nil {
+		w.cfg.Logger.ErrorContext(ctx, "failed to create EKS resync backoff", "region", region, "error", err)
+		return
+	}
+
+	for {
+		if err := w.syncRegion(ctx, region); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.cfg.Logger.WarnContext(ctx, "EKS resync failed, retrying with backoff", "region", region, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff.Duration()):
+			}
+			backoff.Inc()
+			continue
+		}
+		backoff.Reset()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.cfg.ResyncInterval):
+		}
+	}
+}
+
+// syncRegion lists and describes every cluster in region, applies matching
+// clusters to the store (emitting Added/Updated events as they change), and
+// emits Deleted events for any previously known cluster in region that no
+// longer came back.
+func (w *EKSWatcher) syncRegion(ctx context.Context, region string) error {
+	client, err := w.cfg.ClientGetter.GetAWSEKSClient(ctx, region)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var names []string
+	var nextToken *string
+	for {
+		var out *eks.ListClustersOutput
+		err := w.withThrottleBackoff(ctx, func() (err error) {
+			out, err = client.ListClusters(ctx, &eks.ListClustersInput{NextToken: nextToken})
+			return err
+		})
+		if err != nil {
+			return trace.Wrap(err, "listing EKS clusters in %v", region)
+		}
+		names = append(names, out.Clusters...)
+		if out.NextToken == nil {
+			break
+		}
+		nextToken = out.NextToken
+	}
+
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		var out *eks.DescribeClusterOutput
+		err := w.withThrottleBackoff(ctx, func() (err error) {
+			out, err = client.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+			return err
+		})
+		if err != nil {
+			w.cfg.Logger.WarnContext(ctx, "failed to describe EKS cluster", "region", region, "cluster", name, "error", err)
+			continue
+		}
+		cluster := out.Cluster
+		if cluster == nil || cluster.Arn == nil {
+			continue
+		}
+		matches, _, err := utils.MatchLabels(w.cfg.FilterLabels, cluster.Tags)
+		if err != nil {
+			w.cfg.Logger.WarnContext(ctx, "failed to match EKS cluster labels", "cluster", name, "error", err)
+			continue
+		}
+		if !matches {
+			continue
+		}
+		seen[aws.ToString(cluster.Arn)] = struct{}{}
+		w.applyUpsert(ctx, cluster)
+	}
+
+	for _, arn := range w.store.arnsInRegion(region) {
+		if _, ok := seen[arn]; !ok {
+			w.applyDelete(ctx, arn)
+		}
+	}
+	return nil
+}
+
+// consumeStateChanges refreshes individual clusters as cfg.StateChangeSource
+// reports them changing, so Events can reflect out-of-band changes faster
+// than the next resync. It exits (falling back entirely to periodic resync)
+// if the source itself fails.
+func (w *EKSWatcher) consumeStateChanges(ctx context.Context) {
+	defer w.wg.Done()
+
+	for {
+		arns, err := w.cfg.StateChangeSource.Next(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.cfg.Logger.WarnContext(ctx, "EKS state-change source failed, relying on periodic resync", "error", err)
+			}
+			return
+		}
+		for _, arn := range arns {
+			w.refreshCluster(ctx, arn)
+		}
+	}
+}
+
+// refreshCluster re-describes a single cluster by ARN, used for the
+// EKSStateChangeSource fast path. Any DescribeCluster failure other than
+// throttling (which is retried) is treated as a deletion, as is a cluster
+// that no longer matches FilterLabels.
+func (w *EKSWatcher) refreshCluster(ctx context.Context, arn string) {
+	region, name := regionFromARN(arn), clusterNameFromARN(arn)
+	if region == "" || name == "" {
+		return
+	}
+
+	client, err := w.cfg.ClientGetter.GetAWSEKSClient(ctx, region)
+	if err != nil {
+		w.cfg.Logger.WarnContext(ctx, "failed to get EKS client for state-change refresh", "region", region, "error", err)
+		return
+	}
+
+	var out *eks.DescribeClusterOutput
+	err = w.withThrottleBackoff(ctx, func() (err error) {
+		out, err = client.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+		return err
+	})
+	if err != nil {
+		// Most likely the cluster is gone; either way the store shouldn't
+		// keep serving stale data for it.
+		w.applyDelete(ctx, arn)
+		return
+	}
+	cluster := out.Cluster
+	if cluster == nil {
+		return
+	}
+
+	matches, _, err := utils.MatchLabels(w.cfg.FilterLabels, cluster.Tags)
+	if err != nil || !matches {
+		w.applyDelete(ctx, arn)
+		return
+	}
+	w.applyUpsert(ctx, cluster)
+}
+
+func (w *EKSWatcher) applyUpsert(ctx context.Context, cluster *ekstypes.Cluster) {
+	evtType, changed := w.store.upsert(cluster)
+	if !changed {
+		return
+	}
+	w.emit(ctx, evtType, cluster)
+}
+
+func (w *EKSWatcher) applyDelete(ctx context.Context, arn string) {
+	cluster, ok := w.store.delete(arn)
+	if !ok {
+		return
+	}
+	w.emit(ctx, EKSClusterEventDeleted, cluster)
+}
+
+func (w *EKSWatcher) emit(ctx context.Context, evtType EKSClusterEventType, cluster *ekstypes.Cluster) {
+	select {
+	case w.eventsCh <- EKSClusterEvent{Type: evtType, Cluster: cluster}:
+	case <-ctx.Done():
+	}
+}
+
+// withThrottleBackoff retries fn with exponential backoff while it keeps
+// failing with an AWS throttling error, and returns immediately on any
+// other error (or success).
+func (w *EKSWatcher) withThrottleBackoff(ctx context.Context, fn func() error) error {
+	backoff, err := retryutils.NewExponential(retryutils.ExponentialConfig{
+		First: 250 * time.Millisecond,
+		Step:  2,
+		Max:   30 * time.Second,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	for {
+		err := fn()
+		if err == nil || !isThrottlingError(err) {
+			return trace.Wrap(err)
+		}
+		select {
+		case <-ctx.Done():
+			return trace.Wrap(ctx.Err())
+		case <-time.After(backoff.Duration()):
+		}
+		backoff.Inc()
+	}
+}
+
+func isThrottlingError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "TooManyRequestsException", "RequestLimitExceeded":
+		return true
+	default:
+		return false
+	}
+}
+
+// clusterIndexer is a thread-safe store of EKS clusters keyed by ARN,
+// shared by every region shard (and, through EKSWatcher, by any number of
+// Matchers consuming List/Events).
+type clusterIndexer struct {
+	mu    sync.RWMutex
+	byARN map[string]*ekstypes.Cluster
+}
+
+func newClusterIndexer() *clusterIndexer {
+	return &clusterIndexer{byARN: make(map[string]*ekstypes.Cluster)}
+}
+
+func (s *clusterIndexer) list() []*ekstypes.Cluster {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*ekstypes.Cluster, 0, len(s.byARN))
+	for _, cluster := range s.byARN {
+		out = append(out, cluster)
+	}
+	return out
+}
+
+// upsert stores cluster under its ARN and reports whether this is a newly
+// seen ARN (EKSClusterEventAdded), an existing one whose status, endpoint,
+// version or tags changed (EKSClusterEventUpdated), or neither (changed is
+// false).
+func (s *clusterIndexer) upsert(cluster *ekstypes.Cluster) (evtType EKSClusterEventType, changed bool) {
+	arn := aws.ToString(cluster.Arn)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.byARN[arn]
+	s.byARN[arn] = cluster
+	if !ok {
+		return EKSClusterEventAdded, true
+	}
+	if clustersEqual(existing, cluster) {
+		return "", false
+	}
+	return EKSClusterEventUpdated, true
+}
+
+func (s *clusterIndexer) delete(arn string) (*ekstypes.Cluster, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cluster, ok := s.byARN[arn]
+	if ok {
+		delete(s.byARN, arn)
+	}
+	return cluster, ok
+}
+
+func (s *clusterIndexer) arnsInRegion(region string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var arns []string
+	for arn := range s.byARN {
+		if regionFromARN(arn) == region {
+			arns = append(arns, arn)
+		}
+	}
+	return arns
+}
+
+func clustersEqual(a, b *ekstypes.Cluster) bool {
+	return a.Status == b.Status &&
+		aws.ToString(a.Endpoint) == aws.ToString(b.Endpoint) &&
+		aws.ToString(a.Version) == aws.ToString(b.Version) &&
+		tagsEqual(a.Tags, b.Tags)
+}
+
+func tagsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// regionFromARN extracts the region component of an
+// arn:aws:eks:<region>:<account>:cluster/<name> ARN, or "" if arn isn't
+// shaped that way.
+func regionFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 4 {
+		return ""
+	}
+	return parts[3]
+}
+
+// clusterNameFromARN extracts the cluster name from an
+// arn:aws:eks:<region>:<account>:cluster/<name> ARN, or "" if arn isn't
+// shaped that way.
+func clusterNameFromARN(arn string) string {
+	idx := strings.LastIndex(arn, "/")
+	if idx == -1 {
+		return ""
+	}
+	return arn[idx+1:]
+}
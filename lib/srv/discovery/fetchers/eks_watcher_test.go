@@ -0,0 +1,162 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package fetchers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gravitational/teleport/api/types"
+	"github.com/gravitational/teleport/lib/cloud/awsconfig"
+	"github.com/gravitational/teleport/lib/utils"
+)
+
+// watcherMockEKSClientGetter hands out an EKSClient backed by a shared,
+// mutable cluster set, so tests can mutate it between resyncs.
+type watcherMockEKSClientGetter struct {
+	mu       sync.Mutex
+	clusters []*ekstypes.Cluster
+}
+
+func (g *watcherMockEKSClientGetter) setClusters(clusters []*ekstypes.Cluster) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.clusters = clusters
+}
+
+func (g *watcherMockEKSClientGetter) GetAWSEKSClient(_ context.Context, _ string, _ ...awsconfig.OptionsFn) (EKSClient, error) {
+	return &watcherMockEKSAPI{getter: g}, nil
+}
+
+type watcherMockEKSAPI struct {
+	EKSClient
+	getter *watcherMockEKSClientGetter
+}
+
+func (m *watcherMockEKSAPI) ListClusters(_ context.Context, _ *eks.ListClustersInput, _ ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	m.getter.mu.Lock()
+	defer m.getter.mu.Unlock()
+	var names []string
+	for _, cluster := range m.getter.clusters {
+		names = append(names, aws.ToString(cluster.Name))
+	}
+	return &eks.ListClustersOutput{Clusters: names}, nil
+}
+
+func (m *watcherMockEKSAPI) DescribeCluster(_ context.Context, req *eks.DescribeClusterInput, _ ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	m.getter.mu.Lock()
+	defer m.getter.mu.Unlock()
+	for _, cluster := range m.getter.clusters {
+		if aws.ToString(cluster.Name) == aws.ToString(req.Name) {
+			return &eks.DescribeClusterOutput{Cluster: cluster}, nil
+		}
+	}
+	return nil, trace.NotFound("cluster %q not found", aws.ToString(req.Name))
+}
+
+func collectEvents(t *testing.T, w *EKSWatcher, count int, timeout time.Duration) []EKSClusterEvent {
+	t.Helper()
+	var events []EKSClusterEvent
+	deadline := time.After(timeout)
+	for len(events) < count {
+		select {
+		case evt := <-w.Events():
+			events = append(events, evt)
+		case <-deadline:
+			t.Fatalf("timed out waiting for %d events, got %d", count, len(events))
+		}
+	}
+	return events
+}
+
+func TestEKSWatcherInitialSync(t *testing.T) {
+	getter := &watcherMockEKSClientGetter{clusters: eksMockClusters}
+
+	w, err := NewEKSWatcher(context.Background(), EKSWatcherConfig{
+		ClientGetter:   getter,
+		Regions:        []string{"eu-west-1"},
+		ResyncInterval: time.Hour,
+		Logger:         utils.NewSlogLoggerForTests(),
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	events := collectEvents(t, w, len(eksMockClusters), 5*time.Second)
+	for _, evt := range events {
+		require.Equal(t, EKSClusterEventAdded, evt.Type)
+	}
+	require.ElementsMatch(t, clusterNames(eksMockClusters), clusterNames(w.List()))
+}
+
+func TestEKSWatcherFiltersByLabels(t *testing.T) {
+	getter := &watcherMockEKSClientGetter{clusters: eksMockClusters}
+
+	w, err := NewEKSWatcher(context.Background(), EKSWatcherConfig{
+		ClientGetter:   getter,
+		FilterLabels:   types.Labels{"env": []string{"prod"}},
+		Regions:        []string{"eu-west-1"},
+		ResyncInterval: time.Hour,
+		Logger:         utils.NewSlogLoggerForTests(),
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	events := collectEvents(t, w, 2, 5*time.Second)
+	for _, evt := range events {
+		require.Equal(t, "prod", evt.Cluster.Tags["env"])
+	}
+}
+
+func TestEKSWatcherDetectsDeletion(t *testing.T) {
+	initial := []*ekstypes.Cluster{eksMockClusters[0], eksMockClusters[1]}
+	getter := &watcherMockEKSClientGetter{clusters: initial}
+
+	w, err := NewEKSWatcher(context.Background(), EKSWatcherConfig{
+		ClientGetter:   getter,
+		Regions:        []string{"eu-west-1"},
+		ResyncInterval: 20 * time.Millisecond,
+		Logger:         utils.NewSlogLoggerForTests(),
+	})
+	require.NoError(t, err)
+	defer w.Close()
+
+	collectEvents(t, w, 2, 5*time.Second)
+
+	getter.setClusters([]*ekstypes.Cluster{eksMockClusters[0]})
+
+	deleted := collectEvents(t, w, 1, 5*time.Second)[0]
+	require.Equal(t, EKSClusterEventDeleted, deleted.Type)
+	require.Equal(t, aws.ToString(eksMockClusters[1].Arn), aws.ToString(deleted.Cluster.Arn))
+}
+
+func clusterNames(clusters []*ekstypes.Cluster) []string {
+	var names []string
+	for _, cluster := range clusters {
+		names = append(names, aws.ToString(cluster.Name))
+	}
+	return names
+}
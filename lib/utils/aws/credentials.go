@@ -20,7 +20,10 @@ package aws
 
 import (
 	"context"
+	"crypto"
+	"crypto/x509"
 	"log/slog"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -51,6 +54,31 @@ type GetCredentialsRequest struct {
 	ExternalID string
 	// Tags is a list of AWS STS session tags.
 	Tags map[string]string
+	// Region is the AWS region used for regional STS/Roles Anywhere
+	// endpoints. Only consulted by the web identity and Roles Anywhere
+	// flows below.
+	Region string
+	// WebIdentityToken is a literal OIDC token to exchange for credentials
+	// via AssumeRoleWithWebIdentity. Mutually exclusive with
+	// WebIdentityTokenFile.
+	WebIdentityToken string
+	// WebIdentityTokenFile is the path to a file containing an OIDC token
+	// (e.g. a Kubernetes projected service account token) to exchange for
+	// credentials via AssumeRoleWithWebIdentity. The file is re-read on
+	// every refresh, so a rotated token is picked up automatically.
+	WebIdentityTokenFile string
+	// RolesAnywhereProfileARN, if set, requests credentials via AWS IAM
+	// Roles Anywhere's CreateSession API instead of STS AssumeRole.
+	RolesAnywhereProfileARN string
+	// TrustAnchorARN is the Roles Anywhere trust anchor that vouches for
+	// SigningCert. Required when RolesAnywhereProfileARN is set.
+	TrustAnchorARN string
+	// SigningCert is the X.509 certificate registered with TrustAnchorARN.
+	// Required when RolesAnywhereProfileARN is set.
+	SigningCert *x509.Certificate
+	// SigningKey is SigningCert's private key. Required when
+	// RolesAnywhereProfileARN is set.
+	SigningKey crypto.Signer
 }
 
 // CredentialsGetter defines an interface for obtaining STS credentials.
@@ -69,6 +97,13 @@ func NewCredentialsGetter() CredentialsGetter {
 
 // Get obtains STS credentials.
 func (g *credentialsGetter) Get(ctx context.Context, request GetCredentialsRequest) (aws.CredentialsProvider, error) {
+	switch {
+	case request.WebIdentityToken != "" || request.WebIdentityTokenFile != "":
+		return g.getWebIdentityCredentials(ctx, request)
+	case request.RolesAnywhereProfileARN != "":
+		return g.getRolesAnywhereCredentials(ctx, request)
+	}
+
 	slog.DebugContext(ctx, "Creating STS session.", "session_name", request.SessionName, "role_arn", request.RoleARN)
 	client := sts.New(sts.Options{
 		Credentials: request.CredentialsProvider,
@@ -89,6 +124,27 @@ func (g *credentialsGetter) Get(ctx context.Context, request GetCredentialsReque
 	}), nil
 }
 
+// getRolesAnywhereCredentials obtains credentials via AWS IAM Roles
+// Anywhere's CreateSession API, authenticating with request's X.509
+// certificate and key instead of an STS session.
+func (g *credentialsGetter) getRolesAnywhereCredentials(ctx context.Context, request GetCredentialsRequest) (aws.CredentialsProvider, error) {
+	if request.TrustAnchorARN == "" || request.SigningCert == nil || request.SigningKey == nil {
+		return nil, trace.BadParameter("roles anywhere credentials require RolesAnywhereProfileARN, TrustAnchorARN, SigningCert, and SigningKey")
+	}
+
+	slog.DebugContext(ctx, "Creating Roles Anywhere session.", "profile_arn", request.RolesAnywhereProfileARN, "role_arn", request.RoleARN)
+
+	return NewRolesAnywhereCredentialsProvider(RolesAnywhereCredentialsConfig{
+		ProfileARN:      request.RolesAnywhereProfileARN,
+		TrustAnchorARN:  request.TrustAnchorARN,
+		RoleARN:         request.RoleARN,
+		SigningCert:     request.SigningCert,
+		SigningKey:      request.SigningKey,
+		Region:          request.Region,
+		SessionDuration: time.Until(request.Expiry),
+	}), nil
+}
+
 // CachedCredentialsGetterConfig is the config for creating a CredentialsGetter that caches credentials.
 type CachedCredentialsGetterConfig struct {
 	// Getter is the CredentialsGetter for obtaining the STS credentials.
@@ -114,23 +170,38 @@ func (c *CachedCredentialsGetterConfig) SetDefaults() {
 
 // credentialRequestCacheKey credentials request cache key.
 type credentialRequestCacheKey struct {
-	provider    aws.CredentialsProvider
-	expiry      time.Time
-	sessionName string
-	roleARN     string
-	externalID  string
-	tags        string
+	provider          aws.CredentialsProvider
+	expiry            time.Time
+	sessionName       string
+	roleARN           string
+	externalID        string
+	tags              string
+	region            string
+	tokenFingerprint  string
+	profileARN        string
+	trustAnchorARN    string
+	signingCertSerial string
 }
 
 // newCredentialRequestCacheKey creates a new cache key for the credentials
 // request.
 func newCredentialRequestCacheKey(req GetCredentialsRequest) credentialRequestCacheKey {
 	k := credentialRequestCacheKey{
-		provider:    req.CredentialsProvider,
-		expiry:      req.Expiry,
-		sessionName: req.SessionName,
-		roleARN:     req.RoleARN,
-		externalID:  req.ExternalID,
+		provider:       req.CredentialsProvider,
+		expiry:         req.Expiry,
+		sessionName:    req.SessionName,
+		roleARN:        req.RoleARN,
+		externalID:     req.ExternalID,
+		region:         req.Region,
+		profileARN:     req.RolesAnywhereProfileARN,
+		trustAnchorARN: req.TrustAnchorARN,
+	}
+
+	if req.WebIdentityToken != "" || req.WebIdentityTokenFile != "" {
+		k.tokenFingerprint = webIdentityTokenFingerprint(req)
+	}
+	if req.SigningCert != nil {
+		k.signingCertSerial = req.SigningCert.SerialNumber.String()
 	}
 
 	tags := make([]string, 0, len(req.Tags))
@@ -181,6 +252,21 @@ func (g *cachedCredentialsGetter) Get(ctx context.Context, request GetCredential
 type MakeCredentialsProviderFunc func(ctx context.Context, region string, integration string) (aws.CredentialsProvider, error)
 
 func DefaultMakeCredentialsProvider(ctx context.Context, region string, integration string) (aws.CredentialsProvider, error) {
+	// When running outside AWS with a Kubernetes-style projected service
+	// account token, prefer AssumeRoleWithWebIdentity over the ambient
+	// credential chain so agents don't need an instance profile or static
+	// keys.
+	if roleARN, tokenFile := os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); roleARN != "" && tokenFile != "" {
+		slog.DebugContext(ctx, "Using AWS web identity token for credentials.", "role_arn", roleARN)
+		return NewCredentialsGetter().Get(ctx, GetCredentialsRequest{
+			Region:               region,
+			RoleARN:              roleARN,
+			WebIdentityTokenFile: tokenFile,
+			SessionName:          "teleport",
+			Expiry:               time.Now().Add(time.Hour),
+		})
+	}
+
 	opts := []func(*config.LoadOptions) error{
 		config.WithRegion(region),
 	}
@@ -195,3 +281,77 @@ func DefaultMakeCredentialsProvider(ctx context.Context, region string, integrat
 
 	return cfg.Credentials, nil
 }
+
+// IntegrationCredentialsConfig configures how
+// MakeCredentialsProviderForIntegration builds credentials for a Teleport
+// integration.
+type IntegrationCredentialsConfig struct {
+	// AssumeRoleARN, if set, is assumed on top of the ambient credentials
+	// via STS AssumeRole. It is ignored when WorkloadIdentity is set.
+	AssumeRoleARN string
+	// WorkloadIdentity, if set, obtains the base credentials via a
+	// non-ambient workload identity flow (web identity federation or IAM
+	// Roles Anywhere) instead of the default AWS SDK credential chain.
+	WorkloadIdentity *WorkloadIdentityConfig
+}
+
+// WorkloadIdentityConfig selects a non-ambient credential source for an
+// integration, for agents running outside AWS.
+type WorkloadIdentityConfig struct {
+	// RoleARN is the role to assume via AssumeRoleWithWebIdentity. Ignored
+	// when RolesAnywhere is set.
+	RoleARN string
+	// WebIdentityTokenFile is the path to an OIDC token file to exchange
+	// for credentials. Ignored when RolesAnywhere is set.
+	WebIdentityTokenFile string
+	// RolesAnywhere, if set, obtains credentials via AWS IAM Roles
+	// Anywhere instead of AssumeRoleWithWebIdentity.
+	RolesAnywhere *RolesAnywhereCredentialsConfig
+}
+
+// MakeCredentialsProviderForIntegration returns a MakeCredentialsProviderFunc
+// that sources credentials per cfg: from the workload identity when one is
+// set, otherwise from the ambient credential chain, optionally with an
+// AssumeRole layered on top.
+func MakeCredentialsProviderForIntegration(cfg IntegrationCredentialsConfig) MakeCredentialsProviderFunc {
+	return func(ctx context.Context, region string, integration string) (aws.CredentialsProvider, error) {
+		if cfg.WorkloadIdentity != nil {
+			return workloadIdentityCredentialsProvider(ctx, region, *cfg.WorkloadIdentity)
+		}
+
+		base, err := DefaultMakeCredentialsProvider(ctx, region, integration)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if cfg.AssumeRoleARN == "" {
+			return base, nil
+		}
+
+		return NewCredentialsGetter().Get(ctx, GetCredentialsRequest{
+			CredentialsProvider: base,
+			RoleARN:             cfg.AssumeRoleARN,
+			Expiry:              time.Now().Add(time.Hour),
+			SessionName:         integration,
+		})
+	}
+}
+
+// workloadIdentityCredentialsProvider obtains credentials per cfg, without
+// touching the ambient AWS credential chain.
+func workloadIdentityCredentialsProvider(ctx context.Context, region string, cfg WorkloadIdentityConfig) (aws.CredentialsProvider, error) {
+	if cfg.RolesAnywhere != nil {
+		rCfg := *cfg.RolesAnywhere
+		if rCfg.Region == "" {
+			rCfg.Region = region
+		}
+		return NewRolesAnywhereCredentialsProvider(rCfg), nil
+	}
+
+	return NewCredentialsGetter().Get(ctx, GetCredentialsRequest{
+		Region:               region,
+		RoleARN:              cfg.RoleARN,
+		WebIdentityTokenFile: cfg.WebIdentityTokenFile,
+		SessionName:          "teleport-integration",
+		Expiry:               time.Now().Add(time.Hour),
+	})
+}
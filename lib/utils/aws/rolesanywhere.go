@@ -0,0 +1,263 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/gravitational/trace"
+)
+
+// RolesAnywhereCredentialsConfig configures a credentials provider that
+// authenticates via AWS IAM Roles Anywhere, exchanging an X.509 client
+// certificate for temporary credentials. This lets Teleport agents running
+// outside AWS (on-prem hosts with an X.509 identity instead of an instance
+// profile) assume an AWS role without any ambient AWS credentials.
+type RolesAnywhereCredentialsConfig struct {
+	// ProfileARN is the Roles Anywhere profile to assume.
+	ProfileARN string
+	// TrustAnchorARN is the Roles Anywhere trust anchor that vouches for
+	// SigningCert.
+	TrustAnchorARN string
+	// RoleARN is the IAM role to assume; required unless ProfileARN has
+	// exactly one role configured.
+	RoleARN string
+	// SigningCert is the X.509 certificate registered with TrustAnchorARN.
+	SigningCert *x509.Certificate
+	// SigningKey is SigningCert's private key. Must be *rsa.PrivateKey or
+	// *ecdsa.PrivateKey, matching the two signing algorithms AWS Roles
+	// Anywhere's CreateSession endpoint accepts.
+	SigningKey crypto.Signer
+	// Region is the AWS region the Roles Anywhere endpoint lives in.
+	Region string
+	// SessionDuration is the requested session duration; defaults to an
+	// hour when unset.
+	SessionDuration time.Duration
+	// HTTPClient sends the CreateSession request; defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type rolesAnywhereCredentialsProvider struct {
+	cfg RolesAnywhereCredentialsConfig
+}
+
+// NewRolesAnywhereCredentialsProvider returns an aws.CredentialsProvider
+// that authenticates via AWS IAM Roles Anywhere's CreateSession API.
+func NewRolesAnywhereCredentialsProvider(cfg RolesAnywhereCredentialsConfig) aws.CredentialsProvider {
+	return &rolesAnywhereCredentialsProvider{cfg: cfg}
+}
+
+// Retrieve implements aws.CredentialsProvider.
+func (p *rolesAnywhereCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, expiry, err := createRolesAnywhereSession(ctx, p.cfg)
+	if err != nil {
+		return aws.Credentials{}, trace.Wrap(err)
+	}
+	creds.CanExpire = true
+	creds.Expires = expiry
+	return creds, nil
+}
+
+type rolesAnywhereCreateSessionRequest struct {
+	DurationSeconds int    `json:"durationSeconds,omitempty"`
+	ProfileArn      string `json:"profileArn"`
+	TrustAnchorArn  string `json:"trustAnchorArn"`
+	RoleArn         string `json:"roleArn,omitempty"`
+}
+
+type rolesAnywhereCreateSessionResponse struct {
+	CredentialSet []struct {
+		Credentials struct {
+			AccessKeyID     string    `json:"accessKeyId"`
+			SecretAccessKey string    `json:"secretAccessKey"`
+			SessionToken    string    `json:"sessionToken"`
+			Expiration      time.Time `json:"expiration"`
+		} `json:"credentials"`
+	} `json:"credentialSet"`
+}
+
+// createRolesAnywhereSession calls the Roles Anywhere CreateSession
+// endpoint, signing the request with cfg's X.509 certificate and key per
+// https://docs.aws.amazon.com/rolesanywhere/latest/userguide/authentication-sign-process.html.
+func createRolesAnywhereSession(ctx context.Context, cfg RolesAnywhereCredentialsConfig) (aws.Credentials, time.Time, error) {
+	duration := cfg.SessionDuration
+	if duration <= 0 {
+		duration = time.Hour
+	}
+
+	body, err := json.Marshal(rolesAnywhereCreateSessionRequest{
+		DurationSeconds: int(duration.Seconds()),
+		ProfileArn:      cfg.ProfileARN,
+		TrustAnchorArn:  cfg.TrustAnchorARN,
+		RoleArn:         cfg.RoleARN,
+	})
+	if err != nil {
+		return aws.Credentials{}, time.Time{}, trace.Wrap(err)
+	}
+
+	endpoint := fmt.Sprintf("https://rolesanywhere.%s.amazonaws.com/sessions", cfg.Region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return aws.Credentials{}, time.Time{}, trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := signRolesAnywhereRequest(req, body, cfg); err != nil {
+		return aws.Credentials{}, time.Time{}, trace.Wrap(err)
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return aws.Credentials{}, time.Time{}, trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return aws.Credentials{}, time.Time{}, trace.Wrap(err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return aws.Credentials{}, time.Time{}, trace.Errorf("roles anywhere CreateSession failed with status %v: %s", resp.StatusCode, respBody)
+	}
+
+	var parsed rolesAnywhereCreateSessionResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return aws.Credentials{}, time.Time{}, trace.Wrap(err)
+	}
+	if len(parsed.CredentialSet) == 0 {
+		return aws.Credentials{}, time.Time{}, trace.NotFound("roles anywhere CreateSession response contained no credentials")
+	}
+
+	c := parsed.CredentialSet[0].Credentials
+	return aws.Credentials{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+	}, c.Expiration, nil
+}
+
+// signRolesAnywhereRequest signs req in place, setting its X-Amz-Date,
+// X-Amz-X509, and Authorization headers.
+func signRolesAnywhereRequest(req *http.Request, body []byte, cfg RolesAnywhereCredentialsConfig) error {
+	algorithm, err := rolesAnywhereSigningAlgorithm(cfg.SigningKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-X509", base64.StdEncoding.EncodeToString(cfg.SigningCert.Raw))
+
+	headerNames := []string{"content-type", "host", "x-amz-date", "x-amz-x509"}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		var value string
+		if name == "host" {
+			value = req.URL.Host
+		} else {
+			value = req.Header.Get(http.CanonicalHeaderKey(name))
+		}
+		canonicalHeaders.WriteString(name)
+		canonicalHeaders.WriteString(":")
+		canonicalHeaders.WriteString(strings.TrimSpace(value))
+		canonicalHeaders.WriteString("\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	credentialScope := fmt.Sprintf("%s/%s/rolesanywhere/aws4_request", dateStamp, cfg.Region)
+	stringToSign := strings.Join([]string{
+		string(algorithm),
+		amzDate,
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature, err := signWithCertKey(cfg.SigningKey, stringToSign)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	serial := hex.EncodeToString(cfg.SigningCert.SerialNumber.Bytes())
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, serial, credentialScope, signedHeaders, signature,
+	))
+	return nil
+}
+
+func rolesAnywhereSigningAlgorithm(key crypto.Signer) (SigningAlgorithm, error) {
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		return SigningAlgorithmX509RSA, nil
+	case *ecdsa.PublicKey:
+		return SigningAlgorithmX509ECDSA, nil
+	default:
+		return "", trace.BadParameter("unsupported Roles Anywhere signing key type %T", key.Public())
+	}
+}
+
+func signWithCertKey(key crypto.Signer, stringToSign string) (string, error) {
+	digest := sha256.Sum256([]byte(stringToSign))
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
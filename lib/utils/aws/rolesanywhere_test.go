@@ -0,0 +1,87 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/gravitational/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRolesAnywhereSigningAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	algorithm, err := rolesAnywhereSigningAlgorithm(rsaKey)
+	require.NoError(t, err)
+	require.Equal(t, SigningAlgorithmX509RSA, algorithm)
+
+	algorithm, err = rolesAnywhereSigningAlgorithm(ecKey)
+	require.NoError(t, err)
+	require.Equal(t, SigningAlgorithmX509ECDSA, algorithm)
+
+	_, err = rolesAnywhereSigningAlgorithm(unsupportedSigner{})
+	require.True(t, trace.IsBadParameter(err))
+}
+
+// unsupportedSigner implements crypto.Signer with a public key type that
+// Roles Anywhere doesn't support, to exercise the default case.
+type unsupportedSigner struct{}
+
+func (unsupportedSigner) Public() crypto.PublicKey { return "not-a-key" }
+func (unsupportedSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, nil
+}
+
+func TestSignRolesAnywhereRequestSetsHeaders(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cert := &x509.Certificate{SerialNumber: big.NewInt(12345), Raw: []byte("der-bytes")}
+
+	req, err := http.NewRequest(http.MethodPost, "https://rolesanywhere.us-east-1.amazonaws.com/sessions", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	cfg := RolesAnywhereCredentialsConfig{
+		SigningCert: cert,
+		SigningKey:  key,
+		Region:      "us-east-1",
+	}
+
+	err = signRolesAnywhereRequest(req, []byte(`{}`), cfg)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, req.Header.Get("X-Amz-Date"))
+	require.NotEmpty(t, req.Header.Get("X-Amz-X509"))
+	require.Contains(t, req.Header.Get("Authorization"), string(SigningAlgorithmX509RSA))
+	require.Contains(t, req.Header.Get("Authorization"), "3039") // hex(12345)
+}
@@ -35,11 +35,27 @@ type Signer interface {
 	// Sign signs AWS v4 requests with the provided body, service name, region the
 	// request is made to, and time the request is signed at.
 	Sign(ctx context.Context, r *http.Request, body []byte, service, region string, signTime time.Time) error
+	// SignMultiRegion signs the request with SigV4A, producing a signature
+	// that verifies against any region in regionSet. Used for S3 multi-region
+	// access points (MRAP) and other cross-region S3 requests, identified by
+	// ARNs of the form "arn:aws:s3::<account>:accesspoint/<alias>.mrap".
+	SignMultiRegion(ctx context.Context, r *http.Request, body []byte, service string, regionSet []string, signTime time.Time) error
+	// SignS3Request signs an S3 request addressed to resourceARN, choosing
+	// SignMultiRegion over Sign when resourceARN is a MRAP access point ARN.
+	// Callers that build S3 requests from an ARN rather than a fixed region
+	// should go through this instead of calling Sign/SignMultiRegion
+	// directly, so a MRAP ARN can never be signed with single-region SigV4.
+	SignS3Request(ctx context.Context, r *http.Request, body []byte, resourceARN string, regionSet []string, signTime time.Time) error
 }
 
 type sigv4Signer struct {
 	signer              *v4.Signer
 	credentialsProvider aws.CredentialsProvider
+	// Algorithm is the signing algorithm this signer advertises to callers
+	// deciding whether to use Sign (SigningAlgorithmHMACV4) or
+	// SignMultiRegion (SigningAlgorithmECDSAV4A). It does not change the
+	// behavior of Sign itself.
+	Algorithm SigningAlgorithm
 }
 
 // NewSigner creates a new V4 signer.
@@ -56,6 +72,7 @@ func NewSigner(credentialsProvider aws.CredentialsProvider, signingServiceName s
 	return &sigv4Signer{
 		signer:              v4.NewSigner(options),
 		credentialsProvider: credentialsProvider,
+		Algorithm:           SigningAlgorithmHMACV4,
 	}
 }
 
@@ -0,0 +1,225 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aws
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// SigningAlgorithm identifies which AWS signature version a Signer produces.
+type SigningAlgorithm string
+
+const (
+	// SigningAlgorithmHMACV4 is the standard single-region SigV4 algorithm.
+	SigningAlgorithmHMACV4 SigningAlgorithm = "AWS4-HMAC-SHA256"
+	// SigningAlgorithmECDSAV4A is the asymmetric, multi-region SigV4A algorithm
+	// used by AWS S3 multi-region access points (MRAP).
+	SigningAlgorithmECDSAV4A SigningAlgorithm = "AWS4-ECDSA-P256-SHA256"
+	// SigningAlgorithmX509RSA is used by AWS IAM Roles Anywhere when the
+	// client certificate's key is RSA.
+	SigningAlgorithmX509RSA SigningAlgorithm = "AWS4-X509-RSA-SHA256"
+	// SigningAlgorithmX509ECDSA is used by AWS IAM Roles Anywhere when the
+	// client certificate's key is ECDSA.
+	SigningAlgorithmX509ECDSA SigningAlgorithm = "AWS4-X509-ECDSA-SHA256"
+)
+
+// IsS3MRAPAccessPointARN returns true if arn looks like an S3 multi-region
+// access point ARN, e.g. "arn:aws:s3::123456789012:accesspoint/my-mrap.mrap".
+// Requests addressed to a MRAP ARN must be signed with SignMultiRegion
+// instead of Sign.
+func IsS3MRAPAccessPointARN(arn string) bool {
+	return strings.HasPrefix(arn, "arn:aws:s3::") &&
+		strings.Contains(arn, ":accesspoint/") &&
+		strings.HasSuffix(arn, ".mrap")
+}
+
+// SignS3Request signs r, which is addressed to resourceARN, picking
+// SignMultiRegion when resourceARN is a MRAP access point ARN and Sign
+// (single-region SigV4, against regionSet[0]) otherwise, so a MRAP ARN is
+// never accidentally signed with single-region SigV4.
+//
+// No caller in this tree constructs S3 requests yet, so nothing calls this
+// method outside of sigv4a_signer_test.go. It's defined now, ahead of that
+// caller, so the two branches are implemented and tested as one gate instead
+// of being duplicated at each future call site.
+func (s *sigv4Signer) SignS3Request(ctx context.Context, r *http.Request, body []byte, resourceARN string, regionSet []string, signTime time.Time) error {
+	if IsS3MRAPAccessPointARN(resourceARN) {
+		return trace.Wrap(s.SignMultiRegion(ctx, r, body, "s3", regionSet, signTime))
+	}
+	var region string
+	if len(regionSet) > 0 {
+		region = regionSet[0]
+	}
+	return trace.Wrap(s.Sign(ctx, r, body, "s3", region, signTime))
+}
+
+// SignMultiRegion signs an HTTP request using SigV4A (asymmetric signing),
+// which allows the signature to be verified against any region in
+// regionSet. This is required for S3 multi-region access points (MRAP) and
+// other cross-region S3 requests.
+func (s *sigv4Signer) SignMultiRegion(ctx context.Context, r *http.Request, body []byte, service string, regionSet []string, signTime time.Time) error {
+	creds, err := s.credentialsProvider.Retrieve(ctx)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var payloadHash string
+	if r.Body == nil || len(body) == 0 {
+		payloadHash = emptyPayloadHash
+	} else {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+
+	regionSetHeader := strings.Join(regionSet, ",")
+	r.Header.Set("X-Amz-Region-Set", regionSetHeader)
+	r.Header.Set("X-Amz-Date", signTime.UTC().Format("20060102T150405Z"))
+	if creds.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	key, err := deriveECDSAKey(creds.SecretAccessKey)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	credentialScope := fmt.Sprintf("%s/%s/aws4_request", signTime.UTC().Format("20060102"), service)
+	signedHeaders, canonicalRequest := canonicalRequestV4A(r, payloadHash)
+
+	stringToSign := strings.Join([]string{
+		string(SigningAlgorithmECDSAV4A),
+		signTime.UTC().Format("20060102T150405Z"),
+		credentialScope,
+		hashHex(canonicalRequest),
+	}, "\n")
+
+	sig, err := signECDSA(key, stringToSign)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	r.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		SigningAlgorithmECDSAV4A, creds.AccessKeyID, credentialScope, signedHeaders, sig,
+	))
+
+	return nil
+}
+
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalRequestV4A builds the SigV4A canonical request for r, returning
+// the semicolon-joined list of signed header names and the canonical
+// request string.
+func canonicalRequestV4A(r *http.Request, payloadHash string) (signedHeaders, canonicalRequest string) {
+	headerNames := make([]string, 0, len(r.Header)+1)
+	headerNames = append(headerNames, "host")
+	for name := range r.Header {
+		headerNames = append(headerNames, strings.ToLower(name))
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		var value string
+		if name == "host" {
+			value = r.Host
+		} else {
+			value = strings.Join(r.Header.Values(http.CanonicalHeaderKey(name)), ",")
+		}
+		canonicalHeaders.WriteString(name)
+		canonicalHeaders.WriteString(":")
+		canonicalHeaders.WriteString(strings.TrimSpace(value))
+		canonicalHeaders.WriteString("\n")
+	}
+	signedHeaders = strings.Join(headerNames, ";")
+
+	canonicalRequest = strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	return signedHeaders, canonicalRequest
+}
+
+// p256Order is the order N of the NIST P-256 curve.
+var p256Order = elliptic.P256().Params().N
+
+// deriveECDSAKey derives an ECDSA P-256 key pair from an AWS secret access
+// key, following the deterministic SigV4A key derivation algorithm: HMAC-SHA256
+// of "AWS4A" || secret is used to seed a counter-based candidate generator,
+// and candidates are rejected (and the counter incremented) until one falls
+// in the range [1, N-1], per FIPS 186-4 rejection sampling.
+func deriveECDSAKey(secretAccessKey string) (*ecdsa.PrivateKey, error) {
+	inputKey := append([]byte("AWS4A"), []byte(secretAccessKey)...)
+
+	nMinusTwo := new(big.Int).Sub(p256Order, big.NewInt(2))
+
+	for counter := 1; counter < 254; counter++ {
+		mac := hmac.New(sha256.New, inputKey)
+		mac.Write([]byte("AWS4-ECDSA-P256-SHA256"))
+		mac.Write([]byte{0x00})
+		mac.Write(nMinusTwo.Bytes())
+		mac.Write([]byte{byte(counter)})
+		candidate := new(big.Int).SetBytes(mac.Sum(nil))
+		candidate.Add(candidate, big.NewInt(1))
+
+		if candidate.Sign() > 0 && candidate.Cmp(p256Order) < 0 {
+			priv := new(ecdsa.PrivateKey)
+			priv.PublicKey.Curve = elliptic.P256()
+			priv.D = candidate
+			priv.PublicKey.X, priv.PublicKey.Y = elliptic.P256().ScalarBaseMult(candidate.Bytes())
+			return priv, nil
+		}
+	}
+
+	return nil, trace.BadParameter("failed to derive SigV4A key pair after 253 attempts")
+}
+
+// signECDSA signs stringToSign with key and returns the hex-encoded ASN.1
+// DER signature, as required by the AWS4-ECDSA-P256-SHA256 algorithm.
+func signECDSA(key *ecdsa.PrivateKey, stringToSign string) (string, error) {
+	digest := sha256.Sum256([]byte(stringToSign))
+	der, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	return hex.EncodeToString(der), nil
+}
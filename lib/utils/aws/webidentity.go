@@ -0,0 +1,91 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/gravitational/trace"
+)
+
+// webIdentityTokenRetriever implements stscreds.IdentityTokenRetriever,
+// returning either a static token or the current contents of a token file.
+// The file is re-read on every call so a rotated projected service account
+// token (as Kubernetes writes) is picked up the next time STS credentials
+// expire.
+type webIdentityTokenRetriever struct {
+	token     string
+	tokenFile string
+}
+
+func (r webIdentityTokenRetriever) GetIdentityToken() ([]byte, error) {
+	if r.tokenFile != "" {
+		token, err := os.ReadFile(r.tokenFile)
+		if err != nil {
+			return nil, trace.Wrap(err, "failed to read web identity token file")
+		}
+		return token, nil
+	}
+	return []byte(r.token), nil
+}
+
+// getWebIdentityCredentials obtains STS credentials via
+// AssumeRoleWithWebIdentity, used for agents running outside AWS with an
+// OIDC token (e.g. a Kubernetes projected service account token).
+func (g *credentialsGetter) getWebIdentityCredentials(ctx context.Context, request GetCredentialsRequest) (aws.CredentialsProvider, error) {
+	slog.DebugContext(ctx, "Creating STS web identity session.", "session_name", request.SessionName, "role_arn", request.RoleARN)
+
+	client := sts.New(sts.Options{Region: request.Region})
+	retriever := webIdentityTokenRetriever{token: request.WebIdentityToken, tokenFile: request.WebIdentityTokenFile}
+
+	return stscreds.NewWebIdentityRoleProvider(client, request.RoleARN, retriever, func(o *stscreds.WebIdentityRoleOptions) {
+		o.RoleSessionName = MaybeHashRoleSessionName(request.SessionName)
+		if !request.Expiry.IsZero() {
+			o.Duration = time.Until(request.Expiry)
+		}
+	}), nil
+}
+
+// webIdentityTokenFingerprint returns a string that changes whenever the
+// token request refers to would produce a different credential, so the
+// credentials cache doesn't keep serving a stale provider after the
+// underlying token rotates.
+func webIdentityTokenFingerprint(request GetCredentialsRequest) string {
+	switch {
+	case request.WebIdentityTokenFile != "":
+		info, err := os.Stat(request.WebIdentityTokenFile)
+		if err != nil {
+			return request.WebIdentityTokenFile
+		}
+		return request.WebIdentityTokenFile + "@" + info.ModTime().UTC().Format(time.RFC3339Nano)
+	case request.WebIdentityToken != "":
+		sum := sha256.Sum256([]byte(request.WebIdentityToken))
+		return hex.EncodeToString(sum[:])
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebIdentityTokenFingerprint(t *testing.T) {
+	require.Empty(t, webIdentityTokenFingerprint(GetCredentialsRequest{}))
+
+	a := webIdentityTokenFingerprint(GetCredentialsRequest{WebIdentityToken: "token-a"})
+	b := webIdentityTokenFingerprint(GetCredentialsRequest{WebIdentityToken: "token-b"})
+	require.NotEmpty(t, a)
+	require.NotEqual(t, a, b)
+	require.Equal(t, a, webIdentityTokenFingerprint(GetCredentialsRequest{WebIdentityToken: "token-a"}))
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("v1"), 0o600))
+
+	before := webIdentityTokenFingerprint(GetCredentialsRequest{WebIdentityTokenFile: tokenFile})
+	require.NotEmpty(t, before)
+
+	// Rewriting the file with a newer mtime changes the fingerprint, so a
+	// cache keyed on it won't keep serving credentials for the old token.
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(tokenFile, future, future))
+	after := webIdentityTokenFingerprint(GetCredentialsRequest{WebIdentityTokenFile: tokenFile})
+	require.NotEqual(t, before, after)
+}
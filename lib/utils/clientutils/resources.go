@@ -57,3 +57,71 @@ func PaginatedResourceIterator[T any](
 		}
 	}
 }
+
+// PrefetchOptions configures PaginatedResourceIteratorWithPrefetch.
+type PrefetchOptions struct {
+	// BufferDepth is the number of pages that may be fetched ahead of the
+	// consumer. Defaults to 2 if unset or non-positive.
+	BufferDepth int
+}
+
+// PaginatedResourceIteratorWithPrefetch is like PaginatedResourceIterator,
+// but fetches pages in a background goroutine up to opts.BufferDepth ahead
+// of the consumer, so a slow per-item consumer overlaps with the next
+// page's fetch instead of paying for both in sequence. Iteration order is
+// unchanged, and the first error is yielded exactly once, after any
+// resources from the page it was returned alongside. Breaking out of the
+// iteration early cancels the background fetch. Backends that can't
+// tolerate overlapping list calls should keep using PaginatedResourceIterator.
+func PaginatedResourceIteratorWithPrefetch[T any](
+	ctx context.Context,
+	listPageFunc func(context.Context, int, string) ([]T, string, error),
+	opts PrefetchOptions,
+) iter.Seq2[T, error] {
+	bufferDepth := opts.BufferDepth
+	if bufferDepth <= 0 {
+		bufferDepth = 2
+	}
+
+	type fetchedPage struct {
+		resources []T
+		err       error
+	}
+
+	return func(yield func(T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		pages := make(chan fetchedPage, bufferDepth)
+
+		go func() {
+			defer close(pages)
+			var pageToken string
+			for {
+				resources, nextToken, err := listPageFunc(ctx, defaults.DefaultChunkSize, pageToken)
+				select {
+				case pages <- fetchedPage{resources: resources, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil || nextToken == "" {
+					return
+				}
+				pageToken = nextToken
+			}
+		}()
+
+		for p := range pages {
+			for _, resource := range p.resources {
+				if !yield(resource, nil) {
+					return
+				}
+			}
+			if p.err != nil {
+				var t T
+				yield(t, p.err)
+				return
+			}
+		}
+	}
+}
@@ -21,6 +21,7 @@ package clientutils
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/gravitational/trace"
 	"github.com/stretchr/testify/require"
@@ -112,3 +113,98 @@ func TestPaginatedResourceIterator(t *testing.T) {
 		})
 	}
 }
+
+// slowPaginator simulates a backend with non-trivial per-page latency, so
+// tests can distinguish "fetch and consume run back to back" from "fetch
+// and consume overlap".
+type slowPaginator struct {
+	pages    int
+	fetchDur time.Duration
+}
+
+func (p *slowPaginator) List(_ context.Context, pageSize int, token string) ([]bool, string, error) {
+	time.Sleep(p.fetchDur)
+
+	pageNum := 0
+	if token != "" {
+		n, err := parsePageToken(token)
+		if err != nil {
+			return nil, "", trace.BadParameter("invalid token")
+		}
+		pageNum = n
+	}
+
+	if pageNum == p.pages-1 {
+		return make([]bool, pageSize), "", nil
+	}
+	return make([]bool, pageSize), nextPageToken(pageNum + 1), nil
+}
+
+func nextPageToken(pageNum int) string {
+	return string(rune('a' + pageNum))
+}
+
+func parsePageToken(token string) (int, error) {
+	if len(token) != 1 {
+		return 0, trace.BadParameter("invalid token")
+	}
+	return int(token[0] - 'a'), nil
+}
+
+func TestPaginatedResourceIteratorWithPrefetch(t *testing.T) {
+	const pages = 5
+	const fetchDur = 20 * time.Millisecond
+	const consumeDur = 20 * time.Millisecond
+
+	paginator := &slowPaginator{pages: pages, fetchDur: fetchDur}
+
+	var count int
+	start := time.Now()
+	for item, err := range PaginatedResourceIteratorWithPrefetch(context.Background(), paginator.List, PrefetchOptions{}) {
+		require.NoError(t, err)
+		_ = item
+		// consumeDur is the consumer's cost per page, paid on each page's
+		// first item.
+		if count%defaults.DefaultChunkSize == 0 {
+			time.Sleep(consumeDur)
+		}
+		count++
+	}
+	elapsed := time.Since(start)
+
+	require.Equal(t, pages*defaults.DefaultChunkSize, count)
+	// With fetch and consume overlapping, total time should track
+	// max(fetch, consume)*pages, not their sum*pages. Allow generous
+	// slack for scheduling jitter.
+	require.Less(t, elapsed, time.Duration(pages)*(fetchDur+consumeDur)*3/4)
+
+	// Breaking out early must not leak the background fetch goroutine or
+	// block on the unbuffered consumer.
+	var seen int
+	for _, err := range PaginatedResourceIteratorWithPrefetch(context.Background(), paginator.List, PrefetchOptions{BufferDepth: 1}) {
+		require.NoError(t, err)
+		seen++
+		if seen == 2 {
+			break
+		}
+	}
+	require.Equal(t, 2, seen)
+}
+
+func TestPaginatedResourceIteratorWithPrefetchPropagatesError(t *testing.T) {
+	paginatorPage2Error := &mockPaginator{errorOnPage2: true}
+
+	var outputError error
+	var trueCount int
+	for item, err := range PaginatedResourceIteratorWithPrefetch(context.Background(), paginatorPage2Error.List, PrefetchOptions{}) {
+		if err != nil {
+			outputError = err
+			break
+		}
+		if item {
+			trueCount++
+		}
+	}
+	require.Error(t, outputError)
+	require.Equal(t, defaults.DefaultChunkSize/2, trueCount)
+}
@@ -0,0 +1,92 @@
+package eventlog
+
+import "log/slog"
+
+// EventID identifies a distinct, stable Windows Event Log event. Stable IDs
+// let Event Viewer custom views and SIEM rules (Splunk UF, Windows Event
+// Forwarding) filter on event type instead of parsing free-form message
+// text.
+//
+// Event IDs are allocated in 1000-wide, per-component blocks (see
+// Component.EventID) so components can evolve independently without
+// colliding. Do not renumber or reuse an ID once shipped: that silently
+// breaks any saved filter or subscription built against it.
+type EventID uint32
+
+// EventGenericMessage is used for records from a Component not covered by
+// Component.EventID's known switch, or logged without a Component at all.
+const EventGenericMessage EventID = 1
+
+// Category groups related events for Event Viewer's "Task Category" column.
+// It has no effect beyond display and filtering.
+type Category uint16
+
+// Categories shared across components. Components are free to define
+// additional categories starting at CategoryTeleportMax.
+const (
+	CategoryGeneral Category = iota + 1
+	CategoryLifecycle
+	CategorySession
+	CategorySecurity
+	CategoryVNet
+
+	// CategoryTeleportMax is the first Category value not reserved by this
+	// package.
+	CategoryTeleportMax
+)
+
+// Component identifies which Teleport component is writing to the event
+// log; each gets its own stable EventID block so components can be
+// filtered independently in Event Viewer and SIEM tooling.
+type Component string
+
+// Components with a registered EventID block.
+const (
+	ComponentAuth  Component = "auth"
+	ComponentProxy Component = "proxy"
+	ComponentNode  Component = "node"
+	ComponentAgent Component = "agent"
+	ComponentTSH   Component = "tsh"
+)
+
+// componentBases maps each known Component to the start of its 1000-wide
+// EventID block.
+//
+// These numeric IDs, and the Category values above, must stay in sync with
+// messages.mc, which a Windows build compiles into the message-table
+// resource DLL Install's msgFile argument points at; renumbering either
+// without recompiling and re-shipping that DLL leaves Event Viewer unable
+// to render the affected events' text.
+var componentBases = map[Component]EventID{
+	ComponentAuth:  1000,
+	ComponentProxy: 2000,
+	ComponentNode:  3000,
+	ComponentAgent: 4000,
+	ComponentTSH:   5000,
+}
+
+// EventID returns c's stable EventID for level. Components not in
+// componentBases fall back to EventGenericMessage.
+func (c Component) EventID(level slog.Level) EventID {
+	base, ok := componentBases[c]
+	if !ok {
+		return EventGenericMessage
+	}
+	return base + levelOffset(level)
+}
+
+// levelOffset maps an slog.Level to a small, stable offset within a
+// component's EventID block, so e.g. auth's error events always land on
+// EventID 1003 regardless of which log call produced them.
+func levelOffset(level slog.Level) EventID {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 2
+	case level >= slog.LevelInfo:
+		return 1
+	default: // slog.LevelDebug and below.
+		return 0
+	}
+}
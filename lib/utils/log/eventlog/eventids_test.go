@@ -0,0 +1,47 @@
+package eventlog
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentEventID(t *testing.T) {
+	tests := []struct {
+		name      string
+		component Component
+		level     slog.Level
+		want      EventID
+	}{
+		{"auth debug", ComponentAuth, slog.LevelDebug, 1000},
+		{"auth info", ComponentAuth, slog.LevelInfo, 1001},
+		{"auth warn", ComponentAuth, slog.LevelWarn, 1002},
+		{"auth error", ComponentAuth, slog.LevelError, 1003},
+		{"proxy info", ComponentProxy, slog.LevelInfo, 2001},
+		{"node error", ComponentNode, slog.LevelError, 3003},
+		{"agent warn", ComponentAgent, slog.LevelWarn, 4002},
+		{"tsh info", ComponentTSH, slog.LevelInfo, 5001},
+		{"unknown component falls back to generic", Component("unknown"), slog.LevelError, EventGenericMessage},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.component.EventID(tc.level))
+		})
+	}
+}
+
+func TestComponentEventIDStableAcrossBlocks(t *testing.T) {
+	// Each component's block must not overlap another's, so filtering by
+	// EventID range in Event Viewer/SIEM reliably isolates one component.
+	seen := map[EventID]Component{}
+	for _, c := range []Component{ComponentAuth, ComponentProxy, ComponentNode, ComponentAgent, ComponentTSH} {
+		for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+			id := c.EventID(level)
+			if owner, ok := seen[id]; ok {
+				t.Fatalf("EventID %d claimed by both %q and %q", id, owner, c)
+			}
+			seen[id] = c
+		}
+	}
+}
@@ -5,12 +5,52 @@ import (
 	"golang.org/x/sys/windows/svc/eventlog"
 )
 
-func Install(source string) error {
+// Install registers source with the Windows Event Log using msgFile (a
+// compiled message-table resource DLL) as its event message file, so Event
+// Viewer and SIEM collectors (Splunk UF, Windows Event Forwarding) can
+// render Teleport's stable EventIDs and categories instead of the single
+// generic formatted string InstallLegacy produces.
+func Install(source, msgFile string) error {
+	const useExpandKey = false
+	return trace.Wrap(
+		eventlog.Install(source, msgFile, useExpandKey, eventlog.Info|eventlog.Warning|eventlog.Error),
+	)
+}
+
+// InstallLegacy registers source the way Install used to: against the
+// generic "EventCreate"-style message DLL, with every event collapsed to a
+// single formatted string and no distinct EventID or category.
+//
+// Deprecated: use Install with a real message file. InstallLegacy is kept
+// only so Migrate can detect and replace an existing legacy registration.
+func InstallLegacy(source string) error {
 	return trace.Wrap(
 		eventlog.InstallAsEventCreate(source, eventlog.Info|eventlog.Warning|eventlog.Error),
 	)
 }
 
+// Migrate upgrades an existing source registration (whether absent, or
+// previously created by InstallLegacy) to a real Teleport message-file
+// registration. Install fails outright if the registry key for source
+// already exists, so Migrate removes it first, making the upgrade safe to
+// run unconditionally on every startup.
+func Migrate(source, msgFile string) error {
+	// Best-effort: a fresh deployment has no prior registration to remove.
+	_ = Remove(source)
+	return trace.Wrap(Install(source, msgFile))
+}
+
+// Remove deregisters source from the Windows Event Log.
+//
+// Deprecated: use Uninstall, which additionally closes any Logger handles
+// still open for source.
 func Remove(source string) error {
 	return trace.Wrap(eventlog.Remove(source))
 }
+
+// Uninstall deregisters source from the Windows Event Log and closes any
+// Logger handles still open for it.
+func Uninstall(source string) error {
+	closeLoggersFor(source)
+	return trace.Wrap(eventlog.Remove(source))
+}
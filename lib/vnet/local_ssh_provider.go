@@ -3,14 +3,21 @@ package vnet
 import (
 	"context"
 	"errors"
+	"io"
 	"strings"
 
 	"github.com/gravitational/teleport/api/client/proto"
 	vnetv1 "github.com/gravitational/teleport/gen/proto/go/teleport/lib/vnet/v1"
 	"github.com/gravitational/trace"
 	"github.com/jonboulle/clockwork"
+	"golang.org/x/crypto/ssh"
 )
 
+// defaultForcePasswordAuthSuffix is the username suffix that switches an
+// incoming VNet SSH connection into password-auth mode, for clients that
+// mishandle a "none" auth success. See sshHandler.buildSSHServerConfig.
+const defaultForcePasswordAuthSuffix = "+password"
+
 type localSSHProvider struct {
 	ClientApplication
 	clusterConfigCache *ClusterConfigCache
@@ -146,6 +153,44 @@ func (p *localSSHProvider) resolveSSHInfoForCluster(
 	}, nil
 }
 
+// NewSSHSessionRecorder always returns (nil, nil): ClientApplication has no
+// config surface yet for a user to opt an SSH VNet session into recording,
+// and no session-upload path exists for it to land on once recorded. Adding
+// one is tracked separately; until then every session channel takes the
+// unrecorded bypass path in forwardChannels.
+func (p *localSSHProvider) NewSSHSessionRecorder(ctx context.Context, sshInfo *SSHInfo, meta SSHSessionMetadata) (io.WriteCloser, error) {
+	return nil, nil
+}
+
+// ForcePasswordAuthConfig enables the force-password-auth escape hatch with
+// the default suffix and a callback that accepts any password: VNet SSH
+// doesn't otherwise check credentials (the target node is what actually
+// authenticates the user, via dialTargetSSH's cert-based user SSH config),
+// so this exists purely to give buggy clients a "password" prompt to get
+// past instead of choking on a "none" auth success. ClientApplication has no
+// config surface yet for an operator to disable this suffix or swap in a
+// real credential check; adding one is tracked separately.
+func (p *localSSHProvider) ForcePasswordAuthConfig() (string, PasswordCallback) {
+	return defaultForcePasswordAuthSuffix, func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+		return nil, nil
+	}
+}
+
+// AuthorizeSSHChannel always allows the channel: the resolved Teleport role
+// set this policy hook is meant to consult (to, say, disable agent
+// forwarding globally or block direct-tcpip outside an allow-listed port
+// range) isn't threaded through ClientApplication yet. Adding that is
+// tracked separately.
+func (p *localSSHProvider) AuthorizeSSHChannel(ctx context.Context, sshInfo *SSHInfo, username, chanType string, extraData []byte) error {
+	return nil
+}
+
+// AuthorizeSSHRequest always allows the request, for the same reason
+// AuthorizeSSHChannel does.
+func (p *localSSHProvider) AuthorizeSSHRequest(ctx context.Context, sshInfo *SSHInfo, username, chanType, reqType string, payload []byte) error {
+	return nil
+}
+
 func isSSHDescendantSubdomain(sshFQDN, zone string) bool {
 	return strings.HasSuffix(sshFQDN, ".ssh."+fullyQualify(zone))
 }
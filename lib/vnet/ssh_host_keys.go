@@ -0,0 +1,296 @@
+// Teleport
+// Copyright (C) 2024 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vnet
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gravitational/teleport/lib/cryptosuites"
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshHostKeyStore persists a distinct SSH host key per resolved FQDN on
+// disk, so VNet presents the same identity to a given target across
+// restarts instead of generating a fresh ephemeral key every time. Keys are
+// generated lazily, on first use, and rotated in place with a grace period
+// during which the outgoing key keeps being presented so an in-progress
+// rotation can't break a client that already pinned it.
+type sshHostKeyStore struct {
+	dir   string
+	clock clockwork.Clock
+
+	mu sync.Mutex
+}
+
+// newSSHHostKeyStore returns a store that persists host keys as one file per
+// FQDN under dir. dir is created on first write; it does not need to exist
+// yet.
+func newSSHHostKeyStore(dir string, clock clockwork.Clock) *sshHostKeyStore {
+	return &sshHostKeyStore{dir: dir, clock: clock}
+}
+
+// hostKeyRecord is the on-disk, per-FQDN record of a host key and, while a
+// rotation's grace period is active, the key it's replacing.
+type hostKeyRecord struct {
+	FQDN                 string    `json:"fqdn"`
+	CurrentKeyPEM        []byte    `json:"current_key_pem"`
+	PreviousKeyPEM       []byte    `json:"previous_key_pem,omitempty"`
+	PreviousKeyExpiresAt time.Time `json:"previous_key_expires_at,omitempty"`
+}
+
+// signerForFQDN returns the ssh.Signer that should currently be presented as
+// the host key for fqdn, generating and persisting a new one on first use.
+// While a prior call to Rotate's grace period hasn't elapsed yet, the
+// outgoing key is returned instead of the new one.
+func (s *sshHostKeyStore) signerForFQDN(fqdn string) (ssh.Signer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.load(fqdn)
+	if err != nil {
+		return nil, trace.Wrap(err, "loading VNet SSH host key for %s", fqdn)
+	}
+	if rec == nil {
+		signer, keyPEM, err := generateHostKey()
+		if err != nil {
+			return nil, trace.Wrap(err, "generating VNet SSH host key for %s", fqdn)
+		}
+		if err := s.save(&hostKeyRecord{FQDN: fqdn, CurrentKeyPEM: keyPEM}); err != nil {
+			return nil, trace.Wrap(err, "persisting VNet SSH host key for %s", fqdn)
+		}
+		return signer, nil
+	}
+
+	if len(rec.PreviousKeyPEM) > 0 && s.clock.Now().Before(rec.PreviousKeyExpiresAt) {
+		return signerFromPEM(rec.PreviousKeyPEM)
+	}
+	return signerFromPEM(rec.CurrentKeyPEM)
+}
+
+// Rotate generates a new host key for fqdn and schedules the switchover to
+// it for gracePeriod from now. Until the grace period elapses,
+// signerForFQDN keeps returning the outgoing key, so a rotation never
+// invalidates a client's already-pinned known_hosts entry or an in-progress
+// reconnect out from under it; Fingerprints lets a caller surface
+// the incoming key's fingerprint ahead of the cutover so it can be
+// pre-populated.
+//
+// Rotate is a no-op if fqdn has no existing key: signerForFQDN will
+// generate its first key on demand.
+func (s *sshHostKeyStore) Rotate(fqdn string, gracePeriod time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.load(fqdn)
+	if err != nil {
+		return trace.Wrap(err, "loading VNet SSH host key for %s", fqdn)
+	}
+	if rec == nil {
+		return nil
+	}
+
+	_, newKeyPEM, err := generateHostKey()
+	if err != nil {
+		return trace.Wrap(err, "generating rotated VNet SSH host key for %s", fqdn)
+	}
+	return trace.Wrap(s.save(&hostKeyRecord{
+		FQDN:                 fqdn,
+		CurrentKeyPEM:        newKeyPEM,
+		PreviousKeyPEM:       rec.CurrentKeyPEM,
+		PreviousKeyExpiresAt: s.clock.Now().Add(gracePeriod),
+	}), "persisting rotated VNet SSH host key for %s", fqdn)
+}
+
+// HostKeyFingerprint describes one host key fingerprint a client may see
+// presented for FQDN, for surfacing via a `tsh vnet ssh-fingerprints`-style
+// command so users can pre-populate known_hosts.
+type HostKeyFingerprint struct {
+	FQDN string
+	// Fingerprint is the SHA256 host key fingerprint, in the same
+	// "SHA256:base64" form OpenSSH prints.
+	Fingerprint string
+	// ExpiresAt is the zero time for a current key, or the time a previous
+	// key (kept alive by an in-progress rotation's grace period) stops
+	// being presented.
+	ExpiresAt time.Time
+}
+
+// Fingerprints returns the fingerprint(s) currently or imminently presented
+// for fqdn: just the current key, or both the outgoing and incoming key
+// while a rotation's grace period is active.
+func (s *sshHostKeyStore) Fingerprints(fqdn string) ([]HostKeyFingerprint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.load(fqdn)
+	if err != nil {
+		return nil, trace.Wrap(err, "loading VNet SSH host key for %s", fqdn)
+	}
+	if rec == nil {
+		return nil, nil
+	}
+	return fingerprintsForRecord(rec, s.clock)
+}
+
+// ListFingerprints returns fingerprints for every FQDN this store has ever
+// generated a host key for, so a `tsh vnet ssh-fingerprints` command can
+// print the full set a user may want to pre-populate, not just one target.
+func (s *sshHostKeyStore) ListFingerprints() ([]HostKeyFingerprint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, trace.Wrap(err, "listing VNet SSH host key directory %s", s.dir)
+	}
+
+	var out []HostKeyFingerprint
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, trace.Wrap(err, "reading VNet SSH host key file %s", entry.Name())
+		}
+		var rec hostKeyRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, trace.Wrap(err, "parsing VNet SSH host key file %s", entry.Name())
+		}
+		fps, err := fingerprintsForRecord(&rec, s.clock)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, fps...)
+	}
+	return out, nil
+}
+
+func fingerprintsForRecord(rec *hostKeyRecord, clock clockwork.Clock) ([]HostKeyFingerprint, error) {
+	var out []HostKeyFingerprint
+	if len(rec.PreviousKeyPEM) > 0 && clock.Now().Before(rec.PreviousKeyExpiresAt) {
+		prevSigner, err := signerFromPEM(rec.PreviousKeyPEM)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		out = append(out, HostKeyFingerprint{
+			FQDN:        rec.FQDN,
+			Fingerprint: ssh.FingerprintSHA256(prevSigner.PublicKey()),
+			ExpiresAt:   rec.PreviousKeyExpiresAt,
+		})
+	}
+	currentSigner, err := signerFromPEM(rec.CurrentKeyPEM)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	out = append(out, HostKeyFingerprint{
+		FQDN:        rec.FQDN,
+		Fingerprint: ssh.FingerprintSHA256(currentSigner.PublicKey()),
+	})
+	return out, nil
+}
+
+func (s *sshHostKeyStore) path(fqdn string) string {
+	sum := sha256.Sum256([]byte(fqdn))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load requires s.mu to be held.
+func (s *sshHostKeyStore) load(fqdn string) (*hostKeyRecord, error) {
+	data, err := os.ReadFile(s.path(fqdn))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var rec hostKeyRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &rec, nil
+}
+
+// save requires s.mu to be held. It writes via a temp file and rename so a
+// crash mid-write can't leave a corrupt record behind.
+func (s *sshHostKeyStore) save(rec *hostKeyRecord) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return trace.Wrap(err)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	path := s.path(rec.FQDN)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(os.Rename(tmpPath, path))
+}
+
+// generateHostKey returns a fresh Ed25519 SSH host key along with its
+// PKCS8/PEM encoding for persistence.
+func generateHostKey() (ssh.Signer, []byte, error) {
+	priv, err := cryptosuites.GenerateKeyWithAlgorithm(cryptosuites.Ed25519)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	signer, err := ssh.NewSignerFromSigner(priv)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, trace.Wrap(err)
+	}
+	return signer, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// signerFromPEM parses a PKCS8/PEM-encoded private key, as written by
+// generateHostKey, back into an ssh.Signer.
+func signerFromPEM(keyPEM []byte) (ssh.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, trace.BadParameter("invalid PEM-encoded VNet SSH host key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	signerKey, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, trace.BadParameter("VNet SSH host key of type %T is not usable as a signer", key)
+	}
+	signer, err := ssh.NewSignerFromSigner(signerKey)
+	return signer, trace.Wrap(err)
+}
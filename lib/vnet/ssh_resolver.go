@@ -19,15 +19,17 @@ package vnet
 import (
 	"context"
 	"crypto/tls"
+	"io"
 	"log/slog"
 	"net"
+	"strings"
 	"sync/atomic"
 
+	"github.com/google/uuid"
 	"github.com/gravitational/teleport"
 	proxyclient "github.com/gravitational/teleport/api/client/proxy"
 	tracessh "github.com/gravitational/teleport/api/observability/tracing/ssh"
 	"github.com/gravitational/teleport/api/utils/grpc/interceptors"
-	"github.com/gravitational/teleport/lib/cryptosuites"
 	"github.com/gravitational/teleport/lib/utils"
 	"github.com/gravitational/trace"
 	"github.com/jonboulle/clockwork"
@@ -37,37 +39,75 @@ import (
 	"google.golang.org/grpc"
 )
 
+// PasswordCallback matches ssh.ServerConfig's PasswordCallback field; x/crypto/ssh
+// doesn't export a named type for it (ssh.PasswordCallback is itself a
+// client-side AuthMethod constructor, not this shape), so sshProvider
+// implementations and callers use this alias instead.
+type PasswordCallback = func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error)
+
 type sshProvider interface {
 	ResolveSSHInfo(ctx context.Context, fqdn string) (*SSHInfo, error)
 	TeleportClientTLSConfig(ctx context.Context, profileName, clusterName string) (*tls.Config, error)
 	UserSSHConfig(ctx context.Context, sshInfo *SSHInfo, username string) (*ssh.ClientConfig, error)
+	// NewSSHSessionRecorder returns a sink session channels are recorded
+	// to, or (nil, nil) if the operator hasn't opted into recording for
+	// sshInfo. Implementations are responsible for getting the returned
+	// bytes into the cluster's session-upload path.
+	NewSSHSessionRecorder(ctx context.Context, sshInfo *SSHInfo, meta SSHSessionMetadata) (io.WriteCloser, error)
+	// ForcePasswordAuthConfig returns the username suffix that switches an
+	// incoming VNet SSH connection from the default "none"-auth success into
+	// password mode, plus the PasswordCallback used to authenticate it.
+	// A connection's username is checked for this suffix before the suffix
+	// is stripped to resolve the target user (see sshHandler.handleTCPConnector).
+	// Returning an empty suffix disables the escape hatch entirely.
+	ForcePasswordAuthConfig() (suffix string, callback PasswordCallback)
+	// AuthorizeSSHChannel decides whether username may open a channel of
+	// chanType (e.g. "session", "direct-tcpip") on sshInfo, given the
+	// channel's raw, channel-type-specific extraData. A non-nil error
+	// rejects the channel without tearing down the underlying connection.
+	AuthorizeSSHChannel(ctx context.Context, sshInfo *SSHInfo, username, chanType string, extraData []byte) error
+	// AuthorizeSSHRequest decides whether username may forward a channel
+	// request of reqType (e.g. "auth-agent-req@openssh.com", "subsystem") to
+	// the target, given the request's raw, request-type-specific payload and
+	// the type of channel it arrived on. A non-nil error rejects the request
+	// without tearing down the channel or connection.
+	AuthorizeSSHRequest(ctx context.Context, sshInfo *SSHInfo, username, chanType, reqType string, payload []byte) error
+}
+
+// channelAuthorizer binds sshProvider's channel-level policy hooks to a
+// single already-authenticated connection, so forwardChannels and
+// forwardChannelRequests don't need to carry sshInfo/username separately.
+type channelAuthorizer struct {
+	sshProvider sshProvider
+	sshInfo     *SSHInfo
+	username    string
+}
+
+func (a *channelAuthorizer) authorizeChannel(ctx context.Context, chanType string, extraData []byte) error {
+	return trace.Wrap(a.sshProvider.AuthorizeSSHChannel(ctx, a.sshInfo, a.username, chanType, extraData))
+}
+
+func (a *channelAuthorizer) authorizeRequest(ctx context.Context, chanType, reqType string, payload []byte) error {
+	return trace.Wrap(a.sshProvider.AuthorizeSSHRequest(ctx, a.sshInfo, a.username, chanType, reqType, payload))
 }
 
 type sshResolver struct {
-	sshProvider     sshProvider
-	log             *slog.Logger
-	clock           clockwork.Clock
-	sshServerConfig *ssh.ServerConfig
+	sshProvider  sshProvider
+	log          *slog.Logger
+	clock        clockwork.Clock
+	hostKeyStore *sshHostKeyStore
 }
 
-func newSSHResolver(sshProvider sshProvider, clock clockwork.Clock) *sshResolver {
-	hostKey, err := cryptosuites.GenerateKeyWithAlgorithm(cryptosuites.Ed25519)
-	if err != nil {
-		panic(err)
-	}
-	hostSigner, err := ssh.NewSignerFromSigner(hostKey)
-	if err != nil {
-		panic(err)
-	}
-	sshServerConfig := &ssh.ServerConfig{
-		NoClientAuth: true,
-	}
-	sshServerConfig.AddHostKey(hostSigner)
+// newSSHResolver returns an sshResolver whose per-target host keys are
+// persisted under hostKeyDir (a stable, per-profile directory; typically
+// something like <profile dir>/vnet_ssh_host_keys), so a VNet restart
+// doesn't force every target's known_hosts entry to be relearned.
+func newSSHResolver(sshProvider sshProvider, hostKeyDir string, clock clockwork.Clock) *sshResolver {
 	return &sshResolver{
-		sshProvider:     sshProvider,
-		log:             log.With(teleport.ComponentKey, "VNet.SSHResolver"),
-		clock:           clock,
-		sshServerConfig: sshServerConfig,
+		sshProvider:  sshProvider,
+		log:          log.With(teleport.ComponentKey, "VNet.SSHResolver"),
+		clock:        clock,
+		hostKeyStore: newSSHHostKeyStore(hostKeyDir, clock),
 	}
 }
 
@@ -76,28 +116,112 @@ func (r sshResolver) resolveTCPHandler(ctx context.Context, fqdn string) (*tcpHa
 	if err != nil {
 		return nil, err
 	}
-	sshHandler := r.newSSHHandler(ctx, sshInfo)
+	sshHandler := r.newSSHHandler(ctx, sshInfo, fqdn)
 	return &tcpHandlerSpec{
 		ipv4CIDRRange: sshInfo.Ipv4CidrRange,
 		tcpHandler:    sshHandler,
 	}, nil
 }
 
-func (r *sshResolver) newSSHHandler(ctx context.Context, sshInfo *SSHInfo) *sshHandler {
+func (r *sshResolver) newSSHHandler(ctx context.Context, sshInfo *SSHInfo, fqdn string) *sshHandler {
 	return &sshHandler{
-		sshInfo:         sshInfo,
-		sshProvider:     r.sshProvider,
-		sshServerConfig: r.sshServerConfig,
+		sshInfo:      sshInfo,
+		fqdn:         fqdn,
+		sshProvider:  r.sshProvider,
+		hostKeyStore: r.hostKeyStore,
+		clock:        r.clock,
 	}
 }
 
 type sshHandler struct {
-	sshInfo         *SSHInfo
-	sshProvider     sshProvider
-	sshServerConfig *ssh.ServerConfig
+	sshInfo      *SSHInfo
+	fqdn         string
+	sshProvider  sshProvider
+	hostKeyStore *sshHostKeyStore
+	clock        clockwork.Clock
 
 	fg              singleflight.Group
 	sshClientConfig atomic.Pointer[ssh.ClientConfig]
+	hostKeySigner   atomic.Pointer[ssh.Signer]
+}
+
+// hostKeySignerForHandler lazily loads h.fqdn's persisted host key on first
+// use and caches it for the handler's lifetime. It's loaded per-handler
+// rather than once per sshResolver because every fqdn has its own key. A
+// Rotate of h.fqdn after that first load is not picked up by this handler:
+// it keeps presenting the key it cached.
+func (h *sshHandler) hostKeySignerForHandler() (ssh.Signer, error) {
+	if s := h.hostKeySigner.Load(); s != nil {
+		return *s, nil
+	}
+	_, err, _ := h.fg.Do("host-key", func() (any, error) {
+		if s := h.hostKeySigner.Load(); s != nil {
+			return nil, nil
+		}
+		signer, err := h.hostKeyStore.signerForFQDN(h.fqdn)
+		if err != nil {
+			return nil, trace.Wrap(err, "loading VNet SSH host key for %s", h.fqdn)
+		}
+		h.hostKeySigner.Store(&signer)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return *h.hostKeySigner.Load(), nil
+}
+
+// buildSSHServerConfig returns a fresh ssh.ServerConfig for a single incoming
+// connection, presenting h.fqdn's persisted host key. Unlike the host key
+// itself, the config is rebuilt on every connection rather than cached,
+// because it's also where the force-password auth escape hatch is wired up,
+// and sshProvider.ForcePasswordAuthConfig is re-consulted every time so an
+// operator can enable, disable, or replace it without restarting VNet.
+//
+// By default NoClientAuthCallback accepts the "none" auth method outright,
+// same as a bare NoClientAuth: true. Some SSH clients (PuTTY, a few mobile
+// clients, jsch derivatives) mishandle that success reply and drop the
+// connection or loop instead of proceeding. As an escape hatch, a client
+// can suffix its username (default "+password") to force the connection
+// into password mode instead: NoClientAuthCallback then rejects "none" for
+// that username so the client falls back to "password", which
+// PasswordCallback accepts unconditionally (or, for an sshProvider wired up
+// with real credential checking, validates for real).
+func (h *sshHandler) buildSSHServerConfig(ctx context.Context) (*ssh.ServerConfig, error) {
+	signer, err := h.hostKeySignerForHandler()
+	if err != nil {
+		return nil, trace.Wrap(err, "loading VNet SSH host key for %s", h.fqdn)
+	}
+
+	suffix, passwordCallback := h.sshProvider.ForcePasswordAuthConfig()
+	cfg := &ssh.ServerConfig{
+		// NoClientAuthCallback is only consulted by x/crypto/ssh when
+		// NoClientAuth is also true; without it the server would reject every
+		// connection outright with "no authentication methods configured".
+		NoClientAuth: true,
+		NoClientAuthCallback: func(conn ssh.ConnMetadata) (*ssh.Permissions, error) {
+			if suffix != "" && strings.HasSuffix(conn.User(), suffix) {
+				return nil, trace.AccessDenied("password required for %s", conn.User())
+			}
+			return nil, nil
+		},
+	}
+	if suffix != "" && passwordCallback != nil {
+		cfg.PasswordCallback = passwordCallback
+	}
+	cfg.AddHostKey(signer)
+	return cfg, nil
+}
+
+// targetUsername strips sshProvider's force-password-auth suffix (if any)
+// from an authenticated connection's username, recovering the username to
+// resolve the target session as.
+func (h *sshHandler) targetUsername(connUsername string) string {
+	suffix, _ := h.sshProvider.ForcePasswordAuthConfig()
+	if suffix == "" {
+		return connUsername
+	}
+	return strings.TrimSuffix(connUsername, suffix)
 }
 
 func (h *sshHandler) handleTCPConnector(ctx context.Context, localPort uint16, connector func() (net.Conn, error)) error {
@@ -113,19 +237,60 @@ func (h *sshHandler) handleTCPConnector(ctx context.Context, localPort uint16, c
 	}
 	defer localTCPConn.Close()
 
-	serverConn, chans, requests, err := ssh.NewServerConn(localTCPConn, h.sshServerConfig)
+	sshServerConfig, err := h.buildSSHServerConfig(ctx)
+	if err != nil {
+		return trace.Wrap(err, "building SSH server config for %s", h.fqdn)
+	}
+
+	serverConn, chans, requests, err := ssh.NewServerConn(localTCPConn, sshServerConfig)
 	if err != nil {
 		return trace.Wrap(err, "accepting incoming SSH conn")
 	}
 	defer serverConn.Close()
 
-	sshClient, err := h.dialTargetSSH(ctx, targetTCPConn, serverConn.User())
+	username := h.targetUsername(serverConn.User())
+
+	sshClient, err := h.dialTargetSSH(ctx, targetTCPConn, username)
 	if err != nil {
 		return trace.Wrap(err, "initiating SSH connection to target")
 	}
 	defer sshClient.Close()
 
-	return trace.Wrap(forwardSSHConnection(ctx, sshClient, serverConn, chans, requests), "proxying SSH connection")
+	recorderFactory := h.newSessionRecorderFactory(username)
+	authorizer := &channelAuthorizer{
+		sshProvider: h.sshProvider,
+		sshInfo:     h.sshInfo,
+		username:    username,
+	}
+
+	return trace.Wrap(forwardSSHConnection(ctx, sshClient, serverConn, chans, requests, authorizer, recorderFactory), "proxying SSH connection")
+}
+
+// newSessionRecorderFactory returns a sessionRecorderFactory that builds a
+// recorder for a single "session" channel on an already-authenticated
+// connection for username. The factory returns (nil, nil) when the
+// sshProvider's NewSSHSessionRecorder returns no sink.
+func (h *sshHandler) newSessionRecorderFactory(username string) sessionRecorderFactory {
+	return func(ctx context.Context) (*sessionRecorder, error) {
+		meta := SSHSessionMetadata{
+			SessionID:   uuid.NewString(),
+			User:        username,
+			Cluster:     h.sshInfo.Cluster,
+			LeafCluster: h.sshInfo.LeafCluster,
+			Hostname:    h.sshInfo.Hostname,
+			Addr:        h.sshInfo.Addr,
+		}
+		dst, err := h.sshProvider.NewSSHSessionRecorder(ctx, h.sshInfo, meta)
+		if err != nil {
+			return nil, trace.Wrap(err, "creating SSH session recorder")
+		}
+		if dst == nil {
+			// The operator hasn't opted into recording.
+			return nil, nil
+		}
+		rec, err := newSessionRecorder(dst, h.clock, meta)
+		return rec, trace.Wrap(err)
+	}
 }
 
 func (h *sshHandler) dialTargetTCP(ctx context.Context) (net.Conn, error) {
@@ -205,20 +370,24 @@ func (h *sshHandler) userSSHConfig(ctx context.Context, username string) (*ssh.C
 }
 
 // forwardSSHConnection forwards all SSH traffic—both global requests and channels—
-// from serverConn to targetClient, and vice versa.
+// from serverConn to targetClient, and vice versa. Each "session" channel is
+// additionally recorded via recorderFactory, if non-nil; every other channel
+// type (direct-tcpip, forwarded-tcpip, etc.) bypasses recording entirely.
 func forwardSSHConnection(
 	ctx context.Context,
 	targetClient *ssh.Client,
 	serverConn *ssh.ServerConn,
 	channels <-chan ssh.NewChannel,
 	requests <-chan *ssh.Request,
+	authorizer *channelAuthorizer,
+	recorderFactory sessionRecorderFactory,
 ) error {
 	g, ctx := errgroup.WithContext(ctx)
 	g.Go(func() error {
 		return forwardGlobalRequests(ctx, targetClient, requests)
 	})
 	g.Go(func() error {
-		return forwardChannels(ctx, g, targetClient, channels)
+		return forwardChannels(ctx, g, targetClient, channels, authorizer, recorderFactory)
 	})
 	return trace.Wrap(g.Wait(), "forwarding SSH connection")
 }
@@ -251,11 +420,17 @@ func forwardGlobalRequests(
 	}
 }
 
+// sessionChannelType is the ssh.NewChannel.ChannelType() value for an
+// interactive or exec session, the only channel type forwardChannels records.
+const sessionChannelType = "session"
+
 func forwardChannels(
 	ctx context.Context,
 	g *errgroup.Group,
 	targetClient *ssh.Client,
 	channels <-chan ssh.NewChannel,
+	authorizer *channelAuthorizer,
+	recorderFactory sessionRecorderFactory,
 ) error {
 	for {
 		select {
@@ -265,8 +440,18 @@ func forwardChannels(
 			if !ok {
 				return nil
 			}
+			chanType := newChan.ChannelType()
+
+			if err := authorizer.authorizeChannel(ctx, chanType, newChan.ExtraData()); err != nil {
+				log.InfoContext(ctx, "Rejecting SSH channel", "type", chanType, "error", err)
+				if rejectErr := newChan.Reject(ssh.Prohibited, err.Error()); rejectErr != nil {
+					return trace.Wrap(rejectErr, "rejecting prohibited SSH channel")
+				}
+				continue
+			}
+
 			// Open a corresponding channel to the target.
-			targetChan, targetRequests, err := targetClient.OpenChannel(newChan.ChannelType(), newChan.ExtraData())
+			targetChan, targetRequests, err := targetClient.OpenChannel(chanType, newChan.ExtraData())
 			if err != nil {
 				err = trace.Wrap(err, "failed to open channel on target")
 				if rejectErr := newChan.Reject(ssh.ConnectionFailed, err.Error()); rejectErr != nil {
@@ -280,9 +465,25 @@ func forwardChannels(
 				targetChan.Close()
 				return trace.Wrap(err, "accepting incoming channel request")
 			}
+
+			// Only "session" channels carry an interactive or exec session
+			// worth recording; direct-tcpip/forwarded-tcpip channels always
+			// take the bypass path below with no recording overhead.
+			var rec *sessionRecorder
+			if chanType == sessionChannelType && recorderFactory != nil {
+				rec, err = recorderFactory(ctx)
+				if err != nil {
+					log.InfoContext(ctx, "Failed to create SSH session recorder, continuing without recording",
+						"error", err)
+				}
+			}
+
 			forwardChannel(ctx, g,
+				chanType,
 				serverChan, serverRequests,
 				targetChan, targetRequests,
+				authorizer,
+				rec,
 			)
 		}
 	}
@@ -291,26 +492,41 @@ func forwardChannels(
 func forwardChannel(
 	ctx context.Context,
 	g *errgroup.Group,
+	chanType string,
 	serverChan ssh.Channel, serverRequests <-chan *ssh.Request,
 	targetChan ssh.Channel, targetRequests <-chan *ssh.Request,
+	authorizer *channelAuthorizer,
+	rec *sessionRecorder,
 ) {
 	g.Go(func() error {
 		// This will close serverChan and targetChan before returning.
-		if err := utils.ProxyConn(ctx, serverChan, targetChan); err != nil {
+		var err error
+		if rec == nil {
+			err = utils.ProxyConn(ctx, serverChan, targetChan)
+		} else {
+			err = proxyRecordedConn(ctx, serverChan, targetChan, rec)
+		}
+		if err != nil {
 			log.InfoContext(ctx, "Proxying SSH channel failed",
 				"error", err)
 		}
+		if rec != nil {
+			if err := rec.Close(); err != nil {
+				log.InfoContext(ctx, "Closing SSH session recorder failed",
+					"error", err)
+			}
+		}
 		return nil
 	})
 	g.Go(func() error {
-		if err := forwardChannelRequests(ctx, targetChan, serverChan, serverRequests); err != nil {
+		if err := forwardChannelRequests(ctx, chanType, targetChan, serverRequests, authorizer, rec); err != nil {
 			log.InfoContext(ctx, "Forwarding channel requests from server to target failed",
 				"error", err)
 		}
 		return nil
 	})
 	g.Go(func() error {
-		if err := forwardChannelRequests(ctx, serverChan, targetChan, targetRequests); err != nil {
+		if err := forwardChannelRequests(ctx, chanType, serverChan, targetRequests, nil, nil); err != nil {
 			log.InfoContext(ctx, "Forwarding channel requests from target to server failed",
 				"error", err)
 		}
@@ -318,10 +534,39 @@ func forwardChannel(
 	})
 }
 
+// proxyRecordedConn is utils.ProxyConn with serverChan's reads (the client's
+// input) additionally teed into rec as session input and targetChan's reads
+// teed in as session output.
+func proxyRecordedConn(ctx context.Context, serverChan, targetChan ssh.Channel, rec *sessionRecorder) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		defer targetChan.Close()
+		_, err := io.Copy(io.MultiWriter(targetChan, rec.inputWriter()), serverChan)
+		return trace.Wrap(err)
+	})
+	g.Go(func() error {
+		defer serverChan.Close()
+		_, err := io.Copy(io.MultiWriter(serverChan, rec.outputWriter()), targetChan)
+		return trace.Wrap(err)
+	})
+	return trace.Wrap(g.Wait())
+}
+
+// forwardChannelRequests forwards requests to dst, inspecting them for
+// recording via rec if rec is non-nil (only the server->target direction of
+// a "session" channel is recorded, since that's the direction carrying
+// pty-req/shell/exec/subsystem/window-change/env), and authorizing them via
+// authorizer if non-nil (only the server->target direction is authorized,
+// since that's the direction a client could use to request agent
+// forwarding, a subsystem, or a pty; a rejected request is replied to with
+// ok=false rather than forwarded, and the channel stays open).
 func forwardChannelRequests(
 	ctx context.Context,
-	dst, src ssh.Channel,
+	chanType string,
+	dst ssh.Channel,
 	requests <-chan *ssh.Request,
+	authorizer *channelAuthorizer,
+	rec *sessionRecorder,
 ) error {
 	for {
 		select {
@@ -331,6 +576,18 @@ func forwardChannelRequests(
 			if !ok {
 				return nil
 			}
+			if authorizer != nil {
+				if err := authorizer.authorizeRequest(ctx, chanType, req.Type, req.Payload); err != nil {
+					log.InfoContext(ctx, "Rejecting SSH channel request", "type", req.Type, "error", err)
+					if err := req.Reply(false, nil); err != nil {
+						return trace.Wrap(err, "replying to prohibited channel request")
+					}
+					continue
+				}
+			}
+			if rec != nil {
+				rec.recordChannelRequest(req.Type, req.Payload)
+			}
 			ok, err := dst.SendRequest(req.Type, req.WantReply, req.Payload)
 			if err != nil {
 				err = trace.Wrap(err, "forwarding channel request")
@@ -0,0 +1,124 @@
+// Teleport
+// Copyright (C) 2024 Gravitational, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package vnet
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeForcePasswordAuthProvider is a minimal sshProvider that only
+// implements ForcePasswordAuthConfig, the one method buildSSHServerConfig
+// consults; every other method panics if called, since these tests never
+// get far enough to reach them.
+type fakeForcePasswordAuthProvider struct {
+	sshProvider
+	suffix   string
+	callback PasswordCallback
+}
+
+func (p *fakeForcePasswordAuthProvider) ForcePasswordAuthConfig() (string, PasswordCallback) {
+	return p.suffix, p.callback
+}
+
+// dialSSH builds an sshHandler for fqdn with sshProvider, listens on a
+// loopback TCP port with its ssh.ServerConfig, and attempts a real SSH
+// client handshake as username against it, returning the client's handshake
+// error (if any). A loopback listener is used instead of net.Pipe because
+// net.Pipe's unbuffered, synchronous Write blocks until a concurrent Read is
+// already pending on the other end, which deadlocks the SSH version
+// exchange (both sides write their banner before either reads the peer's).
+func dialSSH(t *testing.T, sshProvider sshProvider, fqdn, username string) error {
+	t.Helper()
+
+	h := &sshHandler{
+		sshInfo:      &SSHInfo{},
+		fqdn:         fqdn,
+		sshProvider:  sshProvider,
+		hostKeyStore: newSSHHostKeyStore(t.TempDir(), clockwork.NewRealClock()),
+		clock:        clockwork.NewRealClock(),
+	}
+
+	cfg, err := h.buildSSHServerConfig(context.Background())
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		ssh.NewServerConn(conn, cfg)
+	}()
+
+	clientConfig := &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{ssh.Password("irrelevant")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+	clientConn, clientErr := ssh.Dial("tcp", listener.Addr().String(), clientConfig)
+	if clientErr == nil {
+		clientConn.Close()
+	}
+	return clientErr
+}
+
+func TestBuildSSHServerConfig_PlainNoClientAuth(t *testing.T) {
+	provider := &fakeForcePasswordAuthProvider{suffix: defaultForcePasswordAuthSuffix}
+
+	err := dialSSH(t, provider, "plain.example.com", "alice")
+	require.NoError(t, err, "a username without the force-password suffix should authenticate via \"none\" with no password prompt")
+}
+
+func TestBuildSSHServerConfig_ForcePasswordSuffix(t *testing.T) {
+	var calledWith string
+	provider := &fakeForcePasswordAuthProvider{
+		suffix: defaultForcePasswordAuthSuffix,
+		callback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			calledWith = conn.User()
+			return nil, nil
+		},
+	}
+
+	err := dialSSH(t, provider, "password.example.com", "alice"+defaultForcePasswordAuthSuffix)
+	require.NoError(t, err, "a suffixed username should fall back to password auth and succeed via the configured callback")
+	require.Equal(t, "alice"+defaultForcePasswordAuthSuffix, calledWith)
+}
+
+func TestBuildSSHServerConfig_ForcePasswordSuffixRejectsBadPassword(t *testing.T) {
+	provider := &fakeForcePasswordAuthProvider{
+		suffix: defaultForcePasswordAuthSuffix,
+		callback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, trace.AccessDenied("wrong password")
+		},
+	}
+
+	err := dialSSH(t, provider, "password-reject.example.com", "alice"+defaultForcePasswordAuthSuffix)
+	require.Error(t, err, "a suffixed username whose password callback rejects every attempt must not authenticate")
+}
@@ -22,7 +22,9 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"iter"
 	"net"
 	"os"
@@ -34,6 +36,7 @@ import (
 
 	"github.com/gravitational/trace"
 	"github.com/mattn/go-shellwords"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/gravitational/teleport/api/client/proto"
 	"github.com/gravitational/teleport/api/mfa"
@@ -62,6 +65,11 @@ type databaseExecCommand struct {
 	clusterClient      *client.ClusterClient
 	reuseMFAResponse   *proto.MFAAuthenticateResponse
 	reuseMFAResponseMu sync.Mutex
+
+	// templateOverrides holds ~/.tsh/db_exec.yaml's per-protocol command
+	// template overrides, loaded once in run() before runOne fans out
+	// across targets.
+	templateOverrides map[string]string
 }
 
 func (c *databaseExecCommand) run(cf *CLIConf) error {
@@ -88,6 +96,14 @@ func (c *databaseExecCommand) run(cf *CLIConf) error {
 		return trace.BadParameter("no databases found")
 	}
 
+	c.templateOverrides, err = loadDBExecCommandTemplateOverrides()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if err := c.validateCommandTemplates(cf, databases); err != nil {
+		return trace.Wrap(err)
+	}
+
 	clusterClient, err := tc.ConnectToCluster(cf.Context)
 	if err != nil {
 		return trace.Wrap(err)
@@ -97,68 +113,137 @@ func (c *databaseExecCommand) run(cf *CLIConf) error {
 
 	ctx := context.WithValue(cf.Context, "db-exec-mfa", c.reuseMFA)
 
-	// TODO(greedy52) run parallel with errgroup
+	infoWriter := newDatabaseExecInfoPrinter(cf)
+
+	parallel := cf.DBExecParallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	// Up to --parallel targets run at once (one at a time when it's unset or
+	// not positive). By default, targets run independently: one target's
+	// command failing must not cancel or block the others, so the errgroup
+	// uses a plain, non-derived context purely to bound concurrency and
+	// collect errors. --fail-fast opts into the opposite: the first failure
+	// cancels groupCtx, which is threaded down into each target's command so
+	// still-running targets are actually killed rather than left to finish.
+	var g *errgroup.Group
+	groupCtx := ctx
+	if cf.DBExecFailFast {
+		g, groupCtx = errgroup.WithContext(ctx)
+	} else {
+		g = new(errgroup.Group)
+	}
+	g.SetLimit(parallel)
+
+	var summariesMu sync.Mutex
+	var summaries []dbExecSummary
 	for _, db := range databases {
-		dbInfo := &databaseInfo{
-			RouteToDatabase: tlsca.RouteToDatabase{
-				ServiceName: db.GetName(),
-				Protocol:    db.GetProtocol(),
-				Username:    cf.DatabaseUser,
-				Database:    cf.DatabaseName,
-				Roles:       requestedDatabaseRoles(cf),
-			},
-			database: db,
-		}
+		g.Go(func() error {
+			summary, err := c.runOne(groupCtx, cf, tc, profile, db, infoWriter)
 
-		requires := &dbLocalProxyRequirement{
-			localProxy: true,
-			tunnel:     true,
-		}
-		lp, err := c.startLocalProxy(ctx, cf, tc, profile, dbInfo, requires)
-		if err != nil {
-			return trace.Wrap(err)
-		}
+			summariesMu.Lock()
+			summaries = append(summaries, summary)
+			summariesMu.Unlock()
 
-		dbCmd, err := c.makeCommand(cf, tc, dbInfo, lp.GetAddr())
-		if err != nil {
-			return trace.Wrap(err)
-		}
+			return err
+		})
+	}
+	runErr := g.Wait()
+
+	printDBExecSummaryTable(cf.Stdout(), summaries)
+
+	return trace.Wrap(runErr)
+}
+
+// runOne executes the configured command against a single database target,
+// writing its stdout/stderr through per-target printers and its info lines
+// through the shared infoWriter, so output from concurrent targets stays
+// attributable and doesn't interleave mid-line. It
+// always returns a dbExecSummary, even on error, so run can still report the
+// target in the final summary table.
+func (c *databaseExecCommand) runOne(ctx context.Context, cf *CLIConf, tc *client.TeleportClient, profile *client.ProfileStatus, db types.Database, infoWriter io.Writer) (dbExecSummary, error) {
+	summary := dbExecSummary{service: db.GetName()}
+	started := time.Now()
+	defer func() { summary.duration = time.Since(started) }()
+
+	dbInfo := &databaseInfo{
+		RouteToDatabase: tlsca.RouteToDatabase{
+			ServiceName: db.GetName(),
+			Protocol:    db.GetProtocol(),
+			Username:    cf.DatabaseUser,
+			Database:    cf.DatabaseName,
+			Roles:       requestedDatabaseRoles(cf),
+		},
+		database: db,
+	}
+
+	requires := &dbLocalProxyRequirement{
+		localProxy: true,
+		tunnel:     true,
+	}
+	lp, err := c.startLocalProxy(ctx, cf, tc, profile, dbInfo, requires)
+	if err != nil {
+		summary.err = err
+		return summary, trace.Wrap(err)
+	}
 
-		logger.DebugContext(cf.Context, "Executing database command", "command", dbCmd)
+	dbCmd, err := c.makeCommand(ctx, cf, tc, dbInfo, lp.GetAddr())
+	if err != nil {
+		summary.err = err
+		return summary, trace.Wrap(err)
+	}
+
+	logger.DebugContext(ctx, "Executing database command", "command", dbCmd, "database_service", db.GetName())
+
+	var logFileName string
+	if cf.SSHLogDir != "" {
+		logFileName = filepath.Join(cf.SSHLogDir, dbInfo.ServiceName+".log")
+		fmt.Fprintf(infoWriter, "Execute command for database service %s. Logs will be saved at %q.\n", db.GetName(), logFileName)
+	} else {
+		fmt.Fprintf(infoWriter, "Execute command for database service %s.\n", db.GetName())
+	}
+	if cf.DryRun {
+		return summary, nil
+	}
 
-		// TODO(greedy52) add some line prefix to differentiate output from the
-		// targets.
-		var logFileName string
-		if cf.SSHLogDir != "" {
-			logFileName = filepath.Join(cf.SSHLogDir, dbInfo.ServiceName+".log")
-			fmt.Fprintf(cf.Stdout(), "Execute command for database service %s. Logs will be saved at %q.\n", db.GetName(), logFileName)
-		} else {
-			fmt.Fprintf(cf.Stdout(), "Execute command for database service %s.\n", db.GetName())
+	if logFileName != "" {
+		logFilePath, err := utils.EnsureLocalPath(logFileName, "", "")
+		if err != nil {
+			summary.err = err
+			return summary, trace.Wrap(err)
 		}
-		if !cf.DryRun {
-			if logFileName != "" {
-				logFilePath, err := utils.EnsureLocalPath(logFileName, "", "")
-				if err != nil {
-					return trace.Wrap(err)
-				}
-				logFile, err := os.Create(logFilePath)
-				if err != nil {
-					return trace.Wrap(err)
-				}
-				dbCmd.Stdout = logFile
-				dbCmd.Stderr = logFile
-			} else {
-				dbCmd.Stdout = cf.Stdout()
-				dbCmd.Stderr = cf.Stderr()
-			}
-			if err := cf.RunCommand(dbCmd); err != nil {
-				errMsg := fmt.Sprintf("Failed to execute database service %s: %v.\n", db.GetName(), err)
-				dbCmd.Stderr.Write([]byte(errMsg))
-			}
+		summary.logPath = logFilePath
+		logFile, err := os.Create(logFilePath)
+		if err != nil {
+			summary.err = err
+			return summary, trace.Wrap(err)
 		}
-		fmt.Fprintln(cf.Stdout(), "")
+		defer logFile.Close()
+		dbCmd.Stdout = logFile
+		dbCmd.Stderr = logFile
+	} else {
+		dbCmd.Stdout = newDatabaseExecOutputPrinter(cf, db)
+		dbCmd.Stderr = newDatabaseExecErrorPrinter(cf, db)
 	}
-	return nil
+	if err := cf.RunCommand(dbCmd); err != nil {
+		fmt.Fprintf(dbCmd.Stderr, "Failed to execute database service %s: %v.\n", db.GetName(), err)
+		summary.err = err
+		summary.exitCode = exitCodeFromError(err)
+		return summary, trace.Wrap(err)
+	}
+	return summary, nil
+}
+
+// exitCodeFromError extracts the child process's exit code from the error
+// cf.RunCommand returns, for the summary table. Errors that aren't an
+// *exec.ExitError (e.g. the binary couldn't be started at all) report -1.
+func exitCodeFromError(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
 
 func (c *databaseExecCommand) checkInputs(cf *CLIConf) error {
@@ -168,11 +253,16 @@ func (c *databaseExecCommand) checkInputs(cf *CLIConf) error {
 		return trace.BadParameter("Provide at least one database service names or use one of --search-labels,--search-keywords,--search-query")
 	}
 
-	// TODO(greedy52) support command template
 	switch {
 	case cf.DatabaseQuery == "":
 		return trace.BadParameter("--exec-query must be specified")
 	}
+
+	switch dbExecOutputFormat(cf.DBExecOutputFormat) {
+	case "", dbExecOutputFormatText, dbExecOutputFormatJSON:
+	default:
+		return trace.BadParameter("invalid --output-format %q, must be one of: text, json", cf.DBExecOutputFormat)
+	}
 	return nil
 }
 
@@ -207,6 +297,27 @@ func (c *databaseExecCommand) precheckDatabases(cf *CLIConf, dbs []types.Databas
 	return nil
 }
 
+// validateCommandTemplates confirms every target database's protocol
+// resolves to a command template - built-in, file-overridden, or
+// --exec-command-template - before any local proxy is started, so a typo'd
+// or unsupported protocol fails fast instead of partway through a
+// many-target run.
+func (c *databaseExecCommand) validateCommandTemplates(cf *CLIConf, dbs []types.Database) error {
+	seen := make(map[string]bool, len(dbs))
+	for _, db := range dbs {
+		protocol := db.GetProtocol()
+		if seen[protocol] {
+			continue
+		}
+		seen[protocol] = true
+
+		if _, err := dbExecCommandTemplate(protocol, cf.DBExecCommandTemplate, c.templateOverrides); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
 func (c *databaseExecCommand) searchDatabases(cf *CLIConf, tc *client.TeleportClient) ([]types.Database, error) {
 	dbs, err := tc.ListDatabases(cf.Context, tc.ResourceFilter(types.KindDatabaseServer))
 	if err != nil {
@@ -244,17 +355,18 @@ func (c *databaseExecCommand) searchDatabases(cf *CLIConf, tc *client.TeleportCl
 	return dbs, nil
 }
 
-func (c *databaseExecCommand) makeCommand(cf *CLIConf, tc *client.TeleportClient, dbInfo *databaseInfo, lpAddr string) (*exec.Cmd, error) {
+func (c *databaseExecCommand) makeCommand(ctx context.Context, cf *CLIConf, tc *client.TeleportClient, dbInfo *databaseInfo, lpAddr string) (*exec.Cmd, error) {
 	host, port, err := net.SplitHostPort(lpAddr)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
 
-	// TODO(greedy52) do this properly in other places and support general
-	// command template. This is just an example to make mysql works.
-	templ, err := template.New("dbcmd").Parse(
-		`mysql --user {{.db_user}} --port {{.db_port}} --host {{.db_host}} --protocol TCP -e "{{.db_query}}"`,
-	)
+	templateText, err := dbExecCommandTemplate(dbInfo.Protocol, cf.DBExecCommandTemplate, c.templateOverrides)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	templ, err := template.New("dbcmd").Parse(templateText)
 	if err != nil {
 		return nil, trace.Wrap(err)
 	}
@@ -282,7 +394,7 @@ func (c *databaseExecCommand) makeCommand(cf *CLIConf, tc *client.TeleportClient
 		return nil, trace.BadParameter("query is empty")
 	}
 
-	return exec.CommandContext(cf.Context, words[0], words[1:]...), nil
+	return exec.CommandContext(ctx, words[0], words[1:]...), nil
 }
 
 func (c *databaseExecCommand) reuseMFA(ctx context.Context) (*proto.MFAAuthenticateResponse, error) {
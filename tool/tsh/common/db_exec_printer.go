@@ -19,16 +19,34 @@
 package common
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
+	"github.com/gravitational/trace"
+
 	"github.com/gravitational/teleport/api/types"
 	"github.com/gravitational/teleport/lib/utils"
 )
 
+// dbExecOutputFormat selects how databaseExecPrinter output is rendered.
+type dbExecOutputFormat string
+
+const (
+	// dbExecOutputFormatText is the default, human-readable, colorized
+	// output format.
+	dbExecOutputFormatText dbExecOutputFormat = "text"
+	// dbExecOutputFormatJSON emits one NDJSON object per logical line, with
+	// no ANSI escapes, so output from many targets can be parsed in CI.
+	dbExecOutputFormatJSON dbExecOutputFormat = "json"
+)
+
 type ansiEscapeCode string
 
 const (
@@ -60,6 +78,57 @@ func (w *databaseExecPrinter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// dbExecJSONLine is a single NDJSON record emitted by databaseExecJSONPrinter.
+type dbExecJSONLine struct {
+	Timestamp string `json:"ts"`
+	Database  string `json:"db"`
+	Stream    string `json:"stream"`
+	Line      string `json:"line"`
+}
+
+// databaseExecJSONPrinter emits one JSON object per logical line of output
+// to Writer. Writes are buffered until a newline is seen, so partial writes
+// spanning multiple Write calls are reassembled into whole lines rather than
+// being split on every chunk boundary. Write is safe for concurrent use, as
+// the info printer is shared by every target.
+type databaseExecJSONPrinter struct {
+	io.Writer
+	db     string
+	stream string
+	mu     sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (w *databaseExecJSONPrinter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// No newline yet; put back the partial line and wait for more.
+			w.buf.WriteString(line)
+			break
+		}
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			if err := w.emit(trimmed); err != nil {
+				return len(p), trace.Wrap(err)
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (w *databaseExecJSONPrinter) emit(line string) error {
+	enc := json.NewEncoder(w.Writer)
+	return enc.Encode(dbExecJSONLine{
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Database:  w.db,
+		Stream:    w.stream,
+		Line:      line,
+	})
+}
+
 func pickColorIfTerminal(color ansiEscapeCode) ansiEscapeCode {
 	if utils.IsTerminal(os.Stderr) {
 		return color
@@ -68,6 +137,9 @@ func pickColorIfTerminal(color ansiEscapeCode) ansiEscapeCode {
 }
 
 func newDatabaseExecInfoPrinter(cf *CLIConf) io.Writer {
+	if dbExecOutputFormat(cf.DBExecOutputFormat) == dbExecOutputFormatJSON {
+		return &databaseExecJSONPrinter{Writer: cf.Stdout(), stream: "info"}
+	}
 	return &databaseExecPrinter{
 		Writer: cf.Stdout(),
 		name:   "[info]",
@@ -76,6 +148,9 @@ func newDatabaseExecInfoPrinter(cf *CLIConf) io.Writer {
 }
 
 func newDatabaseExecOutputPrinter(cf *CLIConf, db types.Database) io.Writer {
+	if dbExecOutputFormat(cf.DBExecOutputFormat) == dbExecOutputFormatJSON {
+		return &databaseExecJSONPrinter{Writer: cf.Stdout(), db: db.GetName(), stream: "stdout"}
+	}
 	return &databaseExecPrinter{
 		Writer: cf.Stdout(),
 		name:   fmt.Sprintf("[%s][output]", db.GetName()),
@@ -84,9 +159,41 @@ func newDatabaseExecOutputPrinter(cf *CLIConf, db types.Database) io.Writer {
 }
 
 func newDatabaseExecErrorPrinter(cf *CLIConf, db types.Database) io.Writer {
+	if dbExecOutputFormat(cf.DBExecOutputFormat) == dbExecOutputFormatJSON {
+		return &databaseExecJSONPrinter{Writer: cf.Stderr(), db: db.GetName(), stream: "stderr"}
+	}
 	return &databaseExecPrinter{
 		Writer: cf.Stderr(),
 		name:   fmt.Sprintf("[%s][error]", db.GetName()),
 		color:  pickColorIfTerminal(colorYellow),
 	}
 }
+
+// dbExecSummary records the outcome of running the configured command
+// against a single database target, for the final summary table.
+type dbExecSummary struct {
+	service  string
+	exitCode int
+	duration time.Duration
+	logPath  string
+	err      error
+}
+
+// printDBExecSummaryTable writes a one-line-per-target summary (service,
+// exit code, duration, log path) to out, so a multi-target `tsh db exec` run
+// has a single place to see which targets failed without scrolling back
+// through interleaved output.
+func printDBExecSummaryTable(out io.Writer, summaries []dbExecSummary) {
+	fmt.Fprintln(out, "\nSummary:")
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tEXIT CODE\tDURATION\tLOG PATH")
+	for _, s := range summaries {
+		logPath := s.logPath
+		if logPath == "" {
+			logPath = "-"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", s.service, s.exitCode, s.duration.Round(time.Millisecond), logPath)
+	}
+	w.Flush()
+}
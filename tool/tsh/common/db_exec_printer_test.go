@@ -0,0 +1,87 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseExecJSONPrinterReassemblesPartialWrites(t *testing.T) {
+	var buf bytes.Buffer
+	w := &databaseExecJSONPrinter{Writer: &buf, db: "mydb", stream: "stdout"}
+
+	// Split a single logical line across multiple Write calls.
+	_, err := w.Write([]byte("hello "))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("wor"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("ld\nsecond line\n"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var first dbExecJSONLine
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.Equal(t, "mydb", first.Database)
+	require.Equal(t, "stdout", first.Stream)
+	require.Equal(t, "hello world", first.Line)
+
+	var second dbExecJSONLine
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	require.Equal(t, "second line", second.Line)
+}
+
+func TestDatabaseExecJSONPrinterSkipsBlankLines(t *testing.T) {
+	var buf bytes.Buffer
+	w := &databaseExecJSONPrinter{Writer: &buf, stream: "info"}
+
+	_, err := w.Write([]byte("\n   \nreal line\n"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var line dbExecJSONLine
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+	require.Equal(t, "real line", line.Line)
+}
+
+func TestPrintDBExecSummaryTable(t *testing.T) {
+	var buf bytes.Buffer
+	printDBExecSummaryTable(&buf, []dbExecSummary{
+		{service: "db1", exitCode: 0, duration: 1500 * time.Millisecond, logPath: "/tmp/db1.log"},
+		{service: "db2", exitCode: 1, duration: 250 * time.Millisecond},
+	})
+
+	out := buf.String()
+	require.Contains(t, out, "SERVICE")
+	require.Contains(t, out, "db1")
+	require.Contains(t, out, "/tmp/db1.log")
+	require.Contains(t, out, "db2")
+	// A target with no log path (e.g. output went to stdout, not a file)
+	// renders as "-" rather than an empty column.
+	require.Contains(t, out, "-")
+}
@@ -0,0 +1,103 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/gravitational/trace"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gravitational/teleport/api/defaults"
+)
+
+// dbExecCommandTemplates maps a database protocol to the text/template used
+// to build the CLI invocation for `tsh db exec`. Templates are expanded with
+// the same variables regardless of protocol: db_host, db_port, db_query,
+// db_user, db_name, db_service, db_protocol and db_roles.
+var dbExecCommandTemplates = map[string]string{
+	defaults.ProtocolMySQL:     `mysql --user {{.db_user}} --port {{.db_port}} --host {{.db_host}} --protocol TCP -e "{{.db_query}}"`,
+	defaults.ProtocolPostgres:  `psql "host={{.db_host}} port={{.db_port}} user={{.db_user}} dbname={{.db_name}}" -c "{{.db_query}}"`,
+	defaults.ProtocolMongoDB:   `mongosh "mongodb://{{.db_user}}@{{.db_host}}:{{.db_port}}/{{.db_name}}?tls=true" --eval "{{.db_query}}"`,
+	defaults.ProtocolRedis:     `redis-cli -h {{.db_host}} -p {{.db_port}} --tls {{.db_query}}`,
+	defaults.ProtocolSnowflake: `snowsql -a {{.db_service}} -u {{.db_user}} -d {{.db_name}} -q "{{.db_query}}"`,
+	defaults.ProtocolSQLServer: `sqlcmd -S {{.db_host}},{{.db_port}} -U {{.db_user}} -d {{.db_name}} -Q "{{.db_query}}"`,
+}
+
+// dbExecConfigFileName is the path, relative to the user's home directory,
+// of the optional YAML file used to override dbExecCommandTemplates without
+// passing --exec-command-template on every invocation.
+const dbExecConfigFileName = ".tsh/db_exec.yaml"
+
+// dbExecConfig is the schema of ~/.tsh/db_exec.yaml.
+type dbExecConfig struct {
+	// Templates overrides dbExecCommandTemplates, keyed by database
+	// protocol (e.g. "postgres").
+	Templates map[string]string `yaml:"templates"`
+}
+
+// loadDBExecCommandTemplateOverrides reads ~/.tsh/db_exec.yaml, if present,
+// validating every template it defines, and returns its per-protocol
+// overrides. A missing file is not an error: it just means no overrides are
+// configured.
+func loadDBExecCommandTemplateOverrides() (map[string]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, dbExecConfigFileName))
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, trace.Wrap(err)
+	}
+
+	var config dbExecConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, trace.Wrap(err, "parsing %s", dbExecConfigFileName)
+	}
+	for protocol, templ := range config.Templates {
+		if _, err := template.New("dbcmd").Parse(templ); err != nil {
+			return nil, trace.Wrap(err, "invalid exec command template for protocol %q in %s", protocol, dbExecConfigFileName)
+		}
+	}
+	return config.Templates, nil
+}
+
+// dbExecCommandTemplate returns the command template to use for protocol,
+// preferring override (--exec-command-template) first, then fileOverrides
+// (~/.tsh/db_exec.yaml), then the built-in default. It errors if none of
+// the three supply a template for protocol.
+func dbExecCommandTemplate(protocol, override string, fileOverrides map[string]string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if templ, ok := fileOverrides[protocol]; ok {
+		return templ, nil
+	}
+	templ, ok := dbExecCommandTemplates[protocol]
+	if !ok {
+		return "", trace.BadParameter("no default exec command template for database protocol %q, use --exec-command-template or %s to provide one", protocol, dbExecConfigFileName)
+	}
+	return templ, nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gravitational/teleport/api/defaults"
+)
+
+func TestDBExecCommandTemplate(t *testing.T) {
+	t.Run("known protocol returns default template", func(t *testing.T) {
+		templ, err := dbExecCommandTemplate(defaults.ProtocolMySQL, "", nil)
+		require.NoError(t, err)
+		require.Contains(t, templ, "mysql")
+	})
+
+	t.Run("every built-in default parses as a template", func(t *testing.T) {
+		for protocol := range dbExecCommandTemplates {
+			templ, err := dbExecCommandTemplate(protocol, "", nil)
+			require.NoError(t, err, "protocol %q", protocol)
+			require.NotEmpty(t, templ, "protocol %q", protocol)
+			_, err = template.New("dbcmd").Parse(templ)
+			require.NoError(t, err, "protocol %q", protocol)
+		}
+	})
+
+	t.Run("override takes precedence over file override and default", func(t *testing.T) {
+		templ, err := dbExecCommandTemplate(defaults.ProtocolMySQL, "custom {{.db_query}}", map[string]string{
+			defaults.ProtocolMySQL: "file-override {{.db_query}}",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "custom {{.db_query}}", templ)
+	})
+
+	t.Run("file override takes precedence over default", func(t *testing.T) {
+		templ, err := dbExecCommandTemplate(defaults.ProtocolMySQL, "", map[string]string{
+			defaults.ProtocolMySQL: "file-override {{.db_query}}",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "file-override {{.db_query}}", templ)
+	})
+
+	t.Run("unknown protocol without override errors", func(t *testing.T) {
+		_, err := dbExecCommandTemplate("unknown-protocol", "", nil)
+		require.Error(t, err)
+	})
+
+	t.Run("file override can supply a template for a protocol with no default", func(t *testing.T) {
+		templ, err := dbExecCommandTemplate("unknown-protocol", "", map[string]string{
+			"unknown-protocol": "custom-cli {{.db_query}}",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "custom-cli {{.db_query}}", templ)
+	})
+}
+
+func TestLoadDBExecCommandTemplateOverrides(t *testing.T) {
+	t.Run("missing file is not an error", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		overrides, err := loadDBExecCommandTemplateOverrides()
+		require.NoError(t, err)
+		require.Empty(t, overrides)
+	})
+
+	t.Run("valid file is parsed", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		require.NoError(t, os.MkdirAll(filepath.Join(home, ".tsh"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(home, dbExecConfigFileName), []byte(`
+templates:
+  postgres: "custom-psql {{.db_query}}"
+`), 0o600))
+
+		overrides, err := loadDBExecCommandTemplateOverrides()
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"postgres": "custom-psql {{.db_query}}"}, overrides)
+	})
+
+	t.Run("invalid template in file is rejected", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		require.NoError(t, os.MkdirAll(filepath.Join(home, ".tsh"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(home, dbExecConfigFileName), []byte(`
+templates:
+  postgres: "psql {{.db_query"
+`), 0o600))
+
+		_, err := loadDBExecCommandTemplateOverrides()
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,39 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCodeFromError(t *testing.T) {
+	t.Run("exec.ExitError reports the child's exit code", func(t *testing.T) {
+		err := exec.Command("false").Run()
+		require.Error(t, err)
+		require.Equal(t, 1, exitCodeFromError(err))
+	})
+
+	t.Run("non-exit errors report -1", func(t *testing.T) {
+		require.Equal(t, -1, exitCodeFromError(errors.New("boom")))
+	})
+}
@@ -1,43 +1,81 @@
 package common
 
 import (
-	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/gravitational/trace"
-	"golang.org/x/sys/windows/svc/eventlog"
+
+	"github.com/gravitational/teleport/lib/utils/log/eventlog"
 )
 
+// eventSource is the Windows Event Log source name tsh registers itself
+// under. It's distinct from teleport.exe's own source so an admin can
+// filter or forward the two independently.
 const eventSource = "tsh"
 
-func testEventLog() error {
-	// TODO: Copy what the eventlog package is doing and create a separate log for tsh or VNet.
-	log, err := eventlog.Open(eventSource)
+// msgFileName is the message-table resource DLL tsh's build ships next to
+// tsh.exe (compiled from lib/utils/log/eventlog/messages.mc), so Event
+// Viewer and SIEM collectors can render tsh's events instead of a raw
+// "the description for event ID %1 could not be found" message.
+const msgFileName = "tsh-msgfile.dll"
+
+// msgFilePath resolves msgFileName as a sibling of the running tsh.exe.
+func msgFilePath() (string, error) {
+	exe, err := os.Executable()
 	if err != nil {
-		return trace.Wrap(err)
+		return "", trace.Wrap(err)
 	}
+	return filepath.Join(filepath.Dir(exe), msgFileName), nil
+}
 
-	if err := log.Info(10000, "Hello, World!"); err != nil {
+// onEventLogInstall implements `tsh eventlog install`: it registers
+// eventSource with the Windows Event Log against the message file shipped
+// next to tsh.exe.
+func onEventLogInstall(cf *CLIConf) error {
+	msgFile, err := msgFilePath()
+	if err != nil {
 		return trace.Wrap(err)
 	}
-	return nil
+	logger.DebugContext(cf.Context, "Installing tsh Windows Event Log source", "source", eventSource, "msgfile", msgFile)
 
+	return trace.Wrap(eventlog.Install(eventSource, msgFile))
 }
 
-func installEventLog() error {
-	exe, err := os.Executable()
+// onEventLogUninstall implements `tsh eventlog uninstall`.
+func onEventLogUninstall(cf *CLIConf) error {
+	return trace.Wrap(eventlog.Uninstall(eventSource))
+}
+
+// onEventLogTest implements `tsh eventlog test`: it emits one event at each
+// severity tsh can log at, through the same handler initLogger installs
+// when --event-log/--os-log is set, so an operator can confirm the source
+// is registered and rendering correctly in Event Viewer before relying on
+// it.
+func onEventLogTest(cf *CLIConf) error {
+	handler, err := newTSHEventLogHandler()
 	if err != nil {
 		return trace.Wrap(err)
 	}
-	msgFile := filepath.Join(exe, "..", "..", "msgfile.dll")
-	logger.DebugContext(context.Background(), "Calculated msgFile", "path", msgFile)
+	defer handler.Close()
+
+	log := slog.New(handler)
+	log.Debug("tsh Event Log test message (debug)")
+	log.Info("tsh Event Log test message (info)")
+	log.Warn("tsh Event Log test message (warning)")
+	log.Error("tsh Event Log test message (error)")
 
-	return trace.Wrap(
-		eventlog.Install(eventSource, msgFile, false /* useExpandKey */, eventlog.Info|eventlog.Warning|eventlog.Error),
-	)
+	return nil
 }
 
-func uninstallEventLog() error {
-	return trace.Wrap(eventlog.Remove(eventSource))
+// newTSHEventLogHandler opens eventSource (which must already be registered
+// via onEventLogInstall) as an slog.Handler reporting under
+// eventlog.ComponentTSH.
+func newTSHEventLogHandler() (*eventlog.Logger, error) {
+	handler, err := eventlog.NewLogger(eventSource, eventlog.ComponentTSH, eventlog.CategoryGeneral)
+	if err != nil {
+		return nil, trace.Wrap(err, "opening Windows Event Log source %q (has `tsh eventlog install` been run?)", eventSource)
+	}
+	return handler, nil
 }
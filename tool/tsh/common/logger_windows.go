@@ -0,0 +1,37 @@
+package common
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"github.com/gravitational/teleport/lib/utils"
+)
+
+// eventLogEnvVar mirrors osLogEnvVar: it lets --event-log be set
+// non-interactively, the same way TELEPORT_OS_LOG gates --os-log.
+const eventLogEnvVar = "TELEPORT_EVENT_LOG"
+
+// getPlatformInitLoggerOpts installs the Windows Event Log handler
+// alongside tsh's normal CLI logger when cf.OSLog, cf.EventLog or
+// eventLogEnvVar requests it, the Windows analogue of how TELEPORT_OS_LOG/--os-log gates the macOS
+// os_log handler. Event Log registration (`tsh eventlog install`) must have
+// already run; a missing or stale registration only disables the extra
+// handler; it never fails tsh startup.
+func getPlatformInitLoggerOpts(cf *CLIConf) []utils.LoggerOption {
+	eventLog := cf.EventLog
+	if !eventLog {
+		eventLog, _ = strconv.ParseBool(os.Getenv(eventLogEnvVar))
+	}
+	if !cf.OSLog && !eventLog {
+		return nil
+	}
+
+	handler, err := newTSHEventLogHandler()
+	if err != nil {
+		logger.WarnContext(context.Background(), "Failed to initialize Windows Event Log handler, continuing without it", "error", err)
+		return nil
+	}
+
+	return []utils.LoggerOption{utils.WithExtraHandler(handler)}
+}
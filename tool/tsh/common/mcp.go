@@ -20,6 +20,7 @@ package common
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net"
 	"os"
@@ -32,48 +33,149 @@ import (
 	alpncommon "github.com/gravitational/teleport/lib/srv/alpnproxy/common"
 	"github.com/gravitational/teleport/lib/utils"
 	listenerutils "github.com/gravitational/teleport/lib/utils/listener"
+	"github.com/gravitational/teleport/lib/utils/proxyutils"
+)
+
+// upstreamProxyOpts resolves the HTTP(S)/SOCKS5 proxy (if any) that should
+// be used to reach the Teleport proxy at proxyAddr - following
+// HTTPS_PROXY/ALL_PROXY/NO_PROXY the way a browser would - and returns the
+// alpnproxy.LocalProxyConfigOpt that routes the local ALPN tunnel through
+// it. It returns no options, not an error, when no upstream proxy applies.
+func upstreamProxyOpts(proxyAddr string) ([]alpnproxy.LocalProxyConfigOpt, error) {
+	proxyURL, err := proxyutils.ResolveProxyURL(proxyAddr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if proxyURL == nil {
+		return nil, nil
+	}
+	auth := proxyutils.ProxyAuthFromURL(proxyURL, proxyutils.ProxyAuthFromEnvironment())
+	return []alpnproxy.LocalProxyConfigOpt{alpnproxy.WithUpstreamProxy(proxyURL, auth)}, nil
+}
+
+// mcpTransportStdio and mcpTransportHTTP are the values onMCPStart accepts
+// for --transport: stdio bridges the upstream tunnel directly to this
+// process's own stdio, the way every prior `tsh mcp start` invocation has;
+// http instead serves the MCP Streamable HTTP transport on --listen, for
+// hosted clients that speak HTTP rather than spawning a stdio subprocess.
+const (
+	mcpTransportStdio = "stdio"
+	mcpTransportHTTP  = "http"
 )
 
 func onMCPStart(cf *CLIConf) error {
 	cf.OverrideStdout = io.Discard
 
-	err := onAppLogin(cf)
+	in, err := dialMCPStartUpstream(cf)
 	if err != nil {
 		return trace.Wrap(err)
 	}
 
-	tc, err := makeClient(cf)
+	policy, err := mcp.LoadPolicyFile(mcp.DefaultPolicyPath(cf.HomePath))
 	if err != nil {
 		return trace.Wrap(err)
 	}
+	chain := mcp.BuiltinInterceptors(logger, policy)
+
+	switch cf.MCPTransport {
+	case "", mcpTransportStdio:
+		stdioConn := utils.CombinedStdio{}
+		return trace.Wrap(mcp.PumpStdio(cf.Context, logger, chain, stdioConn, stdioConn, in, in))
+	case mcpTransportHTTP:
+		return trace.Wrap(onMCPStartHTTP(cf, chain, in))
+	default:
+		return trace.BadParameter("unsupported --transport %q, must be %q or %q", cf.MCPTransport, mcpTransportStdio, mcpTransportHTTP)
+	}
+}
+
+// dialMCPStartUpstream logs in to cf.AppName and stands up the local ALPN
+// proxy tunnel to it, returning the local end of the pipe the upstream
+// speaks MCP's JSON-RPC framing over - shared by every --transport mode of
+// `tsh mcp start`.
+func dialMCPStartUpstream(cf *CLIConf) (net.Conn, error) {
+	if err := onAppLogin(cf); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	tc, err := makeClient(cf)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
 
 	cert, err := loadAppCertificate(tc, cf.AppName)
 	if err != nil {
-		return trace.Wrap(err)
+		return nil, trace.Wrap(err)
 	}
 
 	in, out := net.Pipe()
 	listener := listenerutils.NewSingleUseListener(out)
-	defer listener.Close()
 
-	lp, err := alpnproxy.NewLocalProxy(
-		makeBasicLocalProxyConfig(cf.Context, tc, listener, tc.InsecureSkipVerify),
+	opts, err := upstreamProxyOpts(tc.WebProxyAddr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	opts = append(opts,
 		alpnproxy.WithALPNProtocol(alpncommon.ProtocolTCP),
 		alpnproxy.WithClientCert(cert),
 		alpnproxy.WithClusterCAsIfConnUpgrade(cf.Context, tc.RootClusterCACertPool),
 	)
+	lp, err := alpnproxy.NewLocalProxy(
+		makeBasicLocalProxyConfig(cf.Context, tc, listener, tc.InsecureSkipVerify),
+		opts...,
+	)
 	if err != nil {
-		return trace.Wrap(err)
+		listener.Close()
+		return nil, trace.Wrap(err)
 	}
 	go func() {
 		defer lp.Close()
-		if err = lp.Start(cf.Context); err != nil {
+		defer listener.Close()
+		if err := lp.Start(cf.Context); err != nil {
 			logger.ErrorContext(cf.Context, "Failed to start local ALPN proxy", "error", err)
 		}
 	}()
 
-	stdioConn := utils.CombinedStdio{}
-	return utils.ProxyConn(cf.Context, in, stdioConn)
+	return in, nil
+}
+
+// onMCPStartHTTP serves the MCP Streamable HTTP transport over the
+// upstream tunnel up, gated by a bearer token that's either read from
+// --token-file or freshly generated and printed to stderr, so another
+// local user can't reach the endpoint just by knowing its port.
+func onMCPStartHTTP(cf *CLIConf, chain mcp.InterceptorChain, up net.Conn) error {
+	if cf.MCPHTTPListenAddr == "" {
+		return trace.BadParameter("--listen is required with --transport=%s", mcpTransportHTTP)
+	}
+
+	token := cf.MCPHTTPBearerToken
+	if token == "" && cf.MCPHTTPTokenFile != "" {
+		fileToken, err := mcp.LoadOrCreateBearerTokenFile(cf.MCPHTTPTokenFile)
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		token = fileToken
+	}
+	if token == "" {
+		generated, err := mcp.GenerateBearerToken()
+		if err != nil {
+			return trace.Wrap(err)
+		}
+		token = generated
+	}
+	fmt.Fprintf(os.Stderr, "MCP HTTP transport listening on %s, bearer token: %s\n", cf.MCPHTTPListenAddr, token)
+
+	transport, err := mcp.NewHTTPTransport(mcp.HTTPTransportConfig{
+		Addr:        cf.MCPHTTPListenAddr,
+		BearerToken: token,
+		Chain:       chain,
+		UpIn:        up,
+		UpOut:       up,
+		Logger:      logger,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(transport.ListenAndServe(cf.Context))
 }
 
 func onMCPForward(cf *CLIConf) error {
@@ -105,12 +207,19 @@ func onMCPForward(cf *CLIConf) error {
 		}
 		left, right := net.Pipe()
 		listener := listenerutils.NewSingleUseListener(right)
-		lp, err := alpnproxy.NewLocalProxy(
-			makeBasicLocalProxyConfig(cf.Context, tc, listener, tc.InsecureSkipVerify),
+		opts, err := upstreamProxyOpts(tc.WebProxyAddr)
+		if err != nil {
+			return nil, nil, trace.Wrap(err)
+		}
+		opts = append(opts,
 			alpnproxy.WithALPNProtocol(alpncommon.ProtocolTCP),
 			alpnproxy.WithClientCert(cert),
 			alpnproxy.WithClusterCAsIfConnUpgrade(cf.Context, tc.RootClusterCACertPool),
 		)
+		lp, err := alpnproxy.NewLocalProxy(
+			makeBasicLocalProxyConfig(cf.Context, tc, listener, tc.InsecureSkipVerify),
+			opts...,
+		)
 		if err != nil {
 			return nil, nil, trace.Wrap(err)
 		}
@@ -0,0 +1,191 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/lib/client/mcp"
+	"github.com/gravitational/teleport/lib/srv/alpnproxy"
+	alpncommon "github.com/gravitational/teleport/lib/srv/alpnproxy/common"
+	"github.com/gravitational/teleport/lib/utils"
+	listenerutils "github.com/gravitational/teleport/lib/utils/listener"
+)
+
+// mcpDaemonSocketName is the Unix socket `tsh mcp daemon` listens on,
+// relative to the tsh home directory, and `tsh mcp start --via-daemon`
+// connects to.
+const mcpDaemonSocketName = "mcp.sock"
+
+// defaultMCPDaemonSocketPath returns the conventional daemon socket path
+// under the given tsh home directory.
+func defaultMCPDaemonSocketPath(homePath string) string {
+	return filepath.Join(homePath, mcpDaemonSocketName)
+}
+
+// onMCPDaemon implements `tsh mcp daemon`: it runs the multiplexing daemon
+// in the foreground until cf.Context is canceled (by this process's
+// top-level signal handling), sharing one warm tunnel per app across every
+// `tsh mcp start --via-daemon` client that attaches to it.
+func onMCPDaemon(cf *CLIConf) error {
+	socketPath := defaultMCPDaemonSocketPath(cf.HomePath)
+
+	daemon, err := mcp.NewDaemon(mcp.DaemonConfig{
+		SocketPath: socketPath,
+		DialTunnel: func(ctx context.Context, key mcp.TunnelKey) (io.ReadWriteCloser, error) {
+			return dialMCPTunnel(ctx, cf, key)
+		},
+		CertExpiry: func(key mcp.TunnelKey) (time.Time, error) {
+			return mcpTunnelCertExpiry(cf, key)
+		},
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	logger.InfoContext(cf.Context, "Starting MCP daemon", "socket", socketPath)
+	defer logger.InfoContext(cf.Context, "MCP daemon stopped")
+
+	return trace.Wrap(daemon.ListenAndServe(cf.Context))
+}
+
+// onMCPDaemonStatus implements `tsh mcp daemon status`: it connects to a
+// running daemon's socket and reports each tunnel it's currently keeping
+// warm, and how many clients are sharing it.
+func onMCPDaemonStatus(cf *CLIConf) error {
+	socketPath := defaultMCPDaemonSocketPath(cf.HomePath)
+
+	statuses, err := mcp.QueryDaemonStatus(cf.Context, socketPath)
+	if err != nil {
+		return trace.Wrap(err, "is `tsh mcp daemon` running?")
+	}
+
+	if len(statuses) == 0 {
+		fmt.Fprintln(cf.Stdout(), "No MCP tunnels are currently open.")
+		return nil
+	}
+	for _, status := range statuses {
+		fmt.Fprintf(cf.Stdout(), "%s/%s\tclients=%d\tage=%s\n",
+			status.Cluster, status.App, status.ClientCount, status.Age.Round(time.Second))
+	}
+	return nil
+}
+
+// onMCPStartViaDaemon implements `tsh mcp start --via-daemon`: instead
+// of logging in, loading a cert, and standing up a local ALPN proxy itself
+// the way onMCPStart does, it asks the already-running daemon for a tunnel
+// to cf.AppName, so repeated invocations (e.g. one per MCP client window)
+// share a single warm login and connection instead of each paying the full
+// setup cost.
+func onMCPStartViaDaemon(cf *CLIConf) error {
+	cf.OverrideStdout = io.Discard
+
+	tc, err := makeClient(cf)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	socketPath := defaultMCPDaemonSocketPath(cf.HomePath)
+	conn, err := mcp.DialDaemon(cf.Context, socketPath, tc.SiteName, cf.AppName)
+	if err != nil {
+		return trace.Wrap(err, "is `tsh mcp daemon` running?")
+	}
+	defer conn.Close()
+
+	stdioConn := utils.CombinedStdio{}
+	errCh := make(chan error, 2)
+	go func() { _, err := io.Copy(conn, stdioConn); errCh <- err }()
+	go func() { _, err := io.Copy(stdioConn, conn); errCh <- err }()
+	return trace.Wrap(<-errCh)
+}
+
+// dialMCPTunnel performs the same app login, cert load, and local ALPN
+// proxy setup onMCPStart does, but for a TunnelKey handed to it by the
+// daemon rather than the current *CLIConf - it's the daemon's DialTunnelFunc.
+func dialMCPTunnel(ctx context.Context, cf *CLIConf, key mcp.TunnelKey) (io.ReadWriteCloser, error) {
+	tunnelCF := *cf
+	tunnelCF.Context = ctx
+	tunnelCF.SiteName = key.Cluster
+	tunnelCF.AppName = key.App
+
+	if err := onAppLogin(&tunnelCF); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	tc, err := makeClient(&tunnelCF)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cert, err := loadAppCertificate(tc, key.App)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	left, right := net.Pipe()
+	singleUse := listenerutils.NewSingleUseListener(right)
+	opts, err := upstreamProxyOpts(tc.WebProxyAddr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	opts = append(opts,
+		alpnproxy.WithALPNProtocol(alpncommon.ProtocolTCP),
+		alpnproxy.WithClientCert(cert),
+		alpnproxy.WithClusterCAsIfConnUpgrade(ctx, tc.RootClusterCACertPool),
+	)
+	lp, err := alpnproxy.NewLocalProxy(
+		makeBasicLocalProxyConfig(ctx, tc, singleUse, tc.InsecureSkipVerify),
+		opts...,
+	)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	go func() {
+		defer lp.Close()
+		if err := lp.Start(ctx); err != nil {
+			logger.ErrorContext(ctx, "Failed to start local ALPN proxy for MCP tunnel", "tunnel", key, "error", err)
+		}
+	}()
+
+	return left, nil
+}
+
+// mcpTunnelCertExpiry returns the expiry of the client certificate backing
+// key's tunnel, so the daemon can proactively re-dial ahead of it - the
+// daemon's CertExpiryFunc.
+func mcpTunnelCertExpiry(cf *CLIConf, key mcp.TunnelKey) (time.Time, error) {
+	tunnelCF := *cf
+	tunnelCF.SiteName = key.Cluster
+	tunnelCF.AppName = key.App
+
+	tc, err := makeClient(&tunnelCF)
+	if err != nil {
+		return time.Time{}, trace.Wrap(err)
+	}
+	cert, err := loadAppCertificate(tc, key.App)
+	if err != nil {
+		return time.Time{}, trace.Wrap(err)
+	}
+	return cert.Leaf.NotAfter, nil
+}
@@ -0,0 +1,216 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"context"
+	"crypto/ed25519"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/gravitational/teleport/lib/client"
+	teleportmcp "github.com/gravitational/teleport/lib/client/mcp"
+)
+
+// mcpKeyFileEnvVar is the fallback environment variable onMCPStartTeleport
+// reads an MCP key file path from when --mcp-key-file isn't set, mirroring
+// how other tsh credentials are configurable by environment for
+// non-interactive invocations.
+const mcpKeyFileEnvVar = "TELEPORT_MCP_KEY_FILE"
+
+// onMCPKeysCreate implements `tsh mcp keys create`: it mints a signed,
+// capability-limited teleportmcp.Key restricting a future `tsh mcp
+// start-teleport --mcp-key-file` invocation to a specific set of tools,
+// resource-name prefix, per-tool argument values, and validity window, then
+// writes it to --out (or stdout).
+func onMCPKeysCreate(cf *CLIConf) error {
+	if len(cf.MCPKeyAllowedTools) == 0 {
+		return trace.BadParameter("at least one --allow-tool is required")
+	}
+
+	tc, err := makeClient(cf)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	signer, subject, err := localIdentitySigner(tc)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	constraints, err := mcpKeyConstraintsFromFlags(cf)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	key, err := teleportmcp.SignKey(constraints, subject, signer)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	out := cf.Stdout()
+	if cf.MCPKeyOutFile != "" {
+		f, err := os.Create(cf.MCPKeyOutFile)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return trace.Wrap(teleportmcp.WriteKeyFile(out, key))
+}
+
+// localIdentitySigner returns the ed25519 private key backing the caller's
+// current Teleport identity and their username, so a minted MCP key carries
+// the same provenance the auth server already trusts for this user.
+//
+// MCP keys are signed with ed25519 specifically; a profile whose SSH key
+// pair uses another algorithm (RSA is still the default in some FIPS
+// configurations) can't mint one yet. Broadening teleportmcp.SignKey to a
+// generic crypto.Signer is tracked separately.
+func localIdentitySigner(tc *client.TeleportClient) (ed25519.PrivateKey, string, error) {
+	keyRing, err := tc.LocalAgent().GetKeyRing(tc.SiteName)
+	if err != nil {
+		return nil, "", trace.Wrap(err, "loading local Teleport identity")
+	}
+	signer, ok := keyRing.SSHPrivateKey.Signer.(ed25519.PrivateKey)
+	if !ok {
+		return nil, "", trace.BadParameter("MCP keys currently require an ed25519 SSH key pair, this profile's key is %T", keyRing.SSHPrivateKey.Signer)
+	}
+	return signer, tc.Username, nil
+}
+
+// mcpKeyConstraintsFromFlags builds the KeyConstraints `tsh mcp keys create`
+// will sign, from its --allow-tool, --name-prefix, --ttl, and
+// --tool-arg-constraint flags.
+func mcpKeyConstraintsFromFlags(cf *CLIConf) (teleportmcp.KeyConstraints, error) {
+	constraints := teleportmcp.KeyConstraints{
+		AllowedTools: cf.MCPKeyAllowedTools,
+		NamePrefix:   cf.MCPKeyNamePrefix,
+		NotBefore:    time.Now(),
+	}
+	if cf.MCPKeyTTL > 0 {
+		constraints.NotAfter = constraints.NotBefore.Add(cf.MCPKeyTTL)
+	}
+
+	for _, raw := range cf.MCPKeyArgConstraints {
+		tool, arg, values, err := parseArgConstraintFlag(raw)
+		if err != nil {
+			return teleportmcp.KeyConstraints{}, trace.Wrap(err)
+		}
+		if constraints.ToolArgConstraints == nil {
+			constraints.ToolArgConstraints = make(map[string]map[string]teleportmcp.ToolArgConstraint)
+		}
+		if constraints.ToolArgConstraints[tool] == nil {
+			constraints.ToolArgConstraints[tool] = make(map[string]teleportmcp.ToolArgConstraint)
+		}
+		constraints.ToolArgConstraints[tool][arg] = teleportmcp.ToolArgConstraint{AllowedValues: values}
+	}
+
+	return constraints, nil
+}
+
+// parseArgConstraintFlag parses a --tool-arg-constraint flag of the form
+// "tool.arg=value1,value2".
+func parseArgConstraintFlag(raw string) (tool, arg string, values []string, err error) {
+	toolArg, valuesStr, ok := strings.Cut(raw, "=")
+	if !ok {
+		return "", "", nil, trace.BadParameter("invalid --tool-arg-constraint %q, expected tool.arg=value1,value2", raw)
+	}
+	tool, arg, ok = strings.Cut(toolArg, ".")
+	if !ok {
+		return "", "", nil, trace.BadParameter("invalid --tool-arg-constraint %q, expected tool.arg=value1,value2", raw)
+	}
+	return tool, arg, strings.Split(valuesStr, ","), nil
+}
+
+// loadMCPKeyForServer loads the MCP key onMCPStartTeleport should restrict
+// itself to, from --mcp-key-file or mcpKeyFileEnvVar. Returns (nil, nil) if
+// neither is set, meaning the server should run unrestricted with the full
+// identity of the invoking user, same as before this capability layer
+// existed.
+//
+// The key is verified against tc's own local identity before it's trusted:
+// without this, any hand-edited or unsigned key file would be honored as-is
+// by newKeyFilteredAddTool, making the whole capability-limiting scheme
+// advisory rather than enforced on the local side.
+func loadMCPKeyForServer(tc *client.TeleportClient, cf *CLIConf) (*teleportmcp.Key, error) {
+	path := cf.MCPKeyFile
+	if path == "" {
+		path = os.Getenv(mcpKeyFileEnvVar)
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+
+	key, err := teleportmcp.ParseKeyFile(f)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	signer, subject, err := localIdentitySigner(tc)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if key.Subject != subject {
+		return nil, trace.AccessDenied("MCP key was signed for subject %q, but the current identity is %q", key.Subject, subject)
+	}
+	if err := key.Verify(signer.Public().(ed25519.PublicKey), time.Now()); err != nil {
+		return nil, trace.Wrap(err, "verifying MCP key")
+	}
+	return key, nil
+}
+
+// newKeyFilteredAddTool returns an mcpServer.AddTool substitute that, when
+// mcpKey is non-nil, silently skips registering any tool mcpKey doesn't
+// allow-list and wraps every registered tool's handler to re-check the
+// key's per-argument constraints and validity window on every call, before
+// the handler ever reaches authClient. This is the only place the key is
+// enforced: the requests the handlers send to the auth server don't carry
+// the key, so the auth server doesn't check its constraints.
+func newKeyFilteredAddTool(mcpServer *server.MCPServer, mcpKey *teleportmcp.Key) func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		if mcpKey == nil {
+			mcpServer.AddTool(tool, handler)
+			return
+		}
+		if !slices.Contains(mcpKey.AllowedTools, tool.Name) {
+			return
+		}
+		mcpServer.AddTool(tool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if err := mcpKey.AuthorizeToolCall(time.Now(), tool.Name, req.Params.Arguments); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return handler(ctx, req)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/gravitational/trace"
+
+	apievents "github.com/gravitational/teleport/api/types/events"
+	"github.com/gravitational/teleport/lib/client"
+	"github.com/gravitational/teleport/lib/session"
+)
+
+// onMCPSessionsPlay implements `tsh mcp sessions play <id>`: it fetches the
+// recorded chunks for an MCP session and prints the JSON-RPC dialog in
+// chronological order, labelling each message with the side that sent it.
+func onMCPSessionsPlay(cf *CLIConf) error {
+	tc, err := makeClient(cf)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	var clusterClient *client.ClusterClient
+	if err := client.RetryWithRelogin(cf.Context, tc, func() error {
+		clusterClient, err = tc.ConnectToCluster(cf.Context)
+		return trace.Wrap(err)
+	}); err != nil {
+		return trace.Wrap(err)
+	}
+	defer clusterClient.Close()
+
+	events, errs := clusterClient.AuthClient.StreamSessionEvents(cf.Context, session.ID(cf.SessionID), 0)
+	for {
+		select {
+		case err := <-errs:
+			return trace.Wrap(err)
+		case evt, ok := <-events:
+			if !ok {
+				return nil
+			}
+			chunk, ok := evt.(*apievents.AppSessionMCPSessionChunk)
+			if !ok {
+				continue
+			}
+			if err := printMCPSessionChunk(cf, chunk); err != nil {
+				return trace.Wrap(err)
+			}
+		}
+	}
+}
+
+// printMCPSessionChunk writes a single recorded MCP message to cf.Stdout in
+// a human-readable form: a timestamp, the direction it travelled, and the
+// raw (possibly redacted) JSON-RPC payload.
+func printMCPSessionChunk(cf *CLIConf, chunk *apievents.AppSessionMCPSessionChunk) error {
+	_, err := fmt.Fprintf(cf.Stdout(), "[%s] %-6s %s\n",
+		chunk.Time.Format("15:04:05.000"),
+		chunk.Direction,
+		chunk.Payload,
+	)
+	return trace.Wrap(err)
+}
@@ -0,0 +1,109 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/lib/client/mcp"
+	"github.com/gravitational/teleport/lib/srv/alpnproxy"
+	alpncommon "github.com/gravitational/teleport/lib/srv/alpnproxy/common"
+	listenerutils "github.com/gravitational/teleport/lib/utils/listener"
+)
+
+// onMCPRoute implements `tsh mcp route --listen :PORT`: a single local
+// port that accepts one MCP app connection per TLS SNI instead of one
+// `tsh mcp start` subprocess per app. A client configures its MCP gateway
+// URL once, as "<app>.mcp.local:PORT", and the router dials the right app
+// on demand using the same cert/loader path as onMCPForward's
+// dialAppServer.
+func onMCPRoute(cf *CLIConf) error {
+	ln, err := net.Listen("tcp", cf.MCPRouteListenAddr)
+	if err != nil {
+		return trace.Wrap(err, "listening on %v", cf.MCPRouteListenAddr)
+	}
+	defer ln.Close()
+
+	router, err := mcp.NewRouter(mcp.RouterConfig{
+		DialApp: func(ctx context.Context, appName string) (io.ReadWriteCloser, error) {
+			return dialRoutedMCPApp(ctx, cf, appName)
+		},
+		Logger: logger,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	logger.InfoContext(cf.Context, "Starting MCP router", "listen", ln.Addr())
+	defer logger.InfoContext(cf.Context, "MCP router stopped")
+
+	return trace.Wrap(router.Serve(cf.Context, ln))
+}
+
+// dialRoutedMCPApp logs in to appName and stands up a local ALPN proxy for
+// it, the same way onMCPForward's dialAppServer does for a single
+// types.AppServer - it's the Router's RouterAppDialerFunc.
+func dialRoutedMCPApp(ctx context.Context, cf *CLIConf, appName string) (io.ReadWriteCloser, error) {
+	routeCF := *cf
+	routeCF.Context = ctx
+	routeCF.AppName = appName
+
+	if err := onAppLogin(&routeCF); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	tc, err := makeClient(&routeCF)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	cert, err := loadAppCertificate(tc, appName)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	left, right := net.Pipe()
+	singleUse := listenerutils.NewSingleUseListener(right)
+	opts, err := upstreamProxyOpts(tc.WebProxyAddr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	opts = append(opts,
+		alpnproxy.WithALPNProtocol(alpncommon.ProtocolTCP),
+		alpnproxy.WithClientCert(cert),
+		alpnproxy.WithClusterCAsIfConnUpgrade(ctx, tc.RootClusterCACertPool),
+	)
+	lp, err := alpnproxy.NewLocalProxy(
+		makeBasicLocalProxyConfig(ctx, tc, singleUse, tc.InsecureSkipVerify),
+		opts...,
+	)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	go func() {
+		defer lp.Close()
+		if err := lp.Start(ctx); err != nil {
+			logger.ErrorContext(ctx, "Failed to start local ALPN proxy for routed MCP app", "app", appName, "error", err)
+		}
+	}()
+
+	return left, nil
+}
@@ -21,6 +21,9 @@ package common
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,11 +32,181 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/gravitational/teleport"
+	"github.com/gravitational/teleport/api/client/proto"
+	"github.com/gravitational/teleport/api/defaults"
 	"github.com/gravitational/teleport/api/types"
 	"github.com/gravitational/teleport/lib/client"
 	libevents "github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/session"
 )
 
+const (
+	// defaultInventoryToolLimit is used for a cache-backed inventory tool's
+	// "limit" parameter when the caller doesn't supply one.
+	defaultInventoryToolLimit = 100
+	// maxInventoryToolResponseBytes caps the JSON a single inventory tool
+	// call can return, so one call can't blow through an LLM's context
+	// window. If the full result exceeds this, trailing items are dropped
+	// (the returned "next_key" still lets the caller page through them).
+	maxInventoryToolResponseBytes = 64 * 1024
+	// maxFieldValueBytes truncates any single projected field value longer
+	// than this, for the same reason.
+	maxFieldValueBytes = 2048
+)
+
+// listToolArgs are the filtering, pagination, and projection parameters
+// shared by every cache-backed inventory tool added below: "filter" (a
+// predicate expression, same syntax as `tsh ls --query`), "labels", "search"
+// keywords (same syntax as `tsh ls --search`), "limit"/"start_key"
+// pagination, and "fields" projection.
+type listToolArgs struct {
+	predicateExpression string
+	labels              map[string]string
+	searchKeywords      []string
+	limit               int
+	startKey            string
+	fields              []string
+}
+
+func parseListToolArgs(args map[string]any) (listToolArgs, error) {
+	var out listToolArgs
+	var ok bool
+
+	out.predicateExpression, ok = args["filter"].(string)
+	if !ok && args["filter"] != nil {
+		return out, trace.BadParameter("invalid type for 'filter' parameter, expected string, got %T", args["filter"])
+	}
+
+	if rawLabels := args["labels"]; rawLabels != nil {
+		labelsArg, ok := rawLabels.(map[string]any)
+		if !ok {
+			return out, trace.BadParameter("invalid type for 'labels' parameter, expected object, got %T", rawLabels)
+		}
+		out.labels = make(map[string]string, len(labelsArg))
+		for k, v := range labelsArg {
+			s, ok := v.(string)
+			if !ok {
+				return out, trace.BadParameter("invalid type for label %q, expected string, got %T", k, v)
+			}
+			out.labels[k] = s
+		}
+	}
+
+	var err error
+	out.searchKeywords, err = parseStringArrayArg(args["search"])
+	if err != nil {
+		return out, trace.Wrap(err, "invalid 'search' parameter")
+	}
+	out.fields, err = parseStringArrayArg(args["fields"])
+	if err != nil {
+		return out, trace.Wrap(err, "invalid 'fields' parameter")
+	}
+
+	out.limit = defaultInventoryToolLimit
+	if rawLimit := args["limit"]; rawLimit != nil {
+		limit, ok := rawLimit.(float64)
+		if !ok {
+			return out, trace.BadParameter("invalid type for 'limit' parameter, expected number, got %T", rawLimit)
+		}
+		out.limit = int(limit)
+	}
+
+	out.startKey, ok = args["start_key"].(string)
+	if !ok && args["start_key"] != nil {
+		return out, trace.BadParameter("invalid type for 'start_key' parameter, expected string, got %T", args["start_key"])
+	}
+
+	return out, nil
+}
+
+// parseStringArrayArg converts an MCP tool argument decoded from JSON (so a
+// string array arrives as []any, not []string) into a []string. Returns
+// (nil, nil) for a missing/nil argument.
+func parseStringArrayArg(v any) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, ok := v.([]any)
+	if !ok {
+		return nil, trace.BadParameter("expected array, got %T", v)
+	}
+	out := make([]string, 0, len(raw))
+	for _, elem := range raw {
+		s, ok := elem.(string)
+		if !ok {
+			return nil, trace.BadParameter("expected array of strings, got element of type %T", elem)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// listToolOptions returns the mcp.ToolOption set for the parameters
+// parseListToolArgs understands, shared by every cache-backed inventory
+// tool. resourceDescription is used in the "labels" option's description,
+// e.g. "nodes" or "databases".
+func listToolOptions(resourceDescription string) []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("filter", mcp.Description("predicate expression to filter results, same syntax as `tsh ls --query`")),
+		mcp.WithObject("labels", mcp.Description("only return "+resourceDescription+" matching all of these labels")),
+		mcp.WithArray("search", mcp.Description("fuzzy search keywords, same syntax as `tsh ls --search`")),
+		mcp.WithNumber("limit", mcp.Description("maximum number of results to return (default 100)")),
+		mcp.WithString("start_key", mcp.Description("pagination cursor; must be taken from a previous call's 'next_key'")),
+		mcp.WithArray("fields", mcp.Description("if set, project only these top-level fields of each result instead of returning it in full, to save tokens")),
+	}
+}
+
+// projectFields marshals v to JSON and, if fields is non-empty, keeps only
+// those top-level fields, dropping the rest. Every remaining string value
+// longer than maxFieldValueBytes is truncated. Results feed a token-limited
+// LLM, so trimming the response this way matters more than it would for a
+// human-facing command.
+func projectFields(v any, fields []string) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		// v isn't a JSON object (e.g. a bare scalar); nothing to project.
+		return v, nil
+	}
+	if len(fields) > 0 {
+		projected := make(map[string]any, len(fields))
+		for _, f := range fields {
+			if val, ok := m[f]; ok {
+				projected[f] = val
+			}
+		}
+		m = projected
+	}
+	for k, v := range m {
+		if s, ok := v.(string); ok && len(s) > maxFieldValueBytes {
+			m[k] = s[:maxFieldValueBytes] + "...(truncated)"
+		}
+	}
+	return m, nil
+}
+
+// newInventoryToolResult marshals items and nextKey into a tool result,
+// dropping trailing items if needed to stay under
+// maxInventoryToolResponseBytes.
+func newInventoryToolResult(items []any, nextKey string) (*mcp.CallToolResult, error) {
+	for {
+		result, err := json.Marshal(map[string]any{
+			"items":    items,
+			"next_key": nextKey,
+		})
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if len(result) <= maxInventoryToolResponseBytes || len(items) == 0 {
+			return mcp.NewToolResultText(string(result)), nil
+		}
+		items = items[:len(items)-1]
+	}
+}
+
 func onMCPStartTeleport(cf *CLIConf) error {
 	tc, err := makeClient(cf)
 	if err != nil {
@@ -51,8 +224,14 @@ func onMCPStartTeleport(cf *CLIConf) error {
 
 	authClient := clusterClient.AuthClient
 
+	mcpKey, err := loadMCPKeyForServer(tc, cf)
+	if err != nil {
+		return trace.Wrap(err, "loading MCP key")
+	}
+
 	mcpServer := server.NewMCPServer("teleport_tools", teleport.Version)
-	mcpServer.AddTool(
+	addTool := newKeyFilteredAddTool(mcpServer, mcpKey)
+	addTool(
 		mcp.NewTool(
 			"teleport_search_events",
 			mcp.WithDescription(`Search Teleport audit events.
@@ -141,7 +320,7 @@ Here is a complete description of all event types.
 		},
 	)
 
-	mcpServer.AddTool(
+	addTool(
 		mcp.NewTool(
 			"teleport_access_request",
 			mcp.WithDescription(`Create Teleport access request.
@@ -179,6 +358,153 @@ an access request should be submitted for.
 		},
 	)
 
+	addResourceListTool := func(name, description, resourceType string) {
+		addTool(
+			mcp.NewTool(
+				name,
+				append([]mcp.ToolOption{mcp.WithDescription(description)}, listToolOptions(strings.TrimPrefix(name, "teleport_list_"))...)...,
+			),
+			func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				args, err := parseListToolArgs(request.Params.Arguments)
+				if err != nil {
+					return nil, trace.Wrap(err)
+				}
+
+				var resp *types.ListResourcesResponse
+				if err := client.RetryWithRelogin(ctx, tc, func() error {
+					resp, err = authClient.ListResources(ctx, proto.ListResourcesRequest{
+						ResourceType:        resourceType,
+						Namespace:           defaults.Namespace,
+						Limit:               int32(args.limit),
+						StartKey:            args.startKey,
+						Labels:              args.labels,
+						SearchKeywords:      args.searchKeywords,
+						PredicateExpression: args.predicateExpression,
+					})
+					return trace.Wrap(err)
+				}); err != nil {
+					return nil, trace.Wrap(err)
+				}
+
+				items := make([]any, 0, len(resp.Resources))
+				for _, r := range resp.Resources {
+					projected, err := projectFields(r, args.fields)
+					if err != nil {
+						return nil, trace.Wrap(err)
+					}
+					items = append(items, projected)
+				}
+				return newInventoryToolResult(items, resp.NextKey)
+			},
+		)
+	}
+
+	addResourceListTool("teleport_list_nodes", "List Teleport SSH nodes the caller has access to.", types.KindNode)
+	addResourceListTool("teleport_list_kube_clusters", "List Teleport Kubernetes clusters the caller has access to.", types.KindKubernetesCluster)
+	addResourceListTool("teleport_list_kube_servers", "List Teleport kube_server resources (one per Kubernetes service instance) the caller has access to.", types.KindKubeServer)
+	addResourceListTool("teleport_list_apps", "List Teleport applications the caller has access to.", types.KindAppServer)
+	addResourceListTool("teleport_list_databases", "List Teleport databases the caller has access to.", types.KindDatabaseServer)
+
+	addTool(
+		mcp.NewTool(
+			"teleport_list_access_requests",
+			append(
+				[]mcp.ToolOption{mcp.WithDescription(`List Teleport access requests visible to the caller.
+
+Unlike the other list tools, "filter", "labels", and "search" aren't
+applicable to access requests and are ignored; only "fields" and pagination
+take effect.`)},
+				listToolOptions("access requests")...,
+			)...,
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, err := parseListToolArgs(request.Params.Arguments)
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+
+			requests, err := authClient.GetAccessRequests(ctx, types.AccessRequestFilter{})
+			if err != nil {
+				return nil, trace.Wrap(err)
+			}
+
+			startIdx := 0
+			if args.startKey != "" {
+				for i, r := range requests {
+					if r.GetName() == args.startKey {
+						startIdx = i + 1
+						break
+					}
+				}
+			}
+
+			var nextKey string
+			items := make([]any, 0, args.limit)
+			for i := startIdx; i < len(requests) && len(items) < args.limit; i++ {
+				projected, err := projectFields(requests[i], args.fields)
+				if err != nil {
+					return nil, trace.Wrap(err)
+				}
+				items = append(items, projected)
+				nextKey = requests[i].GetName()
+			}
+			if startIdx+len(items) >= len(requests) {
+				nextKey = ""
+			}
+			return newInventoryToolResult(items, nextKey)
+		},
+	)
+
+	addTool(
+		mcp.NewTool(
+			"teleport_get_session_recording",
+			mcp.WithDescription(`Return a JSON transcript of a recorded session by streaming its events from the audit log.
+
+The tool takes a mandatory "session_id" parameter.
+`),
+			mcp.WithString("session_id", mcp.Required(), mcp.Description("ID of the recorded session to fetch")),
+			mcp.WithArray("fields", mcp.Description("if set, project only these top-level fields of each event instead of returning it in full, to save tokens")),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			sessionIDStr, ok := request.Params.Arguments["session_id"].(string)
+			if !ok {
+				return nil, trace.BadParameter("missing string parameter 'session_id'")
+			}
+			if mcpKey != nil {
+				if err := mcpKey.CheckResourceName(sessionIDStr); err != nil {
+					return nil, trace.Wrap(err)
+				}
+			}
+			fields, err := parseStringArrayArg(request.Params.Arguments["fields"])
+			if err != nil {
+				return nil, trace.Wrap(err, "invalid 'fields' parameter")
+			}
+
+			eventsCh, errCh := authClient.StreamSessionEvents(ctx, session.ID(sessionIDStr), 0)
+			var items []any
+		readLoop:
+			for {
+				select {
+				case evt, ok := <-eventsCh:
+					if !ok {
+						break readLoop
+					}
+					projected, err := projectFields(evt, fields)
+					if err != nil {
+						return nil, trace.Wrap(err)
+					}
+					items = append(items, projected)
+				case err := <-errCh:
+					if err != nil && !errors.Is(err, io.EOF) {
+						return nil, trace.Wrap(err, "streaming session events for %s", sessionIDStr)
+					}
+					break readLoop
+				}
+			}
+			return newInventoryToolResult(items, "")
+		},
+	)
+
 	return trace.Wrap(
 		server.NewStdioServer(mcpServer).Listen(cf.Context, cf.Stdin(), cf.Stdout()),
 	)
@@ -0,0 +1,59 @@
+/*
+ * Teleport
+ * Copyright (C) 2025  Gravitational, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"os"
+
+	"github.com/gravitational/trace"
+
+	"github.com/gravitational/teleport/lib/client"
+)
+
+// asciicastFormat is the `tsh play --format` value that exports a recording
+// as an asciinema v2 .cast file instead of replaying it to the terminal.
+const asciicastFormat = "asciicast"
+
+// onPlay implements `tsh play --format=asciicast <file>`: it opens the
+// argument as a local recording file and exports it as an asciinema .cast
+// file to stdout (or --out). Interactive terminal replay is not implemented
+// here: any other --format value returns a NotImplemented error.
+func onPlay(cf *CLIConf) error {
+	if cf.Format != asciicastFormat {
+		return trace.NotImplemented("tsh play only supports --format=%s in this build", asciicastFormat)
+	}
+
+	f, err := os.Open(cf.SessionID)
+	if err != nil {
+		return trace.ConvertSystemError(err)
+	}
+	defer f.Close()
+
+	out := cf.Stdout()
+	if cf.OutFile != "" {
+		outFile, err := os.Create(cf.OutFile)
+		if err != nil {
+			return trace.ConvertSystemError(err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+
+	return trace.Wrap(client.ExportAsciicast(cf.Context, f, out, cf.TerminalWidth, cf.TerminalHeight))
+}